@@ -19,6 +19,7 @@ import (
 	"encr.dev/internal/conf"
 	"encr.dev/internal/env"
 	"encr.dev/internal/goldfish"
+	"encr.dev/internal/version"
 	"encr.dev/pkg/appfile"
 	"encr.dev/pkg/fns"
 	"encr.dev/pkg/watcher"
@@ -372,7 +373,14 @@ func (i *Instance) Experiments(environ []string) (*experiments.Set, error) {
 		return nil, err
 	}
 
-	return experiments.FromAppFileAndEnviron(exp, environ)
+	set, err := experiments.FromAppFileAndEnviron(exp, environ)
+	if err != nil {
+		return nil, err
+	}
+	if err := set.CheckVersion(version.Version); err != nil {
+		return nil, err
+	}
+	return set, nil
 }
 
 func (i *Instance) Lang() appfile.Lang {
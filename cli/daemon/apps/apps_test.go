@@ -0,0 +1,34 @@
+package apps
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"encore.dev/appruntime/exported/experiments"
+	"encr.dev/internal/version"
+)
+
+func TestInstance_Experiments_Version(t *testing.T) {
+	c := qt.New(t)
+
+	root := c.TempDir()
+	appFile := `{"experiments": ["future-experiment>=v1.50.0"]}`
+	c.Assert(os.WriteFile(filepath.Join(root, "encore.app"), []byte(appFile), 0o644), qt.IsNil)
+
+	orig := version.Version
+	c.Cleanup(func() { version.Version = orig })
+	version.Version = "v1.45.0"
+
+	_, err := NewInstance(root, "local", "").Experiments(nil)
+	var verErr *experiments.VersionError
+	c.Assert(errors.As(err, &verErr), qt.IsTrue, qt.Commentf("got error %v", err))
+	c.Assert(verErr, qt.DeepEquals, &experiments.VersionError{
+		Name:       "future-experiment",
+		MinVersion: "v1.50.0",
+		Version:    "v1.45.0",
+	})
+}
@@ -78,6 +78,10 @@ type RuntimeConfigGenerator struct {
 	// Whether to include the metadata as an environment variable.
 	IncludeMetaEnv bool
 
+	// Whether ForTests stores buckets in memory rather than in
+	// the local object storage emulator.
+	MemoryBuckets bool
+
 	// The values of defined secrets.
 	DefinedSecrets map[string]string
 	// The configs, per service.
@@ -643,6 +647,9 @@ func (g *RuntimeConfigGenerator) ForTests(newRuntimeConf bool) (envs []string, e
 
 	var runtimeCfgStr string
 	if newRuntimeConf {
+		if g.MemoryBuckets {
+			return nil, errors.New("in-memory buckets are not supported by this runtime")
+		}
 		runtimeCfgBytes, err := proto.Marshal(conf)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to marshal runtime config")
@@ -658,6 +665,11 @@ func (g *RuntimeConfigGenerator) ForTests(newRuntimeConf bool) (envs []string, e
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to generate runtime config")
 		}
+		if g.MemoryBuckets {
+			for i := range runtimeCfg.BucketProviders {
+				runtimeCfg.BucketProviders[i] = &config.BucketProvider{Memory: &config.MemoryBucketProvider{}}
+			}
+		}
 		runtimeCfgBytes, err := json.Marshal(runtimeCfg)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to marshal runtime config")
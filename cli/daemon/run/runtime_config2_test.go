@@ -0,0 +1,103 @@
+package run
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"encore.dev/appruntime/exported/config"
+	"encr.dev/pkg/appfile"
+	"encr.dev/pkg/option"
+	meta "encr.dev/proto/encore/parser/meta/v1"
+)
+
+type fakeApp struct{}
+
+func (fakeApp) PlatformID() string                    { return "" }
+func (fakeApp) PlatformOrLocalID() string             { return "app" }
+func (fakeApp) GlobalCORS() (appfile.CORS, error)     { return appfile.CORS{}, nil }
+func (fakeApp) AppFile() (*appfile.File, error)       { return &appfile.File{}, nil }
+func (fakeApp) BuildSettings() (appfile.Build, error) { return appfile.Build{}, nil }
+
+// fakeInfra provides the config of the infrastructure of an app
+// with a local object storage emulator.
+type fakeInfra struct{}
+
+func (fakeInfra) SQLServerConfig() (config.SQLServer, error) { return config.SQLServer{}, nil }
+func (fakeInfra) PubSubProviderConfig() (config.PubsubProvider, error) {
+	return config.PubsubProvider{}, nil
+}
+func (fakeInfra) SQLDatabaseConfig(*meta.SQLDatabase) (config.SQLDatabase, error) {
+	return config.SQLDatabase{}, nil
+}
+func (fakeInfra) PubSubTopicConfig(*meta.PubSubTopic) (config.PubsubProvider, config.PubsubTopic, error) {
+	return config.PubsubProvider{}, config.PubsubTopic{}, nil
+}
+func (fakeInfra) PubSubSubscriptionConfig(*meta.PubSubTopic, *meta.PubSubTopic_Subscription) (config.PubsubSubscription, error) {
+	return config.PubsubSubscription{}, nil
+}
+func (fakeInfra) RedisConfig(*meta.CacheCluster) (config.RedisServer, config.RedisDatabase, error) {
+	return config.RedisServer{}, config.RedisDatabase{}, nil
+}
+func (fakeInfra) BucketProviderConfig() (config.BucketProvider, string, error) {
+	return config.BucketProvider{GCS: &config.GCSBucketProvider{Endpoint: "http://localhost:4443"}}, "http://localhost:4443", nil
+}
+
+func TestForTests_MemoryBuckets(t *testing.T) {
+	c := qt.New(t)
+
+	testRuntimeConfig := func(c *qt.C, memoryBuckets bool) *config.Runtime {
+		g := &RuntimeConfigGenerator{
+			app:          fakeApp{},
+			infraManager: fakeInfra{},
+			md: &meta.Data{
+				Svcs:    []*meta.Service{{Name: "svc"}},
+				Buckets: []*meta.Bucket{{Name: "bucket"}},
+			},
+			EnvID:         option.Some("test"),
+			MemoryBuckets: memoryBuckets,
+		}
+		envs, err := g.ForTests(false)
+		c.Assert(err, qt.IsNil)
+
+		for _, env := range envs {
+			if val, ok := strings.CutPrefix(env, runtimeCfgEnvVar+"="); ok {
+				data, err := base64.RawURLEncoding.DecodeString(val)
+				c.Assert(err, qt.IsNil)
+				var cfg config.Runtime
+				c.Assert(json.Unmarshal(data, &cfg), qt.IsNil)
+				return &cfg
+			}
+		}
+		c.Fatalf("no %s in %v", runtimeCfgEnvVar, envs)
+		return nil
+	}
+
+	c.Run("emulator", func(c *qt.C) {
+		cfg := testRuntimeConfig(c, false)
+		c.Assert(cfg.BucketProviders, qt.HasLen, 1)
+		c.Assert(cfg.BucketProviders[0].GCS, qt.IsNotNil)
+		c.Assert(cfg.BucketProviders[0].Memory, qt.IsNil)
+	})
+
+	c.Run("memory", func(c *qt.C) {
+		cfg := testRuntimeConfig(c, true)
+		c.Assert(cfg.BucketProviders, qt.DeepEquals, []*config.BucketProvider{
+			{Memory: &config.MemoryBucketProvider{}},
+		})
+	})
+
+	c.Run("new_runtime_config", func(c *qt.C) {
+		g := &RuntimeConfigGenerator{
+			app:           fakeApp{},
+			infraManager:  fakeInfra{},
+			md:            &meta.Data{Buckets: []*meta.Bucket{{Name: "bucket"}}},
+			MemoryBuckets: true,
+		}
+		_, err := g.ForTests(true)
+		c.Assert(err, qt.ErrorMatches, "in-memory buckets are not supported by this runtime")
+	})
+}
@@ -200,6 +200,7 @@ func (mgr *Manager) testSpec(ctx context.Context, bld builder.Impl, expSet *expe
 		EnvType:        option.Some(runtimev1.Environment_TYPE_TEST),
 		DeployID:       option.Some(fmt.Sprintf("clitest_%s", xid.New().String())),
 		IncludeMetaEnv: bld.NeedsMeta(),
+		MemoryBuckets:  experiments.MemoryBuckets.Enabled(expSet),
 	}
 
 	env, err := configGen.ForTests(bld.UseNewRuntimeConfig())
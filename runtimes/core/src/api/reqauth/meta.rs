@@ -27,6 +27,7 @@ pub enum MetaKey {
     SvcAuthMethod,
     SvcAuthEncoreAuthHash,
     SvcAuthEncoreAuthDate,
+    SvcAuthEncoreAuthNonce,
 }
 
 impl MetaKey {
@@ -44,6 +45,7 @@ impl MetaKey {
             SvcAuthMethod => "x-encore-meta-svc-auth-method",
             SvcAuthEncoreAuthHash => "x-encore-meta-svc-auth",
             SvcAuthEncoreAuthDate => "x-encore-meta-date",
+            SvcAuthEncoreAuthNonce => "x-encore-meta-svc-auth-nonce",
         }
     }
 }
@@ -67,6 +69,7 @@ impl FromStr for MetaKey {
             "x-encore-meta-svc-auth-method" => SvcAuthMethod,
             "x-encore-meta-svc-auth" => SvcAuthEncoreAuthHash,
             "x-encore-meta-date" => SvcAuthEncoreAuthDate,
+            "x-encore-meta-svc-auth-nonce" => SvcAuthEncoreAuthNonce,
             _ => return Err(NotMetaKey),
         })
     }
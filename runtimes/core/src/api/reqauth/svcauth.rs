@@ -1,5 +1,7 @@
+use std::collections::HashMap;
 use std::fmt::{Debug, Display};
-use std::time::SystemTime;
+use std::sync::Mutex;
+use std::time::{Duration, SystemTime};
 
 use anyhow::Context;
 use sha3::digest::Digest;
@@ -46,6 +48,9 @@ pub struct EncoreAuth {
     env_name: String,
     keys: Vec<EncoreAuthKey>,
     latest_idx: usize, // index into keys
+
+    // Nonces of verified requests, to reject replays.
+    nonces: Mutex<NonceCache>,
 }
 
 impl Debug for EncoreAuth {
@@ -80,10 +85,55 @@ impl EncoreAuth {
             env_name,
             keys,
             latest_idx,
+            nonces: Mutex::new(NonceCache::new(MAX_CLOCK_SKEW)),
         }
     }
 }
 
+/// The maximum difference between the time a request was signed
+/// and the time it is verified.
+const MAX_CLOCK_SKEW: Duration = Duration::from_secs(120);
+
+/// Keeps track of the nonces of verified requests so replays can be rejected.
+///
+/// Nonces only need to be remembered for as long as the request's timestamp
+/// is accepted, since older requests are rejected regardless.
+struct NonceCache {
+    window: Duration,
+    seen: HashMap<String, SystemTime>, // nonce -> expiry
+    last_prune: Option<SystemTime>,
+}
+
+impl NonceCache {
+    fn new(window: Duration) -> Self {
+        Self {
+            window,
+            seen: HashMap::new(),
+            last_prune: None,
+        }
+    }
+
+    /// Records the nonce of a request signed at the given time.
+    /// Reports false if the nonce has already been recorded.
+    fn add(&mut self, nonce: &str, signed_at: SystemTime, now: SystemTime) -> bool {
+        // Periodically remove expired nonces to bound memory usage.
+        let prune = match self.last_prune {
+            None => true,
+            Some(last) => now.duration_since(last).unwrap_or_default() >= self.window,
+        };
+        if prune {
+            self.seen.retain(|_, expiry| *expiry >= now);
+            self.last_prune = Some(now);
+        }
+
+        if self.seen.contains_key(nonce) {
+            return false;
+        }
+        self.seen.insert(nonce.to_string(), signed_at + self.window);
+        true
+    }
+}
+
 #[derive(Debug)]
 pub enum VerifyError {
     NoAuthorizationHeader,
@@ -91,6 +141,7 @@ pub enum VerifyError {
     InvalidHeader(encoreauth::InvalidSignature),
     SignatureMismatch,
     DateSkew,
+    Replayed,
     UnknownKey,
     ResolveKeyData(secrets::ResolveError),
 }
@@ -104,6 +155,7 @@ impl Display for VerifyError {
             InvalidHeader(e) => write!(f, "invalid header: {}", e),
             SignatureMismatch => write!(f, "signature mismatch"),
             DateSkew => write!(f, "date skew"),
+            Replayed => write!(f, "request replayed"),
             UnknownKey => write!(f, "unknown key"),
             ResolveKeyData(e) => write!(f, "unable to resolve secret key data: {}", e),
         }
@@ -155,7 +207,7 @@ impl ServiceAuthMethod for EncoreAuth {
         let diff = now
             .duration_since(components.timestamp)
             .unwrap_or_else(|e| e.duration());
-        if diff.as_secs() > 120 {
+        if diff > MAX_CLOCK_SKEW {
             return Err(VerifyError::DateSkew);
         }
 
@@ -187,6 +239,16 @@ impl ServiceAuthMethod for EncoreAuth {
             return Err(VerifyError::SignatureMismatch);
         }
 
+        // Finally make sure this request hasn't been seen before.
+        // The nonce is part of the operation hash, so it can't have been tampered with.
+        // Requests without a nonce are accepted, as callers only add one when configured to.
+        if let Some(nonce) = headers.get_meta(MetaKey::SvcAuthEncoreAuthNonce) {
+            let mut nonces = self.nonces.lock().unwrap_or_else(|e| e.into_inner());
+            if !nonces.add(nonce, components.timestamp, now) {
+                return Err(VerifyError::Replayed);
+            }
+        }
+
         Ok(())
     }
 }
@@ -207,7 +269,13 @@ impl EncoreAuth {
                     // by things like load balancers.
                 }
 
-                XCorrelationId | Version | UserId | UserData | Caller | Callee => {
+                XCorrelationId
+                | Version
+                | UserId
+                | UserData
+                | Caller
+                | Callee
+                | SvcAuthEncoreAuthNonce => {
                     // Read all values for this key, and sort them.
                     let mut values = req.meta_values(key).collect::<Vec<_>>();
                     values.sort();
@@ -274,6 +342,7 @@ mod tests {
                 data: Secret::new_for_test("secret data"),
             }],
             latest_idx: 0,
+            nonces: Mutex::new(NonceCache::new(MAX_CLOCK_SKEW)),
         };
 
         let now = SystemTime::UNIX_EPOCH + std::time::Duration::from_secs(1234567890);
@@ -297,4 +366,44 @@ mod tests {
 
         Ok(())
     }
+
+    #[test]
+    fn test_encore_auth_nonce() -> anyhow::Result<()> {
+        let auth = EncoreAuth::new(
+            "app".into(),
+            "env".into(),
+            vec![EncoreAuthKey {
+                key_id: 123,
+                data: Secret::new_for_test("secret data"),
+            }],
+        );
+
+        let now = SystemTime::UNIX_EPOCH + std::time::Duration::from_secs(1234567890);
+        let mut headers = reqwest::header::HeaderMap::new();
+        headers.set(MetaKey::SvcAuthEncoreAuthNonce, "nonce".into())?;
+        auth.sign(&mut headers, now)
+            .context("unable to sign request")?;
+        let out_headers = convert_header_map(headers);
+
+        // The nonce is covered by the signature.
+        let mut tampered = out_headers.clone();
+        tampered.insert(
+            MetaKey::SvcAuthEncoreAuthNonce.header_key(),
+            "other".parse().unwrap(),
+        );
+        assert!(matches!(
+            auth.verify(&tampered, now),
+            Err(VerifyError::SignatureMismatch)
+        ));
+
+        // The same request can only be verified once.
+        auth.verify(&out_headers, now)
+            .context("unable to verify request")?;
+        assert!(matches!(
+            auth.verify(&out_headers, now),
+            Err(VerifyError::Replayed)
+        ));
+
+        Ok(())
+    }
 }
@@ -274,6 +274,23 @@ func (s *Server) MetaFromRequest(req transport.Transport) (meta CallMeta, err er
 	return meta, nil
 }
 
+// isSvcAuthError reports whether err is due to a request failing
+// service to service authentication, as opposed to an internal error.
+func isSvcAuthError(err error) bool {
+	for _, target := range []error{
+		svcauth.ErrUnknownAuthMethod,
+		svcauth.ErrMissingAuthMeta,
+		svcauth.ErrSignatureInvalid,
+		svcauth.ErrRequestExpired,
+		svcauth.ErrRequestReplayed,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
 // parseTraceParent parses the trace and span ids from s, which is assumed
 // to be in the format of the traceparent header (see https://www.w3.org/TR/trace-context/).
 // If it's not a valid traceparent header it returns zero ids and ok == false.
@@ -653,7 +653,7 @@ func (d *Desc[Req, Resp]) runCall(c CallContext, req Req, mocked bool, executor
 
 		// Now round-trip any auth data that was set on the request
 		// to emulate what happens in the HTTP case.
-		if experiments.AuthDataRoundTrip.Enabled(c.server.experiments) && reqModel.RPCData.AuthData != nil {
+		if experiments.AuthDataRoundTrip.Enabled(c.server.experiments.For(d.Service)) && reqModel.RPCData.AuthData != nil {
 			jsonBytes, err := jsonapi.Default.Marshal(reqModel.RPCData.AuthData)
 			if err != nil {
 				c.server.rootLogger.Err(err).Msg("unable to marshal auth data")
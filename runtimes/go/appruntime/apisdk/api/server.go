@@ -27,6 +27,7 @@ import (
 	"encore.dev/appruntime/exported/experiments"
 	"encore.dev/appruntime/exported/model"
 	"encore.dev/appruntime/shared/cfgutil"
+	"encore.dev/appruntime/shared/cloud"
 	"encore.dev/appruntime/shared/cloudtrace"
 	"encore.dev/appruntime/shared/health"
 	"encore.dev/appruntime/shared/platform"
@@ -121,8 +122,8 @@ type Server struct {
 	private          *httprouter.Router
 	privateFallback  *httprouter.Router
 	encore           *httprouter.Router
-	inboundSvcAuth   map[string]svcauth.ServiceAuth // auth methods used to accept inbound service-to-service calls
-	outboundSvcAuth  map[string]svcauth.ServiceAuth // auth methods used to make outbound service-to-service calls
+	inboundSvcAuth   svcauth.Methods // auth methods used to accept inbound service-to-service calls
+	outboundSvcAuth  svcauth.Methods // auth methods used to make outbound service-to-service calls
 	httpsrv          *http.Server
 	httpCtx          context.Context
 	httpCtxCancel    context.CancelFunc
@@ -158,6 +159,20 @@ func NewServer(static *config.Static, runtime *config.Runtime, rt *reqtrack.Requ
 	if err != nil {
 		panic(fmt.Errorf("error loading service auth methods: %w", err))
 	}
+	if runtime.EnvCloud != cloud.Local && runtime.EnvType != "test" && svcauth.AcceptsUnauthenticated(inboundSvcAuth) {
+		rootLogger.Warn().Msg("service to service authentication is disabled (noop), internal calls are not verified")
+	}
+
+	exp := experiments.FromConfig(static, runtime)
+
+	// Dynamic experiments are enabled at runtime rather than at build time,
+	// so only the static experiments are compared with the build.
+	staticExp := experiments.FromConfig(static, nil)
+	if built := static.ExperimentsFingerprint; built != "" && built != staticExp.Fingerprint() {
+		rootLogger.Warn().
+			Strs("experiments", staticExp.StringList()).
+			Msg("the enabled experiments differ from those the app was built with")
+	}
 
 	s := &Server{
 		static:              static,
@@ -174,7 +189,7 @@ func NewServer(static *config.Static, runtime *config.Runtime, rt *reqtrack.Requ
 		rootLogger:          rootLogger,
 		json:                json,
 		tracingEnabled:      rt.TracingEnabled(),
-		experiments:         experiments.FromConfig(static, runtime),
+		experiments:         exp,
 		functionsToHandlers: make(map[uintptr]Handler),
 
 		public:           newRouter(),
@@ -516,7 +531,11 @@ func (s *Server) extractCallMeta(w http.ResponseWriter, req *http.Request) (upda
 	meta, err := s.MetaFromRequest(transport.HTTPRequest(req))
 	if err != nil {
 		s.rootLogger.Error().Err(err).Msg("failed to extract metadata from request")
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		status := http.StatusInternalServerError
+		if isSvcAuthError(err) {
+			status = http.StatusUnauthorized
+		}
+		http.Error(w, http.StatusText(status), status)
 		return nil, nil, false
 	}
 
@@ -0,0 +1,234 @@
+package svcauth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"golang.org/x/crypto/sha3"
+
+	"encore.dev/appruntime/apisdk/api/transport"
+	"encore.dev/beta/errs"
+)
+
+const edSignatureHeader = "Svc-Auth-Signature"
+const edKeyIDHeader = "Svc-Auth-Key-Id"
+
+// ed25519Auth is a ServiceAuth implementation that signs requests with the
+// calling service's Ed25519 private key, and verifies them using the public
+// keys of the services it accepts requests from. Unlike encoreAuth, verifying
+// services don't need to know any secret.
+type ed25519Auth struct {
+	appSlug string
+	envName string
+	clock   clock.Clock
+
+	// privateKey signs requests. It's nil if the service doesn't make calls.
+	privateKey ed25519.PrivateKey
+	keyID      string
+
+	// publicKeys are the keys requests are accepted from, keyed by key ID.
+	publicKeys map[string]ed25519.PublicKey
+
+	// maxClockSkew is the maximum age of (or time until) a request's
+	// signing timestamp for it to be accepted.
+	maxClockSkew time.Duration
+
+	// nonces tracks the nonces of verified requests to reject replays.
+	nonces *nonceCache
+}
+
+func newEd25519Auth(clock clock.Clock, appSlug, envName, privateKeyPEM string, publicKeysPEM []string, maxClockSkew time.Duration) (ServiceAuth, error) {
+	if maxClockSkew <= 0 {
+		maxClockSkew = DefaultMaxClockSkew
+	}
+	ea := &ed25519Auth{
+		appSlug:      appSlug,
+		envName:      envName,
+		clock:        clock,
+		publicKeys:   make(map[string]ed25519.PublicKey, len(publicKeysPEM)),
+		maxClockSkew: maxClockSkew,
+		nonces:       newNonceCache(clock, maxClockSkew),
+	}
+
+	if privateKeyPEM != "" {
+		key, err := ParseEd25519PrivateKey([]byte(privateKeyPEM))
+		if err != nil {
+			return nil, err
+		}
+		ea.privateKey = key
+		ea.keyID = ed25519KeyID(key.Public().(ed25519.PublicKey))
+	}
+	for _, data := range publicKeysPEM {
+		key, err := ParseEd25519PublicKey([]byte(data))
+		if err != nil {
+			return nil, err
+		}
+		ea.publicKeys[ed25519KeyID(key)] = key
+	}
+	return ea, nil
+}
+
+// ParseEd25519PrivateKey parses a PEM-encoded PKCS #8 Ed25519 private key,
+// as generated by "openssl genpkey -algorithm ed25519".
+func ParseEd25519PrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, errors.New("ed25519 private key: no PEM-encoded PRIVATE KEY block found")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("ed25519 private key: %w", err)
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("ed25519 private key: got a %T key, want ed25519", key)
+	}
+	return edKey, nil
+}
+
+// ParseEd25519PublicKey parses a PEM-encoded PKIX Ed25519 public key,
+// as generated by "openssl pkey -pubout".
+func ParseEd25519PublicKey(data []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, errors.New("ed25519 public key: no PEM-encoded PUBLIC KEY block found")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("ed25519 public key: %w", err)
+	}
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("ed25519 public key: got a %T key, want ed25519", key)
+	}
+	return edKey, nil
+}
+
+// ed25519KeyID returns the ID identifying a public key in signed requests.
+func ed25519KeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+func (ea *ed25519Auth) method() string {
+	return "ed25519"
+}
+
+func (ea *ed25519Auth) verify(req transport.Transport, params signParams) error {
+	sigStr, found := req.ReadMeta(edSignatureHeader)
+	if !found {
+		return fmt.Errorf("%w: no signature", ErrMissingAuthMeta)
+	}
+	dateStr, found := req.ReadMeta(ecDateHeader)
+	if !found {
+		return fmt.Errorf("%w: no date", ErrMissingAuthMeta)
+	}
+	keyID, _ := req.ReadMeta(edKeyIDHeader)
+
+	// First the timestamp, and don't do any work if it's too old or too new
+	timestamp, err := time.Parse(time.RFC3339, dateStr)
+	if err != nil {
+		return fmt.Errorf("%w: invalid date: %w", ErrSignatureInvalid, err)
+	}
+	if diff := ea.clock.Since(timestamp); diff > ea.maxClockSkew || diff < -ea.maxClockSkew {
+		return ErrRequestExpired
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(sigStr)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSignatureInvalid, err)
+	}
+
+	// The date, key ID and nonce are part of the signed payload,
+	// so they can't have been tampered with.
+	payload, err := ea.buildPayload(req, params)
+	if err != nil {
+		return err
+	}
+	if !ea.verifySignature(keyID, payload, sig) {
+		return fmt.Errorf("%w: signature mismatch", ErrSignatureInvalid)
+	}
+
+	// Finally make sure this request hasn't been seen before.
+	nonce, found := req.ReadMeta(ecNonceHeader)
+	if !found {
+		return fmt.Errorf("%w: no nonce", ErrMissingAuthMeta)
+	}
+	if !ea.nonces.add(nonce, timestamp) {
+		return ErrRequestReplayed
+	}
+
+	return nil
+}
+
+// verifySignature reports whether sig is a valid signature of payload by the
+// key with the given ID. Requests without a key ID are verified by trying
+// every key.
+func (ea *ed25519Auth) verifySignature(keyID string, payload, sig []byte) bool {
+	if keyID != "" {
+		key, found := ea.publicKeys[keyID]
+		return found && ed25519.Verify(key, payload, sig)
+	}
+	for _, key := range ea.publicKeys {
+		if ed25519.Verify(key, payload, sig) {
+			return true
+		}
+	}
+	return false
+}
+
+func (ea *ed25519Auth) sign(req transport.Transport, params signParams) error {
+	if ea.privateKey == nil {
+		return errors.New("no ed25519 private key configured")
+	}
+
+	// Add the auth metadata before building the payload so it's covered by the signature.
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return errs.B().Code(errs.Internal).Cause(err).Msg("failed to generate nonce").Err()
+	}
+	req.SetMeta(ecNonceHeader, base64.RawURLEncoding.EncodeToString(nonce[:]))
+	req.SetMeta(ecDateHeader, ea.clock.Now().UTC().Format(time.RFC3339))
+	req.SetMeta(edKeyIDHeader, ea.keyID)
+
+	payload, err := ea.buildPayload(req, params)
+	if err != nil {
+		return err
+	}
+	sig := ed25519.Sign(ea.privateKey, payload)
+	req.SetMeta(edSignatureHeader, base64.RawURLEncoding.EncodeToString(sig))
+	return nil
+}
+
+// buildPayload builds the digest of the request that is signed.
+// If params.bodyHash is non-empty it's included in the digest,
+// and only the metadata covered by params is included.
+func (ea *ed25519Auth) buildPayload(req transport.Transport, params signParams) ([]byte, error) {
+	hash := sha3.New256()
+	if _, err := fmt.Fprintf(hash, "encore-svcauth-ed25519\n%s\n%s\n", ea.appSlug, ea.envName); err != nil {
+		return nil, errs.B().Code(errs.Internal).Cause(err).Msg("failed to write to hash").Err()
+	}
+	if err := writeSignedMeta(hash, req, params, map[string]bool{
+		edSignatureHeader: false,
+		ecDateHeader:      true,
+		ecNonceHeader:     true,
+		edKeyIDHeader:     true,
+	}); err != nil {
+		return nil, err
+	}
+	if len(params.bodyHash) > 0 {
+		if _, err := fmt.Fprintf(hash, "body=%x\n", params.bodyHash); err != nil {
+			return nil, errs.B().Code(errs.Internal).Cause(err).Msg("failed to write to hash").Err()
+		}
+	}
+	return hash.Sum(nil), nil
+}
@@ -0,0 +1,231 @@
+package svcauth_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+
+	"encore.dev/appruntime/apisdk/api/svcauth"
+	"encore.dev/appruntime/apisdk/api/svcauth/svcauthtest"
+	"encore.dev/appruntime/apisdk/api/transport"
+	"encore.dev/appruntime/exported/config"
+)
+
+// ed25519Keys generates an Ed25519 key pair, returning them PEM-encoded.
+func ed25519Keys(t *testing.T) (privateKey, publicKey string) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}))
+}
+
+func loadEd25519(t *testing.T, klock clock.Clock, privateKey string, publicKeys ...string) svcauth.Methods {
+	t.Helper()
+	methods, _, err := svcauth.LoadMethods(klock, &config.Runtime{
+		AppSlug: "app",
+		EnvName: "env",
+		ServiceAuth: []config.ServiceAuth{{
+			Method:     "ed25519",
+			PrivateKey: privateKey,
+			PublicKeys: publicKeys,
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return methods
+}
+
+func TestEd25519_RoundTrip(t *testing.T) {
+	priv, pub := ed25519Keys(t)
+	klock := clock.NewMock()
+
+	req, err := http.NewRequest("POST", "http://service/endpoint", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := transport.HTTPRequest(req)
+	tr.SetMeta("Caller", "svc.Endpoint")
+	svcauthtest.TestRoundTrip(t, func() svcauth.ServiceAuth {
+		return loadEd25519(t, klock, priv, pub)["ed25519"]
+	}, tr)
+}
+
+func TestEd25519_Verify(t *testing.T) {
+	klock := clock.NewMock()
+	callerPriv, callerPub := ed25519Keys(t)
+	otherPriv, _ := ed25519Keys(t)
+
+	caller := loadEd25519(t, klock, callerPriv)
+	other := loadEd25519(t, klock, otherPriv)
+	verifier := loadEd25519(t, klock, "", callerPub)
+
+	bodyHash := func(body string) []byte {
+		sum := sha256.Sum256([]byte(body))
+		return sum[:]
+	}
+
+	tests := []struct {
+		name     string
+		signer   svcauth.Methods
+		signBody []byte
+		modify   func(tr transport.Transport)
+		wantErr  error
+	}{
+		{name: "trusted_key", signer: caller},
+		{name: "untrusted_key", signer: other, wantErr: svcauth.ErrSignatureInvalid},
+		{name: "with_body", signer: caller, signBody: bodyHash("body")},
+		{
+			name:    "modified_request",
+			signer:  caller,
+			modify:  func(tr transport.Transport) { tr.SetMeta("Caller", "other") },
+			wantErr: svcauth.ErrSignatureInvalid,
+		},
+		{
+			name:    "missing_signature",
+			signer:  caller,
+			modify:  func(tr transport.Transport) { tr.SetMeta("Svc-Auth-Signature", "") },
+			wantErr: svcauth.ErrMissingAuthMeta,
+		},
+		{
+			name:    "unknown_key_id",
+			signer:  caller,
+			modify:  func(tr transport.Transport) { tr.SetMeta("Svc-Auth-Key-Id", "0123456789abcdef") },
+			wantErr: svcauth.ErrSignatureInvalid,
+		},
+		{
+			name:   "modified_date",
+			signer: caller,
+			modify: func(tr transport.Transport) {
+				tr.SetMeta("Date", klock.Now().UTC().Add(-time.Second).Format(time.RFC3339))
+			},
+			wantErr: svcauth.ErrSignatureInvalid,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "http://service/endpoint", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tr := transport.HTTPRequest(req)
+			tr.SetMeta("Caller", "svc.Endpoint")
+			if err := svcauth.SignWithBody(test.signer["ed25519"], tr, test.signBody); err != nil {
+				t.Fatal(err)
+			}
+			if test.modify != nil {
+				test.modify(tr)
+			}
+
+			_, err = svcauth.VerifyWithBody(tr, verifier, test.signBody)
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("got err %v, want %v", err, test.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	// Services without a private key can't sign requests.
+	req, err := http.NewRequest("POST", "http://service/endpoint", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svcauth.Sign(verifier["ed25519"], transport.HTTPRequest(req)); err == nil {
+		t.Fatal("got nil err, want error signing without a private key")
+	}
+}
+
+func TestParseEd25519Keys(t *testing.T) {
+	priv, pub := ed25519Keys(t)
+	if _, err := svcauth.ParseEd25519PrivateKey([]byte(priv)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svcauth.ParseEd25519PublicKey([]byte(pub)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Public and private keys can't be mixed up.
+	if _, err := svcauth.ParseEd25519PrivateKey([]byte(pub)); err == nil {
+		t.Error("parsed public key as a private key")
+	}
+	if _, err := svcauth.ParseEd25519PublicKey([]byte(priv)); err == nil {
+		t.Error("parsed private key as a public key")
+	}
+	if _, err := svcauth.ParseEd25519PublicKey([]byte("not pem")); err == nil {
+		t.Error("parsed invalid PEM")
+	}
+
+	// Other key types are rejected.
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecDER, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: ecDER})
+	if _, err := svcauth.ParseEd25519PrivateKey(ecPEM); err == nil {
+		t.Error("parsed ECDSA key as an Ed25519 key")
+	}
+
+	// Invalid keys are reported when loading the methods.
+	_, _, err = svcauth.LoadMethods(clock.NewMock(), &config.Runtime{
+		ServiceAuth: []config.ServiceAuth{{Method: "ed25519", PublicKeys: []string{"not pem"}}},
+	})
+	if err == nil {
+		t.Error("loaded ed25519 method with an invalid public key")
+	}
+}
+
+func TestEd25519_ReplayAndExpiry(t *testing.T) {
+	klock := clock.NewMock()
+	priv, pub := ed25519Keys(t)
+	caller := loadEd25519(t, klock, priv)["ed25519"]
+	verifier := loadEd25519(t, klock, "", pub)
+
+	req, err := http.NewRequest("POST", "http://service/endpoint", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := transport.HTTPRequest(req)
+	if err := svcauth.Sign(caller, tr); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svcauth.Verify(tr, verifier); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svcauth.Verify(tr, verifier); !errors.Is(err, svcauth.ErrRequestReplayed) {
+		t.Fatalf("got err %v, want ErrRequestReplayed", err)
+	}
+
+	// Requests are only accepted within the allowed clock skew.
+	klock.Add(svcauth.DefaultMaxClockSkew + time.Second)
+	if _, err := svcauth.Verify(tr, verifier); !errors.Is(err, svcauth.ErrRequestExpired) {
+		t.Fatalf("got err %v, want ErrRequestExpired", err)
+	}
+}
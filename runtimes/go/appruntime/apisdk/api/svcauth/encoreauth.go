@@ -1,7 +1,11 @@
 package svcauth
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
 	"fmt"
+	"io"
 	"sort"
 	"time"
 
@@ -16,17 +20,35 @@ import (
 
 const ecAuthHashHeader = "Svc-Auth"
 const ecDateHeader = "Date"
+const ecNonceHeader = "Svc-Auth-Nonce"
+
+// DefaultMaxClockSkew is the default maximum difference between the time
+// a request was signed and the time it is verified.
+const DefaultMaxClockSkew = 2 * time.Minute
 
 // encoreAuth is a ServiceAuth implementation that uses the Encore auth package to sign requests.
 type encoreAuth struct {
-	appSlug   string
-	envName   string
+	appSlug string
+	envName string
+	clock   clock.Clock
+
+	// To support key rotation, requests are signed using latestKey
+	// (the key with the highest ID), while requests signed with any of keys are accepted.
 	keys      []auth.Key
 	latestKey auth.Key
-	clock     clock.Clock
+
+	// maxClockSkew is the maximum age of (or time until) a request's
+	// signing timestamp for it to be accepted.
+	maxClockSkew time.Duration
+
+	// signNonce reports whether signed requests include a nonce.
+	signNonce bool
+
+	// nonces tracks the nonces of verified requests to reject replays.
+	nonces *nonceCache
 }
 
-func newEncoreAuth(clock clock.Clock, appSlug string, envName string, keys []config.EncoreAuthKey) ServiceAuth {
+func newEncoreAuth(clock clock.Clock, appSlug string, envName string, keys []config.EncoreAuthKey, maxClockSkew time.Duration, signNonce bool) ServiceAuth {
 	var keySet []auth.Key
 	var latestKey auth.Key
 	for _, key := range keys {
@@ -42,12 +64,19 @@ func newEncoreAuth(clock clock.Clock, appSlug string, envName string, keys []con
 		}
 	}
 
+	if maxClockSkew <= 0 {
+		maxClockSkew = DefaultMaxClockSkew
+	}
+
 	return &encoreAuth{
-		appSlug:   appSlug,
-		envName:   envName,
-		keys:      keySet,
-		latestKey: latestKey,
-		clock:     clock,
+		appSlug:      appSlug,
+		envName:      envName,
+		keys:         keySet,
+		latestKey:    latestKey,
+		clock:        clock,
+		maxClockSkew: maxClockSkew,
+		signNonce:    signNonce,
+		nonces:       newNonceCache(clock, maxClockSkew),
 	}
 }
 
@@ -55,28 +84,27 @@ func (ea *encoreAuth) method() string {
 	return "encore-auth"
 }
 
-func (ea *encoreAuth) verify(req transport.Transport) error {
+func (ea *encoreAuth) verify(req transport.Transport, params signParams) error {
 	headers := &auth.Headers{}
 	if authStr, found := req.ReadMeta(ecAuthHashHeader); !found {
-		return auth.ErrNoAuthorizationHeader
+		return fmt.Errorf("%w: %w", ErrMissingAuthMeta, auth.ErrNoAuthorizationHeader)
 	} else {
 		headers.Authorization = authStr
 	}
 	if dateStr, found := req.ReadMeta(ecDateHeader); !found {
-		return auth.ErrNoDateHeader
+		return fmt.Errorf("%w: %w", ErrMissingAuthMeta, auth.ErrNoDateHeader)
 	} else {
 		headers.Date = dateStr
 	}
 
 	keyID, appSlug, envName, timestamp, opHash, err := headers.SigningComponents()
 	if err != nil {
-		return err
+		return fmt.Errorf("%w: %w", ErrSignatureInvalid, err)
 	}
 
 	// First the timestamp, and don't do any work if it's too old or too new
-	const allowedClockSkew = 2 * time.Minute
-	if diff := ea.clock.Since(timestamp); diff > allowedClockSkew || diff < -allowedClockSkew {
-		return auth.ErrAuthenticationExpired
+	if diff := ea.clock.Since(timestamp); diff > ea.maxClockSkew || diff < -ea.maxClockSkew {
+		return ErrRequestExpired
 	}
 
 	// Find the key
@@ -88,33 +116,42 @@ func (ea *encoreAuth) verify(req transport.Transport) error {
 		}
 	}
 	if key.KeyID == 0 {
-		return auth.ErrAuthenticationFailed
+		return fmt.Errorf("%w: %w", ErrSignatureInvalid, auth.ErrAuthenticationFailed)
 	}
 
 	// Rebuild the signature
 	expectedHeaders := auth.SignForVerification(&key, appSlug, envName, timestamp, opHash)
 
-	// Verify the signature
+	// Verify the signature. Headers.Equal compares the MACs using hmac.Equal,
+	// so the comparison does not leak how much of the signature matched.
 	if !expectedHeaders.Equal(headers) {
-		return auth.ErrAuthenticationFailed
+		return fmt.Errorf("%w: %w", ErrSignatureInvalid, auth.ErrAuthenticationFailed)
 	}
 
 	// Now we're verified the signature - now let's compare the OpHash received
 	// against the OpHash we would have generated for this request.
 	// We do this here to minimize the risk of timing attacks.
-	expectedOpHash, err := ea.buildOpHash(req)
+	expectedOpHash, err := ea.buildOpHash(req, params)
 	if err != nil {
 		return err
 	}
-	if expectedOpHash != opHash {
-		return auth.ErrAuthenticationFailed
+	if subtle.ConstantTimeCompare([]byte(expectedOpHash), []byte(opHash)) != 1 {
+		return fmt.Errorf("%w: %w", ErrSignatureInvalid, auth.ErrAuthenticationFailed)
+	}
+
+	// Finally make sure this request hasn't been seen before.
+	// The nonce is part of the operation hash, so it can't have been tampered with.
+	// Requests without a nonce are accepted, as callers only add one
+	// when configured to (and the Rust runtime doesn't add one yet).
+	if nonce, found := req.ReadMeta(ecNonceHeader); found && !ea.nonces.add(nonce, timestamp) {
+		return ErrRequestReplayed
 	}
 
 	return nil
 }
 
-func (ea *encoreAuth) sign(req transport.Transport) error {
-	opHash, err := ea.buildOpHash(req)
+func (ea *encoreAuth) sign(req transport.Transport, params signParams) error {
+	opHash, err := ea.prepareSign(req, params)
 	if err != nil {
 		return err
 	}
@@ -126,39 +163,89 @@ func (ea *encoreAuth) sign(req transport.Transport) error {
 	return nil
 }
 
+// prepareSign adds a nonce to the request if configured to,
+// and returns its operation hash, ready to be signed.
+func (ea *encoreAuth) prepareSign(req transport.Transport, params signParams) (auth.OperationHash, error) {
+	// Add the nonce before building the operation hash so it's covered by the signature.
+	if ea.signNonce {
+		var nonce [16]byte
+		if _, err := rand.Read(nonce[:]); err != nil {
+			return "", errs.B().Code(errs.Internal).Cause(err).Msg("failed to generate nonce").Err()
+		}
+		req.SetMeta(ecNonceHeader, base64.RawURLEncoding.EncodeToString(nonce[:]))
+	}
+
+	return ea.buildOpHash(req, params)
+}
+
 // buildOpHash builds the operation hash for the request.
-func (ea *encoreAuth) buildOpHash(req transport.Transport) (auth.OperationHash, error) {
-	// Build a deterministic hash of the meta keys and values
+// If params.bodyHash is non-empty it's included in the hash,
+// and only the metadata covered by params is included.
+func (ea *encoreAuth) buildOpHash(req transport.Transport, params signParams) (auth.OperationHash, error) {
+	// Build a deterministic hash of the meta keys and values.
+	// The date is covered by the auth headers themselves.
 	hash := sha3.New256()
+	if err := writeSignedMeta(hash, req, params, map[string]bool{
+		ecAuthHashHeader: false,
+		ecDateHeader:     false,
+		ecNonceHeader:    true,
+	}); err != nil {
+		return "", err
+	}
+
+	// Generate the operation hash
+	var additionalContext [][]byte
+	if len(params.bodyHash) > 0 {
+		additionalContext = append(additionalContext, params.bodyHash)
+	}
+	opHash, err := auth.NewOperationHash("internal-api", "call", auth.BytesPayload(hash.Sum(nil)), additionalContext...)
+	if err != nil {
+		return "", errs.B().Code(errs.Internal).Cause(err).Msg("failed to create operation hash for internal API call").Err()
+	}
+	return opHash, nil
+}
+
+// writeSignedMeta writes the metadata of req covered by the signature to w,
+// in a deterministic order.
+//
+// authKeys are the keys used by the authentication method itself,
+// mapping to whether they're always covered by the signature
+// or never are, regardless of params.
+func writeSignedMeta(w io.Writer, req transport.Transport, params signParams, authKeys map[string]bool) error {
 	for _, key := range req.ListMetaKeys() {
-		switch key {
-		case AuthMethodMetaKey, ecAuthHashHeader, ecDateHeader:
-			// Skip these headers, as they are part of the auth mechanism itself
+		signed, isAuthKey := authKeys[key]
+		switch {
+		case key == AuthMethodMetaKey:
+			// Skip the method, as it's used to select the auth mechanism
 
-		case transport.TraceParentKey, transport.TraceStateKey:
+		case key == transport.TraceParentKey, key == transport.TraceStateKey:
 			// Skip these headers, as they are part of the tracing mechanism and could be changed
 			// by things like load balancers
 
-		default:
-			// Read all values for this key, and sort them
-			values, found := req.ReadMetaValues(key)
-			if !found {
-				return "", errs.B().Code(errs.Internal).Msg("failed to read metadata value").Err()
-			}
-			sort.Strings(values)
+		case isAuthKey && !signed:
+			// Skip these headers, as they are part of the auth mechanism itself
 
-			for _, value := range values {
-				if _, err := fmt.Fprintf(hash, "%s=%s\n", key, value); err != nil {
-					return "", errs.B().Code(errs.Internal).Cause(err).Msg("failed to write to hash").Err()
-				}
+		case isAuthKey || params.covers(key):
+			if err := writeMetaValues(w, req, key); err != nil {
+				return err
 			}
 		}
 	}
+	return nil
+}
 
-	// Generate the operation hash
-	opHash, err := auth.NewOperationHash("internal-api", "call", auth.BytesPayload(hash.Sum(nil)))
-	if err != nil {
-		return "", errs.B().Code(errs.Internal).Cause(err).Msg("failed to create operation hash for internal API call").Err()
+// writeMetaValues writes the sorted values of the metadata key to w.
+func writeMetaValues(w io.Writer, req transport.Transport, key string) error {
+	values, found := req.ReadMetaValues(key)
+	if !found {
+		return errs.B().Code(errs.Internal).Msg("failed to read metadata value").Err()
 	}
-	return opHash, nil
+	sort.Strings(values)
+
+	for _, value := range values {
+		if _, err := fmt.Fprintf(w, "%s=%s\n", key, value); err != nil {
+			return errs.B().Code(errs.Internal).Cause(err).Msg("failed to write to hash").Err()
+		}
+	}
+	return nil
 }
@@ -0,0 +1,258 @@
+package svcauth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/golang-jwt/jwt/v4"
+	"golang.org/x/crypto/sha3"
+
+	"encore.dev/appruntime/apisdk/api/transport"
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/beta/errs"
+)
+
+const jwtTokenHeader = "Svc-Auth-Token"
+
+// jwtAuth is a ServiceAuth implementation that authenticates requests
+// using a signed JWT, for interoperability with systems that issue them.
+type jwtAuth struct {
+	clock clock.Clock
+	alg   jwt.SigningMethod
+
+	// signKey signs tokens, and verifyKeys are the keys tokens are accepted from.
+	// For HS256 both are the shared secret. signKey is nil if there's no key to sign with.
+	signKey    any
+	signKeyID  string // the "kid" of signed tokens, if any
+	verifyKeys []jwtKey
+
+	issuer   string
+	audience string
+	ttl      time.Duration
+	leeway   time.Duration
+
+	// nonces tracks the IDs of verified tokens signed by jwtAuth to reject replays.
+	// Tokens issued by other systems can be replayed until they expire.
+	nonces *nonceCache
+}
+
+// jwtKey is a key tokens are accepted from.
+type jwtKey struct {
+	id  string // the "kid" of tokens signed with the key, if known
+	key any
+}
+
+// jwtClaims are the claims of a token signed by jwtAuth.
+type jwtClaims struct {
+	jwt.RegisteredClaims
+
+	// RequestHash binds the token to the request it was sent with.
+	// It's not set in tokens issued by other systems.
+	RequestHash string `json:"encore_req,omitempty"`
+}
+
+func newJWTAuth(clock clock.Clock, cfg *config.JWTServiceAuth) (ServiceAuth, error) {
+	if cfg == nil {
+		return nil, errors.New("jwt: missing configuration")
+	}
+	ja := &jwtAuth{
+		clock:    clock,
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+		ttl:      cfg.TTL,
+		leeway:   cfg.Leeway,
+	}
+	if ja.ttl <= 0 {
+		ja.ttl = DefaultMaxClockSkew
+	}
+	// Token IDs are remembered past their expiry, as long as they're accepted.
+	ja.nonces = newNonceCache(clock, ja.ttl+ja.leeway)
+
+	switch cfg.Algorithm {
+	case "HS256":
+		if len(cfg.Secret) == 0 {
+			return nil, errors.New("jwt: HS256 requires a secret")
+		}
+		ja.alg = jwt.SigningMethodHS256
+		ja.signKey = cfg.Secret
+		ja.verifyKeys = []jwtKey{{key: cfg.Secret}}
+
+	case "RS256":
+		ja.alg = jwt.SigningMethodRS256
+		if cfg.PrivateKey != "" {
+			key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(cfg.PrivateKey))
+			if err != nil {
+				return nil, fmt.Errorf("jwt: rsa private key: %w", err)
+			}
+			ja.signKey = key
+			if ja.signKeyID, err = rsaKeyID(&key.PublicKey); err != nil {
+				return nil, fmt.Errorf("jwt: rsa private key: %w", err)
+			}
+		}
+		for _, data := range cfg.PublicKeys {
+			key, err := jwt.ParseRSAPublicKeyFromPEM([]byte(data))
+			if err != nil {
+				return nil, fmt.Errorf("jwt: rsa public key: %w", err)
+			}
+			id, err := rsaKeyID(key)
+			if err != nil {
+				return nil, fmt.Errorf("jwt: rsa public key: %w", err)
+			}
+			ja.verifyKeys = append(ja.verifyKeys, jwtKey{id: id, key: key})
+		}
+
+	default:
+		return nil, fmt.Errorf("jwt: unsupported algorithm %q", cfg.Algorithm)
+	}
+	return ja, nil
+}
+
+func (ja *jwtAuth) method() string {
+	return "jwt"
+}
+
+func (ja *jwtAuth) verify(req transport.Transport, params signParams) error {
+	tokenStr, found := req.ReadMeta(jwtTokenHeader)
+	if !found {
+		return fmt.Errorf("%w: no token", ErrMissingAuthMeta)
+	}
+
+	// Only accept the configured algorithm, so a token can't pick a weaker one.
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{ja.alg.Alg()}), jwt.WithoutClaimsValidation())
+	var (
+		claims jwtClaims
+		err    = errors.New("no keys to verify with")
+	)
+	for _, k := range ja.candidateKeys(parser, tokenStr) {
+		claims = jwtClaims{}
+		if _, err = parser.ParseWithClaims(tokenStr, &claims, func(*jwt.Token) (any, error) { return k.key, nil }); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSignatureInvalid, err)
+	}
+
+	now := ja.clock.Now()
+	if !claims.VerifyExpiresAt(now.Add(-ja.leeway), true) || !claims.VerifyNotBefore(now.Add(ja.leeway), false) {
+		return ErrRequestExpired
+	}
+	if ja.issuer != "" && !claims.VerifyIssuer(ja.issuer, true) {
+		return fmt.Errorf("%w: unexpected issuer %q", ErrSignatureInvalid, claims.Issuer)
+	}
+	if ja.audience != "" && !claims.VerifyAudience(ja.audience, true) {
+		return fmt.Errorf("%w: unexpected audience", ErrSignatureInvalid)
+	}
+
+	// Tokens issued by other systems aren't bound to the request,
+	// but those bound to a body must be.
+	if claims.RequestHash != "" || len(params.bodyHash) > 0 {
+		expected, err := ja.requestHash(req, params)
+		if err != nil {
+			return err
+		}
+		if claims.RequestHash != expected {
+			return fmt.Errorf("%w: token was issued for another request", ErrSignatureInvalid)
+		}
+	}
+
+	// Finally make sure this token hasn't been seen before, if it was signed
+	// by jwtAuth for a single request. Tokens issued by other systems may
+	// have an ID while still being meant to be reused, so they're not checked.
+	if claims.RequestHash != "" && !ja.nonces.add(claims.ID, claims.ExpiresAt.Time) {
+		return ErrRequestReplayed
+	}
+
+	return nil
+}
+
+func (ja *jwtAuth) sign(req transport.Transport, params signParams) error {
+	if ja.signKey == nil {
+		return errors.New("no jwt signing key configured")
+	}
+
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return errs.B().Code(errs.Internal).Cause(err).Msg("failed to generate token id").Err()
+	}
+	reqHash, err := ja.requestHash(req, params)
+	if err != nil {
+		return err
+	}
+
+	now := ja.clock.Now()
+	claims := jwtClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        base64.RawURLEncoding.EncodeToString(id[:]),
+			Issuer:    ja.issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ja.ttl)),
+		},
+		RequestHash: reqHash,
+	}
+	if ja.audience != "" {
+		claims.Audience = jwt.ClaimStrings{ja.audience}
+	}
+
+	t := jwt.NewWithClaims(ja.alg, claims)
+	if ja.signKeyID != "" {
+		t.Header["kid"] = ja.signKeyID
+	}
+	token, err := t.SignedString(ja.signKey)
+	if err != nil {
+		return errs.B().Code(errs.Internal).Cause(err).Msg("failed to sign token").Err()
+	}
+	req.SetMeta(jwtTokenHeader, token)
+	return nil
+}
+
+// candidateKeys returns the keys that may have signed the token.
+// If the token's "kid" identifies one of the keys only that key is returned,
+// and otherwise all keys are, for tokens without one.
+func (ja *jwtAuth) candidateKeys(parser *jwt.Parser, tokenStr string) []jwtKey {
+	if len(ja.verifyKeys) > 1 {
+		if token, _, err := parser.ParseUnverified(tokenStr, &jwtClaims{}); err == nil {
+			if kid, ok := token.Header["kid"].(string); ok && kid != "" {
+				for _, k := range ja.verifyKeys {
+					if k.id == kid {
+						return []jwtKey{k}
+					}
+				}
+			}
+		}
+	}
+	return ja.verifyKeys
+}
+
+// rsaKeyID returns the "kid" identifying an RSA public key in signed tokens.
+func rsaKeyID(key *rsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// requestHash returns the hash binding a token to the request's metadata and body.
+func (ja *jwtAuth) requestHash(req transport.Transport, params signParams) (string, error) {
+	hash := sha3.New256()
+	if err := writeSignedMeta(hash, req, params, map[string]bool{jwtTokenHeader: false}); err != nil {
+		return "", err
+	}
+	if len(params.bodyHash) > 0 {
+		if _, err := fmt.Fprintf(hash, "body=%x\n", params.bodyHash); err != nil {
+			return "", errs.B().Code(errs.Internal).Cause(err).Msg("failed to write to hash").Err()
+		}
+	}
+	return base64.RawURLEncoding.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,309 @@
+package svcauth_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/golang-jwt/jwt/v4"
+
+	"encore.dev/appruntime/apisdk/api/svcauth"
+	"encore.dev/appruntime/apisdk/api/svcauth/svcauthtest"
+	"encore.dev/appruntime/apisdk/api/transport"
+	"encore.dev/appruntime/exported/config"
+)
+
+func loadJWT(t *testing.T, klock clock.Clock, cfg config.JWTServiceAuth) svcauth.ServiceAuth {
+	t.Helper()
+	methods, _, err := svcauth.LoadMethods(klock, &config.Runtime{
+		ServiceAuth: []config.ServiceAuth{{Method: "jwt", JWT: &cfg}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return methods["jwt"]
+}
+
+func newJWTReq(t *testing.T) transport.Transport {
+	t.Helper()
+	req, err := http.NewRequest("POST", "http://service/endpoint", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := transport.HTTPRequest(req)
+	tr.SetMeta("Caller", "svc.Endpoint")
+	return tr
+}
+
+func TestJWT_RoundTrip(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privDER := x509.MarshalPKCS1PrivateKey(rsaKey)
+	pubDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cfg := range []config.JWTServiceAuth{
+		{Algorithm: "HS256", Secret: []byte("secret"), Issuer: "app", Audience: "svc"},
+		{
+			Algorithm:  "RS256",
+			PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: privDER})),
+			PublicKeys: []string{string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}))},
+		},
+	} {
+		t.Run(cfg.Algorithm, func(t *testing.T) {
+			klock := clock.NewMock()
+			svcauthtest.TestRoundTrip(t, func() svcauth.ServiceAuth {
+				return loadJWT(t, klock, cfg)
+			}, newJWTReq(t))
+		})
+	}
+}
+
+func TestJWT_Verify(t *testing.T) {
+	klock := clock.NewMock()
+	klock.Set(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	cfg := config.JWTServiceAuth{
+		Algorithm: "HS256",
+		Secret:    []byte("secret"),
+		Issuer:    "app",
+		Audience:  "svc",
+		TTL:       time.Minute,
+		Leeway:    10 * time.Second,
+	}
+	method := loadJWT(t, klock, cfg)
+	methods := svcauth.Methods{"jwt": method}
+
+	// external signs a token the way another system would,
+	// without binding it to the request.
+	external := func(claims jwt.RegisteredClaims) transport.Transport {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tr := newJWTReq(t)
+		tr.SetMeta(svcauth.AuthMethodMetaKey, "jwt")
+		tr.SetMeta("Svc-Auth-Token", token)
+		return tr
+	}
+	valid := jwt.RegisteredClaims{
+		Issuer:    "app",
+		Audience:  jwt.ClaimStrings{"svc"},
+		ExpiresAt: jwt.NewNumericDate(klock.Now().Add(time.Minute)),
+	}
+
+	tests := []struct {
+		name    string
+		req     func() transport.Transport
+		wantErr error
+	}{
+		{name: "external_token", req: func() transport.Transport { return external(valid) }},
+		{
+			name: "expired_within_leeway",
+			req: func() transport.Transport {
+				c := valid
+				c.ExpiresAt = jwt.NewNumericDate(klock.Now().Add(-5 * time.Second))
+				return external(c)
+			},
+		},
+		{
+			name: "expired",
+			req: func() transport.Transport {
+				c := valid
+				c.ExpiresAt = jwt.NewNumericDate(klock.Now().Add(-time.Minute))
+				return external(c)
+			},
+			wantErr: svcauth.ErrRequestExpired,
+		},
+		{
+			name: "not_yet_valid",
+			req: func() transport.Transport {
+				c := valid
+				c.NotBefore = jwt.NewNumericDate(klock.Now().Add(time.Minute))
+				return external(c)
+			},
+			wantErr: svcauth.ErrRequestExpired,
+		},
+		{
+			name: "no_expiry",
+			req: func() transport.Transport {
+				c := valid
+				c.ExpiresAt = nil
+				return external(c)
+			},
+			wantErr: svcauth.ErrRequestExpired,
+		},
+		{
+			name: "wrong_issuer",
+			req: func() transport.Transport {
+				c := valid
+				c.Issuer = "other"
+				return external(c)
+			},
+			wantErr: svcauth.ErrSignatureInvalid,
+		},
+		{
+			name: "wrong_audience",
+			req: func() transport.Transport {
+				c := valid
+				c.Audience = jwt.ClaimStrings{"other"}
+				return external(c)
+			},
+			wantErr: svcauth.ErrSignatureInvalid,
+		},
+		{
+			name: "wrong_algorithm",
+			req: func() transport.Transport {
+				token, err := jwt.NewWithClaims(jwt.SigningMethodHS512, valid).SignedString([]byte("secret"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				tr := external(valid)
+				tr.SetMeta("Svc-Auth-Token", token)
+				return tr
+			},
+			wantErr: svcauth.ErrSignatureInvalid,
+		},
+		{
+			name: "modified_request",
+			req: func() transport.Transport {
+				tr := newJWTReq(t)
+				if err := svcauth.Sign(method, tr); err != nil {
+					t.Fatal(err)
+				}
+				tr.SetMeta("Caller", "other")
+				return tr
+			},
+			wantErr: svcauth.ErrSignatureInvalid,
+		},
+		{
+			name: "missing_token",
+			req: func() transport.Transport {
+				tr := external(valid)
+				tr.SetMeta("Svc-Auth-Token", "")
+				return tr
+			},
+			wantErr: svcauth.ErrMissingAuthMeta,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := svcauth.Verify(test.req(), methods)
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("got err %v, want %v", err, test.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	// Tokens issued by other systems can be reused, even with an ID.
+	c := valid
+	c.ID = "token"
+	for range 2 {
+		if _, err := svcauth.Verify(external(c), methods); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Tokens signed for a request can only be used once.
+	tr := newJWTReq(t)
+	if err := svcauth.Sign(method, tr); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svcauth.Verify(tr, methods); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svcauth.Verify(tr, methods); !errors.Is(err, svcauth.ErrRequestReplayed) {
+		t.Fatalf("got err %v, want ErrRequestReplayed", err)
+	}
+}
+
+func TestJWT_InvalidConfig(t *testing.T) {
+	for _, cfg := range []*config.JWTServiceAuth{
+		nil,
+		{Algorithm: "none"},
+		{Algorithm: "HS256"},
+		{Algorithm: "RS256", PublicKeys: []string{"not pem"}},
+	} {
+		_, _, err := svcauth.LoadMethods(clock.NewMock(), &config.Runtime{
+			ServiceAuth: []config.ServiceAuth{{Method: "jwt", JWT: cfg}},
+		})
+		if err == nil {
+			t.Errorf("LoadMethods(%+v): got nil err, want error", cfg)
+		}
+	}
+}
+
+func TestJWT_KeyID(t *testing.T) {
+	rsaPEM := func() (privateKey *rsa.PrivateKey, publicKey string) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	}
+	key1, pub1 := rsaPEM()
+	key2, pub2 := rsaPEM()
+
+	klock := clock.NewMock()
+	verifier := loadJWT(t, klock, config.JWTServiceAuth{Algorithm: "RS256", PublicKeys: []string{pub1, pub2}})
+	signer := loadJWT(t, klock, config.JWTServiceAuth{
+		Algorithm:  "RS256",
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key2)})),
+	})
+
+	// Signed tokens identify their key.
+	tr := newJWTReq(t)
+	if err := svcauth.Sign(signer, tr); err != nil {
+		t.Fatal(err)
+	}
+	tokenStr, _ := tr.ReadMeta("Svc-Auth-Token")
+	token, _, err := jwt.NewParser().ParseUnverified(tokenStr, &jwt.RegisteredClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid, _ := token.Header["kid"].(string); kid == "" {
+		t.Fatalf("got no kid in token header %v", token.Header)
+	}
+	if internal, err := svcauth.Verify(tr, svcauth.Methods{"jwt": verifier}); err != nil || !internal {
+		t.Fatalf("got internal=%v, err=%v", internal, err)
+	}
+
+	// Tokens without a kid, or with one that isn't known, are verified against every key.
+	for _, kid := range []any{nil, "unknown"} {
+		for _, key := range []*rsa.PrivateKey{key1, key2} {
+			token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(klock.Now().Add(time.Minute)),
+			})
+			if kid != nil {
+				token.Header["kid"] = kid
+			}
+			signed, err := token.SignedString(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tr := newJWTReq(t)
+			tr.SetMeta(svcauth.AuthMethodMetaKey, "jwt")
+			tr.SetMeta("Svc-Auth-Token", signed)
+			if internal, err := svcauth.Verify(tr, svcauth.Methods{"jwt": verifier}); err != nil || !internal {
+				t.Errorf("kid %v: got internal=%v, err=%v", kid, internal, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package svcauth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+)
+
+// nonceCache keeps track of the nonces of verified requests
+// so that replayed requests can be rejected.
+//
+// Nonces only need to be remembered for as long as the request's timestamp
+// is accepted, since requests older than that are rejected regardless.
+type nonceCache struct {
+	clock  clock.Clock
+	window time.Duration
+
+	mu        sync.Mutex
+	seen      map[string]time.Time // nonce -> expiry
+	lastPrune time.Time
+}
+
+func newNonceCache(clock clock.Clock, window time.Duration) *nonceCache {
+	return &nonceCache{
+		clock:     clock,
+		window:    window,
+		seen:      make(map[string]time.Time),
+		lastPrune: clock.Now(),
+	}
+}
+
+// add records the nonce of a request signed at the given time.
+// It reports false if the nonce has already been recorded.
+func (c *nonceCache) add(nonce string, signedAt time.Time) bool {
+	now := c.clock.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Periodically remove expired nonces to bound memory usage.
+	if now.Sub(c.lastPrune) >= c.window {
+		for n, expiry := range c.seen {
+			if now.After(expiry) {
+				delete(c.seen, n)
+			}
+		}
+		c.lastPrune = now
+	}
+
+	if _, ok := c.seen[nonce]; ok {
+		return false
+	}
+	c.seen[nonce] = signedAt.Add(c.window)
+	return true
+}
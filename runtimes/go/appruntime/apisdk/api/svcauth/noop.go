@@ -8,6 +8,7 @@ import (
 //
 // It is intended to be used for local development or where services are running within their own
 // private network and there is no threat model resulting in the need to authenticate requests.
+// Any caller can make internal calls to a service accepting it, so it must not be used otherwise.
 type noop struct{}
 
 var Noop noop
@@ -18,10 +19,22 @@ func (n noop) method() string {
 	return "noop"
 }
 
-func (n noop) verify(transport.Transport) error {
+func (n noop) verify(transport.Transport, signParams) error {
 	return nil
 }
 
-func (n noop) sign(transport.Transport) error {
+func (n noop) sign(transport.Transport, signParams) error {
 	return nil
 }
+
+// AcceptsUnauthenticated reports whether any of the given authentication methods
+// accepts internal calls without verifying them, such as the noop method.
+func AcceptsUnauthenticated(methods Methods) bool {
+	for _, m := range methods {
+		switch m.(type) {
+		case noop, *noop:
+			return true
+		}
+	}
+	return false
+}
@@ -15,7 +15,30 @@ const (
 
 // Sign signs the request using the given authentication method.
 func Sign(method ServiceAuth, req transport.Transport) error {
-	if err := method.sign(req); err != nil {
+	return SignWithBody(method, req, nil)
+}
+
+// SignWithBody is like Sign, but also binds the hash of the request body
+// to the signature, so the body can't be replaced without invalidating it.
+//
+// Computing bodyHash is the responsibility of the caller, and the same hash
+// must be passed to VerifyWithBody. A nil bodyHash is equivalent to Sign.
+func SignWithBody(method ServiceAuth, req transport.Transport, bodyHash []byte) error {
+	return sign(method, req, signParams{bodyHash: bodyHash})
+}
+
+// SignWithHeaders is like SignWithBody, but the signature only covers the
+// metadata keys in signedHeaders, so metadata added to the request later,
+// for example by a proxy, doesn't affect it. A nil signedHeaders covers all
+// metadata, like SignWithBody.
+//
+// The request must be verified using VerifyWithHeaders with the same keys.
+func SignWithHeaders(method ServiceAuth, req transport.Transport, bodyHash []byte, signedHeaders []string) error {
+	return sign(method, req, signParams{bodyHash: bodyHash, signedHeaders: headerSet(signedHeaders)})
+}
+
+func sign(method ServiceAuth, req transport.Transport, params signParams) error {
+	if err := method.sign(req, params); err != nil {
 		return fmt.Errorf("failed to sign request: %w", err)
 	}
 	req.SetMeta(AuthMethodMetaKey, method.method())
@@ -24,36 +47,113 @@ func Sign(method ServiceAuth, req transport.Transport) error {
 }
 
 // Verify verifies the authenticity of the request using the given authentication methods.
-func Verify(req transport.Transport, loadedAuthMethods map[string]ServiceAuth) (internalCall bool, err error) {
+func Verify(req transport.Transport, loadedAuthMethods Methods) (internalCall bool, err error) {
+	_, internalCall, err = VerifyWithMethod(req, loadedAuthMethods)
+	return internalCall, err
+}
+
+// VerifyWithMethod is like Verify, but also returns the name of the
+// authentication method that verified the request.
+//
+// The method is empty if the request is not an internal service to service call.
+func VerifyWithMethod(req transport.Transport, loadedAuthMethods Methods) (method string, internalCall bool, err error) {
+	return verify(req, loadedAuthMethods, signParams{})
+}
+
+// CallKind classifies a request by its service to service authentication.
+type CallKind int
+
+const (
+	// External is a request without service to service authentication
+	// metadata, meaning it's not an internal call.
+	External CallKind = iota
+
+	// InternalVerified is an internal call that was verified.
+	InternalVerified
+
+	// InternalRejected is a request claiming to be an internal call
+	// that failed verification.
+	InternalRejected
+)
+
+func (k CallKind) String() string {
+	switch k {
+	case External:
+		return "external"
+	case InternalVerified:
+		return "internal_verified"
+	case InternalRejected:
+		return "internal_rejected"
+	default:
+		return fmt.Sprintf("CallKind(%d)", int(k))
+	}
+}
+
+// VerifyCall is like Verify, but reports what kind of call the request is,
+// distinguishing external calls from internal calls that failed verification.
+// The error is non-nil if and only if the kind is InternalRejected.
+func VerifyCall(req transport.Transport, loadedAuthMethods Methods) (kind CallKind, err error) {
+	_, internalCall, err := verify(req, loadedAuthMethods, signParams{})
+	switch {
+	case err != nil:
+		return InternalRejected, err
+	case internalCall:
+		return InternalVerified, nil
+	default:
+		return External, nil
+	}
+}
+
+// VerifyWithBody is like Verify, but for requests signed using SignWithBody.
+// The request is only considered authentic if it was signed with the same bodyHash.
+func VerifyWithBody(req transport.Transport, loadedAuthMethods Methods, bodyHash []byte) (internalCall bool, err error) {
+	_, internalCall, err = verify(req, loadedAuthMethods, signParams{bodyHash: bodyHash})
+	return internalCall, err
+}
+
+// VerifyWithHeaders is like VerifyWithBody, but for requests signed using SignWithHeaders.
+// Only the metadata keys in signedHeaders are trusted as part of the signature,
+// and other metadata is ignored, so it must not be relied upon by the caller.
+// A nil signedHeaders trusts all metadata, like VerifyWithBody.
+func VerifyWithHeaders(req transport.Transport, loadedAuthMethods Methods, bodyHash []byte, signedHeaders []string) (internalCall bool, err error) {
+	_, internalCall, err = verify(req, loadedAuthMethods, signParams{bodyHash: bodyHash, signedHeaders: headerSet(signedHeaders)})
+	return internalCall, err
+}
+
+func verify(req transport.Transport, loadedAuthMethods Methods, params signParams) (method string, internalCall bool, err error) {
 	method, found := req.ReadMeta(AuthMethodMetaKey)
 	if !found {
 		// If this is not set, it means that the request is not an internal service to service call.
-		return false, nil
+		return "", false, nil
 	}
 
 	for _, authMethod := range loadedAuthMethods {
 		if authMethod.method() == method {
-			if err := authMethod.verify(req); err != nil {
-				return false, fmt.Errorf("failed to verify request: %w", err)
+			if err := authMethod.verify(req, params); err != nil {
+				return method, false, fmt.Errorf("failed to verify request: %w", err)
 			}
-			return true, nil
+			return method, true, nil
 		}
 	}
 
-	return false, fmt.Errorf("unknown service to service authentication method: %s", method)
+	return method, false, fmt.Errorf("%w: %s", ErrUnknownAuthMethod, method)
 }
 
 // LoadMethods loads the service to service authentication methods from the given config.
-func LoadMethods(clock clock.Clock, cfg *config.Runtime) (inbound, outbound map[string]ServiceAuth, err error) {
-	inbound = make(map[string]ServiceAuth)
-	outbound = make(map[string]ServiceAuth)
+func LoadMethods(clock clock.Clock, cfg *config.Runtime) (inbound, outbound Methods, err error) {
+	inbound = make(Methods)
+	outbound = make(Methods)
 
 	load := func(authCfg config.ServiceAuth) (ServiceAuth, error) {
 		switch authCfg.Method {
 		case "noop":
 			return &noop{}, nil
 		case "encore-auth":
-			return newEncoreAuth(clock, cfg.AppSlug, cfg.EnvName, cfg.AuthKeys), nil
+			return newEncoreAuth(clock, cfg.AppSlug, cfg.EnvName, cfg.AuthKeys, authCfg.MaxClockSkew, authCfg.SignNonce), nil
+		case "ed25519":
+			return newEd25519Auth(clock, cfg.AppSlug, cfg.EnvName, authCfg.PrivateKey, authCfg.PublicKeys, authCfg.MaxClockSkew)
+		case "jwt":
+			return newJWTAuth(clock, authCfg.JWT)
 		default:
 			return nil, fmt.Errorf("unknown service to service authentication method: %s", authCfg.Method)
 		}
@@ -0,0 +1,137 @@
+package svcauth
+
+import (
+	"crypto/hmac"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/crypto/sha3"
+
+	"encore.dev/appruntime/apisdk/api/transport"
+)
+
+// Signer signs requests using a single authentication method, reusing
+// signing work across requests where the method allows it, such as
+// deriving the signing key of the encore-auth method. It's intended
+// for services fanning out many internal calls, where Sign would otherwise
+// repeat it for every call.
+//
+// Requests signed by a Signer are verified like any other. It's safe for
+// concurrent use.
+type Signer struct {
+	method ServiceAuth
+	sign   func(req transport.Transport, params signParams) error
+}
+
+// preparedSigner is implemented by authentication methods whose signing
+// material can be derived once and reused by a Signer.
+type preparedSigner interface {
+	prepareSigner() func(req transport.Transport, params signParams) error
+}
+
+// NewSigner returns a Signer for the given authentication method.
+func NewSigner(method ServiceAuth) *Signer {
+	s := &Signer{method: method, sign: method.sign}
+	if p, ok := method.(preparedSigner); ok {
+		s.sign = p.prepareSigner()
+	}
+	return s
+}
+
+// Sign signs the request, like the package-level Sign.
+func (s *Signer) Sign(req transport.Transport) error {
+	return s.SignWithBody(req, nil)
+}
+
+// SignWithBody signs the request, like the package-level SignWithBody.
+func (s *Signer) SignWithBody(req transport.Transport, bodyHash []byte) error {
+	return s.signWithParams(req, signParams{bodyHash: bodyHash})
+}
+
+// SignWithHeaders signs the request, like the package-level SignWithHeaders.
+func (s *Signer) SignWithHeaders(req transport.Transport, bodyHash []byte, signedHeaders []string) error {
+	return s.signWithParams(req, signParams{bodyHash: bodyHash, signedHeaders: headerSet(signedHeaders)})
+}
+
+func (s *Signer) signWithParams(req transport.Transport, params signParams) error {
+	if err := s.sign(req, params); err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+	req.SetMeta(AuthMethodMetaKey, s.method.method())
+	return nil
+}
+
+// The signature format of the auth package, which signs requests with a key
+// derived from the shared secret for the date, app and environment.
+const (
+	ecSignatureVersion = "ENCORE1"
+	ecAuthScheme       = ecSignatureVersion + "-HMAC-SHA3-256"
+)
+
+// ecSigningKey is the key derived from an auth key for signing
+// the requests of a single date.
+type ecSigningKey struct {
+	date        string // in YYYYMMDD format
+	credentials string
+	key         []byte
+}
+
+// prepareSigner returns a sign function that derives the signing key once
+// per date, rather than for every request like auth.Sign. The signatures are
+// identical to those of auth.Sign, so requests are verified like any other.
+func (ea *encoreAuth) prepareSigner() func(req transport.Transport, params signParams) error {
+	var cached atomic.Pointer[ecSigningKey]
+	return func(req transport.Transport, params signParams) error {
+		opHash, err := ea.prepareSign(req, params)
+		if err != nil {
+			return err
+		}
+
+		now := ea.clock.Now().UTC()
+		key := cached.Load()
+		if date := now.Format("20060102"); key == nil || key.date != date {
+			key = ea.deriveSigningKey(date)
+			cached.Store(key)
+		}
+
+		digest := strings.Join([]string{
+			ecAuthScheme,
+			now.Format(time.RFC3339),
+			key.credentials,
+			opHash.HashString(),
+		}, "\n")
+		signature := hex.EncodeToString(hmacSHA3(key.key, []byte(digest)))
+
+		req.SetMeta(ecAuthHashHeader, ecAuthScheme+" cred="+strconv.Quote(key.credentials)+
+			", op="+opHash.HashString()+", sig="+signature)
+		req.SetMeta(ecDateHeader, now.Format(http.TimeFormat))
+		return nil
+	}
+}
+
+// deriveSigningKey derives the key for signing requests on the given date
+// with the latest auth key, like the auth package does.
+func (ea *encoreAuth) deriveSigningKey(date string) *ecSigningKey {
+	key := append([]byte(ecSignatureVersion), ea.latestKey.Data...)
+	for _, data := range []string{date, ea.appSlug, ea.envName, "encore_request"} {
+		key = hmacSHA3(key, []byte(data))
+	}
+	return &ecSigningKey{
+		date:        date,
+		credentials: fmt.Sprintf("%s/%s/%s/%d", date, ea.appSlug, ea.envName, ea.latestKey.KeyID),
+		key:         key,
+	}
+}
+
+func hmacSHA3(key, data []byte) []byte {
+	h := hmac.New(sha3.New256, key)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+var _ preparedSigner = (*encoreAuth)(nil)
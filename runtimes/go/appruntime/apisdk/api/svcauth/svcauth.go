@@ -1,9 +1,35 @@
 package svcauth
 
 import (
+	"errors"
+	"net/http"
+	"sort"
+
 	"encore.dev/appruntime/apisdk/api/transport"
 )
 
+var (
+	// ErrUnknownAuthMethod is returned when verifying a request
+	// using an authentication method that is not loaded.
+	ErrUnknownAuthMethod = errors.New("unknown service to service authentication method")
+
+	// ErrMissingAuthMeta is returned when verifying a request
+	// that is missing metadata required by the authentication method.
+	ErrMissingAuthMeta = errors.New("missing authentication metadata")
+
+	// ErrSignatureInvalid is returned when verifying a request
+	// whose signature is malformed or does not match the request.
+	ErrSignatureInvalid = errors.New("invalid signature")
+
+	// ErrRequestExpired is returned when verifying a request that was signed
+	// too long ago (or too far in the future) to be accepted.
+	ErrRequestExpired = errors.New("request expired")
+
+	// ErrRequestReplayed is returned when verifying a request
+	// that has already been verified before.
+	ErrRequestReplayed = errors.New("request replayed")
+)
+
 // ServiceAuth is an interface that provides authentication for internal service to service
 // calls within the same Encore application.
 type ServiceAuth interface {
@@ -11,10 +37,53 @@ type ServiceAuth interface {
 	method() string
 
 	// Verify verifies the authenticity of the request.
+	// The request must have been signed with the same params.
 	// If the request is not authentic, an error is returned.
-	verify(req transport.Transport) error
+	verify(req transport.Transport, params signParams) error
 
-	// Sign signs the request.
+	// Sign signs the request, binding the params to the signature.
 	// If the request cannot be signed, an error is returned.
-	sign(req transport.Transport) error
+	sign(req transport.Transport, params signParams) error
+}
+
+// signParams are the parameters a request is signed and verified with.
+type signParams struct {
+	// bodyHash, if non-empty, is the hash of the request body.
+	bodyHash []byte
+
+	// signedHeaders, if non-nil, is the set of metadata keys covered by
+	// the signature, in canonical form. Other metadata is ignored.
+	// If nil, all metadata is covered.
+	signedHeaders map[string]bool
+}
+
+// covers reports whether the metadata key is covered by the signature.
+func (p signParams) covers(key string) bool {
+	return p.signedHeaders == nil || p.signedHeaders[http.CanonicalHeaderKey(key)]
+}
+
+// headerSet returns the set of the given metadata keys in canonical form,
+// or nil if keys is nil.
+func headerSet(keys []string) map[string]bool {
+	if keys == nil {
+		return nil
+	}
+	set := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		set[http.CanonicalHeaderKey(key)] = true
+	}
+	return set
+}
+
+// Methods is a set of loaded authentication methods, keyed by method name.
+type Methods map[string]ServiceAuth
+
+// Names returns the sorted names of the authentication methods.
+func (m Methods) Names() []string {
+	names := make([]string, 0, len(m))
+	for _, method := range m {
+		names = append(names, method.method())
+	}
+	sort.Strings(names)
+	return names
 }
@@ -0,0 +1,569 @@
+package svcauth_test
+
+import (
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"google.golang.org/grpc/metadata"
+
+	"encore.dev/appruntime/apisdk/api/svcauth"
+	"encore.dev/appruntime/apisdk/api/svcauth/svcauthtest"
+	"encore.dev/appruntime/apisdk/api/transport"
+	"encore.dev/appruntime/exported/config"
+)
+
+func TestRoundTrip(t *testing.T) {
+	klock := clock.NewMock()
+	for _, method := range []string{"noop", "encore-auth"} {
+		t.Run(method, func(t *testing.T) {
+			load := func() svcauth.Methods {
+				inbound, _, err := svcauth.LoadMethods(klock, &config.Runtime{
+					AppSlug:     "app",
+					EnvName:     "env",
+					AuthKeys:    []config.EncoreAuthKey{{KeyID: 1, Data: []byte("secret")}},
+					ServiceAuth: []config.ServiceAuth{{Method: method, SignNonce: true}},
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return inbound
+			}
+			if got, want := svcauth.AcceptsUnauthenticated(load()), method == "noop"; got != want {
+				t.Fatalf("AcceptsUnauthenticated = %v, want %v", got, want)
+			}
+
+			req, err := http.NewRequest("POST", "http://service/endpoint", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tr := transport.HTTPRequest(req)
+			tr.SetMeta("Caller", "svc.Endpoint")
+			svcauthtest.TestRoundTrip(t, func() svcauth.ServiceAuth { return load()[method] }, tr)
+		})
+	}
+}
+
+func TestVerify_Replay(t *testing.T) {
+	klock := clock.NewMock()
+	klock.Set(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	inbound, _, err := svcauth.LoadMethods(klock, &config.Runtime{
+		AppSlug:     "app",
+		EnvName:     "env",
+		AuthKeys:    []config.EncoreAuthKey{{KeyID: 1, Data: []byte("secret")}},
+		ServiceAuth: []config.ServiceAuth{{Method: "encore-auth", MaxClockSkew: time.Minute, SignNonce: true}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	method := inbound["encore-auth"]
+
+	sign := func() transport.Transport {
+		req, err := http.NewRequest("POST", "http://service/endpoint", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tr := transport.HTTPRequest(req)
+		if err := svcauth.Sign(method, tr); err != nil {
+			t.Fatal(err)
+		}
+		return tr
+	}
+
+	// The same request can only be verified once.
+	req := sign()
+	if _, err := svcauth.Verify(req, inbound); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svcauth.Verify(req, inbound); !errors.Is(err, svcauth.ErrRequestReplayed) {
+		t.Fatalf("got err %v, want ErrRequestReplayed", err)
+	}
+
+	// Requests older than the max clock skew are rejected.
+	req = sign()
+	klock.Add(time.Minute + time.Second)
+	if _, err := svcauth.Verify(req, inbound); !errors.Is(err, svcauth.ErrRequestExpired) {
+		t.Fatalf("got err %v, want ErrRequestExpired", err)
+	}
+
+	// Distinct requests are accepted.
+	for range 3 {
+		if _, err := svcauth.Verify(sign(), inbound); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestVerify_NoNonce(t *testing.T) {
+	inbound, _, err := svcauth.LoadMethods(clock.NewMock(), &config.Runtime{
+		AppSlug:     "app",
+		EnvName:     "env",
+		AuthKeys:    []config.EncoreAuthKey{{KeyID: 1, Data: []byte("secret")}},
+		ServiceAuth: []config.ServiceAuth{{Method: "encore-auth"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("POST", "http://service/endpoint", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := transport.HTTPRequest(req)
+	if err := svcauth.Sign(inbound["encore-auth"], tr); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := tr.ReadMeta("Svc-Auth-Nonce"); found {
+		t.Fatal("got nonce, want none")
+	}
+
+	// Requests from callers that don't add a nonce, such as services
+	// running an older version or the Rust runtime, are accepted.
+	for range 2 {
+		if _, err := svcauth.Verify(tr, inbound); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestVerifyWithMethod(t *testing.T) {
+	inbound, _, err := svcauth.LoadMethods(clock.NewMock(), &config.Runtime{
+		AppSlug:     "app",
+		EnvName:     "env",
+		AuthKeys:    []config.EncoreAuthKey{{KeyID: 1, Data: []byte("secret")}},
+		ServiceAuth: []config.ServiceAuth{{Method: "noop"}, {Method: "encore-auth"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newReq := func() transport.Transport {
+		req, err := http.NewRequest("POST", "http://service/endpoint", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return transport.HTTPRequest(req)
+	}
+
+	for _, name := range []string{"noop", "encore-auth"} {
+		req := newReq()
+		if err := svcauth.Sign(inbound[name], req); err != nil {
+			t.Fatal(err)
+		}
+		method, internal, err := svcauth.VerifyWithMethod(req, inbound)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if method != name || !internal {
+			t.Fatalf("got method=%q internal=%v, want method=%q internal=true", method, internal, name)
+		}
+	}
+
+	// Requests without an auth method are not internal calls.
+	method, internal, err := svcauth.VerifyWithMethod(newReq(), inbound)
+	if method != "" || internal || err != nil {
+		t.Fatalf("got method=%q internal=%v err=%v, want empty method, not internal and nil err", method, internal, err)
+	}
+}
+
+func TestVerifyCall(t *testing.T) {
+	inbound, _, err := svcauth.LoadMethods(clock.NewMock(), &config.Runtime{
+		AppSlug:     "app",
+		EnvName:     "env",
+		AuthKeys:    []config.EncoreAuthKey{{KeyID: 1, Data: []byte("secret")}},
+		ServiceAuth: []config.ServiceAuth{{Method: "encore-auth"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(tr transport.Transport)
+		want    svcauth.CallKind
+	}{
+		{name: "external", prepare: func(tr transport.Transport) {}, want: svcauth.External},
+		{
+			name: "verified",
+			prepare: func(tr transport.Transport) {
+				if err := svcauth.Sign(inbound["encore-auth"], tr); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: svcauth.InternalVerified,
+		},
+		{
+			name: "rejected",
+			prepare: func(tr transport.Transport) {
+				if err := svcauth.Sign(inbound["encore-auth"], tr); err != nil {
+					t.Fatal(err)
+				}
+				tr.SetMeta("Svc-Auth", "garbage")
+			},
+			want: svcauth.InternalRejected,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "http://service/endpoint", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tr := transport.HTTPRequest(req)
+			test.prepare(tr)
+
+			kind, err := svcauth.VerifyCall(tr, inbound)
+			if kind != test.want {
+				t.Fatalf("got kind %v, want %v", kind, test.want)
+			}
+			if gotErr := err != nil; gotErr != (test.want == svcauth.InternalRejected) {
+				t.Fatalf("got err %v for kind %v", err, kind)
+			}
+		})
+	}
+}
+
+func TestEncoreAuth_KeyRotation(t *testing.T) {
+	klock := clock.NewMock()
+	oldKey := config.EncoreAuthKey{KeyID: 1, Data: []byte("old secret")}
+	newKey := config.EncoreAuthKey{KeyID: 2, Data: []byte("new secret")}
+
+	load := func(keys ...config.EncoreAuthKey) svcauth.Methods {
+		methods, _, err := svcauth.LoadMethods(klock, &config.Runtime{
+			AppSlug:     "app",
+			EnvName:     "env",
+			AuthKeys:    keys,
+			ServiceAuth: []config.ServiceAuth{{Method: "encore-auth"}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return methods
+	}
+
+	tests := []struct {
+		name     string
+		signer   []config.EncoreAuthKey
+		verifier []config.EncoreAuthKey
+		wantErr  bool
+	}{
+		{name: "signed_with_old_verify_with_both", signer: []config.EncoreAuthKey{oldKey}, verifier: []config.EncoreAuthKey{oldKey, newKey}},
+		{name: "signed_with_new_verify_with_both", signer: []config.EncoreAuthKey{newKey}, verifier: []config.EncoreAuthKey{oldKey, newKey}},
+		{name: "signs_with_latest_key", signer: []config.EncoreAuthKey{newKey, oldKey}, verifier: []config.EncoreAuthKey{newKey}},
+		{name: "old_key_retired", signer: []config.EncoreAuthKey{oldKey}, verifier: []config.EncoreAuthKey{newKey}, wantErr: true},
+		{name: "new_key_unknown", signer: []config.EncoreAuthKey{oldKey, newKey}, verifier: []config.EncoreAuthKey{oldKey}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "http://service/endpoint", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tr := transport.HTTPRequest(req)
+			if err := svcauth.Sign(load(test.signer...)["encore-auth"], tr); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = svcauth.Verify(tr, load(test.verifier...))
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("got err %v, want error: %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestEncoreAuth_SignatureMismatch(t *testing.T) {
+	inbound, _, err := svcauth.LoadMethods(clock.NewMock(), &config.Runtime{
+		AppSlug:     "app",
+		EnvName:     "env",
+		AuthKeys:    []config.EncoreAuthKey{{KeyID: 1, Data: []byte("secret")}},
+		ServiceAuth: []config.ServiceAuth{{Method: "encore-auth"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("POST", "http://service/endpoint", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := transport.HTTPRequest(req)
+	if err := svcauth.Sign(inbound["encore-auth"], tr); err != nil {
+		t.Fatal(err)
+	}
+
+	// Changing only the last character of the signature
+	// must cause verification to fail.
+	sig, _ := tr.ReadMeta("Svc-Auth")
+	last := sig[len(sig)-1]
+	if last == 'A' {
+		last = 'B'
+	} else {
+		last = 'A'
+	}
+	tr.SetMeta("Svc-Auth", sig[:len(sig)-1]+string(last))
+
+	if _, err := svcauth.Verify(tr, inbound); err == nil {
+		t.Fatal("got nil err, want signature mismatch")
+	}
+}
+
+func TestAcceptsUnauthenticated(t *testing.T) {
+	for _, test := range []struct {
+		methods []config.ServiceAuth
+		want    bool
+	}{
+		{methods: []config.ServiceAuth{{Method: "encore-auth"}}, want: false},
+		{methods: []config.ServiceAuth{{Method: "noop"}}, want: true},
+		{methods: []config.ServiceAuth{{Method: "encore-auth"}, {Method: "noop"}}, want: true},
+	} {
+		inbound, _, err := svcauth.LoadMethods(clock.NewMock(), &config.Runtime{ServiceAuth: test.methods})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := svcauth.AcceptsUnauthenticated(inbound); got != test.want {
+			t.Errorf("AcceptsUnauthenticated(%v) = %v, want %v", test.methods, got, test.want)
+		}
+	}
+}
+
+func TestMethods_Names(t *testing.T) {
+	inbound, outbound, err := svcauth.LoadMethods(clock.NewMock(), &config.Runtime{
+		ServiceAuth: []config.ServiceAuth{{Method: "noop"}, {Method: "encore-auth"}},
+		ServiceDiscovery: map[string]config.Service{
+			"svc": {Name: "svc", ServiceAuth: config.ServiceAuth{Method: "encore-auth"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := inbound.Names(), []string{"encore-auth", "noop"}; !slices.Equal(got, want) {
+		t.Errorf("inbound.Names() = %v, want %v", got, want)
+	}
+	if got, want := outbound.Names(), []string{"encore-auth"}; !slices.Equal(got, want) {
+		t.Errorf("outbound.Names() = %v, want %v", got, want)
+	}
+}
+
+func TestVerify_Errors(t *testing.T) {
+	inbound, _, err := svcauth.LoadMethods(clock.NewMock(), &config.Runtime{
+		AppSlug:     "app",
+		EnvName:     "env",
+		AuthKeys:    []config.EncoreAuthKey{{KeyID: 1, Data: []byte("secret")}},
+		ServiceAuth: []config.ServiceAuth{{Method: "encore-auth"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(tr transport.Transport)
+		wantErr error
+	}{
+		{
+			name:    "unknown_method",
+			modify:  func(tr transport.Transport) { tr.SetMeta(svcauth.AuthMethodMetaKey, "other") },
+			wantErr: svcauth.ErrUnknownAuthMethod,
+		},
+		{
+			name:    "missing_signature",
+			modify:  func(tr transport.Transport) { tr.SetMeta("Svc-Auth", "") },
+			wantErr: svcauth.ErrMissingAuthMeta,
+		},
+		{
+			name:    "invalid_signature",
+			modify:  func(tr transport.Transport) { tr.SetMeta("Svc-Auth", "garbage") },
+			wantErr: svcauth.ErrSignatureInvalid,
+		},
+		{
+			name:    "modified_request",
+			modify:  func(tr transport.Transport) { tr.SetMeta("Caller", "other") },
+			wantErr: svcauth.ErrSignatureInvalid,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "http://service/endpoint", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tr := transport.HTTPRequest(req)
+			tr.SetMeta("Caller", "svc.Endpoint")
+			if err := svcauth.Sign(inbound["encore-auth"], tr); err != nil {
+				t.Fatal(err)
+			}
+			test.modify(tr)
+
+			if _, err := svcauth.Verify(tr, inbound); !errors.Is(err, test.wantErr) {
+				t.Fatalf("got err %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestSignWithBody(t *testing.T) {
+	inbound, _, err := svcauth.LoadMethods(clock.NewMock(), &config.Runtime{
+		AppSlug:     "app",
+		EnvName:     "env",
+		AuthKeys:    []config.EncoreAuthKey{{KeyID: 1, Data: []byte("secret")}},
+		ServiceAuth: []config.ServiceAuth{{Method: "encore-auth"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bodyHash := func(body string) []byte {
+		sum := sha256.Sum256([]byte(body))
+		return sum[:]
+	}
+
+	tests := []struct {
+		name       string
+		signBody   []byte
+		verifyBody []byte
+		wantErr    bool
+	}{
+		{name: "same_body", signBody: bodyHash("body"), verifyBody: bodyHash("body")},
+		{name: "swapped_body", signBody: bodyHash("body"), verifyBody: bodyHash("other"), wantErr: true},
+		{name: "signed_without_body", signBody: nil, verifyBody: bodyHash("body"), wantErr: true},
+		{name: "verified_without_body", signBody: bodyHash("body"), verifyBody: nil, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "http://service/endpoint", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tr := transport.HTTPRequest(req)
+			if err := svcauth.SignWithBody(inbound["encore-auth"], tr, test.signBody); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = svcauth.VerifyWithBody(tr, inbound, test.verifyBody)
+			if test.wantErr {
+				if !errors.Is(err, svcauth.ErrSignatureInvalid) {
+					t.Fatalf("got err %v, want ErrSignatureInvalid", err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestSignWithHeaders(t *testing.T) {
+	inbound, _, err := svcauth.LoadMethods(clock.NewMock(), &config.Runtime{
+		AppSlug:     "app",
+		EnvName:     "env",
+		AuthKeys:    []config.EncoreAuthKey{{KeyID: 1, Data: []byte("secret")}},
+		ServiceAuth: []config.ServiceAuth{{Method: "encore-auth"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedHeaders := []string{"caller", "Trace-Id"}
+
+	tests := []struct {
+		name    string
+		modify  func(tr transport.Transport)
+		wantErr bool
+	}{
+		{name: "unmodified", modify: func(tr transport.Transport) {}},
+		{name: "injected_header", modify: func(tr transport.Transport) { tr.SetMeta("Injected", "value") }},
+		{name: "modified_unsigned_header", modify: func(tr transport.Transport) { tr.SetMeta("Unsigned", "other") }},
+		{name: "modified_signed_header", modify: func(tr transport.Transport) { tr.SetMeta("Caller", "other") }, wantErr: true},
+		{name: "added_signed_header", modify: func(tr transport.Transport) { tr.SetMeta("Trace-Id", "id") }, wantErr: true},
+		{name: "modified_nonce", modify: func(tr transport.Transport) { tr.SetMeta("Svc-Auth-Nonce", "nonce") }, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "http://service/endpoint", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tr := transport.HTTPRequest(req)
+			tr.SetMeta("Caller", "svc.Endpoint")
+			tr.SetMeta("Unsigned", "value")
+			if err := svcauth.SignWithHeaders(inbound["encore-auth"], tr, nil, signedHeaders); err != nil {
+				t.Fatal(err)
+			}
+			test.modify(tr)
+
+			_, err = svcauth.VerifyWithHeaders(tr, inbound, nil, signedHeaders)
+			if test.wantErr {
+				if !errors.Is(err, svcauth.ErrSignatureInvalid) {
+					t.Fatalf("got err %v, want ErrSignatureInvalid", err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestVerify_PlainHTTPHeaders(t *testing.T) {
+	inbound, _, err := svcauth.LoadMethods(clock.NewMock(), &config.Runtime{
+		AppSlug:     "app",
+		EnvName:     "env",
+		AuthKeys:    []config.EncoreAuthKey{{KeyID: 1, Data: []byte("secret")}},
+		ServiceAuth: []config.ServiceAuth{{Method: "encore-auth"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("POST", "http://service/endpoint", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Caller", "svc")
+	signedHeaders := []string{"X-Caller"}
+	if err := svcauth.SignWithHeaders(inbound["encore-auth"], transport.HTTPHeader(req.Header), nil, signedHeaders); err != nil {
+		t.Fatal(err)
+	}
+	if req.Header.Get("Svc-Auth-Method") != "encore-auth" {
+		t.Fatalf("got headers %v, want auth metadata set as plain headers", req.Header)
+	}
+
+	// Headers added by the HTTP client aren't covered by the signature.
+	req.Header.Set("User-Agent", "Go-http-client/1.1")
+	if _, err := svcauth.VerifyWithHeaders(transport.HTTPHeader(req.Header), inbound, nil, signedHeaders); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerify_AcrossTransports(t *testing.T) {
+	inbound, _, err := svcauth.LoadMethods(clock.NewMock(), &config.Runtime{
+		AppSlug:     "app",
+		EnvName:     "env",
+		AuthKeys:    []config.EncoreAuthKey{{KeyID: 1, Data: []byte("secret")}},
+		ServiceAuth: []config.ServiceAuth{{Method: "encore-auth"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("POST", "http://service/endpoint", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := transport.HTTPRequest(req)
+	tr.SetMeta("Caller", "svc.Endpoint")
+	if err := svcauth.Sign(inbound["encore-auth"], tr); err != nil {
+		t.Fatal(err)
+	}
+
+	// Forward the signed headers as gRPC metadata.
+	md := metadata.MD{}
+	for key, values := range req.Header {
+		md.Append(key, values...)
+	}
+	if _, err := svcauth.Verify(transport.GRPCMetadata(md), inbound); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,66 @@
+// Package svcauthtest provides helpers for testing ServiceAuth implementations.
+package svcauthtest
+
+import (
+	"errors"
+	"testing"
+
+	"encore.dev/appruntime/apisdk/api/svcauth"
+	"encore.dev/appruntime/apisdk/api/transport"
+)
+
+// TestRoundTrip signs req using a method returned by newMethod and checks
+// that the method verifies the signed request as an internal call.
+//
+// It then checks that tampering with any of the signed metadata, both the
+// metadata set before signing and the metadata added by signing, causes
+// verification to fail with ErrSignatureInvalid, to ensure that it is covered
+// by the signature. Once the metadata is restored the request must verify again.
+//
+// Each verification uses a new method from newMethod, so methods that
+// reject replayed requests don't reject the repeated verifications.
+//
+// Methods that accept unauthenticated requests, such as noop,
+// are only checked to verify the request.
+func TestRoundTrip(t testing.TB, newMethod func() svcauth.ServiceAuth, req transport.Transport) {
+	t.Helper()
+
+	if err := svcauth.Sign(newMethod(), req); err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	verify := func() (bool, error) {
+		t.Helper()
+		return svcauth.Verify(req, svcauth.Methods{"method": newMethod()})
+	}
+
+	if internal, err := verify(); err != nil {
+		t.Fatalf("verify signed request: %v", err)
+	} else if !internal {
+		t.Fatalf("verify signed request: got internalCall=false, want true")
+	}
+
+	// Nothing is signed, so there's nothing to tamper with.
+	if svcauth.AcceptsUnauthenticated(svcauth.Methods{"method": newMethod()}) {
+		return
+	}
+
+	for _, key := range req.ListMetaKeys() {
+		switch key {
+		case svcauth.AuthMethodMetaKey, transport.TraceParentKey, transport.TraceStateKey:
+			// Not covered by the signature.
+			continue
+		}
+
+		orig, _ := req.ReadMeta(key)
+		req.SetMeta(key, orig+"tampered")
+		if _, err := verify(); !errors.Is(err, svcauth.ErrSignatureInvalid) {
+			t.Errorf("verify request with tampered %q: got err %v, want ErrSignatureInvalid", key, err)
+		}
+		req.SetMeta(key, orig)
+	}
+
+	if _, err := verify(); err != nil {
+		t.Fatalf("verify restored request: %v", err)
+	}
+}
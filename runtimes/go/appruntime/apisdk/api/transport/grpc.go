@@ -0,0 +1,59 @@
+package transport
+
+import (
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// GRPCMetadata returns a Transport implementation for the given gRPC metadata.
+//
+// Metadata keys are mapped to the same names as the HTTP implementation,
+// lowercased as required by gRPC, so that requests signed using one
+// transport can be verified using the other.
+func GRPCMetadata(md metadata.MD) Transport {
+	return &grpcMetadata{md: md}
+}
+
+// grpcMetadata is a Transport implementation for gRPC metadata.
+type grpcMetadata struct {
+	md metadata.MD
+}
+
+var _ Transport = (*grpcMetadata)(nil)
+
+func metaKeyToGRPCKey(key string) string {
+	return strings.ToLower(metaKeyToHTTPHeader(key))
+}
+
+func (g *grpcMetadata) SetMeta(key string, value string) {
+	g.md.Set(metaKeyToGRPCKey(key), value)
+}
+
+func (g *grpcMetadata) ReadMeta(key string) (value string, found bool) {
+	if values := g.md.Get(metaKeyToGRPCKey(key)); len(values) > 0 {
+		value = values[0]
+	}
+	return value, value != ""
+}
+
+func (g *grpcMetadata) ReadMetaValues(key string) (values []string, found bool) {
+	values = g.md.Get(metaKeyToGRPCKey(key))
+	return values, len(values) > 0
+}
+
+func (g *grpcMetadata) ListMetaKeys() []string {
+	rtn := make([]string, 0, len(g.md))
+
+	// List all keys
+	for key := range g.md {
+		if metaKey, ok := httpHeaderToMetaKey(key); ok {
+			rtn = append(rtn, metaKey)
+		}
+	}
+
+	sort.Strings(rtn)
+
+	return rtn
+}
@@ -0,0 +1,38 @@
+package transport
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestGRPCMetadata_MatchesHTTP(t *testing.T) {
+	md := metadata.MD{}
+	header := http.Header{}
+	transports := []Transport{GRPCMetadata(md), &httpHeaders{headers: header}}
+
+	for _, tr := range transports {
+		tr.SetMeta("Caller", "svc.Endpoint")
+		tr.SetMeta("User-Id", "user")
+		tr.SetMeta(TraceParentKey, "00-trace-span-01")
+		tr.SetMeta(CorrelationIDKey, "corr")
+	}
+
+	if got, want := md.Get("x-encore-meta-caller"), []string{"svc.Endpoint"}; !slices.Equal(got, want) {
+		t.Fatalf("got caller metadata %v, want %v", got, want)
+	}
+
+	grpcKeys, httpKeys := transports[0].ListMetaKeys(), transports[1].ListMetaKeys()
+	if !slices.Equal(grpcKeys, httpKeys) {
+		t.Fatalf("got gRPC keys %v, want HTTP keys %v", grpcKeys, httpKeys)
+	}
+	for _, key := range grpcKeys {
+		grpcVals, _ := transports[0].ReadMetaValues(key)
+		httpVals, _ := transports[1].ReadMetaValues(key)
+		if !slices.Equal(grpcVals, httpVals) {
+			t.Errorf("key %q: got gRPC values %v, want HTTP values %v", key, grpcVals, httpVals)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package transport
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// HTTPHeader returns a Transport implementation that uses the given headers
+// directly as metadata, for use outside of Encore, such as in standard
+// net/http middleware.
+//
+// Unlike HTTPRequest, metadata keys are used as header names as-is, without
+// being prefixed or renamed. Keys are case-insensitive: SetMeta sets the
+// canonical form of the key, and reads match headers in any case.
+//
+// ListMetaKeys lists every header, including those added or changed by HTTP
+// clients and proxies, so requests should only be signed over the headers
+// they rely on using svcauth.SignWithHeaders.
+func HTTPHeader(h http.Header) Transport {
+	return plainHeaders(h)
+}
+
+// plainHeaders is a Transport implementation using headers as metadata as-is.
+type plainHeaders http.Header
+
+var _ Transport = plainHeaders(nil)
+
+func (h plainHeaders) SetMeta(key string, value string) {
+	http.Header(h).Set(key, value)
+}
+
+func (h plainHeaders) ReadMeta(key string) (value string, found bool) {
+	if values, _ := h.ReadMetaValues(key); len(values) > 0 {
+		value = values[0]
+	}
+	return value, value != ""
+}
+
+func (h plainHeaders) ReadMetaValues(key string) (values []string, found bool) {
+	values = http.Header(h).Values(key)
+	if len(values) == 0 {
+		// The headers may have been set without canonicalizing the key.
+		for k, v := range h {
+			if strings.EqualFold(k, key) {
+				values = append(values, v...)
+			}
+		}
+	}
+	return values, len(values) > 0
+}
+
+func (h plainHeaders) ListMetaKeys() []string {
+	rtn := make([]string, 0, len(h))
+
+	// List all keys, in canonical form
+	for key := range h {
+		rtn = append(rtn, http.CanonicalHeaderKey(key))
+	}
+
+	sort.Strings(rtn)
+
+	return slices.Compact(rtn)
+}
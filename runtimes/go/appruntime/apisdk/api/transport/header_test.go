@@ -0,0 +1,39 @@
+package transport
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestHTTPHeader(t *testing.T) {
+	h := http.Header{}
+	tr := HTTPHeader(h)
+
+	tr.SetMeta("x-request-id", "id")
+	if got := h["X-Request-Id"]; !slices.Equal(got, []string{"id"}) {
+		t.Fatalf("got header %v, want canonicalized key", h)
+	}
+	if got, found := tr.ReadMeta("X-REQUEST-ID"); !found || got != "id" {
+		t.Fatalf("ReadMeta = %q, %v, want %q, true", got, found, "id")
+	}
+
+	// Headers set with non-canonical keys are read and listed as one key.
+	h["user-id"] = []string{"a"}
+	h["User-Id"] = []string{"b"}
+	if got, found := tr.ReadMetaValues("User-ID"); !found || !slices.Equal(got, []string{"b"}) {
+		t.Fatalf("ReadMetaValues = %v, %v, want [b], true", got, found)
+	}
+	delete(h, "User-Id")
+	if got, found := tr.ReadMetaValues("User-ID"); !found || !slices.Equal(got, []string{"a"}) {
+		t.Fatalf("ReadMetaValues = %v, %v, want [a], true", got, found)
+	}
+	h["User-Id"] = []string{"b"}
+	if got, want := tr.ListMetaKeys(), []string{"User-Id", "X-Request-Id"}; !slices.Equal(got, want) {
+		t.Fatalf("ListMetaKeys = %v, want %v", got, want)
+	}
+
+	if _, found := tr.ReadMeta("Missing"); found {
+		t.Fatal("found missing key")
+	}
+}
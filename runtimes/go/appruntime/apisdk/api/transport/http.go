@@ -62,17 +62,8 @@ func (h *httpHeaders) ListMetaKeys() []string {
 
 	// List all keys
 	for key := range h.headers {
-		key := http.CanonicalHeaderKey(key)
-
-		switch {
-		case key == "Traceparent":
-			rtn = append(rtn, TraceParentKey)
-		case key == "Tracestate":
-			rtn = append(rtn, TraceStateKey)
-		case key == "X-Correlation-Id":
-			rtn = append(rtn, CorrelationIDKey)
-		case strings.HasPrefix(key, "X-Encore-Meta-"):
-			rtn = append(rtn, key[14:])
+		if metaKey, ok := httpHeaderToMetaKey(key); ok {
+			rtn = append(rtn, metaKey)
 		}
 	}
 
@@ -80,3 +71,22 @@ func (h *httpHeaders) ListMetaKeys() []string {
 
 	return rtn
 }
+
+// httpHeaderToMetaKey returns the metadata key for the given header,
+// reporting false if the header is not used for metadata.
+func httpHeaderToMetaKey(header string) (key string, ok bool) {
+	header = http.CanonicalHeaderKey(header)
+
+	switch {
+	case header == "Traceparent":
+		return TraceParentKey, true
+	case header == "Tracestate":
+		return TraceStateKey, true
+	case header == "X-Correlation-Id":
+		return CorrelationIDKey, true
+	case strings.HasPrefix(header, "X-Encore-Meta-"):
+		return header[14:], true
+	default:
+		return "", false
+	}
+}
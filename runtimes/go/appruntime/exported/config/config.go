@@ -32,6 +32,16 @@ type Static struct {
 	// which where enabled at compile time.
 	EnabledExperiments []string `json:"experiments,omitempty"`
 
+	// ServiceExperiments are the experiments enabled or disabled at compile
+	// time for individual services, on top of EnabledExperiments, keyed by
+	// service name. The names of disabled experiments are prefixed with "-".
+	ServiceExperiments map[string][]string `json:"service_experiments,omitempty"`
+
+	// ExperimentsFingerprint is the fingerprint of the experiments the app
+	// was built with, as computed by experiments.Set.Fingerprint.
+	// It's empty for apps built by compilers that don't record it.
+	ExperimentsFingerprint string `json:"experiments_fingerprint,omitempty"`
+
 	// EmbeddedEnvs is a set of embedded environment variables.
 	EmbeddedEnvs map[string]string
 }
@@ -157,6 +167,56 @@ const (
 type ServiceAuth struct {
 	// Method is the name of the authentication method.
 	Method string `json:"method"`
+
+	// MaxClockSkew is the maximum age of a signed request for it to be accepted,
+	// for authentication methods that sign requests.
+	// If zero, svcauth.DefaultMaxClockSkew is used.
+	MaxClockSkew time.Duration `json:"max_clock_skew,omitempty"`
+
+	// SignNonce adds a random nonce to requests signed with the
+	// "encore-auth" method, so replayed requests can be rejected.
+	// Requests without a nonce are still accepted, so it should only be
+	// enabled once every service verifying the requests understands nonces.
+	SignNonce bool `json:"sign_nonce,omitempty"`
+
+	// PrivateKey is the PEM-encoded PKCS #8 Ed25519 private key this
+	// service signs requests with, for the "ed25519" method.
+	PrivateKey string `json:"private_key,omitempty"`
+
+	// PublicKeys are the PEM-encoded PKIX Ed25519 public keys of the
+	// services whose requests are accepted, for the "ed25519" method.
+	PublicKeys []string `json:"public_keys,omitempty"`
+
+	// JWT configures the "jwt" method.
+	JWT *JWTServiceAuth `json:"jwt,omitempty"`
+}
+
+// JWTServiceAuth configures service to service authentication using JWTs.
+type JWTServiceAuth struct {
+	// Algorithm is the signing algorithm, either "HS256" or "RS256".
+	Algorithm string `json:"algorithm"`
+
+	// Secret is the shared secret tokens are signed with, for HS256.
+	Secret []byte `json:"secret,omitempty"`
+
+	// PrivateKey is the PEM-encoded RSA private key tokens are signed with,
+	// and PublicKeys the PEM-encoded PKIX RSA public keys tokens are
+	// accepted from, for RS256.
+	PrivateKey string   `json:"private_key,omitempty"`
+	PublicKeys []string `json:"public_keys,omitempty"`
+
+	// Issuer and Audience are set in signed tokens, and if non-empty
+	// are required to match when verifying tokens.
+	Issuer   string `json:"issuer,omitempty"`
+	Audience string `json:"audience,omitempty"`
+
+	// TTL is how long signed tokens are valid for.
+	// If zero, svcauth.DefaultMaxClockSkew is used.
+	TTL time.Duration `json:"ttl,omitempty"`
+
+	// Leeway is the clock skew allowed when validating
+	// a token's expiry and not before times.
+	Leeway time.Duration `json:"leeway,omitempty"`
 }
 
 // UnsafeAllOriginWithCredentials can be used to specify that all origins are
@@ -402,8 +462,10 @@ type RedisDatabase struct {
 }
 
 type BucketProvider struct {
-	S3  *S3BucketProvider  `json:"s3,omitempty"`  // set if the provider is S3
-	GCS *GCSBucketProvider `json:"gcs,omitempty"` // set if the provider is GCS
+	S3     *S3BucketProvider     `json:"s3,omitempty"`     // set if the provider is S3
+	GCS    *GCSBucketProvider    `json:"gcs,omitempty"`    // set if the provider is GCS
+	Local  *LocalBucketProvider  `json:"local,omitempty"`  // set if the provider is the local file system
+	Memory *MemoryBucketProvider `json:"memory,omitempty"` // set if the provider is in-memory
 }
 
 type S3BucketProvider struct {
@@ -415,6 +477,203 @@ type S3BucketProvider struct {
 	// The access key to use. If either is nil, the default credentials are used.
 	AccessKeyID     *string `json:"access_key_id"`
 	SecretAccessKey *string `json:"secret_access_key"`
+
+	// Whether to address buckets in the path of the URL (endpoint/bucket/key)
+	// rather than in the host name. Required by MinIO and some other
+	// S3-compatible stores.
+	UsePathStyle bool `json:"use_path_style,omitempty"`
+
+	// Upload configures how objects are uploaded to the provider's buckets.
+	// If nil, the defaults are used.
+	Upload *S3UploadOptions `json:"upload,omitempty"`
+
+	// Download configures how objects are downloaded from the provider's buckets.
+	// If nil, objects are downloaded using a single request.
+	Download *S3DownloadOptions `json:"download,omitempty"`
+
+	// Whether the provider's buckets are requester-pays buckets,
+	// acknowledging that the app is charged for reading from them.
+	RequesterPays bool `json:"requester_pays,omitempty"`
+
+	// RequestTimeout bounds how long each request to S3 may take.
+	// If zero, requests have no timeout.
+	RequestTimeout time.Duration `json:"request_timeout,omitempty"`
+
+	// Whether to create OpenTelemetry spans for operations on the provider's
+	// buckets, using the global tracer provider.
+	Tracing bool `json:"tracing,omitempty"`
+
+	// Whether to report operations on the provider's buckets as metrics.
+	Metrics bool `json:"metrics,omitempty"`
+
+	// Whether uploads and copies reject object keys containing control characters.
+	RejectControlChars bool `json:"reject_control_chars,omitempty"`
+
+	// Profile is the name of the profile in the shared AWS config and
+	// credentials files to use, instead of the default credential chain.
+	Profile string `json:"profile,omitempty"`
+
+	// WebIdentity, if set, assumes a role by exchanging a web identity token,
+	// as used by IAM Roles for Service Accounts (IRSA) on EKS.
+	WebIdentity *S3WebIdentity `json:"web_identity,omitempty"`
+
+	// AssumeRoleARN, if set, is the ARN of a role to assume using
+	// the otherwise configured credentials.
+	AssumeRoleARN string `json:"assume_role_arn,omitempty"`
+
+	// Whether to send requests through S3 Transfer Acceleration.
+	// It can't be combined with UsePathStyle.
+	UseAccelerate bool `json:"use_accelerate,omitempty"`
+
+	// Whether to use dual-stack endpoints, which support IPv6 as well as IPv4.
+	UseDualStack bool `json:"use_dual_stack,omitempty"`
+
+	// Retry configures how requests that fail with a transient error are
+	// retried, for all operations on the provider's buckets. It takes
+	// precedence over Upload.MaxRetries. If nil, only uploads, copies
+	// and bulk removals are retried.
+	Retry *S3RetryPolicy `json:"retry,omitempty"`
+
+	// CircuitBreaker, if set, guards requests to the provider's buckets
+	// with a circuit breaker, which makes them fail fast during an outage.
+	CircuitBreaker *S3CircuitBreaker `json:"circuit_breaker,omitempty"`
+
+	// Whether removed objects are moved to a trash prefix
+	// rather than being deleted permanently.
+	SoftDelete bool `json:"soft_delete,omitempty"`
+
+	// Whether to record the trace and span IDs of the request
+	// each object is uploaded from as metadata of the object.
+	ContextMetadata bool `json:"context_metadata,omitempty"`
+
+	// ReadCache, if set, caches the contents of small objects
+	// downloaded from the provider's buckets in memory.
+	ReadCache *S3ReadCache `json:"read_cache,omitempty"`
+}
+
+// S3UploadOptions configures how objects are uploaded to S3.
+type S3UploadOptions struct {
+	// MaxRetries is the maximum number of times a request that fails
+	// with a transient error is retried. If nil, it defaults to 3.
+	MaxRetries *int `json:"max_retries,omitempty"`
+
+	// Concurrency is the maximum number of parts of a multipart upload
+	// that are uploaded in parallel. If zero, it defaults to 4.
+	Concurrency int `json:"concurrency,omitempty"`
+
+	// Checksum is the algorithm of the additional checksums S3 verifies
+	// uploaded data with, either "crc32" or "sha256".
+	// If empty, no additional checksums are sent.
+	Checksum string `json:"checksum,omitempty"`
+
+	// Encryption configures server-side encryption of uploaded objects.
+	// If nil, the bucket's default encryption is used.
+	Encryption *S3Encryption `json:"encryption,omitempty"`
+
+	// StorageClass is the S3 storage class of uploaded objects,
+	// such as "STANDARD_IA". If empty, S3 uses STANDARD.
+	StorageClass string `json:"storage_class,omitempty"`
+
+	// DryRun validates uploads and builds their requests without sending them.
+	DryRun bool `json:"dry_run,omitempty"`
+
+	// ObjectLock configures S3 Object Lock retention and legal holds
+	// for uploaded objects. If nil, the bucket's defaults are used.
+	ObjectLock *S3ObjectLock `json:"object_lock,omitempty"`
+
+	// CompressGzip compresses uploaded objects with gzip
+	// and sets their Content-Encoding to "gzip".
+	CompressGzip bool `json:"compress_gzip,omitempty"`
+
+	// ACL is the canned ACL of uploaded objects, such as "bucket-owner-full-control".
+	// If empty, no ACL is sent and S3 applies the bucket's default.
+	ACL string `json:"acl,omitempty"`
+
+	// Verify looks up each object once its upload completes,
+	// and checks that its size and ETag match what was uploaded.
+	Verify bool `json:"verify,omitempty"`
+}
+
+// S3Encryption configures server-side encryption of S3 objects.
+type S3Encryption struct {
+	// Mode is the kind of encryption: "s3" for S3-managed keys (SSE-S3),
+	// "kms" for AWS KMS keys (SSE-KMS), or "customer" for keys provided
+	// by the customer (SSE-C).
+	Mode string `json:"mode"`
+
+	// KMSKeyID is the ID or ARN of the KMS key, for the "kms" mode.
+	// If empty, the AWS managed key for S3 is used.
+	KMSKeyID string `json:"kms_key_id,omitempty"`
+
+	// CustomerKey is the 256-bit AES key, for the "customer" mode.
+	CustomerKey []byte `json:"customer_key,omitempty"`
+}
+
+// S3DownloadOptions configures how objects are downloaded from S3.
+type S3DownloadOptions struct {
+	// Concurrency is the number of chunks of an object that are downloaded
+	// in parallel. If less than 2, objects are downloaded using a single request.
+	Concurrency int `json:"concurrency,omitempty"`
+
+	// ChunkSize is the size in bytes of each chunk when downloading in parallel.
+	// If zero, it defaults to 8 MiB.
+	ChunkSize int64 `json:"chunk_size,omitempty"`
+}
+
+// S3ObjectLock configures S3 Object Lock settings of uploaded objects.
+type S3ObjectLock struct {
+	// Mode is the retention mode, either "governance" or "compliance".
+	// If empty, no retention is set.
+	Mode string `json:"mode,omitempty"`
+
+	// RetainFor is the retention period, starting when each object is uploaded.
+	// It must be set if and only if Mode is set.
+	RetainFor time.Duration `json:"retain_for,omitempty"`
+
+	// LegalHold places a legal hold on uploaded objects.
+	LegalHold bool `json:"legal_hold,omitempty"`
+}
+
+// S3WebIdentity configures assuming a role with a web identity token.
+type S3WebIdentity struct {
+	RoleARN   string `json:"role_arn"`
+	TokenFile string `json:"token_file"`
+}
+
+// S3RetryPolicy configures how requests to S3 are retried.
+type S3RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of each request,
+	// including the first.
+	MaxAttempts int `json:"max_attempts,omitempty"`
+
+	// BaseDelay is the delay before the first retry, which doubles with
+	// each subsequent retry, up to MaxDelay. If zero, the defaults are used.
+	BaseDelay time.Duration `json:"base_delay,omitempty"`
+	MaxDelay  time.Duration `json:"max_delay,omitempty"`
+
+	// Jitter is the fraction of each delay that's randomized, between 0 and 1.
+	Jitter float64 `json:"jitter,omitempty"`
+}
+
+// S3CircuitBreaker configures a circuit breaker for requests to S3.
+type S3CircuitBreaker struct {
+	// FailureThreshold is the number of consecutive failed requests
+	// that trip the breaker. If zero, it defaults to 5.
+	FailureThreshold int `json:"failure_threshold,omitempty"`
+
+	// Cooldown is how long the breaker stays open before letting
+	// a probe request through. If zero, it defaults to 30s.
+	Cooldown time.Duration `json:"cooldown,omitempty"`
+}
+
+// S3ReadCache configures an in-memory cache of downloaded objects.
+type S3ReadCache struct {
+	// MaxBytes is the memory budget for the contents of cached objects.
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+
+	// MaxObjectSize is the size of the largest object to cache.
+	// If zero, it defaults to 1 MiB.
+	MaxObjectSize int64 `json:"max_object_size,omitempty"`
 }
 
 type GCSBucketProvider struct {
@@ -426,6 +685,16 @@ type GCSBucketProvider struct {
 	LocalSign *GCSLocalSignOptions `json:"local_sign,omitempty"`
 }
 
+type LocalBucketProvider struct {
+	// The directory to store buckets in.
+	// Each bucket is stored in a subdirectory named after its cloud name.
+	Dir string `json:"dir"`
+}
+
+// MemoryBucketProvider stores objects in memory, for use in tests.
+// Objects are lost when the process exits.
+type MemoryBucketProvider struct{}
+
 type GCSLocalSignOptions struct {
 	BaseURL    string `json:"base_url"`
 	AccessID   string `json:"access_id"`
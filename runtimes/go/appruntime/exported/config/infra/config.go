@@ -38,9 +38,10 @@ type InfraConfig struct {
 }
 
 type ObjectStorage struct {
-	Type string `json:"type"`
-	GCS  *GCS   `json:"gcs,omitempty"`
-	S3   *S3    `json:"s3,omitempty"`
+	Type  string `json:"type"`
+	GCS   *GCS   `json:"gcs,omitempty"`
+	S3    *S3    `json:"s3,omitempty"`
+	Local *Local `json:"local,omitempty"`
 }
 
 func (o *ObjectStorage) GetBuckets() map[string]*Bucket {
@@ -49,6 +50,8 @@ func (o *ObjectStorage) GetBuckets() map[string]*Bucket {
 		return o.GCS.Buckets
 	case "s3":
 		return o.S3.Buckets
+	case "local":
+		return o.Local.Buckets
 	default:
 		panic("unsupported object storage type")
 	}
@@ -60,6 +63,8 @@ func (o *ObjectStorage) DeleteBucket(name string) {
 		delete(o.GCS.Buckets, name)
 	case "s3":
 		delete(o.S3.Buckets, name)
+	case "local":
+		delete(o.Local.Buckets, name)
 	default:
 		panic("unsupported object storage type")
 	}
@@ -67,12 +72,14 @@ func (o *ObjectStorage) DeleteBucket(name string) {
 }
 
 func (a *ObjectStorage) Validate(v *validator) {
-	v.ValidateField("Type", OneOf(a.Type, "gcs", "s3"))
+	v.ValidateField("Type", OneOf(a.Type, "gcs", "s3", "local"))
 	switch a.Type {
 	case "gcs":
 		a.GCS.Validate(v)
 	case "s3":
 		a.S3.Validate(v)
+	case "local":
+		a.Local.Validate(v)
 	default:
 		v.ValidateField("type", Err("unsupported object storage type"))
 	}
@@ -99,6 +106,12 @@ func (p *ObjectStorage) MarshalJSON() ([]byte, error) {
 				m[k] = v
 			}
 		}
+	case "local":
+		if p.Local != nil {
+			for k, v := range structToMap(p.Local) {
+				m[k] = v
+			}
+		}
 	default:
 		return nil, errors.New("unsupported object storage type")
 	}
@@ -133,6 +146,12 @@ func (p *ObjectStorage) UnmarshalJSON(data []byte) error {
 			return err
 		}
 		p.S3 = &a
+	case "local":
+		var l Local
+		if err := json.Unmarshal(data, &l); err != nil {
+			return err
+		}
+		p.Local = &l
 	default:
 		return errors.New("unsupported object storage type")
 	}
@@ -146,6 +165,25 @@ type S3 struct {
 
 	AccessKeyID     string    `json:"access_key_id,omitempty"`
 	SecretAccessKey EnvString `json:"secret_access_key,omitempty"`
+	UsePathStyle    bool      `json:"use_path_style,omitempty"`
+
+	Upload             *S3Upload         `json:"upload,omitempty"`
+	Download           *S3Download       `json:"download,omitempty"`
+	RequesterPays      bool              `json:"requester_pays,omitempty"`
+	RequestTimeout     int               `json:"request_timeout,omitempty"` // seconds
+	Tracing            bool              `json:"tracing,omitempty"`
+	Metrics            bool              `json:"metrics,omitempty"`
+	RejectControlChars bool              `json:"reject_control_chars,omitempty"`
+	Profile            string            `json:"profile,omitempty"`
+	WebIdentity        *S3WebIdentity    `json:"web_identity,omitempty"`
+	AssumeRoleARN      string            `json:"assume_role_arn,omitempty"`
+	UseAccelerate      bool              `json:"use_accelerate,omitempty"`
+	UseDualStack       bool              `json:"use_dual_stack,omitempty"`
+	Retry              *S3Retry          `json:"retry,omitempty"`
+	CircuitBreaker     *S3CircuitBreaker `json:"circuit_breaker,omitempty"`
+	SoftDelete         bool              `json:"soft_delete,omitempty"`
+	ContextMetadata    bool              `json:"context_metadata,omitempty"`
+	ReadCache          *S3ReadCache      `json:"read_cache,omitempty"`
 
 	Buckets map[string]*Bucket `json:"buckets,omitempty"`
 }
@@ -155,9 +193,141 @@ func (a *S3) Validate(v *validator) {
 	if a.AccessKeyID != "" {
 		v.ValidatePtrEnvRef("secret_access_key", &a.SecretAccessKey, "S3 Secret Access Key", NotZero[string])
 	}
+	v.ValidateChild("upload", a.Upload)
+	v.ValidateChild("download", a.Download)
+	v.ValidateField("request_timeout", GreaterOrEqual(0)(a.RequestTimeout))
+	sources := 0
+	for _, set := range []bool{a.AccessKeyID != "", a.Profile != "", a.WebIdentity != nil} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		v.ValidateField("profile", Err("At most one of access_key_id, profile and web_identity can be set"))
+	}
+	v.ValidateChild("web_identity", a.WebIdentity)
+	if a.UseAccelerate && a.UsePathStyle {
+		v.ValidateField("use_accelerate", Err("Can't be used with use_path_style"))
+	}
+	v.ValidateChild("retry", a.Retry)
+	v.ValidateChild("circuit_breaker", a.CircuitBreaker)
+	v.ValidateChild("read_cache", a.ReadCache)
 	ValidateChildMap(v, "buckets", a.Buckets)
 }
 
+// S3Upload configures how objects are uploaded to S3.
+type S3Upload struct {
+	MaxRetries   *int          `json:"max_retries,omitempty"`
+	Concurrency  int           `json:"concurrency,omitempty"`
+	Checksum     string        `json:"checksum,omitempty"`
+	Encryption   *S3Encryption `json:"encryption,omitempty"`
+	StorageClass string        `json:"storage_class,omitempty"`
+	DryRun       bool          `json:"dry_run,omitempty"`
+	ObjectLock   *S3ObjectLock `json:"object_lock,omitempty"`
+	CompressGzip bool          `json:"compress_gzip,omitempty"`
+	ACL          string        `json:"acl,omitempty"`
+	Verify       bool          `json:"verify,omitempty"`
+}
+
+func (u *S3Upload) Validate(v *validator) {
+	v.ValidateField("max_retries", NilOr(u.MaxRetries, GreaterOrEqual(0)))
+	v.ValidateField("concurrency", GreaterOrEqual(0)(u.Concurrency))
+	v.ValidateField("checksum", OneOf(u.Checksum, "", "crc32", "sha256"))
+	v.ValidateChild("encryption", u.Encryption)
+	v.ValidateChild("object_lock", u.ObjectLock)
+}
+
+// S3Encryption configures server-side encryption of S3 objects.
+type S3Encryption struct {
+	Mode     string `json:"mode,omitempty"`
+	KMSKeyID string `json:"kms_key_id,omitempty"`
+
+	// CustomerKey is the base64-encoded key for the "customer" mode.
+	CustomerKey EnvString `json:"customer_key,omitempty"`
+}
+
+func (e *S3Encryption) Validate(v *validator) {
+	v.ValidateField("mode", OneOf(e.Mode, "s3", "kms", "customer"))
+	if e.Mode == "customer" {
+		v.ValidateEnvString("customer_key", e.CustomerKey, "S3 SSE-C Customer Key", NotZero[string])
+	}
+}
+
+// S3Download configures how objects are downloaded from S3.
+type S3Download struct {
+	Concurrency int   `json:"concurrency,omitempty"`
+	ChunkSize   int64 `json:"chunk_size,omitempty"`
+}
+
+func (d *S3Download) Validate(v *validator) {
+	v.ValidateField("concurrency", GreaterOrEqual(0)(d.Concurrency))
+	v.ValidateField("chunk_size", GreaterOrEqual(int64(0))(d.ChunkSize))
+}
+
+// S3ObjectLock configures S3 Object Lock settings of uploaded objects.
+type S3ObjectLock struct {
+	Mode       string `json:"mode,omitempty"`
+	RetainDays int    `json:"retain_days,omitempty"`
+	LegalHold  bool   `json:"legal_hold,omitempty"`
+}
+
+func (l *S3ObjectLock) Validate(v *validator) {
+	v.ValidateField("mode", OneOf(l.Mode, "", "governance", "compliance"))
+	if l.Mode != "" {
+		v.ValidateField("retain_days", GreaterOrEqual(1)(l.RetainDays))
+	} else if l.RetainDays != 0 {
+		v.ValidateField("mode", Err("Must be set when retain_days is set"))
+	}
+}
+
+// S3WebIdentity configures assuming a role with a web identity token.
+type S3WebIdentity struct {
+	RoleARN   string `json:"role_arn"`
+	TokenFile string `json:"token_file"`
+}
+
+func (w *S3WebIdentity) Validate(v *validator) {
+	v.ValidateField("role_arn", NotZero(w.RoleARN))
+	v.ValidateField("token_file", NotZero(w.TokenFile))
+}
+
+// S3Retry configures how requests to S3 are retried.
+type S3Retry struct {
+	MaxAttempts int     `json:"max_attempts,omitempty"`
+	BaseDelayMs int     `json:"base_delay_ms,omitempty"`
+	MaxDelayMs  int     `json:"max_delay_ms,omitempty"`
+	Jitter      float64 `json:"jitter,omitempty"`
+}
+
+func (r *S3Retry) Validate(v *validator) {
+	v.ValidateField("max_attempts", GreaterOrEqual(0)(r.MaxAttempts))
+	v.ValidateField("base_delay_ms", GreaterOrEqual(0)(r.BaseDelayMs))
+	v.ValidateField("max_delay_ms", GreaterOrEqual(0)(r.MaxDelayMs))
+	v.ValidateField("jitter", Between(0.0, 1.0)(r.Jitter))
+}
+
+// S3CircuitBreaker configures a circuit breaker for requests to S3.
+type S3CircuitBreaker struct {
+	FailureThreshold int `json:"failure_threshold,omitempty"`
+	Cooldown         int `json:"cooldown,omitempty"` // seconds
+}
+
+func (b *S3CircuitBreaker) Validate(v *validator) {
+	v.ValidateField("failure_threshold", GreaterOrEqual(0)(b.FailureThreshold))
+	v.ValidateField("cooldown", GreaterOrEqual(0)(b.Cooldown))
+}
+
+// S3ReadCache configures an in-memory cache of downloaded objects.
+type S3ReadCache struct {
+	MaxBytes      int64 `json:"max_bytes"`
+	MaxObjectSize int64 `json:"max_object_size,omitempty"`
+}
+
+func (c *S3ReadCache) Validate(v *validator) {
+	v.ValidateField("max_bytes", GreaterOrEqual(int64(1))(c.MaxBytes))
+	v.ValidateField("max_object_size", GreaterOrEqual(int64(0))(c.MaxObjectSize))
+}
+
 type GCS struct {
 	Endpoint string             `json:"endpoint,omitempty"`
 	Buckets  map[string]*Bucket `json:"buckets,omitempty"`
@@ -167,6 +337,19 @@ func (a *GCS) Validate(v *validator) {
 	ValidateChildMap(v, "buckets", a.Buckets)
 }
 
+// Local stores the objects of its buckets on the local file system.
+type Local struct {
+	// Dir is the directory to store buckets in.
+	// Each bucket is stored in a subdirectory named after its cloud name.
+	Dir     string             `json:"dir,omitempty"`
+	Buckets map[string]*Bucket `json:"buckets,omitempty"`
+}
+
+func (a *Local) Validate(v *validator) {
+	v.ValidateField("dir", NotZero(a.Dir))
+	ValidateChildMap(v, "buckets", a.Buckets)
+}
+
 type Bucket struct {
 	Name          string `json:"name,omitempty"`
 	KeyPrefix     string `json:"key_prefix,omitempty"`
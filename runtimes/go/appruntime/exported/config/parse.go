@@ -86,7 +86,7 @@ func parseProcessConfigEnv(processCfg string, cfg *Runtime) {
 	cfg.Gateways = hostedGateways
 
 	// Use noop service auth method if not specified
-	svcAuth := ServiceAuth{"noop"}
+	svcAuth := ServiceAuth{Method: "noop"}
 	if len(cfg.ServiceAuth) > 0 {
 		// Use the first service auth method from the runtime config
 		svcAuth = cfg.ServiceAuth[0]
@@ -366,6 +366,7 @@ func parseInfraConfigEnv(infraCfgPath string) *Runtime {
 
 	// Map Buckets
 	cfg.BucketProviders = make([]*BucketProvider, len(infraCfg.ObjectStorage))
+	cfg.Buckets = map[string]*Bucket{}
 	for i, storage := range infraCfg.ObjectStorage {
 		switch storage.Type {
 		case "gcs":
@@ -375,16 +376,72 @@ func parseInfraConfigEnv(infraCfgPath string) *Runtime {
 				},
 			}
 		case "s3":
+			s3 := &S3BucketProvider{
+				Region:             storage.S3.Region,
+				Endpoint:           nilOr(storage.S3.Endpoint),
+				AccessKeyID:        nilOr(storage.S3.AccessKeyID),
+				SecretAccessKey:    nilOr(storage.S3.SecretAccessKey.Value()),
+				UsePathStyle:       storage.S3.UsePathStyle,
+				RequesterPays:      storage.S3.RequesterPays,
+				RequestTimeout:     time.Duration(storage.S3.RequestTimeout) * time.Second,
+				Tracing:            storage.S3.Tracing,
+				Metrics:            storage.S3.Metrics,
+				RejectControlChars: storage.S3.RejectControlChars,
+				Profile:            storage.S3.Profile,
+				AssumeRoleARN:      storage.S3.AssumeRoleARN,
+				UseAccelerate:      storage.S3.UseAccelerate,
+				UseDualStack:       storage.S3.UseDualStack,
+				SoftDelete:         storage.S3.SoftDelete,
+				ContextMetadata:    storage.S3.ContextMetadata,
+			}
+			if upload := storage.S3.Upload; upload != nil {
+				s3.Upload = &S3UploadOptions{
+					MaxRetries:   upload.MaxRetries,
+					Concurrency:  upload.Concurrency,
+					Checksum:     upload.Checksum,
+					Encryption:   parseS3Encryption(upload.Encryption),
+					StorageClass: upload.StorageClass,
+					DryRun:       upload.DryRun,
+					ObjectLock:   parseS3ObjectLock(upload.ObjectLock),
+					CompressGzip: upload.CompressGzip,
+					ACL:          upload.ACL,
+					Verify:       upload.Verify,
+				}
+			}
+			if download := storage.S3.Download; download != nil {
+				s3.Download = &S3DownloadOptions{
+					Concurrency: download.Concurrency,
+					ChunkSize:   download.ChunkSize,
+				}
+			}
+			if w := storage.S3.WebIdentity; w != nil {
+				s3.WebIdentity = &S3WebIdentity{RoleARN: w.RoleARN, TokenFile: w.TokenFile}
+			}
+			if retry := storage.S3.Retry; retry != nil {
+				s3.Retry = &S3RetryPolicy{
+					MaxAttempts: retry.MaxAttempts,
+					BaseDelay:   time.Duration(retry.BaseDelayMs) * time.Millisecond,
+					MaxDelay:    time.Duration(retry.MaxDelayMs) * time.Millisecond,
+					Jitter:      retry.Jitter,
+				}
+			}
+			if cb := storage.S3.CircuitBreaker; cb != nil {
+				s3.CircuitBreaker = &S3CircuitBreaker{
+					FailureThreshold: cb.FailureThreshold,
+					Cooldown:         time.Duration(cb.Cooldown) * time.Second,
+				}
+			}
+			if rc := storage.S3.ReadCache; rc != nil {
+				s3.ReadCache = &S3ReadCache{MaxBytes: rc.MaxBytes, MaxObjectSize: rc.MaxObjectSize}
+			}
+			cfg.BucketProviders[i] = &BucketProvider{S3: s3}
+		case "local":
 			cfg.BucketProviders[i] = &BucketProvider{
-				S3: &S3BucketProvider{
-					Region:          storage.S3.Region,
-					Endpoint:        nilOr(storage.S3.Endpoint),
-					AccessKeyID:     nilOr(storage.S3.AccessKeyID),
-					SecretAccessKey: nilOr(storage.S3.SecretAccessKey.Value()),
+				Local: &LocalBucketProvider{
+					Dir: storage.Local.Dir,
 				},
 			}
 		}
-		cfg.Buckets = map[string]*Bucket{}
 		for bucketName, bucket := range storage.GetBuckets() {
 			cfg.Buckets[bucketName] = &Bucket{
 				ProviderID:    i,
@@ -418,6 +475,33 @@ func parseInfraConfigEnv(infraCfgPath string) *Runtime {
 	return &cfg
 }
 
+// parseS3Encryption maps the encryption settings of an S3 provider.
+func parseS3Encryption(enc *infra.S3Encryption) *S3Encryption {
+	if enc == nil {
+		return nil
+	}
+	out := &S3Encryption{Mode: enc.Mode, KMSKeyID: enc.KMSKeyID}
+	if key := enc.CustomerKey.Value(); key != "" {
+		var err error
+		if out.CustomerKey, err = base64.StdEncoding.DecodeString(key); err != nil {
+			log.Fatalf("encore runtime: fatal error: invalid S3 customer key: %v", err)
+		}
+	}
+	return out
+}
+
+// parseS3ObjectLock maps the Object Lock settings of an S3 provider.
+func parseS3ObjectLock(lock *infra.S3ObjectLock) *S3ObjectLock {
+	if lock == nil {
+		return nil
+	}
+	return &S3ObjectLock{
+		Mode:      lock.Mode,
+		RetainFor: time.Duration(lock.RetainDays) * 24 * time.Hour,
+		LegalHold: lock.LegalHold,
+	}
+}
+
 func nilOr[T comparable](val T) *T {
 	var zero T
 	if val == zero {
@@ -5,58 +5,262 @@
 package experiments
 
 import (
+	"encoding/json"
+	"fmt"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
 // FromAppFileAndEnviron creates an experiment set which represents the enabled experiments
 // within a particular run of Encore.
 //
-// All errors reported by FromAppFileAndEnviron are due to unknown experiment names.
-// The error type is of type *UnknownExperimentError.
+// Experiments can be explicitly disabled by prefixing their name with "-",
+// such as ENCORE_EXPERIMENT=-metrics. Disabling takes precedence over
+// enabling: a disabled experiment is removed from the set after all
+// experiments from the app file and environment have been added,
+// regardless of the order they were specified in.
+//
+// Experiments can also be listed in a JSON or YAML file, whose path is given
+// by ENCORE_EXPERIMENT_FILE. The file contains a list of experiment names,
+// which may likewise be prefixed with "-" to disable them.
+//
+// In the environment variable and the experiments file, the special value
+// "all" enables every known experiment, and "none" disables every experiment
+// enabled or disabled before it, including those from the app file.
+// For example ENCORE_EXPERIMENT=all,-v2 enables every experiment except v2.
+//
+// An experiment can be enabled or disabled for a single service by suffixing
+// its name with "@" and the service name, such as ENCORE_EXPERIMENT=v2@svc-a
+// or ENCORE_EXPERIMENT=v2,-v2@svc-b. Such experiments are only reflected in
+// the set returned by Set.For for that service, and are applied on top of
+// the experiments enabled for every service. Apps built with the set
+// evaluate them for the service handling each API call.
+//
+// An experiment can be qualified with the version of Encore it requires by
+// suffixing its name with ">=" and the version, such as "name>=v1.50.0".
+// Older versions of Encore then report it as requiring an upgrade when
+// the set is checked with Set.CheckVersion, instead of as unknown.
+//
+// Unknown experiment names are reported as an *UnknownExperimentError,
+// unless they're qualified with a version.
 func FromAppFileAndEnviron(fromAppFile []Name, environ []string) (*Set, error) {
-	const envName = "ENCORE_EXPERIMENT"
+	return fromAppFileAndEnviron(defaultEnvName, fromAppFile, environ, false)
+}
+
+// FromAppFileAndEnvironWithEnvName is like FromAppFileAndEnviron, but reads
+// the enabled experiments from the environment variable envName
+// instead of ENCORE_EXPERIMENT, and the experiments file from
+// envName+"_FILE".
+//
+// It is intended for tools embedding Encore that use their own
+// environment variable conventions.
+func FromAppFileAndEnvironWithEnvName(envName string, fromAppFile []Name, environ []string) (*Set, error) {
+	return fromAppFileAndEnviron(envName, fromAppFile, environ, false)
+}
+
+// FromAppFileAndEnvironLenient is like FromAppFileAndEnviron, but unknown
+// experiment names are recorded in the set's Warnings instead of being reported
+// as an error. This allows older versions of Encore to run apps that enable
+// experiments introduced in newer versions, without those experiments.
+func FromAppFileAndEnvironLenient(fromAppFile []Name, environ []string) (*Set, error) {
+	return fromAppFileAndEnviron(defaultEnvName, fromAppFile, environ, true)
+}
+
+// FromList creates an experiment set with the given experiments enabled,
+// without consulting the environment. This makes it suitable for tests
+// and for tools embedding Encore that manage experiments themselves.
+//
+// The names are interpreted and validated like those in the app file:
+// names prefixed with "-" are disabled, and unknown names are reported as an
+// *UnknownExperimentError.
+func FromList(names ...Name) (*Set, error) {
+	set := &Set{enabled: make(map[Name]struct{})}
+	disabled, err := set.add(false, names...)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range disabled {
+		delete(set.enabled, key)
+	}
+	return set, nil
+}
 
-	set := &Set{make(map[Name]struct{})}
+// defaultEnvName is the environment variable experiments are read from by default.
+const defaultEnvName = "ENCORE_EXPERIMENT"
+
+// fileEnvSuffix is appended to the name of the experiments environment variable
+// to get the name of the variable holding the path to an experiments file.
+const fileEnvSuffix = "_FILE"
+
+// envValues returns the non-empty values of the environment variable name,
+// first from this process and then from environ.
+func envValues(name string, environ []string) []string {
+	var vals []string
+	if val := os.Getenv(name); val != "" {
+		vals = append(vals, val)
+	}
+	prefix := name + "="
+	for _, env := range environ {
+		if val, ok := strings.CutPrefix(env, prefix); ok && val != "" {
+			vals = append(vals, val)
+		}
+	}
+	return vals
+}
+
+// readExperimentsFile reads the list of experiments in the file at path.
+// Files with a .json extension are decoded as JSON and others as YAML.
+func readExperimentsFile(path string) ([]Name, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read experiments file: %w", err)
+	}
+
+	var names []Name
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &names)
+	} else {
+		err = yaml.Unmarshal(data, &names)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse experiments file %s: %w", path, err)
+	}
+	return names, nil
+}
+
+func fromAppFileAndEnviron(envName string, fromAppFile []Name, environ []string, lenient bool) (*Set, error) {
+	set := &Set{enabled: make(map[Name]struct{})}
+	var disabled []Name
+	add := func(keys ...Name) error {
+		d, err := set.add(lenient, keys...)
+		disabled = append(disabled, d...)
+		return err
+	}
+	addExpanded := func(keys []Name) error {
+		keys, reset := expandSpecial(keys)
+		if reset {
+			clear(set.enabled)
+			clear(set.services)
+			clear(set.required)
+			disabled = nil
+		}
+		return add(keys...)
+	}
 
 	// Add experiments enabled in the app file
-	if err := set.add(fromAppFile...); err != nil {
+	if err := add(fromAppFile...); err != nil {
 		return nil, err
 	}
 
-	// Grab experiments from the environmental variables of this process.
-	if val := os.Getenv(envName); val != "" {
-		if err := set.add(parseEnvVal(val)...); err != nil {
+	// Grab experiments from the experiments files, if any.
+	for _, path := range envValues(envName+fileEnvSuffix, environ) {
+		names, err := readExperimentsFile(path)
+		if err != nil {
 			return nil, err
 		}
+		if err := addExpanded(names); err != nil {
+			return nil, fmt.Errorf("experiments file %s: %w", path, err)
+		}
 	}
 
-	// Grab experiments from the environmental variables of the caller
-	const prefix = envName + "="
-	for _, env := range environ {
-		if strings.HasPrefix(env, prefix) {
-			val := env[len(prefix):]
-			if err := set.add(parseEnvVal(val)...); err != nil {
-				return nil, err
-			}
+	// Grab experiments from the environmental variables
+	// of this process and of the caller.
+	for _, val := range envValues(envName, environ) {
+		if err := addExpanded(parseEnvVal(val)); err != nil {
+			return nil, err
 		}
 	}
 
+	// Disabling wins over enabling.
+	for _, key := range disabled {
+		delete(set.enabled, key)
+	}
 	return set, nil
 }
 
-func (s *Set) add(keys ...Name) error {
+// add adds the given experiments to the set.
+// Names prefixed with "-" are not added, and are instead returned
+// so they can be removed once all experiments have been added.
+// Names suffixed with "@service" are added to the overrides for that
+// service instead, where disabling already takes precedence.
+//
+// If lenient is true unknown experiments are skipped and added to s.Warnings
+// instead of being reported as an error.
+func (s *Set) add(lenient bool, keys ...Name) (disabled []Name, err error) {
 	for _, key := range keys {
 		if key == "" {
 			continue
 		}
 
-		if !key.Valid() {
-			return &UnknownExperimentError{key}
+		name, disable := strings.CutPrefix(string(key), "-")
+		name, minVersion, versioned := strings.Cut(name, ">=")
+		if _, ok := parseVersion(minVersion); versioned && !ok {
+			return nil, fmt.Errorf("experiment %s: invalid version %q", key, minVersion)
+		}
+		name, service, scoped := strings.Cut(name, "@")
+		if scoped && service == "" {
+			return nil, fmt.Errorf("experiment %s: missing service name after @", key)
+		}
+		if versioned && !disable {
+			if s.required == nil {
+				s.required = make(map[Name]string)
+			}
+			s.required[Name(name)] = minVersion
+		}
+		if !Name(name).Valid() {
+			if versioned {
+				// Reported by CheckVersion.
+				continue
+			}
+			if !lenient {
+				return nil, &UnknownExperimentError{Name(name)}
+			}
+			s.Warnings = append(s.Warnings, UnknownExperimentError{Name(name)})
+			continue
+		}
+
+		switch {
+		case scoped:
+			s.overridesFor(service).add(Name(name), disable)
+		case disable:
+			disabled = append(disabled, Name(name))
+		default:
+			s.enabled[Name(name)] = struct{}{}
+		}
+	}
+	return disabled, nil
+}
+
+// Special values that can be used in place of experiment names.
+const (
+	// allExperimentsValue enables every known experiment.
+	allExperimentsValue Name = "all"
+
+	// noExperimentsValue disables every experiment enabled before it.
+	noExperimentsValue Name = "none"
+)
+
+// expandSpecial expands "all" into every known experiment, in sorted order.
+// The experiments up to and including the last "none" are dropped,
+// in which case reset is true and previously added experiments
+// should be removed as well.
+func expandSpecial(keys []Name) (expanded []Name, reset bool) {
+	for _, key := range keys {
+		switch key {
+		case allExperimentsValue:
+			expanded = append(expanded, slices.Sorted(maps.Keys(known))...)
+		case noExperimentsValue:
+			expanded, reset = expanded[:0], true
+		default:
+			expanded = append(expanded, key)
 		}
-		s.enabled[key] = struct{}{}
 	}
-	return nil
+	return expanded, reset
 }
 
 func parseEnvVal(val string) []Name {
@@ -0,0 +1,270 @@
+//go:build !encore_app
+
+package experiments
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestFromAppFileAndEnviron_Disable(t *testing.T) {
+	tests := []struct {
+		name    string
+		appFile []Name
+		environ []string
+		want    []Name
+	}{
+		{name: "env_disables_app_file", appFile: []Name{Metrics, V2}, environ: []string{"ENCORE_EXPERIMENT=-metrics"}, want: []Name{V2}},
+		{name: "disable_wins_same_var", environ: []string{"ENCORE_EXPERIMENT=metrics,-metrics,v2"}, want: []Name{V2}},
+		{name: "disable_wins_any_order", environ: []string{"ENCORE_EXPERIMENT=-metrics", "ENCORE_EXPERIMENT=metrics"}, want: nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			set, err := FromAppFileAndEnviron(test.appFile, test.environ)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := set.List(); !slices.Equal(got, test.want) {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	_, err := FromAppFileAndEnviron(nil, []string{"ENCORE_EXPERIMENT=-unknown"})
+	var unknown *UnknownExperimentError
+	if !errors.As(err, &unknown) || unknown.Name != "unknown" {
+		t.Fatalf("got err %v, want unknown experiment error", err)
+	}
+}
+
+func TestFromAppFileAndEnviron_Service(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		want    map[string][]Name // by service; "" is a service without overrides
+	}{
+		{
+			name:    "enable_for_service",
+			environ: []string{"ENCORE_EXPERIMENT=v2@svc-a,metrics"},
+			want:    map[string][]Name{"": {Metrics}, "svc-a": {Metrics, V2}},
+		},
+		{
+			name:    "disable_for_service",
+			environ: []string{"ENCORE_EXPERIMENT=v2,-v2@svc-b"},
+			want:    map[string][]Name{"": {V2}, "svc-a": {V2}, "svc-b": nil},
+		},
+		{
+			name:    "disable_wins_for_service",
+			environ: []string{"ENCORE_EXPERIMENT=-v2@svc-a", "ENCORE_EXPERIMENT=v2@svc-a"},
+			want:    map[string][]Name{"": nil, "svc-a": nil},
+		},
+		{
+			name:    "global_disable",
+			environ: []string{"ENCORE_EXPERIMENT=v2@svc-a,-v2"},
+			want:    map[string][]Name{"": nil, "svc-a": {V2}},
+		},
+		{
+			name:    "none_resets_services",
+			environ: []string{"ENCORE_EXPERIMENT=v2@svc-a,none,metrics"},
+			want:    map[string][]Name{"": {Metrics}, "svc-a": {Metrics}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			set, err := FromAppFileAndEnviron(nil, test.environ)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := set.List(); !slices.Equal(got, test.want[""]) {
+				t.Fatalf("got %v for every service, want %v", got, test.want[""])
+			}
+			for service, want := range test.want {
+				if got := set.For(service).List(); !slices.Equal(got, want) {
+					t.Fatalf("got %v for service %q, want %v", got, service, want)
+				}
+			}
+		})
+	}
+
+	if _, err := FromAppFileAndEnviron(nil, []string{"ENCORE_EXPERIMENT=b@"}); err == nil {
+		t.Fatal("got nil err for missing service name")
+	}
+}
+
+func TestFromAppFileAndEnvironLenient(t *testing.T) {
+	set, err := FromAppFileAndEnvironLenient([]Name{Metrics, "future"}, []string{"ENCORE_EXPERIMENT=-other,v2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := set.List(), []Name{Metrics, V2}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	want := []UnknownExperimentError{{Name: "future"}, {Name: "other"}}
+	if !slices.Equal(set.Warnings, want) {
+		t.Fatalf("got warnings %v, want %v", set.Warnings, want)
+	}
+
+	if _, err := FromAppFileAndEnviron([]Name{"future"}, nil); err == nil {
+		t.Fatal("got nil err, want unknown experiment error")
+	}
+}
+
+func TestFromAppFileAndEnvironWithEnvName(t *testing.T) {
+	environ := []string{"ENCORE_EXPERIMENT=metrics", "MYTOOL_EXPERIMENT=v2"}
+	set, err := FromAppFileAndEnvironWithEnvName("MYTOOL_EXPERIMENT", nil, environ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := set.List(), []Name{V2}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestFromAppFileAndEnviron_File(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, contents string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	yamlFile := write("experiments.yaml", "- metrics\n- v2\n")
+	jsonFile := write("experiments.json", `["-metrics"]`)
+
+	tests := []struct {
+		name    string
+		environ []string
+		want    []Name
+	}{
+		{name: "yaml", environ: []string{"ENCORE_EXPERIMENT_FILE=" + yamlFile}, want: []Name{Metrics, V2}},
+		{name: "json_disables", environ: []string{"ENCORE_EXPERIMENT_FILE=" + jsonFile, "ENCORE_EXPERIMENT=metrics,v2"}, want: []Name{V2}},
+		{name: "merged_with_env", environ: []string{"ENCORE_EXPERIMENT=v2", "ENCORE_EXPERIMENT_FILE=" + yamlFile}, want: []Name{Metrics, V2}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			set, err := FromAppFileAndEnviron(nil, test.environ)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := set.List(); !slices.Equal(got, test.want) {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	unknownFile := write("unknown.yaml", "[metrics, unknown]")
+	_, err := FromAppFileAndEnviron(nil, []string{"ENCORE_EXPERIMENT_FILE=" + unknownFile})
+	var unknown *UnknownExperimentError
+	if !errors.As(err, &unknown) || unknown.Name != "unknown" {
+		t.Fatalf("got err %v, want unknown experiment error", err)
+	}
+
+	invalidFile := write("invalid.json", `{"metrics": true}`)
+	if _, err := FromAppFileAndEnviron(nil, []string{"ENCORE_EXPERIMENT_FILE=" + invalidFile}); err == nil {
+		t.Fatal("got nil err, want parse error")
+	}
+	missing := filepath.Join(dir, "missing.yaml")
+	if _, err := FromAppFileAndEnviron(nil, []string{"ENCORE_EXPERIMENT_FILE=" + missing}); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("got err %v, want not exist error", err)
+	}
+}
+
+func TestFromAppFileAndEnviron_AllNone(t *testing.T) {
+	all := make([]Name, 0, len(allExperiments))
+	for _, meta := range allExperiments {
+		all = append(all, meta.Name)
+	}
+	slices.Sort(all)
+	withoutV2 := slices.DeleteFunc(slices.Clone(all), func(n Name) bool { return n == V2 })
+
+	tests := []struct {
+		name    string
+		appFile []Name
+		environ []string
+		want    []Name
+	}{
+		{name: "all", environ: []string{"ENCORE_EXPERIMENT=all"}, want: all},
+		{name: "all_except", environ: []string{"ENCORE_EXPERIMENT=all,-v2"}, want: withoutV2},
+		{name: "none", appFile: []Name{Metrics}, environ: []string{"ENCORE_EXPERIMENT=v2,none"}, want: nil},
+		{name: "none_then_enable", appFile: []Name{Metrics}, environ: []string{"ENCORE_EXPERIMENT=-v2,none,v2"}, want: []Name{V2}},
+		{name: "none_resets_earlier_vars", environ: []string{"ENCORE_EXPERIMENT=all", "ENCORE_EXPERIMENT=none,metrics"}, want: []Name{Metrics}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			set, err := FromAppFileAndEnviron(test.appFile, test.environ)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := set.List(); !slices.Equal(got, test.want) {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFromList(t *testing.T) {
+	// The environment is not consulted.
+	t.Setenv(defaultEnvName, string(V2))
+
+	set, err := FromList(Metrics, LocalSecretsOverride, "-"+LocalSecretsOverride)
+	if err != nil {
+		t.Fatalf("got err %v, want nil", err)
+	}
+	if got, want := set.List(), []Name{Metrics}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	var unknown *UnknownExperimentError
+	if _, err := FromList("unknown"); !errors.As(err, &unknown) || unknown.Name != "unknown" {
+		t.Fatalf("got err %v, want unknown experiment", err)
+	}
+}
+
+func TestSet_CheckVersion_Qualified(t *testing.T) {
+	const future Name = "future-experiment"
+
+	set, err := FromList(Metrics+">=v1.40.0", future+">=v1.50.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Metrics.Enabled(set) || future.Enabled(set) {
+		t.Fatalf("got enabled experiments %v, want [%s]", set.List(), Metrics)
+	}
+
+	tests := []struct {
+		version string
+		want    error
+	}{
+		{version: "v1.39.0", want: &VersionError{Name: future, MinVersion: "v1.50.0", Version: "v1.39.0"}},
+		{version: "v1.45.0", want: &VersionError{Name: future, MinVersion: "v1.50.0", Version: "v1.45.0"}},
+		{version: "v1.50.0", want: &UnknownExperimentError{Name: future}},
+		{version: "v0.0.0-develop", want: &UnknownExperimentError{Name: future}},
+	}
+	for _, tt := range tests {
+		err := set.CheckVersion(tt.version)
+		if err == nil || err.Error() != tt.want.Error() {
+			t.Errorf("CheckVersion(%q) = %v, want %v", tt.version, err, tt.want)
+		}
+	}
+
+	// A known experiment qualified with a newer version requires it as well.
+	set, err = FromList(Metrics + ">=v1.40.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var verErr *VersionError
+	if err := set.CheckVersion("v1.39.0"); !errors.As(err, &verErr) || verErr.Name != Metrics {
+		t.Errorf("CheckVersion(%q) = %v, want a *VersionError for %s", "v1.39.0", err, Metrics)
+	}
+	if err := set.CheckVersion("v1.40.0"); err != nil {
+		t.Errorf("CheckVersion(%q) = %v, want nil", "v1.40.0", err)
+	}
+
+	if _, err := FromList("metrics>=latest"); err == nil {
+		t.Errorf("FromList with an invalid version = nil, want error")
+	}
+}
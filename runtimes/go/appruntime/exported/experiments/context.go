@@ -0,0 +1,29 @@
+package experiments
+
+import "context"
+
+// ctxKey is the context key for the experiment set.
+type ctxKey struct{}
+
+// WithContext returns a copy of ctx carrying the experiment set,
+// which can be retrieved with FromContext.
+func WithContext(ctx context.Context, set *Set) context.Context {
+	return context.WithValue(ctx, ctxKey{}, set)
+}
+
+// FromContext returns the experiment set carried by ctx,
+// or nil if ctx is nil or doesn't carry one.
+// Like other methods on *Set, the nil set has no experiments enabled.
+func FromContext(ctx context.Context) *Set {
+	if ctx == nil {
+		return nil
+	}
+	set, _ := ctx.Value(ctxKey{}).(*Set)
+	return set
+}
+
+// IsEnabled reports whether the experiment is enabled
+// in the experiment set carried by ctx.
+func IsEnabled(ctx context.Context, name Name) bool {
+	return name.Enabled(FromContext(ctx))
+}
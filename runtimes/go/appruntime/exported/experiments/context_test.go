@@ -0,0 +1,33 @@
+package experiments
+
+import (
+	"context"
+	"testing"
+
+	"encore.dev/appruntime/exported/config"
+)
+
+func TestContext(t *testing.T) {
+	set := FromConfig(&config.Static{EnabledExperiments: []string{"metrics"}}, nil)
+	ctx := WithContext(context.Background(), set)
+
+	if got := FromContext(ctx); got != set {
+		t.Fatalf("got FromContext=%p, want %p", got, set)
+	}
+	if !IsEnabled(ctx, Metrics) {
+		t.Fatalf("got IsEnabled(metrics)=false, want true")
+	}
+	if IsEnabled(ctx, V2) {
+		t.Fatalf("got IsEnabled(v2)=true, want false")
+	}
+
+	// Contexts without a set behave like the nil set.
+	for _, ctx := range []context.Context{context.Background(), nil, WithContext(context.Background(), nil)} {
+		if got := FromContext(ctx); got != nil {
+			t.Fatalf("got FromContext=%p, want nil", got)
+		}
+		if IsEnabled(ctx, Metrics) {
+			t.Fatalf("got IsEnabled(metrics)=true, want false")
+		}
+	}
+}
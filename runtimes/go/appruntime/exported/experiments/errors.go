@@ -7,5 +7,18 @@ type UnknownExperimentError struct {
 }
 
 func (e *UnknownExperimentError) Error() string {
-	return "unknown experiment: " + string(e.Name)
+	return "unknown experiment: " + string(e.Name) + " (it may require a newer version of Encore)"
+}
+
+// VersionError is an error returned when an experiment is enabled
+// with a version of Encore older than the experiment requires.
+type VersionError struct {
+	Name       Name
+	MinVersion string // the version the experiment requires
+	Version    string // the version in use
+}
+
+func (e *VersionError) Error() string {
+	return "experiment " + string(e.Name) + " requires Encore " + e.MinVersion +
+		" or later, but this is Encore " + e.Version + "; upgrade with: encore version update"
 }
@@ -0,0 +1,23 @@
+package experiments
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Fingerprint returns a stable hash of the experiments enabled in the set,
+// which only depends on their names and not on the order they were enabled.
+//
+// The compiler records the fingerprint of the experiments an app was built
+// with in the static config, so the runtime can detect when it's running
+// with a different set. A nil set has the same fingerprint as an empty set.
+func (s *Set) Fingerprint() string {
+	h := sha256.New()
+	for _, name := range s.List() {
+		// Terminate each name so that, for example, {"ab"} and
+		// {"a", "b"} don't hash the same input.
+		h.Write([]byte(name))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
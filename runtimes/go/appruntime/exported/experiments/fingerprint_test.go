@@ -0,0 +1,38 @@
+package experiments
+
+import (
+	"testing"
+
+	"encore.dev/appruntime/exported/config"
+)
+
+func TestSet_Fingerprint(t *testing.T) {
+	set := func(names ...string) *Set {
+		return FromConfig(&config.Static{EnabledExperiments: names}, nil)
+	}
+
+	if a, b := set("metrics", "v2").Fingerprint(), set("v2", "metrics").Fingerprint(); a != b {
+		t.Fatalf("fingerprint depends on order: %s != %s", a, b)
+	}
+	if a, b := (*Set)(nil).Fingerprint(), set().Fingerprint(); a != b {
+		t.Fatalf("nil and empty sets differ: %s != %s", a, b)
+	}
+
+	distinct := []*Set{set(), set("v2"), set("metrics", "v2"), set("ab"), set("a", "b")}
+	seen := make(map[string]int)
+	for i, s := range distinct {
+		fp := s.Fingerprint()
+		if j, ok := seen[fp]; ok {
+			t.Fatalf("sets %v and %v have the same fingerprint %s", distinct[j].List(), s.List(), fp)
+		}
+		seen[fp] = i
+	}
+
+	// Enabling an experiment changes the fingerprint.
+	s := set("v2")
+	before := s.Fingerprint()
+	s.Enable(Metrics)
+	if s.Fingerprint() == before {
+		t.Fatal("fingerprint unchanged after Enable")
+	}
+}
@@ -0,0 +1,49 @@
+//go:build !encore_app
+
+package experiments
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+)
+
+// MarshalJSON encodes the set as a sorted list of the enabled experiments,
+// followed by the experiments enabled or disabled for individual services
+// in the "name@service" and "-name@service" format of FromAppFileAndEnviron.
+func (s *Set) MarshalJSON() ([]byte, error) {
+	names := s.StringList()
+	overrides := s.ServiceOverrides()
+	for _, service := range slices.Sorted(maps.Keys(overrides)) {
+		for _, name := range overrides[service] {
+			names = append(names, name+"@"+service)
+		}
+	}
+	return json.Marshal(names)
+}
+
+// UnmarshalJSON decodes a list of experiments previously encoded with MarshalJSON,
+// replacing the experiments in the set.
+//
+// The experiments are validated the same way as FromAppFileAndEnviron,
+// reporting unknown experiments as an *UnknownExperimentError.
+func (s *Set) UnmarshalJSON(data []byte) error {
+	var names []Name
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+
+	set := &Set{enabled: make(map[Name]struct{}, len(names))}
+	disabled, err := set.add(false, names...)
+	if err != nil {
+		return err
+	}
+	for _, name := range disabled {
+		delete(set.enabled, name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enabled, s.services, s.required, s.Warnings = set.enabled, set.services, set.required, nil
+	return nil
+}
@@ -0,0 +1,81 @@
+//go:build !encore_app
+
+package experiments
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestSet_JSON(t *testing.T) {
+	set, err := FromAppFileAndEnviron([]Name{V2, Metrics}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), `["metrics","v2"]`; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	var got Set
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(set) {
+		t.Fatalf("got %v, want %v", got.List(), set.List())
+	}
+
+	empty, err := json.Marshal(&Set{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(empty) != "[]" {
+		t.Fatalf("got %s, want []", empty)
+	}
+}
+
+func TestSet_JSON_ServiceOverrides(t *testing.T) {
+	set, err := FromAppFileAndEnviron([]Name{Metrics}, []string{"ENCORE_EXPERIMENT=v2@svc-a,-metrics@svc-b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), `["metrics","v2@svc-a","-metrics@svc-b"]`; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	// Decoding replaces the overrides of the set.
+	got, err := FromAppFileAndEnviron(nil, []string{"ENCORE_EXPERIMENT=metrics@svc-c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, got); err != nil {
+		t.Fatal(err)
+	}
+	for _, service := range []string{"svc-a", "svc-b", "svc-c"} {
+		if g, w := got.For(service).List(), set.For(service).List(); !slices.Equal(g, w) {
+			t.Errorf("For(%q) = %v, want %v", service, g, w)
+		}
+	}
+}
+
+func TestSet_UnmarshalJSON_Invalid(t *testing.T) {
+	var set Set
+	err := json.Unmarshal([]byte(`["metrics","unknown"]`), &set)
+	var unknown *UnknownExperimentError
+	if !errors.As(err, &unknown) || unknown.Name != "unknown" {
+		t.Fatalf("got err %v, want unknown experiment error", err)
+	}
+
+	if got := set.List(); !slices.Equal(got, []Name{}) {
+		t.Fatalf("got %v, want set to be unchanged", got)
+	}
+}
@@ -1,5 +1,7 @@
 package experiments
 
+import "slices"
+
 // Name is the name of an experiment
 type Name string
 
@@ -44,26 +46,105 @@ const (
 
 	// BunRuntime enables bun as the nodejs runtime
 	BunRuntime Name = "bun-runtime"
+
+	// MemoryBuckets stores buckets in memory when running tests,
+	// instead of in the local object storage emulator.
+	MemoryBuckets Name = "memory-buckets"
 )
 
+// ExperimentMeta describes an experiment, for use by tooling
+// that lists the available experiments.
+type ExperimentMeta struct {
+	Name Name
+
+	// Description is a human-readable explanation of the experiment.
+	Description string
+
+	// MinVersion is the oldest Encore version that can use the experiment,
+	// such as "v1.40.0". If empty, any version can. See Set.CheckVersion.
+	MinVersion string
+}
+
+// allExperiments lists all known experiments.
+// New experiments must be added here as well as to the const block above.
+var allExperiments = []ExperimentMeta{
+	{
+		Name:        LocalSecretsOverride,
+		Description: "Allow secrets to be overridden with values from a .secrets.local file.",
+	},
+	{
+		Name:        Metrics,
+		Description: "Enable metrics.",
+	},
+	{
+		Name:        V2,
+		Description: "Use the new parser and compiler.",
+	},
+	{
+		Name:        BetaRuntime,
+		Description: "Use the beta runtime.",
+	},
+	{
+		Name:        LocalMultiProcess,
+		Description: "Run each service as its own process locally, emulating a multi-process deployment.",
+	},
+	{
+		Name:        AuthDataRoundTrip,
+		Description: "Round-trip auth data through the wire format for internal API calls.",
+	},
+	{
+		Name:        TypeScript,
+		Description: "Build the app with TypeScript support.",
+	},
+	{
+		Name:        StreamTraces,
+		Description: "Stream traces to the Encore platform as requests are processed.",
+	},
+	{
+		Name:        AdaptiveGCPPubSubGoroutines,
+		Description: "Adapt the number of goroutines used for GCP Pub/Sub subscriptions.",
+	},
+	{
+		Name:        TSWorkerThreads,
+		Description: "Use multiple worker threads for Encore.ts.",
+	},
+	{
+		Name:        BunRuntime,
+		Description: "Use Bun as the JavaScript runtime for Encore.ts.",
+	},
+	{
+		Name:        MemoryBuckets,
+		Description: "Store buckets in memory when running tests.",
+	},
+}
+
+// known maps the known experiments to their metadata.
+var known = func() map[Name]*ExperimentMeta {
+	m := make(map[Name]*ExperimentMeta, len(allExperiments))
+	for i := range allExperiments {
+		m[allExperiments[i].Name] = &allExperiments[i]
+	}
+	return m
+}()
+
+// Meta returns the metadata for the given experiment.
+// It reports false if the experiment is not known.
+func Meta(x Name) (ExperimentMeta, bool) {
+	if m, ok := known[x]; ok {
+		return *m, true
+	}
+	return ExperimentMeta{}, false
+}
+
+// AllMeta returns the metadata for all known experiments.
+func AllMeta() []ExperimentMeta {
+	return slices.Clone(allExperiments)
+}
+
 // Valid reports whether the given name is a known experiment.
 func (x Name) Valid() bool {
-	switch x {
-	case LocalSecretsOverride,
-		Metrics,
-		V2,
-		BetaRuntime,
-		LocalMultiProcess,
-		AuthDataRoundTrip,
-		TypeScript,
-		StreamTraces,
-		AdaptiveGCPPubSubGoroutines,
-		TSWorkerThreads,
-		BunRuntime:
-		return true
-	default:
-		return false
-	}
+	_, ok := known[x]
+	return ok
 }
 
 // Enabled returns true if this experiment enabled in the given set
@@ -74,6 +155,11 @@ func (x Name) Enabled(set *Set) bool {
 	}
 
 	// Does the release set contain this?
-	_, ok := set.enabled[x]
+	// The callback is called without holding the lock,
+	// so it can check other experiments.
+	ok := set.has(x)
+	if set.OnEval != nil {
+		set.OnEval(x, ok)
+	}
 	return ok
 }
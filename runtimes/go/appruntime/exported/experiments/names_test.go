@@ -0,0 +1,64 @@
+package experiments
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+// TestAllExperimentsRegistered checks that every experiment
+// declared in names.go is included in allExperiments.
+func TestAllExperimentsRegistered(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "names.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registered := make(map[Name]bool, len(allExperiments))
+	for _, x := range allExperiments {
+		if registered[x.Name] {
+			t.Errorf("experiment %q is registered more than once", x.Name)
+		}
+		if x.Description == "" {
+			t.Errorf("experiment %q has no description", x.Name)
+		}
+		if _, ok := parseVersion(x.MinVersion); x.MinVersion != "" && !ok {
+			t.Errorf("experiment %q has invalid min version %q", x.Name, x.MinVersion)
+		}
+		registered[x.Name] = true
+	}
+
+	var declared int
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			if ident, ok := vs.Type.(*ast.Ident); !ok || ident.Name != "Name" {
+				continue
+			}
+			for i, ident := range vs.Names {
+				lit, ok := vs.Values[i].(*ast.BasicLit)
+				if !ok {
+					t.Fatalf("experiment %s must be a string literal", ident.Name)
+				}
+				name := Name(lit.Value[1 : len(lit.Value)-1])
+				declared++
+				if !registered[name] {
+					t.Errorf("experiment %s (%q) is not included in allExperiments", ident.Name, name)
+				}
+				if !name.Valid() {
+					t.Errorf("experiment %s (%q) is not valid", ident.Name, name)
+				}
+			}
+		}
+	}
+
+	if declared != len(allExperiments) {
+		t.Errorf("got %d declared experiments, but %d registered", declared, len(allExperiments))
+	}
+}
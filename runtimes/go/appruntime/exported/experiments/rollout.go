@@ -0,0 +1,53 @@
+package experiments
+
+import (
+	"hash/fnv"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// rolloutEnvName is the environment variable configuring gradual rollouts,
+// as a comma-separated list of "name:percentage" pairs, e.g. "metrics:10".
+const rolloutEnvName = "ENCORE_EXPERIMENT_ROLLOUT"
+
+// EnabledFor reports whether the experiment is rolled out to the given app.
+//
+// The decision is deterministic: the app ID and experiment name are hashed
+// into the range [0, 100) and compared against the rollout percentage
+// configured for the experiment using ENCORE_EXPERIMENT_ROLLOUT.
+// If no percentage is configured the experiment is not rolled out.
+func (x Name) EnabledFor(appID string) bool {
+	pct, ok := rolloutPercentage(os.Getenv(rolloutEnvName), x)
+	if !ok {
+		return false
+	}
+	return rolloutBucket(appID, x) < pct
+}
+
+// rolloutPercentage returns the rollout percentage for the experiment
+// from the value of the rollout environment variable.
+// Malformed entries are ignored.
+func rolloutPercentage(val string, x Name) (pct int, ok bool) {
+	for _, entry := range strings.Split(strings.Trim(val, `"'`), ",") {
+		name, pctStr, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if !found || Name(name) != x {
+			continue
+		}
+		pct, err := strconv.Atoi(pctStr)
+		if err != nil || pct < 0 || pct > 100 {
+			continue
+		}
+		return pct, true
+	}
+	return 0, false
+}
+
+// rolloutBucket deterministically maps an app and experiment to [0, 100).
+func rolloutBucket(appID string, x Name) int {
+	h := fnv.New32a()
+	h.Write([]byte(appID))
+	h.Write([]byte{0})
+	h.Write([]byte(x))
+	return int(h.Sum32() % 100)
+}
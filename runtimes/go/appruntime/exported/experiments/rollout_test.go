@@ -0,0 +1,64 @@
+package experiments
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEnabledFor(t *testing.T) {
+	t.Setenv(rolloutEnvName, "metrics:0,v2:100,beta-runtime:50")
+
+	apps := make([]string, 1000)
+	for i := range apps {
+		apps[i] = fmt.Sprintf("app-%d", i)
+	}
+
+	var enabled int
+	for _, app := range apps {
+		if Metrics.EnabledFor(app) {
+			t.Errorf("metrics enabled for %s at 0%%", app)
+		}
+		if !V2.EnabledFor(app) {
+			t.Errorf("v2 not enabled for %s at 100%%", app)
+		}
+		if TypeScript.EnabledFor(app) {
+			t.Errorf("typescript enabled for %s without a rollout", app)
+		}
+
+		got := BetaRuntime.EnabledFor(app)
+		if got != BetaRuntime.EnabledFor(app) {
+			t.Errorf("rollout for %s is not deterministic", app)
+		}
+		if got {
+			enabled++
+		}
+	}
+
+	// The rollout should be roughly 50%.
+	if enabled < 400 || enabled > 600 {
+		t.Errorf("beta-runtime enabled for %d of %d apps at 50%%", enabled, len(apps))
+	}
+}
+
+func TestRolloutPercentage(t *testing.T) {
+	tests := []struct {
+		val    string
+		pct    int
+		wantOK bool
+	}{
+		{val: "", wantOK: false},
+		{val: "metrics:10", pct: 10, wantOK: true},
+		{val: "v2:5, metrics:20", pct: 20, wantOK: true},
+		{val: `"metrics:30"`, pct: 30, wantOK: true},
+		{val: "metrics", wantOK: false},
+		{val: "metrics:abc", wantOK: false},
+		{val: "metrics:101", wantOK: false},
+		{val: "metrics:-1,metrics:15", pct: 15, wantOK: true},
+	}
+	for _, test := range tests {
+		pct, ok := rolloutPercentage(test.val, Metrics)
+		if ok != test.wantOK || pct != test.pct {
+			t.Errorf("rolloutPercentage(%q) = %d, %v, want %d, %v", test.val, pct, ok, test.pct, test.wantOK)
+		}
+	}
+}
@@ -1,14 +1,46 @@
 package experiments
 
 import (
+	"maps"
 	"slices"
+	"strings"
+	"sync"
 
 	"encore.dev/appruntime/exported/config"
 )
 
-// Set is a set of experiments enabled within this app
+// Set is a set of experiments enabled within this app.
+//
+// Sets returned by FromConfig and FromAppFileAndEnviron are not modified
+// after construction unless Enable or Disable is called, for example to
+// toggle experiments from an admin endpoint. All methods are safe for
+// concurrent use, including with Enable and Disable.
+//
+// Experiments can also be enabled or disabled for individual services,
+// which only affects the sets returned by For. The other methods only
+// consider the experiments enabled for every service.
 type Set struct {
+	mu      sync.RWMutex
 	enabled map[Name]struct{}
+
+	// services holds the experiments enabled or disabled for
+	// individual services, keyed by service name.
+	services map[string]*serviceOverrides
+
+	// required holds the versions of Encore required by the experiments
+	// whose names were qualified with one, such as "name>=v1.50.0".
+	// Unknown experiments are only recorded here, and not enabled.
+	required map[Name]string
+
+	// Warnings contains the unknown experiments that were skipped
+	// when constructing the set with FromAppFileAndEnvironLenient.
+	Warnings []UnknownExperimentError
+
+	// OnEval, if non-nil, is called with the outcome every time an
+	// experiment is checked using Name.Enabled, for example to log which
+	// experiments a request depended on. It must be set before the set
+	// is used, and may be called concurrently.
+	OnEval func(name Name, enabled bool)
 }
 
 // FromConfig constructs a new Experiments object from both the static and runtime configs.
@@ -17,7 +49,7 @@ type Set struct {
 //
 // Unknown experiments are ignored.
 func FromConfig(static *config.Static, runtime *config.Runtime) *Set {
-	e := &Set{make(map[Name]struct{})}
+	e := &Set{enabled: make(map[Name]struct{})}
 
 	// Note we don't check for valid experiments here, because the static and runtime configs
 	// are already validated by the compiler, and from the platform side
@@ -27,6 +59,12 @@ func FromConfig(static *config.Static, runtime *config.Runtime) *Set {
 		for _, exp := range static.EnabledExperiments {
 			e.enabled[Name(exp)] = struct{}{}
 		}
+		for service, exps := range static.ServiceExperiments {
+			for _, exp := range exps {
+				name, disable := strings.CutPrefix(exp, "-")
+				e.overridesFor(service).add(Name(name), disable)
+			}
+		}
 	}
 
 	if runtime != nil {
@@ -38,11 +76,139 @@ func FromConfig(static *config.Static, runtime *config.Runtime) *Set {
 	return e
 }
 
+// serviceOverrides are the experiments enabled or disabled for a single
+// service, on top of those enabled for every service.
+type serviceOverrides struct {
+	enabled  []Name
+	disabled []Name
+}
+
+// overridesFor returns the overrides for the given service,
+// creating them if necessary.
+func (s *Set) overridesFor(service string) *serviceOverrides {
+	o := s.services[service]
+	if o == nil {
+		if s.services == nil {
+			s.services = make(map[string]*serviceOverrides)
+		}
+		o = &serviceOverrides{}
+		s.services[service] = o
+	}
+	return o
+}
+
+// add enables or disables the experiment for the service.
+func (o *serviceOverrides) add(name Name, disable bool) {
+	if disable {
+		o.disabled = append(o.disabled, name)
+	} else {
+		o.enabled = append(o.enabled, name)
+	}
+}
+
+// ServiceOverrides returns the experiments enabled or disabled for
+// individual services, keyed by service name, in the format of
+// config.Static.ServiceExperiments: the names of disabled experiments
+// are prefixed with "-", and follow the enabled ones.
+func (s *Set) ServiceOverrides() map[string][]string {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.services) == 0 {
+		return nil
+	}
+	overrides := make(map[string][]string, len(s.services))
+	for service, o := range s.services {
+		var names []string
+		for _, name := range slices.Sorted(slices.Values(o.enabled)) {
+			names = append(names, string(name))
+		}
+		for _, name := range slices.Sorted(slices.Values(o.disabled)) {
+			names = append(names, "-"+string(name))
+		}
+		overrides[service] = slices.Compact(names)
+	}
+	return overrides
+}
+
+// For returns the experiments enabled for the given service: those enabled
+// for every service, plus those enabled for the service alone, minus those
+// disabled for it. With ENCORE_EXPERIMENT=v2@svc-a,metrics, for example,
+// For("svc-a") has both v2 and metrics enabled while other services only
+// have metrics enabled.
+//
+// The returned set is a copy that shares the OnEval callback of s,
+// so enabling or disabling experiments in one doesn't affect the other.
+// For returns nil if s is nil.
+func (s *Set) For(service string) *Set {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	set := &Set{enabled: make(map[Name]struct{}, len(s.enabled)), OnEval: s.OnEval}
+	maps.Copy(set.enabled, s.enabled)
+	if o := s.services[service]; o != nil {
+		for _, name := range o.enabled {
+			set.enabled[name] = struct{}{}
+		}
+		// Disabling wins over enabling.
+		for _, name := range o.disabled {
+			delete(set.enabled, name)
+		}
+	}
+	return set
+}
+
+// Enable enables the given experiments in the set.
+//
+// Unlike when constructing a set, the experiments aren't validated:
+// unknown experiments are enabled like any other.
+func (s *Set) Enable(names ...Name) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.enabled == nil {
+		s.enabled = make(map[Name]struct{}, len(names))
+	}
+	for _, name := range names {
+		s.enabled[name] = struct{}{}
+	}
+}
+
+// Disable disables the given experiments in the set.
+// Experiments that aren't enabled are ignored.
+func (s *Set) Disable(names ...Name) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, name := range names {
+		delete(s.enabled, name)
+	}
+}
+
+// has reports whether the experiment is enabled in the set.
+// Unlike Name.Enabled it doesn't call OnEval, so it's used for
+// checks that aren't made on behalf of the app.
+func (s *Set) has(name Name) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.enabled[name]
+	return ok
+}
+
 // List returns a list of all experiments enabled in this set.
 func (s *Set) List() []Name {
 	if s == nil {
 		return nil
 	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	names := make([]Name, 0, len(s.enabled))
 	for key := range s.enabled {
 		names = append(names, key)
@@ -60,3 +226,28 @@ func (s *Set) StringList() []string {
 	}
 	return rtn
 }
+
+// Equal reports whether s and other have the same experiments enabled.
+// A nil set is equal to an empty set.
+func (s *Set) Equal(other *Set) bool {
+	added, removed := s.Diff(other)
+	return len(added) == 0 && len(removed) == 0
+}
+
+// Diff reports the differences between s and other.
+// The added experiments are enabled in other but not in s,
+// and the removed experiments are enabled in s but not in other.
+// Both lists are sorted. A nil set is treated as an empty set.
+func (s *Set) Diff(other *Set) (added, removed []Name) {
+	for _, name := range other.List() {
+		if !s.has(name) {
+			added = append(added, name)
+		}
+	}
+	for _, name := range s.List() {
+		if !other.has(name) {
+			removed = append(removed, name)
+		}
+	}
+	return added, removed
+}
@@ -0,0 +1,155 @@
+package experiments
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+
+	"encore.dev/appruntime/exported/config"
+)
+
+func TestSet_Diff(t *testing.T) {
+	set := func(names ...string) *Set {
+		return FromConfig(&config.Static{EnabledExperiments: names}, nil)
+	}
+
+	tests := []struct {
+		name           string
+		a, b           *Set
+		added, removed []Name
+	}{
+		{name: "equal", a: set("metrics", "v2"), b: set("v2", "metrics")},
+		{name: "nil", a: nil, b: nil},
+		{name: "nil_and_empty", a: nil, b: set()},
+		{name: "added", a: set("v2"), b: set("metrics", "v2"), added: []Name{Metrics}},
+		{name: "removed", a: set("metrics", "v2"), b: set("v2"), removed: []Name{Metrics}},
+		{name: "from_nil", a: nil, b: set("v2"), added: []Name{V2}},
+		{name: "to_nil", a: set("v2"), b: nil, removed: []Name{V2}},
+		{name: "both", a: set("metrics"), b: set("v2"), added: []Name{V2}, removed: []Name{Metrics}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			added, removed := test.a.Diff(test.b)
+			if !slices.Equal(added, test.added) || !slices.Equal(removed, test.removed) {
+				t.Fatalf("got added=%v removed=%v, want added=%v removed=%v", added, removed, test.added, test.removed)
+			}
+			wantEqual := len(test.added) == 0 && len(test.removed) == 0
+			if got := test.a.Equal(test.b); got != wantEqual {
+				t.Fatalf("got Equal=%v, want %v", got, wantEqual)
+			}
+		})
+	}
+}
+
+func TestSet_EnableDisable(t *testing.T) {
+	set := FromConfig(&config.Static{EnabledExperiments: []string{"v2"}}, nil)
+	set.Enable(Metrics, StreamTraces)
+	set.Disable(V2, TypeScript)
+	if got, want := set.List(), []Name{Metrics, StreamTraces}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	var zero Set
+	zero.Enable(V2)
+	if !V2.Enabled(&zero) {
+		t.Fatal("v2 not enabled in zero set")
+	}
+}
+
+func TestSet_OnEval(t *testing.T) {
+	type eval struct {
+		name    Name
+		enabled bool
+	}
+	var evals []eval
+	set := FromConfig(&config.Static{EnabledExperiments: []string{"v2"}}, nil)
+	set.OnEval = func(name Name, enabled bool) {
+		evals = append(evals, eval{name, enabled})
+	}
+
+	V2.Enabled(set)
+	Metrics.Enabled(set)
+	if want := []eval{{V2, true}, {Metrics, false}}; !slices.Equal(evals, want) {
+		t.Fatalf("got %v, want %v", evals, want)
+	}
+}
+
+func TestSet_Concurrent(t *testing.T) {
+	set := FromConfig(nil, nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				set.Enable(Metrics)
+				set.Disable(Metrics)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_ = Metrics.Enabled(set)
+				_ = set.List()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestSet_For(t *testing.T) {
+	if (*Set)(nil).For("svc") != nil {
+		t.Fatal("got non-nil set for nil set")
+	}
+
+	// The set for a service is a copy.
+	set := FromConfig(&config.Static{EnabledExperiments: []string{"v2"}}, nil)
+	svc := set.For("svc")
+	svc.Enable(Metrics)
+	if Metrics.Enabled(set) {
+		t.Fatal("enabling in the service set changed the original set")
+	}
+	set.Disable(V2)
+	if !V2.Enabled(svc) {
+		t.Fatal("disabling in the original set changed the service set")
+	}
+}
+
+func TestFromConfig_ServiceExperiments(t *testing.T) {
+	set := FromConfig(&config.Static{
+		EnabledExperiments: []string{"metrics"},
+		ServiceExperiments: map[string][]string{
+			"svc-a": {"v2"},
+			"svc-b": {"-metrics"},
+		},
+	}, nil)
+
+	for service, want := range map[string][]Name{
+		"svc-a": {Metrics, V2},
+		"svc-b": {},
+		"svc-c": {Metrics},
+	} {
+		if got := set.For(service).List(); !slices.Equal(got, want) {
+			t.Errorf("For(%q) = %v, want %v", service, got, want)
+		}
+	}
+
+	want := map[string][]string{"svc-a": {"v2"}, "svc-b": {"-metrics"}}
+	if got := set.ServiceOverrides(); !maps.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("ServiceOverrides() = %v, want %v", got, want)
+	}
+}
+
+func TestSet_OnEval_Internal(t *testing.T) {
+	a := FromConfig(&config.Static{EnabledExperiments: []string{"v2"}}, nil)
+	b := FromConfig(&config.Static{EnabledExperiments: []string{"metrics"}}, nil)
+	onEval := func(name Name, enabled bool) {
+		t.Errorf("OnEval called with %s", name)
+	}
+	a.OnEval, b.OnEval = onEval, onEval
+
+	// Comparing sets doesn't evaluate experiments on behalf of the app.
+	a.Diff(b)
+	a.Equal(b)
+}
@@ -0,0 +1,82 @@
+package experiments
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// CheckVersion reports a *VersionError if an experiment in the set
+// requires a newer version of Encore than the given version, either
+// according to its metadata or to the version its name was qualified
+// with. Unknown experiments qualified with a version the given version
+// satisfies are reported as an *UnknownExperimentError.
+//
+// Development builds, whose version is v0.0.0 or can't be parsed,
+// support every known experiment.
+func (s *Set) CheckVersion(version string) error {
+	cur, ok := parseVersion(version)
+	dev := !ok || cur == [3]int{}
+	olderThan := func(minVersion string) bool {
+		minVer, ok := parseVersion(minVersion)
+		return !dev && ok && compareVersions(cur, minVer) < 0
+	}
+
+	s.mu.RLock()
+	required := maps.Clone(s.required)
+	s.mu.RUnlock()
+	for _, name := range slices.Sorted(maps.Keys(required)) {
+		if olderThan(required[name]) {
+			return &VersionError{Name: name, MinVersion: required[name], Version: version}
+		} else if !name.Valid() {
+			return &UnknownExperimentError{Name: name}
+		}
+	}
+
+	for _, name := range s.List() {
+		if m, ok := known[name]; ok && olderThan(m.MinVersion) {
+			return &VersionError{Name: name, MinVersion: m.MinVersion, Version: version}
+		}
+	}
+	return nil
+}
+
+// parseVersion parses a version like "v1.2.3" into its major, minor
+// and patch numbers. Pre-release and build suffixes are ignored,
+// so "v1.2.3-beta.1" is treated as "v1.2.3".
+func parseVersion(v string) (parsed [3]int, ok bool) {
+	v, ok = strings.CutPrefix(v, "v")
+	if !ok {
+		return parsed, false
+	}
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return parsed, false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return parsed, false
+		}
+		parsed[i] = n
+	}
+	return parsed, true
+}
+
+// compareVersions returns -1, 0 or 1 depending on whether a is older than,
+// the same as, or newer than b.
+func compareVersions(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,40 @@
+package experiments
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSet_CheckVersion(t *testing.T) {
+	orig := known[Metrics].MinVersion
+	known[Metrics].MinVersion = "v1.40.0"
+	t.Cleanup(func() { known[Metrics].MinVersion = orig })
+
+	set, err := FromList(Metrics, V2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		version string
+		wantErr bool
+	}{
+		{version: "v1.39.9", wantErr: true},
+		{version: "v1.40.0-beta.1", wantErr: false},
+		{version: "v1.40.0", wantErr: false},
+		{version: "v2.0.0", wantErr: false},
+		{version: "v0.0.0-develop+0140ab0f", wantErr: false},
+		{version: "", wantErr: false},
+	}
+	for _, tt := range tests {
+		err := set.CheckVersion(tt.version)
+		var verErr *VersionError
+		if got := errors.As(err, &verErr); got != tt.wantErr {
+			t.Errorf("CheckVersion(%q) = %v, want error %v", tt.version, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr && (verErr.Name != Metrics || verErr.MinVersion != "v1.40.0" || verErr.Version != tt.version) {
+			t.Errorf("CheckVersion(%q) = %+v", tt.version, verErr)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package et
+
+import (
+	"encore.dev/storage/objects"
+)
+
+// Bucket returns a BucketHelpers for the given bucket.
+//
+// It requires the bucket to be stored in memory, which is done by
+// enabling the "memory-buckets" experiment when running the tests.
+func Bucket(bkt *objects.Bucket) BucketHelpers {
+	return objects.GetTestBucketInstance(bkt).(BucketHelpers)
+}
+
+// BucketHelpers provides functions for interacting with the backing bucket implementation
+// during unit tests. It is designed to help test code that uses the objects.Bucket
+//
+// Note all functions on this BucketHelpers are scoped to the current test
+// and will only impact and observe state from the current test
+type BucketHelpers interface {
+	// Seed stores an object with the given contents, replacing any existing object.
+	Seed(object string, data []byte)
+
+	// Dump returns a copy of the contents of every object stored in the bucket.
+	Dump() map[string][]byte
+}
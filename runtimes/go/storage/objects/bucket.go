@@ -3,11 +3,19 @@ package objects
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"iter"
+	"mime"
+	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"golang.org/x/sync/errgroup"
+
 	"encore.dev/appruntime/exported/config"
 	"encore.dev/appruntime/exported/stack"
 	"encore.dev/appruntime/exported/trace2"
@@ -28,6 +36,10 @@ type Bucket struct {
 	// Prefix to prepend to all cloud names.
 	baseCloudPrefix string
 
+	// subPrefix is the prefix of a handle returned by Sub,
+	// prepended to object names after any cloud prefix.
+	subPrefix string
+
 	// publicBaseURL, if the bucket is public
 	publicBaseURL *url.URL
 }
@@ -92,6 +104,17 @@ func newBucket(mgr *Manager, name string) *Bucket {
 	panic("unreachable")
 }
 
+// Sub returns a handle to the objects in the bucket whose names start with prefix,
+// like a subdirectory. Object names passed to the handle are prefixed with it,
+// and it's stripped from the names of the objects it returns.
+//
+// The prefix is used as-is, so it usually ends with a "/".
+func (b *Bucket) Sub(prefix string) *Bucket {
+	sub := *b
+	sub.subPrefix += prefix
+	return &sub
+}
+
 // Upload uploads a new object to the bucket.
 //
 // The returned writer must be successfully closed for the upload to complete.
@@ -144,7 +167,7 @@ func (b *Bucket) PublicURL(object string, options ...PublicURLOption) *url.URL {
 	if !strings.HasSuffix(u.Path, "/") {
 		u.Path += "/"
 	}
-	u.Path += escape(object, encodePath)
+	u.Path += escape(b.subPrefix+object, encodePath)
 
 	return &u
 }
@@ -161,6 +184,12 @@ type Writer struct {
 	// Initialized on first write
 	u types.Uploader
 
+	// Set when the upload completes successfully
+	attrs *ObjectAttrs
+
+	// Set when Close fails
+	closeErr error
+
 	// Set if tracing
 	curr         reqtrack.Current
 	startEventID trace2.EventID
@@ -172,6 +201,17 @@ func (w *Writer) Write(p []byte) (int, error) {
 	return u.Write(p)
 }
 
+// ReadFrom writes the data from r to the object being uploaded.
+// It's used by io.Copy, and lets providers upload readers whose
+// size is known, such as files, more efficiently.
+func (w *Writer) ReadFrom(r io.Reader) (int64, error) {
+	u := w.initUpload()
+	if rf, ok := u.(io.ReaderFrom); ok {
+		return rf.ReadFrom(r)
+	}
+	return io.Copy(struct{ io.Writer }{u}, r)
+}
+
 // Abort aborts the upload.
 func (w *Writer) Abort(err error) {
 	if err == nil {
@@ -185,6 +225,9 @@ func (w *Writer) Abort(err error) {
 func (w *Writer) Close() error {
 	u := w.initUpload()
 	attrs, err := u.Complete()
+	if w.opt.pre.NotExists && errors.Is(err, ErrPreconditionFailed) {
+		err = ErrObjectExists
+	}
 
 	if w.curr.Trace != nil {
 		params := trace2.BucketObjectUploadEndParams{
@@ -204,9 +247,44 @@ func (w *Writer) Close() error {
 		w.curr.Trace.BucketObjectUploadEnd(params)
 	}
 
+	if err != nil {
+		w.closeErr = err
+	} else if attrs != nil {
+		w.attrs = w.bkt.mapAttrs(attrs)
+	}
 	return err
 }
 
+// errUploadNotCompleted is reported by Writer.Attrs before Close has been called.
+var errUploadNotCompleted = errors.New("objects: upload not completed")
+
+// Attrs returns the attributes of the uploaded object,
+// such as its ETag and size.
+//
+// It returns an error until Close has been called and the upload
+// has completed successfully. If Close failed, it returns that error.
+func (w *Writer) Attrs() (*ObjectAttrs, error) {
+	switch {
+	case w.closeErr != nil:
+		return nil, w.closeErr
+	case w.attrs == nil:
+		return nil, errUploadNotCompleted
+	}
+	return w.attrs, nil
+}
+
+// VersionID returns the version ID of the uploaded object, and whether
+// it has one. Objects only have a version ID if the bucket is versioned.
+//
+// It reports false until Close has been called and the upload
+// has completed successfully.
+func (w *Writer) VersionID() (id string, ok bool) {
+	if w.attrs == nil || w.attrs.Version == "" {
+		return "", false
+	}
+	return w.attrs.Version, true
+}
+
 func (w *Writer) initUpload() types.Uploader {
 	if w.u == nil {
 		u, err := w.bkt.impl.Upload(types.UploadData{
@@ -216,6 +294,9 @@ func (w *Writer) initUpload() types.Uploader {
 			Pre: types.Preconditions{
 				NotExists: w.opt.pre.NotExists,
 			},
+			PartSize:   w.opt.partSize,
+			Progress:   w.opt.progress,
+			StateStore: w.opt.stateStore,
 		})
 		if err != nil {
 			w.u = &errUploader{err: err}
@@ -272,10 +353,125 @@ func (b *Bucket) Download(ctx context.Context, object string, options ...Downloa
 		Ctx:     ctx,
 		Object:  b.toCloudObject(object),
 		Version: opt.version,
+		Offset:  opt.offset,
+		Length:  opt.length,
+
+		Conditions: types.DownloadConditions{
+			IfNoneMatch:     opt.conditions.IfNoneMatch,
+			IfModifiedSince: opt.conditions.IfModifiedSince,
+		},
 	})
 	return &Reader{r: r, err: err, curr: curr, startEventID: startEventID}
 }
 
+// DownloadToFile downloads an object from the bucket to the file at path,
+// replacing the file if it already exists. It accepts the same options as Download.
+//
+// The object is first written to a temporary file in the same directory,
+// which is renamed to path once the download completes. This means path
+// never contains a partially downloaded object; on error the temporary
+// file is removed and any existing file at path is left untouched.
+func (b *Bucket) DownloadToFile(ctx context.Context, object, path string, options ...DownloadOption) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}()
+
+	r := b.Download(ctx, object, options...)
+	_, err = io.Copy(f, r)
+	// The reader reports io.EOF from Close after being fully read.
+	if closeErr := r.Close(); err == nil && !errors.Is(closeErr, io.EOF) {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	// CreateTemp creates files only accessible by the current user.
+	if err := f.Chmod(0o644); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// UploadFromFile uploads the file at path to an object in the bucket,
+// returning the attributes of the uploaded object. It accepts the same
+// options as Upload.
+//
+// Unless the options specify a content type, it's determined from the
+// file's extension, falling back to sniffing the first 512 bytes of its
+// contents. Buckets that support UploadReaderAt upload the file with it,
+// reading the parts of large files in parallel.
+func (b *Bucket) UploadFromFile(ctx context.Context, object, path string, options ...UploadOption) (*ObjectAttrs, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	} else if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%w: %s is not a regular file", ErrInvalidArgument, path)
+	}
+
+	var opt uploadOptions
+	for _, o := range options {
+		o.applyUpload(&opt)
+	}
+	if opt.attrs.ContentType == "" {
+		contentType := mime.TypeByExtension(filepath.Ext(path))
+		if contentType == "" {
+			head := make([]byte, sniffLen)
+			n, err := f.ReadAt(head, 0)
+			if err != nil && !errors.Is(err, io.EOF) {
+				return nil, err
+			}
+			contentType = http.DetectContentType(head[:n])
+		}
+		options = append(options, withContentTypeOption{contentType: contentType})
+	}
+
+	// Providers that can read the parts of the file in parallel upload it directly.
+	if _, ok := b.impl.(types.ReaderAtUploader); ok {
+		return b.UploadReaderAt(ctx, object, f, info.Size(), options...)
+	}
+
+	w := b.Upload(ctx, object, options...)
+	if _, err := io.Copy(w, f); err != nil {
+		w.Abort(err)
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return w.Attrs()
+}
+
+// sniffLen is the number of bytes http.DetectContentType considers.
+const sniffLen = 512
+
+// withContentTypeOption sets the content type of an upload,
+// keeping any other attributes set by earlier options.
+type withContentTypeOption struct {
+	contentType string
+}
+
+func (o withContentTypeOption) uploadOption() {}
+
+func (o withContentTypeOption) applyUpload(opts *uploadOptions) {
+	opts.attrs.ContentType = o.contentType
+}
+
 // Reader is the reader for an object being downloaded from a bucket.
 type Reader struct {
 	err       error // any error encountered
@@ -345,13 +541,26 @@ type Query struct {
 
 	// Maximum number of objects to return. Zero means no limit.
 	Limit int64
+
+	// Delimiter, if non-empty, groups objects whose names contain the
+	// delimiter after Prefix, similar to directories in a file system.
+	// Each group is returned as a single entry with IsPrefix set,
+	// whose name is the common prefix up to and including the delimiter.
+	Delimiter string
+
+	// PageSize is the number of entries to fetch from the provider
+	// per request. Zero means the provider default.
+	// Additional pages are only fetched as the entries are consumed.
+	PageSize int
 }
 
 func (b *Bucket) mapQuery(ctx context.Context, q *Query) types.ListData {
 	return types.ListData{
-		Ctx:    ctx,
-		Prefix: b.baseCloudPrefix + q.Prefix,
-		Limit:  ptrOrNil(q.Limit),
+		Ctx:       ctx,
+		Prefix:    b.listPrefix() + q.Prefix,
+		Limit:     ptrOrNil(q.Limit),
+		Delimiter: q.Delimiter,
+		PageSize:  q.PageSize,
 	}
 }
 
@@ -371,6 +580,14 @@ type ObjectAttrs struct {
 
 	// The computed ETag of the object.
 	ETag string
+
+	// The time the object was last modified.
+	// It's always set by Attrs, but may be zero for other operations.
+	LastModified time.Time
+
+	// The custom metadata stored with the object.
+	// It's always set by Attrs, but may be nil for other operations.
+	Metadata map[string]string
 }
 
 func (b *Bucket) mapAttrs(attrs *types.ObjectAttrs) *ObjectAttrs {
@@ -380,6 +597,9 @@ func (b *Bucket) mapAttrs(attrs *types.ObjectAttrs) *ObjectAttrs {
 		ContentType: attrs.ContentType,
 		Size:        attrs.Size,
 		ETag:        attrs.ETag,
+
+		LastModified: attrs.LastModified,
+		Metadata:     attrs.Metadata,
 	}
 }
 
@@ -391,24 +611,43 @@ type ListEntry struct {
 	Size int64
 	// The computed ETag of the object.
 	ETag string
+	// IsPrefix is true if the entry is a common prefix of several objects
+	// rather than an object, which happens when listing with a Delimiter.
+	// Name is then the prefix, and Size and ETag are not set.
+	IsPrefix bool
 }
 
 func (b *Bucket) mapListEntry(entry *types.ListEntry) *ListEntry {
 	return &ListEntry{
-		Name: b.fromCloudObject(entry.Object),
-		Size: entry.Size,
-		ETag: entry.ETag,
+		Name:     b.fromCloudObject(entry.Object),
+		Size:     entry.Size,
+		ETag:     entry.ETag,
+		IsPrefix: entry.IsPrefix,
 	}
 }
 
 type SignedUploadURL struct {
 	// The signed URL
 	URL string
+
+	// The HTTP method to use with the URL.
+	Method string
+
+	// Headers that are part of the signature, and therefore must be
+	// sent with the upload request, such as encryption settings.
+	Headers http.Header
 }
 
 type SignedDownloadURL struct {
 	// The signed URL
 	URL string
+
+	// The HTTP method to use with the URL.
+	Method string
+
+	// Headers that are part of the signature, and therefore must be
+	// sent with the download request.
+	Headers http.Header
 }
 
 // List lists objects in the bucket.
@@ -434,26 +673,27 @@ func (b *Bucket) List(ctx context.Context, query *Query, options ...ListOption)
 				Stack:  stack.Build(1),
 			})
 
-			defer curr.Trace.BucketListObjectsEnd(trace2.BucketListObjectsEndParams{
-				StartID: startEventID,
-				EventParams: trace2.EventParams{
-					TraceID: curr.Req.TraceID,
-					SpanID:  curr.Req.SpanID,
-					Goid:    curr.Goctr,
-				},
-				Err:      listErr,
-				Observed: observed,
-				HasMore:  hasMore,
-			})
+			defer func() {
+				curr.Trace.BucketListObjectsEnd(trace2.BucketListObjectsEndParams{
+					StartID: startEventID,
+					EventParams: trace2.EventParams{
+						TraceID: curr.Req.TraceID,
+						SpanID:  curr.Req.SpanID,
+						Goid:    curr.Goctr,
+					},
+					Err:      listErr,
+					Observed: observed,
+					HasMore:  hasMore,
+				})
+			}()
 		}
 
 		iter := b.impl.List(b.mapQuery(ctx, query))
 		for entry, err := range iter {
 			if err != nil {
 				listErr = err
-				if !yield(nil, err) {
-					return
-				}
+				yield(nil, err)
+				return
 			}
 
 			observed++
@@ -466,6 +706,54 @@ func (b *Bucket) List(ctx context.Context, query *Query, options ...ListOption)
 	}
 }
 
+// Walk calls fn for each object in the bucket whose name starts with prefix,
+// in lexicographical order unless WithConcurrency is used.
+//
+// Walking stops at the first error returned by fn, which Walk then returns.
+// If listing the objects fails that error is returned instead.
+func (b *Bucket) Walk(ctx context.Context, prefix string, fn func(*ListEntry) error, options ...WalkOption) error {
+	var opts walkOptions
+	for _, o := range options {
+		o.applyWalk(&opts)
+	}
+
+	if opts.concurrency <= 1 {
+		for entry, err := range b.List(ctx, &Query{Prefix: prefix}) {
+			if err != nil {
+				return err
+			}
+			// Providers may list a page of entries without checking ctx.
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(opts.concurrency)
+	var listErr error
+	for entry, err := range b.List(gctx, &Query{Prefix: prefix}) {
+		if err != nil {
+			listErr = err
+			break
+		} else if gctx.Err() != nil {
+			break // fn failed; don't start any more calls
+		}
+		g.Go(func() error { return fn(entry) })
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	} else if listErr == nil {
+		// Listing stops without an error if ctx is canceled between entries.
+		listErr = ctx.Err()
+	}
+	return listErr
+}
+
 // Remove removes an object from the bucket.
 func (b *Bucket) Remove(ctx context.Context, object string, options ...RemoveOption) error {
 	var opts removeOptions
@@ -512,6 +800,78 @@ func (b *Bucket) Remove(ctx context.Context, object string, options ...RemoveOpt
 	return removeErr
 }
 
+// RemoveResult is the outcome of removing a single object with RemoveAll.
+type RemoveResult struct {
+	// The name of the object.
+	Name string
+	// Err is the error removing the object, or nil if it was removed.
+	Err error
+}
+
+// RemoveAll removes multiple objects from the bucket, batching requests
+// where the provider supports it.
+//
+// Failing to remove an object does not stop the others from being removed;
+// instead the returned results report the outcome for each object, in the
+// same order as objects. An error is only returned if the operation as a
+// whole failed, in which case the results cover the objects processed
+// before the failure.
+func (b *Bucket) RemoveAll(ctx context.Context, objects []string, options ...RemoveAllOption) ([]RemoveResult, error) {
+	var opts removeAllOptions
+	for _, o := range options {
+		o.applyRemoveAll(&opts)
+	}
+	if len(objects) == 0 {
+		return nil, nil
+	}
+
+	var removeErr error
+	curr := b.mgr.rt.Current()
+	if curr.Req != nil && curr.Trace != nil {
+		entries := make([]trace2.BucketDeleteObjectsEntry, len(objects))
+		for i, obj := range objects {
+			entries[i] = trace2.BucketDeleteObjectsEntry{Object: obj}
+		}
+		startEventID := curr.Trace.BucketDeleteObjectsStart(trace2.BucketDeleteObjectsStartParams{
+			EventParams: trace2.EventParams{
+				TraceID: curr.Req.TraceID,
+				SpanID:  curr.Req.SpanID,
+				Goid:    curr.Goctr,
+			},
+			Bucket:  b.name,
+			Objects: entries,
+			Stack:   stack.Build(1),
+		})
+
+		defer func() {
+			curr.Trace.BucketDeleteObjectsEnd(trace2.BucketDeleteObjectsEndParams{
+				StartID: startEventID,
+				EventParams: trace2.EventParams{
+					TraceID: curr.Req.TraceID,
+					SpanID:  curr.Req.SpanID,
+					Goid:    curr.Goctr,
+				},
+				Err: removeErr,
+			})
+		}()
+	}
+
+	cloudObjects := make([]types.CloudObject, len(objects))
+	for i, obj := range objects {
+		cloudObjects[i] = b.toCloudObject(obj)
+	}
+	res, removeErr := b.impl.RemoveAll(types.RemoveAllData{
+		Ctx:     ctx,
+		Objects: cloudObjects,
+	})
+
+	results := make([]RemoveResult, len(res))
+	for i, r := range res {
+		results[i] = RemoveResult{Name: b.fromCloudObject(r.Object), Err: r.Err}
+	}
+	return results, removeErr
+}
+
 var (
 	// ErrObjectNotFound is returned when requested object does not exist in the bucket.
 	ErrObjectNotFound = types.ErrObjectNotExist
@@ -520,9 +880,26 @@ var (
 	// such as when an object already exists and Preconditions.NotExists is true.
 	ErrPreconditionFailed = types.ErrPreconditionFailed
 
+	// ErrObjectExists is returned when uploading an object using Preconditions.NotExists
+	// and the object already exists. It wraps ErrPreconditionFailed.
+	ErrObjectExists = types.ErrObjectExists
+
 	// ErrInvalidArgument is returned when an argument for an operation is invalid or out
 	// of bounds. Such as when a too long time-to-live is passed to a sign URL operation.
 	ErrInvalidArgument = types.ErrInvalidArgument
+
+	// ErrChecksumMismatch is returned when the checksum of an uploaded object,
+	// as reported by the storage provider, does not match the checksum of the data sent.
+	ErrChecksumMismatch = types.ErrChecksumMismatch
+
+	// ErrNotModified is returned when downloading an object using DownloadConditions
+	// and the object hasn't changed.
+	ErrNotModified = types.ErrNotModified
+
+	// ErrUnsupportedByProvider is returned when the storage provider doesn't
+	// support an operation or option, such as when an S3-compatible service
+	// like MinIO or Ceph doesn't implement an S3 API.
+	ErrUnsupportedByProvider = types.ErrUnsupportedByProvider
 )
 
 // Attrs returns the attributes of an object in the bucket.
@@ -602,7 +979,7 @@ func (b *Bucket) SignedUploadURL(ctx context.Context, object string, options ...
 	if opt.TTL > 7*24*time.Hour {
 		return nil, types.ErrInvalidArgument
 	}
-	url, err := b.impl.SignedUploadURL(types.UploadURLData{
+	signed, err := b.impl.SignedUploadURL(types.UploadURLData{
 		Ctx:    ctx,
 		Object: b.toCloudObject(object),
 		TTL:    opt.TTL,
@@ -610,7 +987,7 @@ func (b *Bucket) SignedUploadURL(ctx context.Context, object string, options ...
 	if err != nil {
 		return nil, err
 	}
-	return &SignedUploadURL{URL: url}, nil
+	return &SignedUploadURL{URL: signed.URL, Method: signed.Method, Headers: signed.Headers}, nil
 }
 
 // Generates an external URL to allow downloading an object from the bucket.
@@ -628,7 +1005,7 @@ func (b *Bucket) SignedDownloadURL(ctx context.Context, object string, options .
 	if opt.TTL > 7*24*time.Hour {
 		return nil, types.ErrInvalidArgument
 	}
-	url, err := b.impl.SignedDownloadURL(types.DownloadURLData{
+	signed, err := b.impl.SignedDownloadURL(types.DownloadURLData{
 		Ctx:    ctx,
 		Object: b.toCloudObject(object),
 		TTL:    opt.TTL,
@@ -636,10 +1013,13 @@ func (b *Bucket) SignedDownloadURL(ctx context.Context, object string, options .
 	if err != nil {
 		return nil, err
 	}
-	return &SignedDownloadURL{URL: url}, nil
+	return &SignedDownloadURL{URL: signed.URL, Method: signed.Method, Headers: signed.Headers}, nil
 }
 
 // Exists reports whether an object exists in the bucket.
+//
+// It only fetches the object's metadata, making it cheaper
+// than attempting to download the object.
 func (b *Bucket) Exists(ctx context.Context, object string, options ...ExistsOption) (bool, error) {
 	var opt existsOptions
 	for _, o := range options {
@@ -647,8 +1027,8 @@ func (b *Bucket) Exists(ctx context.Context, object string, options ...ExistsOpt
 	}
 
 	var (
-		attrs    *types.ObjectAttrs
-		attrsErr error
+		exists    bool
+		existsErr error
 	)
 
 	curr := b.mgr.rt.Current()
@@ -666,39 +1046,99 @@ func (b *Bucket) Exists(ctx context.Context, object string, options ...ExistsOpt
 		})
 
 		defer func() {
-			params := trace2.BucketObjectGetAttrsEndParams{
+			curr.Trace.BucketObjectGetAttrsEnd(trace2.BucketObjectGetAttrsEndParams{
 				StartID: startEventID,
 				EventParams: trace2.EventParams{
 					TraceID: curr.Req.TraceID,
 					SpanID:  curr.Req.SpanID,
 					Goid:    curr.Goctr,
 				},
-				Err: attrsErr,
-			}
-			if attrs != nil {
-				size := uint64(attrs.Size)
-				params.Attrs = &trace2.BucketObjectAttributes{
-					Size:        &size,
-					Version:     ptrOrNil(attrs.Version),
-					ETag:        ptrOrNil(attrs.ETag),
-					ContentType: ptrOrNil(attrs.ContentType),
-				}
-			}
-			curr.Trace.BucketObjectGetAttrsEnd(params)
+				Err: existsErr,
+			})
 		}()
 	}
 
-	attrs, attrsErr = b.impl.Attrs(types.AttrsData{
+	exists, existsErr = b.impl.Exists(types.ExistsData{
+		Ctx:     ctx,
+		Object:  b.toCloudObject(object),
+		Version: opt.version,
+	})
+	return exists, existsErr
+}
+
+// SetTags replaces the tags of an existing object.
+// An empty map removes all of the object's tags.
+//
+// Tags can also be set when uploading an object, using UploadAttrs.
+// S3 allows at most 10 tags per object. GCS doesn't support object tags.
+func (b *Bucket) SetTags(ctx context.Context, object string, tags map[string]string, options ...SetTagsOption) error {
+	var opt setTagsOptions
+	for _, o := range options {
+		o.applySetTags(&opt)
+	}
+
+	return b.impl.SetTags(types.SetTagsData{
 		Ctx:     ctx,
 		Object:  b.toCloudObject(object),
 		Version: opt.version,
+		Tags:    tags,
 	})
-	if errors.Is(attrsErr, ErrObjectNotFound) {
-		return false, nil
-	} else if attrsErr != nil {
-		return false, attrsErr
+}
+
+// Copy copies an object to dst, returning the attributes of the copy.
+//
+// By default the object is copied within the same bucket, keeping its
+// content type and metadata. Use WithDestinationBucket to copy the object
+// to another bucket, and WithUploadAttrs to replace its attributes.
+func (b *Bucket) Copy(ctx context.Context, src, dst string, options ...CopyOption) (*ObjectAttrs, error) {
+	var opt copyOptions
+	for _, o := range options {
+		o.applyCopy(&opt)
 	}
-	return true, nil
+
+	dstBkt := b
+	if opt.dst != nil {
+		dstBkt = opt.dst
+	}
+
+	var dstImpl types.BucketImpl
+	if dstBkt.impl != b.impl {
+		dstImpl = dstBkt.impl
+	}
+	attrs, err := b.impl.Copy(types.CopyData{
+		Ctx:       ctx,
+		Object:    b.toCloudObject(src),
+		Version:   opt.version,
+		DstBucket: dstImpl,
+		DstObject: dstBkt.toCloudObject(dst),
+		Attrs:     opt.attrs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dstBkt.mapAttrs(attrs), nil
+}
+
+// Move moves an object to dst by copying it and then removing the source.
+// It accepts the same options as Copy.
+//
+// The operation is not atomic: if removing the source fails,
+// both objects exist and the error is returned along with the copy's attributes.
+func (b *Bucket) Move(ctx context.Context, src, dst string, options ...CopyOption) (*ObjectAttrs, error) {
+	attrs, err := b.Copy(ctx, src, dst, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	var opt copyOptions
+	for _, o := range options {
+		o.applyCopy(&opt)
+	}
+	var removeOpts []RemoveOption
+	if opt.version != "" {
+		removeOpts = append(removeOpts, WithVersion(opt.version))
+	}
+	return attrs, b.Remove(ctx, src, removeOpts...)
 }
 
 func (b *Bucket) toCloudObject(object string) types.CloudObject {
@@ -718,7 +1158,17 @@ func (b *Bucket) cloudPrefix() string {
 		prefix += test.Name() + "/__test__/"
 	}
 
-	return prefix
+	return prefix + b.subPrefix
+}
+
+// listPrefix returns the cloud prefix to list objects under.
+func (b *Bucket) listPrefix() string {
+	if b.subPrefix == "" {
+		return b.baseCloudPrefix
+	}
+	// Objects in a sub-bucket are only found under the full prefix,
+	// including the test isolation prefix.
+	return b.cloudPrefix()
 }
 
 func (b *Bucket) fromCloudObject(object types.CloudObject) string {
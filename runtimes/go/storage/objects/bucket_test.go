@@ -0,0 +1,956 @@
+package objects
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/appruntime/shared/reqtrack"
+	"encore.dev/storage/objects/internal/providers/memory"
+	"encore.dev/storage/objects/internal/types"
+)
+
+// newTestBucket returns a bucket backed by an in-memory provider,
+// along with the provider's bucket for seeding and inspecting objects.
+func newTestBucket(c *qt.C) (*Bucket, *memory.Bucket) {
+	cfg := &config.Bucket{EncoreName: "bucket", CloudName: "bucket"}
+	impl := memory.NewBucket(cfg)
+	mgr := &Manager{
+		static:  &config.Static{},
+		runtime: &config.Runtime{},
+		rt:      reqtrack.New(zerolog.Nop(), nil, nil),
+	}
+	return &Bucket{mgr: mgr, runtimeCfg: cfg, impl: impl, name: cfg.EncoreName}, impl
+}
+
+func TestDownloadToFile(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+	bkt, impl := newTestBucket(c)
+	impl.Seed("object", []byte("hello world"))
+
+	c.Run("success", func(c *qt.C) {
+		dir := c.TempDir()
+		path := filepath.Join(dir, "file.txt")
+		c.Assert(bkt.DownloadToFile(ctx, "object", path), qt.IsNil)
+
+		data, err := os.ReadFile(path)
+		c.Assert(err, qt.IsNil)
+		c.Assert(string(data), qt.Equals, "hello world")
+		c.Assert(dirEntries(c, dir), qt.DeepEquals, []string{"file.txt"})
+	})
+
+	c.Run("error_removes_temp_file", func(c *qt.C) {
+		dir := c.TempDir()
+		path := filepath.Join(dir, "file.txt")
+		err := bkt.DownloadToFile(ctx, "missing", path)
+		c.Assert(err, qt.ErrorIs, ErrObjectNotFound)
+		c.Assert(dirEntries(c, dir), qt.HasLen, 0)
+	})
+
+	c.Run("existing_file", func(c *qt.C) {
+		dir := c.TempDir()
+		path := filepath.Join(dir, "file.txt")
+		c.Assert(os.WriteFile(path, []byte("old contents"), 0o644), qt.IsNil)
+
+		// A failed download leaves the existing file untouched.
+		err := bkt.DownloadToFile(ctx, "missing", path)
+		c.Assert(err, qt.ErrorIs, ErrObjectNotFound)
+		data, err := os.ReadFile(path)
+		c.Assert(err, qt.IsNil)
+		c.Assert(string(data), qt.Equals, "old contents")
+
+		// A successful download replaces it.
+		c.Assert(bkt.DownloadToFile(ctx, "object", path), qt.IsNil)
+		data, err = os.ReadFile(path)
+		c.Assert(err, qt.IsNil)
+		c.Assert(string(data), qt.Equals, "hello world")
+		c.Assert(dirEntries(c, dir), qt.DeepEquals, []string{"file.txt"})
+	})
+}
+
+func TestUploadFromFile(t *testing.T) {
+	pngHeader := "\x89PNG\r\n\x1a\n" + "rest of the image"
+	tests := []struct {
+		name            string
+		file, content   string
+		opts            []UploadOption
+		wantContentType string
+	}{
+		{name: "extension", file: "data.json", content: `{"a":1}`, wantContentType: "application/json"},
+		{name: "sniffed", file: "image", content: pngHeader, wantContentType: "image/png"},
+		{name: "unknown_extension", file: "notes.unknownext", content: "hello", wantContentType: "text/plain; charset=utf-8"},
+		{name: "empty", file: "empty", content: "", wantContentType: "text/plain; charset=utf-8"},
+		{
+			name:            "explicit",
+			file:            "data.json",
+			content:         `{"a":1}`,
+			opts:            []UploadOption{WithUploadAttrs(UploadAttrs{ContentType: "text/plain"})},
+			wantContentType: "text/plain",
+		},
+		{
+			name:            "other_attrs",
+			file:            "data.json",
+			content:         `{"a":1}`,
+			opts:            []UploadOption{WithUploadAttrs(UploadAttrs{CacheControl: "no-cache"})},
+			wantContentType: "application/json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			ctx := context.Background()
+			bkt, impl := newTestBucket(c)
+
+			path := filepath.Join(c.TempDir(), tt.file)
+			c.Assert(os.WriteFile(path, []byte(tt.content), 0o644), qt.IsNil)
+
+			attrs, err := bkt.UploadFromFile(ctx, "object", path, tt.opts...)
+			c.Assert(err, qt.IsNil)
+			c.Assert(attrs.Name, qt.Equals, "object")
+			c.Assert(attrs.Size, qt.Equals, int64(len(tt.content)))
+			c.Assert(attrs.ContentType, qt.Equals, tt.wantContentType)
+			c.Assert(string(impl.Dump()["object"]), qt.Equals, tt.content)
+		})
+	}
+
+	c := qt.New(t)
+	bkt, _ := newTestBucket(c)
+	_, err := bkt.UploadFromFile(context.Background(), "object", filepath.Join(c.TempDir(), "missing"))
+	c.Assert(err, qt.ErrorIs, os.ErrNotExist)
+	_, err = bkt.UploadFromFile(context.Background(), "object", c.TempDir())
+	c.Assert(err, qt.ErrorIs, ErrInvalidArgument)
+}
+
+func TestWriter_Attrs(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+	bkt, _ := newTestBucket(c)
+
+	w := bkt.Upload(ctx, "object")
+	_, err := w.Write([]byte("hello"))
+	c.Assert(err, qt.IsNil)
+	_, err = w.Attrs()
+	c.Assert(err, qt.Equals, errUploadNotCompleted)
+	_, ok := w.VersionID()
+	c.Assert(ok, qt.IsFalse)
+
+	c.Assert(w.Close(), qt.IsNil)
+	attrs, err := w.Attrs()
+	c.Assert(err, qt.IsNil)
+	c.Assert(attrs.Name, qt.Equals, "object")
+	c.Assert(attrs.Size, qt.Equals, int64(5))
+	c.Assert(attrs.ETag, qt.Not(qt.Equals), "")
+
+	// Memory buckets aren't versioned.
+	_, ok = w.VersionID()
+	c.Assert(ok, qt.IsFalse)
+
+	c.Run("versioned", func(c *qt.C) {
+		bkt.impl = versionedBucket{BucketImpl: bkt.impl}
+		w := bkt.Upload(ctx, "object")
+		c.Assert(w.Close(), qt.IsNil)
+		id, ok := w.VersionID()
+		c.Assert(ok, qt.IsTrue)
+		c.Assert(id, qt.Equals, "v1")
+	})
+
+	c.Run("close_error", func(c *qt.C) {
+		w := bkt.Upload(ctx, "object")
+		w.Abort(errors.New("boom"))
+		c.Assert(w.Close(), qt.IsNotNil)
+		_, err := w.Attrs()
+		c.Assert(err, qt.ErrorMatches, "boom")
+	})
+}
+
+func TestWriter_ObjectExists(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+	bkt, impl := newTestBucket(c)
+	impl.Seed("object", []byte("hello"))
+
+	w := bkt.Upload(ctx, "object", WithPreconditions(Preconditions{NotExists: true}))
+	_, err := w.Write([]byte("overwritten"))
+	c.Assert(err, qt.IsNil)
+	err = w.Close()
+	c.Assert(errors.Is(err, ErrObjectExists), qt.IsTrue)
+	c.Assert(errors.Is(err, ErrPreconditionFailed), qt.IsTrue)
+	c.Assert(string(impl.Dump()["object"]), qt.Equals, "hello")
+
+	w = bkt.Upload(ctx, "new", WithPreconditions(Preconditions{NotExists: true}))
+	c.Assert(w.Close(), qt.IsNil)
+}
+
+func TestWalk(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+	bkt, impl := newTestBucket(c)
+	var want []string
+	for i := range 20 {
+		name := fmt.Sprintf("dir/%02d", i)
+		impl.Seed(name, []byte("x"))
+		want = append(want, name)
+	}
+	impl.Seed("other", []byte("x"))
+
+	for _, concurrency := range []int{1, 4} {
+		c.Run(fmt.Sprintf("concurrency_%d", concurrency), func(c *qt.C) {
+			opt := WithConcurrency(concurrency)
+
+			c.Run("visits_all", func(c *qt.C) {
+				var (
+					mu   sync.Mutex
+					seen []string
+				)
+				err := bkt.Walk(ctx, "dir/", func(e *ListEntry) error {
+					mu.Lock()
+					defer mu.Unlock()
+					seen = append(seen, e.Name)
+					return nil
+				}, opt)
+				c.Assert(err, qt.IsNil)
+				slices.Sort(seen)
+				c.Assert(seen, qt.DeepEquals, want)
+			})
+
+			c.Run("stops_on_error", func(c *qt.C) {
+				errStop := errors.New("stop")
+				var calls atomic.Int32
+				err := bkt.Walk(ctx, "dir/", func(e *ListEntry) error {
+					calls.Add(1)
+					if e.Name == "dir/03" {
+						return errStop
+					}
+					return nil
+				}, opt)
+				c.Assert(err, qt.Equals, errStop)
+				c.Assert(int(calls.Load()) < len(want), qt.IsTrue)
+			})
+
+			c.Run("context_canceled", func(c *qt.C) {
+				ctx, cancel := context.WithCancel(ctx)
+				defer cancel()
+				var calls atomic.Int32
+				err := bkt.Walk(ctx, "dir/", func(e *ListEntry) error {
+					if calls.Add(1) == 2 {
+						cancel()
+					}
+					return nil
+				}, opt)
+				c.Assert(err, qt.ErrorIs, context.Canceled)
+				c.Assert(int(calls.Load()) < len(want), qt.IsTrue)
+			})
+		})
+	}
+}
+
+func TestWalk_ContextCanceledSequential(t *testing.T) {
+	c := qt.New(t)
+	bkt, impl := newTestBucket(c)
+	for i := range 5 {
+		impl.Seed(fmt.Sprintf("dir/%02d", i), []byte("x"))
+	}
+	bkt.impl = &uncancelableListBucket{Bucket: impl}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	calls := 0
+	err := bkt.Walk(ctx, "dir/", func(e *ListEntry) error {
+		if calls++; calls == 2 {
+			cancel()
+		}
+		return nil
+	})
+	c.Assert(err, qt.ErrorIs, context.Canceled)
+	c.Assert(calls, qt.Equals, 2)
+}
+
+func TestSub(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+	bkt, impl := newTestBucket(c)
+	impl.Seed("outside", []byte("x"))
+	sub := bkt.Sub("dir/")
+
+	w := sub.Upload(ctx, "a.txt")
+	_, err := w.Write([]byte("hello"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(w.Close(), qt.IsNil)
+	attrs, err := w.Attrs()
+	c.Assert(err, qt.IsNil)
+	c.Assert(attrs.Name, qt.Equals, "a.txt")
+	impl.Seed("dir/nested/b.txt", []byte("b"))
+	c.Assert(slices.Sorted(maps.Keys(impl.Dump())), qt.DeepEquals, []string{"dir/a.txt", "dir/nested/b.txt", "outside"})
+
+	r := sub.Download(ctx, "a.txt")
+	data, err := io.ReadAll(r)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(data), qt.Equals, "hello")
+
+	var names []string
+	for e, err := range sub.List(ctx, &Query{}) {
+		c.Assert(err, qt.IsNil)
+		names = append(names, e.Name)
+	}
+	c.Assert(names, qt.DeepEquals, []string{"a.txt", "nested/b.txt"})
+
+	// Subs of subs stack their prefixes.
+	names = nil
+	for e, err := range sub.Sub("nested/").List(ctx, &Query{}) {
+		c.Assert(err, qt.IsNil)
+		names = append(names, e.Name)
+	}
+	c.Assert(names, qt.DeepEquals, []string{"b.txt"})
+
+	c.Assert(sub.Remove(ctx, "a.txt"), qt.IsNil)
+	c.Assert(slices.Sorted(maps.Keys(impl.Dump())), qt.DeepEquals, []string{"dir/nested/b.txt", "outside"})
+}
+
+func TestNormalizeKey(t *testing.T) {
+	c := qt.New(t)
+
+	tests := map[string]string{
+		"":            "",
+		"a/b":         "a/b",
+		"/a/b":        "a/b",
+		"///a//b///c": "a/b/c",
+		"a/b/":        "a/b/",
+		"a//b//":      "a/b/",
+		"//":          "",
+	}
+	for in, want := range tests {
+		c.Check(NormalizeKey(in), qt.Equals, want, qt.Commentf("key %q", in))
+	}
+}
+
+func TestEnsureBucket(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+	bkt, _ := newTestBucket(c)
+
+	// Memory buckets always exist.
+	c.Assert(bkt.EnsureBucket(ctx), qt.ErrorIs, ErrUnsupportedByProvider)
+	_, err := bkt.BucketExists(ctx)
+	c.Assert(err, qt.ErrorIs, ErrUnsupportedByProvider)
+
+	p := &provisionedBucket{BucketImpl: bkt.impl}
+	bkt.impl = p
+	exists, err := bkt.BucketExists(ctx)
+	c.Assert(err, qt.IsNil)
+	c.Assert(exists, qt.IsFalse)
+	c.Assert(bkt.EnsureBucket(ctx), qt.IsNil)
+	exists, err = bkt.BucketExists(ctx)
+	c.Assert(err, qt.IsNil)
+	c.Assert(exists, qt.IsTrue)
+}
+
+func TestCleanupIncompleteUploads(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+	bkt, _ := newTestBucket(c)
+
+	_, err := bkt.CleanupIncompleteUploads(ctx, time.Hour)
+	c.Assert(err, qt.ErrorIs, ErrUnsupportedByProvider)
+
+	var got types.CleanupUploadsData
+	bkt.impl = cleanedBucket{BucketImpl: bkt.impl, data: &got}
+	n, err := bkt.Sub("dir/").CleanupIncompleteUploads(ctx, time.Hour)
+	c.Assert(err, qt.IsNil)
+	c.Assert(n, qt.Equals, 1)
+	c.Assert(got.Prefix, qt.Equals, "dir/")
+	c.Assert(got.OlderThan, qt.Equals, time.Hour)
+}
+
+func TestResumeUpload(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+	bkt, impl := newTestBucket(c)
+	state := UploadState{Bucket: "bucket", Key: "dir/object", UploadID: "id", PartSize: 6}
+
+	_, _, err := bkt.ResumeUpload(ctx, state)
+	c.Assert(err, qt.ErrorIs, ErrUnsupportedByProvider)
+
+	bkt.impl = resumableBucket{BucketImpl: bkt.impl}
+	w, offset, err := bkt.Sub("dir/").ResumeUpload(ctx, state)
+	c.Assert(err, qt.IsNil)
+	c.Assert(offset, qt.Equals, int64(6))
+	_, err = w.Write([]byte("world"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(w.Close(), qt.IsNil)
+	attrs, err := w.Attrs()
+	c.Assert(err, qt.IsNil)
+	c.Assert(attrs.Name, qt.Equals, "object")
+	c.Assert(string(impl.Dump()["dir/object"]), qt.Equals, "hello world")
+}
+
+func TestDownloadSeeker(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+	bkt, impl := newTestBucket(c)
+	impl.Seed("dir/object", []byte("hello world"))
+
+	_, err := bkt.DownloadSeeker(ctx, "object")
+	c.Assert(err, qt.ErrorIs, ErrUnsupportedByProvider)
+
+	bkt.impl = seekableBucket{BucketImpl: bkt.impl}
+	r, err := bkt.Sub("dir/").DownloadSeeker(ctx, "object")
+	c.Assert(err, qt.IsNil)
+	defer r.Close()
+	_, err = r.Seek(-5, io.SeekEnd)
+	c.Assert(err, qt.IsNil)
+	data, err := io.ReadAll(r)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(data), qt.Equals, "world")
+}
+
+func TestDownloadWithAttrs(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+	bkt, impl := newTestBucket(c)
+	impl.Seed("dir/object", []byte("hello world"))
+
+	_, _, err := bkt.DownloadWithAttrs(ctx, "object")
+	c.Assert(err, qt.ErrorIs, ErrUnsupportedByProvider)
+
+	bkt.impl = resultBucket{BucketImpl: bkt.impl}
+	r, attrs, err := bkt.Sub("dir/").DownloadWithAttrs(ctx, "object")
+	c.Assert(err, qt.IsNil)
+	data, err := io.ReadAll(r)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(data), qt.Equals, "hello world")
+	c.Assert(attrs.Name, qt.Equals, "object")
+	c.Assert(attrs.Size, qt.Equals, int64(11))
+
+	_, _, err = bkt.DownloadWithAttrs(ctx, "missing")
+	c.Assert(err, qt.ErrorIs, ErrObjectNotFound)
+}
+
+func TestCopyParts(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+	bkt, impl := newTestBucket(c)
+	impl.Seed("dir/a", []byte("hello "))
+	impl.Seed("b", []byte("my world"))
+
+	_, err := bkt.CopyParts(ctx, "dst", []CopyPart{{Object: "a"}})
+	c.Assert(err, qt.ErrorIs, ErrUnsupportedByProvider)
+
+	bkt.impl = partCopyingBucket{BucketImpl: bkt.impl}
+	sub := bkt.Sub("dir/")
+	attrs, err := sub.CopyParts(ctx, "dst", []CopyPart{
+		{Object: "a"},
+		{Bucket: bkt, Object: "b", Offset: 3, Length: 5},
+	}, WithUploadAttrs(UploadAttrs{ContentType: "text/plain"}))
+	c.Assert(err, qt.IsNil)
+	c.Assert(attrs.Name, qt.Equals, "dst")
+	c.Assert(attrs.ContentType, qt.Equals, "text/plain")
+	c.Assert(string(impl.Dump()["dir/dst"]), qt.Equals, "hello world")
+}
+
+func TestListPage(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+	bkt, impl := newTestBucket(c)
+	for _, name := range []string{"dir/a", "dir/b", "dir/c", "other"} {
+		impl.Seed(name, []byte("x"))
+	}
+
+	_, _, err := bkt.ListPage(ctx, &Query{}, "")
+	c.Assert(err, qt.ErrorIs, ErrUnsupportedByProvider)
+
+	bkt.impl = pagedBucket{BucketImpl: bkt.impl}
+	sub := bkt.Sub("dir/")
+	var names []string
+	cursor := ""
+	for {
+		entries, next, err := sub.ListPage(ctx, &Query{PageSize: 2}, cursor)
+		c.Assert(err, qt.IsNil)
+		c.Assert(len(entries) <= 2, qt.IsTrue)
+		for _, e := range entries {
+			names = append(names, e.Name)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	c.Assert(names, qt.DeepEquals, []string{"a", "b", "c"})
+}
+
+func TestUploadReaderAt(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+	bkt, impl := newTestBucket(c)
+
+	_, err := bkt.UploadReaderAt(ctx, "object", strings.NewReader("hello"), 5)
+	c.Assert(err, qt.ErrorIs, ErrUnsupportedByProvider)
+
+	var calls int
+	bkt.impl = readerAtBucket{BucketImpl: bkt.impl, calls: &calls}
+	sub := bkt.Sub("dir/")
+	attrs, err := sub.UploadReaderAt(ctx, "object", strings.NewReader("hello world"), 5,
+		WithUploadAttrs(UploadAttrs{ContentType: "text/plain"}))
+	c.Assert(err, qt.IsNil)
+	c.Assert(attrs.Name, qt.Equals, "object")
+	c.Assert(attrs.ContentType, qt.Equals, "text/plain")
+	c.Assert(string(impl.Dump()["dir/object"]), qt.Equals, "hello")
+
+	_, err = sub.UploadReaderAt(ctx, "object", strings.NewReader("hello"), 5, WithPreconditions(Preconditions{NotExists: true}))
+	c.Assert(err, qt.ErrorIs, ErrObjectExists)
+
+	// Files are uploaded with UploadReaderAt when it's supported.
+	path := filepath.Join(c.TempDir(), "data.json")
+	c.Assert(os.WriteFile(path, []byte(`{"a":1}`), 0o644), qt.IsNil)
+	attrs, err = sub.UploadFromFile(ctx, "file", path)
+	c.Assert(err, qt.IsNil)
+	c.Assert(attrs.ContentType, qt.Equals, "application/json")
+	c.Assert(string(impl.Dump()["dir/file"]), qt.Equals, `{"a":1}`)
+	c.Assert(calls, qt.Equals, 3)
+}
+
+func TestPurgeTrash(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+	bkt, _ := newTestBucket(c)
+
+	_, err := bkt.PurgeTrash(ctx, time.Hour)
+	c.Assert(err, qt.ErrorIs, ErrUnsupportedByProvider)
+
+	var data types.PurgeTrashData
+	bkt.impl = purgedBucket{BucketImpl: bkt.impl, data: &data}
+	purged, err := bkt.Sub("dir/").PurgeTrash(ctx, time.Hour)
+	c.Assert(err, qt.IsNil)
+	c.Assert(purged, qt.Equals, 1)
+	c.Assert(data.Prefix, qt.Equals, "dir/")
+	c.Assert(data.OlderThan, qt.Equals, time.Hour)
+}
+
+func TestWatch(t *testing.T) {
+	c := qt.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bkt, _ := newTestBucket(c)
+
+	_, err := bkt.Watch(ctx, "", 0)
+	c.Assert(err, qt.ErrorIs, ErrUnsupportedByProvider)
+
+	var data types.WatchData
+	bkt.impl = watchedBucket{BucketImpl: bkt.impl, data: &data, events: []types.ObjectEvent{
+		{Kind: types.ObjectCreated, Object: "dir/sub/a", Size: 1, ETag: "a"},
+		{Kind: types.ObjectDeleted, Object: "dir/sub/b", Size: 2, ETag: "b"},
+	}}
+	events, err := bkt.Sub("dir/").Watch(ctx, "sub/", time.Minute)
+	c.Assert(err, qt.IsNil)
+	c.Assert(data.Prefix, qt.Equals, "dir/sub/")
+	c.Assert(data.Interval, qt.Equals, time.Minute)
+
+	var got []ObjectEvent
+	for ev := range events {
+		got = append(got, ev)
+	}
+	c.Assert(got, qt.DeepEquals, []ObjectEvent{
+		{Kind: ObjectCreated, Name: "sub/a", Size: 1, ETag: "a"},
+		{Kind: ObjectDeleted, Name: "sub/b", Size: 2, ETag: "b"},
+	})
+}
+
+func TestCapabilities(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+	bkt, _ := newTestBucket(c)
+
+	_, err := bkt.Capabilities(ctx)
+	c.Assert(err, qt.ErrorIs, ErrUnsupportedByProvider)
+
+	want := Capabilities{Multipart: true, Versioning: true}
+	bkt.impl = probedBucket{BucketImpl: bkt.impl, caps: want}
+	caps, err := bkt.Sub("dir/").Capabilities(ctx)
+	c.Assert(err, qt.IsNil)
+	c.Assert(caps, qt.Equals, want)
+}
+
+func TestSplitUpload(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+	bkt, impl := newTestBucket(c)
+
+	_, err := bkt.InitUpload(ctx, "object")
+	c.Assert(err, qt.ErrorIs, ErrUnsupportedByProvider)
+
+	bkt.impl = &splitUploadBucket{BucketImpl: bkt.impl}
+	sub := bkt.Sub("dir/")
+	upload, err := sub.InitUpload(ctx, "object", WithUploadAttrs(UploadAttrs{ContentType: "text/plain"}))
+	c.Assert(err, qt.IsNil)
+	c.Assert(upload.Key, qt.Equals, "dir/object")
+
+	// Parts can be uploaded in any order, such as by different workers.
+	part2, err := sub.UploadPart(ctx, upload, 2, []byte("world"))
+	c.Assert(err, qt.IsNil)
+	part1, err := sub.UploadPart(ctx, upload, 1, []byte("hello "))
+	c.Assert(err, qt.IsNil)
+	attrs, err := sub.CompleteUpload(ctx, upload, []UploadedPart{part2, part1})
+	c.Assert(err, qt.IsNil)
+	c.Assert(attrs.Name, qt.Equals, "object")
+	c.Assert(attrs.ContentType, qt.Equals, "text/plain")
+	c.Assert(string(impl.Dump()["dir/object"]), qt.Equals, "hello world")
+
+	upload, err = sub.InitUpload(ctx, "aborted")
+	c.Assert(err, qt.IsNil)
+	_, err = sub.UploadPart(ctx, upload, 1, []byte("hello"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(sub.AbortUpload(ctx, upload), qt.IsNil)
+	_, err = sub.CompleteUpload(ctx, upload, []UploadedPart{part1})
+	c.Assert(err, qt.ErrorIs, ErrInvalidArgument)
+}
+
+// uncancelableListBucket lists objects without checking the context,
+// like providers that yield a page of entries at a time.
+type uncancelableListBucket struct {
+	*memory.Bucket
+}
+
+func (b *uncancelableListBucket) List(data types.ListData) iter.Seq2[*types.ListEntry, error] {
+	data.Ctx = context.Background()
+	return b.Bucket.List(data)
+}
+
+// seekableBucket downloads objects for random access by reading them into memory.
+type seekableBucket struct {
+	types.BucketImpl
+}
+
+func (b seekableBucket) DownloadSeeker(data types.DownloadSeekerData) (io.ReadSeekCloser, error) {
+	r, err := b.Download(types.DownloadData{Ctx: data.Ctx, Object: data.Object, Version: data.Version})
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	contents, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.ReadSeeker
+		io.Closer
+	}{bytes.NewReader(contents), io.NopCloser(nil)}, nil
+}
+
+// resultBucket downloads objects along with their attributes
+// by making separate calls for them.
+type resultBucket struct {
+	types.BucketImpl
+}
+
+func (b resultBucket) DownloadWithResult(data types.DownloadData) (*types.DownloadResult, error) {
+	attrs, err := b.Attrs(types.AttrsData{Ctx: data.Ctx, Object: data.Object, Version: data.Version})
+	if err != nil {
+		return nil, err
+	}
+	r, err := b.Download(data)
+	if err != nil {
+		return nil, err
+	}
+	return &types.DownloadResult{Body: r, Attrs: attrs}, nil
+}
+
+// partCopyingBucket concatenates parts by downloading them.
+type partCopyingBucket struct {
+	types.BucketImpl
+}
+
+func (b partCopyingBucket) CopyParts(data types.CopyPartsData) (*types.ObjectAttrs, error) {
+	var buf bytes.Buffer
+	for _, p := range data.Parts {
+		if p.Bucket != nil {
+			return nil, ErrInvalidArgument
+		}
+		r, err := b.Download(types.DownloadData{Ctx: data.Ctx, Object: p.Object, Offset: p.Offset, Length: p.Length})
+		if err != nil {
+			return nil, err
+		}
+		_, err = io.Copy(&buf, r)
+		r.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	u, err := b.Upload(types.UploadData{Ctx: data.Ctx, Object: data.Object, Attrs: data.Attrs})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := u.Write(buf.Bytes()); err != nil {
+		u.Abort(err)
+		return nil, err
+	}
+	return u.Complete()
+}
+
+// pagedBucket pages through listings using the name
+// of the last entry of the previous page as the cursor.
+type pagedBucket struct {
+	types.BucketImpl
+}
+
+func (b pagedBucket) ListPage(data types.ListPageData) ([]*types.ListEntry, string, error) {
+	var entries []*types.ListEntry
+	for entry, err := range b.List(types.ListData{Ctx: data.Ctx, Prefix: data.Prefix, Delimiter: data.Delimiter}) {
+		if err != nil {
+			return nil, "", err
+		}
+		if string(entry.Object) <= data.Cursor {
+			continue
+		}
+		if len(entries) == data.PageSize {
+			return entries, string(entries[len(entries)-1].Object), nil
+		}
+		entries = append(entries, entry)
+	}
+	return entries, "", nil
+}
+
+// readerAtBucket uploads objects from an io.ReaderAt by streaming them.
+type readerAtBucket struct {
+	types.BucketImpl
+	calls *int
+}
+
+func (b readerAtBucket) UploadReaderAt(data types.UploadReaderAtData) (*types.ObjectAttrs, error) {
+	*b.calls++
+	u, err := b.Upload(data.UploadData)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(u, io.NewSectionReader(data.Reader, 0, data.Size)); err != nil {
+		u.Abort(err)
+		return nil, err
+	}
+	return u.Complete()
+}
+
+// purgedBucket records the purge it's asked to make.
+type purgedBucket struct {
+	types.BucketImpl
+	data *types.PurgeTrashData
+}
+
+func (b purgedBucket) PurgeTrash(data types.PurgeTrashData) (int, error) {
+	*b.data = data
+	return 1, nil
+}
+
+// watchedBucket records the watch it's asked to make,
+// and reports the given events before closing the channel.
+type watchedBucket struct {
+	types.BucketImpl
+	data   *types.WatchData
+	events []types.ObjectEvent
+}
+
+func (b watchedBucket) Watch(data types.WatchData) (<-chan types.ObjectEvent, error) {
+	*b.data = data
+	events := make(chan types.ObjectEvent, len(b.events))
+	for _, ev := range b.events {
+		events <- ev
+	}
+	close(events)
+	return events, nil
+}
+
+// probedBucket reports the given capabilities.
+type probedBucket struct {
+	types.BucketImpl
+	caps types.Capabilities
+}
+
+func (b probedBucket) Capabilities(ctx context.Context) (types.Capabilities, error) {
+	return b.caps, nil
+}
+
+// splitUploadBucket keeps the parts of multipart uploads in memory,
+// and uploads the object once the upload is completed.
+type splitUploadBucket struct {
+	types.BucketImpl
+
+	mu      sync.Mutex
+	lastID  int
+	uploads map[string]*splitUpload // by upload ID
+}
+
+type splitUpload struct {
+	data  types.InitUploadData
+	parts map[int32][]byte
+}
+
+func (b *splitUploadBucket) InitUpload(data types.InitUploadData) (types.MultipartUpload, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.uploads == nil {
+		b.uploads = make(map[string]*splitUpload)
+	}
+	b.lastID++
+	id := fmt.Sprint(b.lastID)
+	b.uploads[id] = &splitUpload{data: data, parts: make(map[int32][]byte)}
+	return types.MultipartUpload{Bucket: "bucket", Key: string(data.Object), UploadID: id}, nil
+}
+
+func (b *splitUploadBucket) UploadPart(data types.UploadPartData) (types.UploadedPart, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	up, ok := b.uploads[data.Upload.UploadID]
+	if !ok {
+		return types.UploadedPart{}, ErrInvalidArgument
+	}
+	up.parts[data.PartNumber] = data.Data
+	return types.UploadedPart{Number: data.PartNumber, Size: int64(len(data.Data))}, nil
+}
+
+func (b *splitUploadBucket) CompleteUpload(data types.CompleteUploadData) (*types.ObjectAttrs, error) {
+	b.mu.Lock()
+	up, ok := b.uploads[data.Upload.UploadID]
+	delete(b.uploads, data.Upload.UploadID)
+	b.mu.Unlock()
+	if !ok {
+		return nil, ErrInvalidArgument
+	}
+
+	parts := slices.SortedFunc(slices.Values(data.Parts), func(a, b types.UploadedPart) int {
+		return int(a.Number - b.Number)
+	})
+	u, err := b.Upload(types.UploadData{Ctx: data.Ctx, Object: up.data.Object, Attrs: up.data.Attrs})
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range parts {
+		if _, err := u.Write(up.parts[p.Number]); err != nil {
+			u.Abort(err)
+			return nil, err
+		}
+	}
+	return u.Complete()
+}
+
+func (b *splitUploadBucket) AbortUpload(ctx context.Context, upload types.MultipartUpload) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.uploads, upload.UploadID)
+	return nil
+}
+
+// resumableBucket resumes uploads after their first part, "hello".
+type resumableBucket struct {
+	types.BucketImpl
+}
+
+func (b resumableBucket) ResumeUpload(data types.ResumeUploadData) (types.Uploader, int64, error) {
+	u, err := b.Upload(types.UploadData{Ctx: data.Ctx, Object: types.CloudObject(data.State.Key)})
+	if err != nil {
+		return nil, 0, err
+	}
+	_, err = u.Write([]byte("hello "))
+	return u, data.State.PartSize, err
+}
+
+// cleanedBucket records the cleanup it's asked to make.
+type cleanedBucket struct {
+	types.BucketImpl
+	data *types.CleanupUploadsData
+}
+
+func (b cleanedBucket) CleanupIncompleteUploads(data types.CleanupUploadsData) (int, error) {
+	*b.data = data
+	return 1, nil
+}
+
+// provisionedBucket is a bucket that exists once created.
+type provisionedBucket struct {
+	types.BucketImpl
+	created bool
+}
+
+func (b *provisionedBucket) EnsureBucket(context.Context) error {
+	b.created = true
+	return nil
+}
+
+func (b *provisionedBucket) BucketExists(context.Context) (bool, error) {
+	return b.created, nil
+}
+
+// versionedBucket reports a version for every uploaded object.
+type versionedBucket struct {
+	types.BucketImpl
+}
+
+func (b versionedBucket) Upload(data types.UploadData) (types.Uploader, error) {
+	u, err := b.BucketImpl.Upload(data)
+	return versionedUploader{u}, err
+}
+
+type versionedUploader struct {
+	types.Uploader
+}
+
+func (u versionedUploader) Complete() (*types.ObjectAttrs, error) {
+	attrs, err := u.Uploader.Complete()
+	if attrs != nil {
+		attrs.Version = "v1"
+	}
+	return attrs, err
+}
+
+// dirEntries returns the names of the files in dir.
+func dirEntries(c *qt.C, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	c.Assert(err, qt.IsNil)
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.Name()
+	}
+	return names
+}
+
+func TestGetTestBucketInstance(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+	bkt, impl := newTestBucket(c)
+	bkt.baseCloudPrefix = "app/"
+	impl.Seed("other", []byte("not in the bucket's prefix"))
+
+	helpers := GetTestBucketInstance(bkt).(*testBucket)
+	helpers.Seed("object", []byte("hello"))
+
+	r := bkt.Download(ctx, "object")
+	data, err := io.ReadAll(r)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(data), qt.Equals, "hello")
+
+	w := bkt.Upload(ctx, "uploaded")
+	_, _ = w.Write([]byte("world"))
+	c.Assert(w.Close(), qt.IsNil)
+
+	c.Assert(helpers.Dump(), qt.DeepEquals, map[string][]byte{
+		"object":   []byte("hello"),
+		"uploaded": []byte("world"),
+	})
+	c.Assert(impl.Dump()["app/object"], qt.DeepEquals, []byte("hello"))
+
+	c.Assert(func() { GetTestBucketInstance(&Bucket{impl: &probedBucket{}}) }, qt.PanicMatches, "GetTestBucketInstance not called with an in-memory bucket.*")
+}
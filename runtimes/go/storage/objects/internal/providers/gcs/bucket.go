@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"cloud.google.com/go/storage"
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
@@ -38,6 +39,8 @@ type localSignOptions struct {
 	privateKey string
 }
 
+var _ types.BucketImpl = (*bucket)(nil)
+
 type bucket struct {
 	client    *storage.Client
 	cfg       *config.Bucket
@@ -60,17 +63,28 @@ func (mgr *Manager) NewBucket(provider *config.BucketProvider, runtimeCfg *confi
 }
 
 func (b *bucket) Download(data types.DownloadData) (types.Downloader, error) {
+	if data.Conditions != (types.DownloadConditions{}) {
+		return nil, fmt.Errorf("%w: download conditions are not supported by GCS", types.ErrInvalidArgument)
+	}
 	obj := b.handle.Object(data.Object.String())
 	if data.Version != "" {
 		if gen, err := strconv.ParseInt(data.Version, 10, 64); err == nil {
 			obj = obj.Generation(gen)
 		}
 	}
-	r, err := obj.NewReader(data.Ctx)
+	length := data.Length
+	if length == 0 {
+		length = -1 // read to the end
+	}
+	r, err := obj.NewRangeReader(data.Ctx, data.Offset, length)
 	return r, mapErr(err)
 }
 
 func (b *bucket) Upload(data types.UploadData) (types.Uploader, error) {
+	if len(data.Attrs.Tags) > 0 {
+		return nil, errTagging
+	}
+
 	ctx, cancel := context.WithCancelCause(data.Ctx)
 	obj := b.handle.Object(data.Object.String())
 
@@ -82,6 +96,15 @@ func (b *bucket) Upload(data types.UploadData) (types.Uploader, error) {
 
 	w := obj.NewWriter(ctx)
 	w.ContentType = data.Attrs.ContentType
+	w.CacheControl = data.Attrs.CacheControl
+	w.ContentEncoding = data.Attrs.ContentEncoding
+	w.Metadata = data.Attrs.Metadata
+	if data.PartSize > 0 {
+		w.ChunkSize = int(data.PartSize)
+	}
+	if progress := data.Progress; progress != nil {
+		w.ProgressFunc = func(uploaded int64) { progress(uploaded, -1) }
+	}
 
 	u := &uploader{
 		cancel: cancel,
@@ -123,10 +146,21 @@ func mapAttrs(attrs *storage.ObjectAttrs) *types.ObjectAttrs {
 		ContentType: attrs.ContentType,
 		Size:        attrs.Size,
 		ETag:        attrs.Etag,
+
+		LastModified: attrs.Updated,
+		Metadata:     attrs.Metadata,
 	}
 }
 
 func mapListEntry(attrs *storage.ObjectAttrs) *types.ListEntry {
+	// When listing with a delimiter, common prefixes
+	// are returned as entries with only Prefix set.
+	if attrs.Prefix != "" {
+		return &types.ListEntry{
+			Object:   types.CloudObject(attrs.Prefix),
+			IsPrefix: true,
+		}
+	}
 	return &types.ListEntry{
 		Object: types.CloudObject(attrs.Name),
 		Size:   attrs.Size,
@@ -136,14 +170,21 @@ func mapListEntry(attrs *storage.ObjectAttrs) *types.ListEntry {
 
 func (b *bucket) List(data types.ListData) iter.Seq2[*types.ListEntry, error] {
 	iter := b.handle.Objects(data.Ctx, &storage.Query{
-		Prefix: data.Prefix,
+		Prefix:    data.Prefix,
+		Delimiter: data.Delimiter,
 	})
+	if data.PageSize > 0 {
+		iter.PageInfo().MaxSize = data.PageSize
+	}
 	var n int64
 	return func(yield func(*types.ListEntry, error) bool) {
 		for {
 			res, err := iter.Next()
 			if err == iterator.Done {
 				return
+			} else if err != nil {
+				yield(nil, mapErr(err))
+				return
 			}
 
 			// Are we over the limit?
@@ -152,12 +193,7 @@ func (b *bucket) List(data types.ListData) iter.Seq2[*types.ListEntry, error] {
 			}
 			n++
 
-			var entry *types.ListEntry
-			if res != nil {
-				entry = mapListEntry(res)
-			}
-
-			if !yield(entry, mapErr(err)) {
+			if !yield(mapListEntry(res), nil) {
 				return
 			}
 		}
@@ -177,6 +213,26 @@ func (b *bucket) Remove(data types.RemoveData) error {
 	return mapErr(err)
 }
 
+// removeConcurrency is the number of objects RemoveAll removes in parallel.
+const removeConcurrency = 16
+
+func (b *bucket) RemoveAll(data types.RemoveAllData) ([]types.RemoveResult, error) {
+	// GCS has no batch delete API in the client library,
+	// so remove the objects concurrently.
+	results := make([]types.RemoveResult, len(data.Objects))
+	var g errgroup.Group
+	g.SetLimit(removeConcurrency)
+	for i, obj := range data.Objects {
+		g.Go(func() error {
+			err := b.handle.Object(obj.String()).Delete(data.Ctx)
+			results[i] = types.RemoveResult{Object: obj, Err: mapErr(err)}
+			return nil
+		})
+	}
+	_ = g.Wait()
+	return results, data.Ctx.Err()
+}
+
 func (b *bucket) Attrs(data types.AttrsData) (*types.ObjectAttrs, error) {
 	obj := b.handle.Object(data.Object.String())
 
@@ -190,7 +246,62 @@ func (b *bucket) Attrs(data types.AttrsData) (*types.ObjectAttrs, error) {
 	return mapAttrs(resp), mapErr(err)
 }
 
-func (b *bucket) SignedUploadURL(data types.UploadURLData) (string, error) {
+func (b *bucket) Exists(data types.ExistsData) (bool, error) {
+	obj := b.handle.Object(data.Object.String())
+
+	if data.Version != "" {
+		if gen, err := strconv.ParseInt(data.Version, 10, 64); err == nil {
+			obj = obj.Generation(gen)
+		}
+	}
+
+	_, err := obj.Attrs(data.Ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return false, nil
+	}
+	return err == nil, mapErr(err)
+}
+
+// errTagging is returned when setting tags, which GCS objects don't have.
+var errTagging = fmt.Errorf("%w: object tags are not supported by GCS", types.ErrInvalidArgument)
+
+func (b *bucket) SetTags(data types.SetTagsData) error {
+	return errTagging
+}
+
+func (b *bucket) Copy(data types.CopyData) (*types.ObjectAttrs, error) {
+	dst := b
+	if data.DstBucket != nil {
+		d, ok := data.DstBucket.(*bucket)
+		if !ok {
+			return nil, fmt.Errorf("%w: cannot copy objects between providers", types.ErrInvalidArgument)
+		}
+		dst = d
+	}
+
+	src := b.handle.Object(data.Object.String())
+	if data.Version != "" {
+		if gen, err := strconv.ParseInt(data.Version, 10, 64); err == nil {
+			src = src.Generation(gen)
+		}
+	}
+
+	// The copier handles objects of any size, rewriting them in chunks as needed.
+	copier := dst.handle.Object(data.DstObject.String()).CopierFrom(src)
+	if attrs := data.Attrs; attrs != nil {
+		if len(attrs.Tags) > 0 {
+			return nil, errTagging
+		}
+		copier.ContentType = attrs.ContentType
+		copier.CacheControl = attrs.CacheControl
+		copier.ContentEncoding = attrs.ContentEncoding
+		copier.Metadata = attrs.Metadata
+	}
+	resp, err := copier.Run(data.Ctx)
+	return mapAttrs(resp), mapErr(err)
+}
+
+func (b *bucket) SignedUploadURL(data types.UploadURLData) (*types.SignedURL, error) {
 	opts := &storage.SignedURLOptions{
 		Scheme:  storage.SigningSchemeV4,
 		Method:  "PUT",
@@ -199,7 +310,7 @@ func (b *bucket) SignedUploadURL(data types.UploadURLData) (string, error) {
 	return b.signedURL(data.Object.String(), opts)
 }
 
-func (b *bucket) SignedDownloadURL(data types.DownloadURLData) (string, error) {
+func (b *bucket) SignedDownloadURL(data types.DownloadURLData) (*types.SignedURL, error) {
 	opts := &storage.SignedURLOptions{
 		Scheme:  storage.SigningSchemeV4,
 		Method:  "GET",
@@ -208,7 +319,7 @@ func (b *bucket) SignedDownloadURL(data types.DownloadURLData) (string, error) {
 	return b.signedURL(data.Object.String(), opts)
 }
 
-func (b *bucket) signedURL(object string, opts *storage.SignedURLOptions) (string, error) {
+func (b *bucket) signedURL(object string, opts *storage.SignedURLOptions) (*types.SignedURL, error) {
 	// We use a fake GCS service for local development. Ideally, the runtime
 	// code would be oblivious to this once the GCS client is set up. But that
 	// turns out to be difficult for URL signing, so we add a special case
@@ -220,7 +331,7 @@ func (b *bucket) signedURL(object string, opts *storage.SignedURLOptions) (strin
 
 	url, err := b.handle.SignedURL(object, opts)
 	if err != nil {
-		return "", mapErr(err)
+		return nil, mapErr(err)
 	}
 
 	// More special handling for the local dev case.
@@ -228,7 +339,7 @@ func (b *bucket) signedURL(object string, opts *storage.SignedURLOptions) (strin
 		url = replaceURLPrefix(url, b.localSign.baseURL)
 	}
 
-	return url, nil
+	return &types.SignedURL{URL: url, Method: opts.Method}, nil
 }
 
 func replaceURLPrefix(origUrl string, base string) string {
@@ -0,0 +1,528 @@
+// Package local implements a bucket provider that stores objects
+// on the local file system, for use in local development and tests.
+//
+// Each bucket is a directory under the provider's root directory.
+// Object contents are stored under the "objects" subdirectory of the bucket,
+// and their attributes in JSON sidecar files under the "meta" subdirectory.
+package local
+
+import (
+	"cmp"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"iter"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/storage/objects/internal/types"
+)
+
+type Manager struct {
+	ctx     context.Context
+	runtime *config.Runtime
+}
+
+func NewManager(ctx context.Context, runtime *config.Runtime) *Manager {
+	return &Manager{ctx: ctx, runtime: runtime}
+}
+
+func (mgr *Manager) ProviderName() string { return "local" }
+
+func (mgr *Manager) Matches(cfg *config.BucketProvider) bool {
+	return cfg.Local != nil
+}
+
+func (mgr *Manager) NewBucket(provider *config.BucketProvider, runtimeCfg *config.Bucket) types.BucketImpl {
+	return NewBucket(provider.Local.Dir, runtimeCfg)
+}
+
+// NewBucket returns a bucket implementation that stores objects
+// in a directory named after the bucket's cloud name under root.
+func NewBucket(root string, cfg *config.Bucket) types.BucketImpl {
+	return &bucket{
+		cfg: cfg,
+		dir: filepath.Join(root, cfg.CloudName),
+	}
+}
+
+var _ types.BucketImpl = (*bucket)(nil)
+
+type bucket struct {
+	cfg *config.Bucket
+	dir string
+}
+
+// metadata is the contents of an object's sidecar file.
+type metadata struct {
+	ContentType     string            `json:"content_type,omitempty"`
+	CacheControl    string            `json:"cache_control,omitempty"`
+	ContentEncoding string            `json:"content_encoding,omitempty"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
+	Tags            map[string]string `json:"tags,omitempty"`
+	Size            int64             `json:"size"`
+	ETag            string            `json:"etag"`
+}
+
+var errVersioning = fmt.Errorf("%w: local buckets don't support versioning", types.ErrInvalidArgument)
+
+// objectPath returns the path of the file storing the object's contents.
+func (b *bucket) objectPath(obj types.CloudObject) (string, error) {
+	name, err := validName(obj)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(b.dir, "objects", filepath.FromSlash(name)), nil
+}
+
+// metaPath returns the path of the sidecar file storing the object's attributes.
+func (b *bucket) metaPath(obj types.CloudObject) (string, error) {
+	name, err := validName(obj)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(b.dir, "meta", filepath.FromSlash(name)+".json"), nil
+}
+
+// validName reports an error if the object name can't be stored as a file,
+// such as if it would escape the bucket directory.
+func validName(obj types.CloudObject) (string, error) {
+	name := string(obj)
+	if name == "" || !fs.ValidPath(name) {
+		return "", fmt.Errorf("%w: invalid object name %q", types.ErrInvalidArgument, name)
+	}
+	return name, nil
+}
+
+func (b *bucket) readMeta(obj types.CloudObject) (*metadata, error) {
+	p, err := b.metaPath(obj)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	var md metadata
+	if err := json.Unmarshal(data, &md); err != nil {
+		return nil, fmt.Errorf("invalid metadata for object %q: %w", obj, err)
+	}
+	return &md, nil
+}
+
+func (b *bucket) writeMeta(obj types.CloudObject, md *metadata) error {
+	p, err := b.metaPath(obj)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(md)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(p, data, 0o644)
+}
+
+func (b *bucket) Download(data types.DownloadData) (types.Downloader, error) {
+	if data.Version != "" {
+		return nil, errVersioning
+	}
+	p, err := b.objectPath(data.Object)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	if data.Conditions != (types.DownloadConditions{}) {
+		if err := b.checkConditions(data, f); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+	}
+	if _, err := f.Seek(data.Offset, io.SeekStart); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	if data.Length == 0 {
+		return f, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(f, data.Length), f}, nil
+}
+
+// checkConditions returns ErrNotModified if the object
+// being downloaded from f doesn't satisfy the conditions.
+func (b *bucket) checkConditions(data types.DownloadData, f *os.File) error {
+	md, err := b.readMeta(data.Object)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if data.Conditions.NotModified(md.ETag, fi.ModTime()) {
+		return types.ErrNotModified
+	}
+	return nil
+}
+
+func (b *bucket) Upload(data types.UploadData) (types.Uploader, error) {
+	dst, err := b.objectPath(data.Object)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Join(b.dir, "tmp"), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(filepath.Join(b.dir, "tmp"), "upload-*")
+	if err != nil {
+		return nil, err
+	}
+	return &uploader{
+		bkt:  b,
+		data: data,
+		dst:  dst,
+		f:    f,
+		hash: md5.New(),
+	}, nil
+}
+
+func (b *bucket) List(data types.ListData) iter.Seq2[*types.ListEntry, error] {
+	return func(yield func(*types.ListEntry, error) bool) {
+		entries, err := b.listEntries(data)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		for i, entry := range entries {
+			if data.Limit != nil && int64(i) >= *data.Limit {
+				return
+			}
+			if !yield(entry, nil) {
+				return
+			}
+		}
+	}
+}
+
+// listEntries returns the entries matching the query, sorted by name.
+func (b *bucket) listEntries(data types.ListData) ([]*types.ListEntry, error) {
+	root := filepath.Join(b.dir, "objects")
+	var (
+		entries  []*types.ListEntry
+		prefixes = make(map[string]bool)
+	)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && p == root {
+			return fs.SkipAll // nothing has been uploaded yet
+		} else if err != nil {
+			return err
+		} else if d.IsDir() {
+			return nil
+		}
+		if err := data.Ctx.Err(); err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if !strings.HasPrefix(name, data.Prefix) {
+			return nil
+		}
+
+		if data.Delimiter != "" {
+			rest := name[len(data.Prefix):]
+			if idx := strings.Index(rest, data.Delimiter); idx >= 0 {
+				prefix := name[:len(data.Prefix)+idx+len(data.Delimiter)]
+				if !prefixes[prefix] {
+					prefixes[prefix] = true
+					entries = append(entries, &types.ListEntry{
+						Object:   types.CloudObject(prefix),
+						IsPrefix: true,
+					})
+				}
+				return nil
+			}
+		}
+
+		md, err := b.readMeta(types.CloudObject(name))
+		if errors.Is(err, types.ErrObjectNotExist) {
+			return nil // the object is still being written
+		} else if err != nil {
+			return err
+		}
+		entries = append(entries, &types.ListEntry{
+			Object: types.CloudObject(name),
+			Size:   md.Size,
+			ETag:   md.ETag,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Walking the directory tree doesn't yield names in lexicographical order,
+	// since the path separator sorts before some other characters.
+	slices.SortFunc(entries, func(a, b *types.ListEntry) int {
+		return cmp.Compare(a.Object, b.Object)
+	})
+	return entries, nil
+}
+
+func (b *bucket) Remove(data types.RemoveData) error {
+	if data.Version != "" {
+		return errVersioning
+	}
+	p, err := b.objectPath(data.Object)
+	if err != nil {
+		return err
+	}
+	mp, err := b.metaPath(data.Object)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil {
+		return mapErr(err)
+	}
+	if err := os.Remove(mp); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (b *bucket) RemoveAll(data types.RemoveAllData) ([]types.RemoveResult, error) {
+	results := make([]types.RemoveResult, 0, len(data.Objects))
+	for _, obj := range data.Objects {
+		if err := data.Ctx.Err(); err != nil {
+			return results, err
+		}
+		err := b.Remove(types.RemoveData{Ctx: data.Ctx, Object: obj})
+		results = append(results, types.RemoveResult{Object: obj, Err: err})
+	}
+	return results, nil
+}
+
+func (b *bucket) Attrs(data types.AttrsData) (*types.ObjectAttrs, error) {
+	if data.Version != "" {
+		return nil, errVersioning
+	}
+	md, err := b.readMeta(data.Object)
+	if err != nil {
+		return nil, err
+	}
+	p, err := b.objectPath(data.Object)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(p)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	return &types.ObjectAttrs{
+		Object:      data.Object,
+		ContentType: md.ContentType,
+		Size:        md.Size,
+		ETag:        md.ETag,
+
+		LastModified: fi.ModTime(),
+		Metadata:     md.Metadata,
+	}, nil
+}
+
+func (b *bucket) Exists(data types.ExistsData) (bool, error) {
+	_, err := b.Attrs(types.AttrsData{Ctx: data.Ctx, Object: data.Object, Version: data.Version})
+	if errors.Is(err, types.ErrObjectNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (b *bucket) SetTags(data types.SetTagsData) error {
+	if data.Version != "" {
+		return errVersioning
+	}
+	md, err := b.readMeta(data.Object)
+	if err != nil {
+		return err
+	}
+	md.Tags = data.Tags
+	return b.writeMeta(data.Object, md)
+}
+
+func (b *bucket) Copy(data types.CopyData) (*types.ObjectAttrs, error) {
+	dst := b
+	if data.DstBucket != nil {
+		d, ok := data.DstBucket.(*bucket)
+		if !ok {
+			return nil, fmt.Errorf("%w: cannot copy objects between providers", types.ErrInvalidArgument)
+		}
+		dst = d
+	}
+
+	md, err := b.readMeta(data.Object)
+	if err != nil {
+		return nil, err
+	}
+	attrs := types.UploadAttrs{
+		ContentType:     md.ContentType,
+		CacheControl:    md.CacheControl,
+		ContentEncoding: md.ContentEncoding,
+		Metadata:        md.Metadata,
+		Tags:            md.Tags,
+	}
+	if data.Attrs != nil {
+		attrs = *data.Attrs
+	}
+
+	r, err := b.Download(types.DownloadData{Ctx: data.Ctx, Object: data.Object, Version: data.Version})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+
+	w, err := dst.Upload(types.UploadData{Ctx: data.Ctx, Object: data.DstObject, Attrs: attrs})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Abort(err)
+		return nil, err
+	}
+	return w.Complete()
+}
+
+func (b *bucket) SignedUploadURL(data types.UploadURLData) (*types.SignedURL, error) {
+	return nil, fmt.Errorf("signed URLs are not supported by local buckets")
+}
+
+func (b *bucket) SignedDownloadURL(data types.DownloadURLData) (*types.SignedURL, error) {
+	return nil, fmt.Errorf("signed URLs are not supported by local buckets")
+}
+
+// uploader writes an object to a temporary file,
+// which is moved into place when the upload completes.
+// This means readers never observe partially written objects.
+type uploader struct {
+	bkt  *bucket
+	data types.UploadData
+	dst  string
+	f    *os.File
+
+	hash    hash.Hash
+	written int64
+	err     error
+}
+
+func (u *uploader) Write(p []byte) (int, error) {
+	if u.err != nil {
+		return 0, u.err
+	} else if err := u.data.Ctx.Err(); err != nil {
+		u.Abort(err)
+		return 0, err
+	}
+	n, err := u.f.Write(p)
+	u.hash.Write(p[:n])
+	u.written += int64(n)
+	if err != nil {
+		u.Abort(err)
+		return n, err
+	}
+	if u.data.Progress != nil {
+		u.data.Progress(u.written, -1)
+	}
+	return n, nil
+}
+
+func (u *uploader) Abort(err error) {
+	if u.err != nil {
+		return
+	}
+	if err == nil {
+		err = errors.New("upload aborted")
+	}
+	u.err = err
+	_ = u.f.Close()
+	_ = os.Remove(u.f.Name())
+}
+
+func (u *uploader) Complete() (attrs *types.ObjectAttrs, err error) {
+	if u.err != nil {
+		return nil, u.err
+	}
+	defer func() {
+		if err != nil {
+			u.Abort(err)
+		}
+	}()
+
+	if err := u.f.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(u.dst), 0o755); err != nil {
+		return nil, err
+	}
+
+	if u.data.Pre.NotExists {
+		// Linking fails if the destination exists, unlike renaming.
+		if err := os.Link(u.f.Name(), u.dst); errors.Is(err, fs.ErrExist) {
+			return nil, types.ErrObjectExists
+		} else if err != nil {
+			return nil, err
+		}
+		_ = os.Remove(u.f.Name())
+	} else if err := os.Rename(u.f.Name(), u.dst); err != nil {
+		return nil, err
+	}
+
+	md := &metadata{
+		ContentType:     u.data.Attrs.ContentType,
+		CacheControl:    u.data.Attrs.CacheControl,
+		ContentEncoding: u.data.Attrs.ContentEncoding,
+		Metadata:        u.data.Attrs.Metadata,
+		Tags:            u.data.Attrs.Tags,
+		Size:            u.written,
+		ETag:            hex.EncodeToString(u.hash.Sum(nil)),
+	}
+	if err := u.bkt.writeMeta(u.data.Object, md); err != nil {
+		return nil, err
+	}
+	u.err = errors.New("upload already completed")
+
+	return &types.ObjectAttrs{
+		Object:      u.data.Object,
+		ContentType: md.ContentType,
+		Size:        md.Size,
+		ETag:        md.ETag,
+	}, nil
+}
+
+func mapErr(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, fs.ErrNotExist):
+		return types.ErrObjectNotExist
+	default:
+		return err
+	}
+}
@@ -0,0 +1,164 @@
+package local
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/storage/objects/internal/types"
+)
+
+func TestBucket(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+	bkt := NewBucket(c.TempDir(), &config.Bucket{CloudName: "bucket"})
+
+	upload(c, bkt, "dir/a.txt", "hello world", types.UploadAttrs{ContentType: "text/plain", Metadata: map[string]string{"k": "v"}})
+	upload(c, bkt, "dir/sub/b.txt", "b", types.UploadAttrs{})
+	upload(c, bkt, "dir-c.txt", "c", types.UploadAttrs{})
+
+	c.Run("download", func(c *qt.C) {
+		c.Assert(download(c, bkt, types.DownloadData{Ctx: ctx, Object: "dir/a.txt"}), qt.Equals, "hello world")
+		c.Assert(download(c, bkt, types.DownloadData{Ctx: ctx, Object: "dir/a.txt", Offset: 6, Length: 3}), qt.Equals, "wor")
+
+		_, err := bkt.Download(types.DownloadData{Ctx: ctx, Object: "missing"})
+		c.Assert(err, qt.Equals, types.ErrObjectNotExist)
+	})
+
+	c.Run("attrs", func(c *qt.C) {
+		attrs, err := bkt.Attrs(types.AttrsData{Ctx: ctx, Object: "dir/a.txt"})
+		c.Assert(err, qt.IsNil)
+		c.Assert(attrs.ContentType, qt.Equals, "text/plain")
+		c.Assert(attrs.Size, qt.Equals, int64(11))
+		c.Assert(attrs.ETag, qt.Equals, "5eb63bbbe01eeed093cb22bb8f5acdc3")
+		c.Assert(attrs.Metadata, qt.DeepEquals, map[string]string{"k": "v"})
+		c.Assert(attrs.LastModified.IsZero(), qt.IsFalse)
+
+		exists, err := bkt.Exists(types.ExistsData{Ctx: ctx, Object: "missing"})
+		c.Assert(err, qt.IsNil)
+		c.Assert(exists, qt.IsFalse)
+	})
+
+	c.Run("conditions", func(c *qt.C) {
+		attrs, err := bkt.Attrs(types.AttrsData{Ctx: ctx, Object: "dir/a.txt"})
+		c.Assert(err, qt.IsNil)
+
+		for _, cond := range []types.DownloadConditions{
+			{IfNoneMatch: attrs.ETag},
+			{IfNoneMatch: `"` + attrs.ETag + `"`},
+			{IfModifiedSince: attrs.LastModified},
+		} {
+			_, err = bkt.Download(types.DownloadData{Ctx: ctx, Object: "dir/a.txt", Conditions: cond})
+			c.Assert(err, qt.Equals, types.ErrNotModified, qt.Commentf("conditions %+v", cond))
+		}
+		c.Assert(download(c, bkt, types.DownloadData{Ctx: ctx, Object: "dir/a.txt",
+			Conditions: types.DownloadConditions{IfNoneMatch: "other"}}), qt.Equals, "hello world")
+		c.Assert(download(c, bkt, types.DownloadData{Ctx: ctx, Object: "dir/a.txt",
+			Conditions: types.DownloadConditions{IfModifiedSince: attrs.LastModified.Add(-time.Hour)}}), qt.Equals, "hello world")
+	})
+
+	c.Run("list", func(c *qt.C) {
+		c.Assert(list(c, bkt, types.ListData{Ctx: ctx}), qt.DeepEquals,
+			[]string{"dir-c.txt", "dir/a.txt", "dir/sub/b.txt"})
+		c.Assert(list(c, bkt, types.ListData{Ctx: ctx, Prefix: "dir/", Delimiter: "/"}), qt.DeepEquals,
+			[]string{"dir/a.txt", "dir/sub/ (prefix)"})
+	})
+
+	c.Run("invalid_name", func(c *qt.C) {
+		_, err := bkt.Upload(types.UploadData{Ctx: ctx, Object: "../escape"})
+		c.Assert(err, qt.ErrorIs, types.ErrInvalidArgument)
+	})
+
+	c.Run("precondition", func(c *qt.C) {
+		w, err := bkt.Upload(types.UploadData{Ctx: ctx, Object: "dir/a.txt", Pre: types.Preconditions{NotExists: true}})
+		c.Assert(err, qt.IsNil)
+		_, err = io.WriteString(w, "overwritten")
+		c.Assert(err, qt.IsNil)
+		_, err = w.Complete()
+		c.Assert(err, qt.Equals, types.ErrObjectExists)
+		c.Assert(download(c, bkt, types.DownloadData{Ctx: ctx, Object: "dir/a.txt"}), qt.Equals, "hello world")
+	})
+
+	c.Run("copy", func(c *qt.C) {
+		attrs, err := bkt.Copy(types.CopyData{Ctx: ctx, Object: "dir/a.txt", DstObject: "copy.txt"})
+		c.Assert(err, qt.IsNil)
+		c.Assert(attrs.ContentType, qt.Equals, "text/plain")
+		c.Assert(download(c, bkt, types.DownloadData{Ctx: ctx, Object: "copy.txt"}), qt.Equals, "hello world")
+	})
+
+	c.Run("tags", func(c *qt.C) {
+		upload(c, bkt, "tagged.txt", "t", types.UploadAttrs{Tags: map[string]string{"a": "1"}})
+		md, err := bkt.(*bucket).readMeta("tagged.txt")
+		c.Assert(err, qt.IsNil)
+		c.Assert(md.Tags, qt.DeepEquals, map[string]string{"a": "1"})
+
+		err = bkt.SetTags(types.SetTagsData{Ctx: ctx, Object: "tagged.txt", Tags: map[string]string{"b": "2"}})
+		c.Assert(err, qt.IsNil)
+		md, err = bkt.(*bucket).readMeta("tagged.txt")
+		c.Assert(err, qt.IsNil)
+		c.Assert(md.Tags, qt.DeepEquals, map[string]string{"b": "2"})
+
+		err = bkt.SetTags(types.SetTagsData{Ctx: ctx, Object: "missing"})
+		c.Assert(err, qt.Equals, types.ErrObjectNotExist)
+	})
+
+	c.Run("remove", func(c *qt.C) {
+		results, err := bkt.RemoveAll(types.RemoveAllData{Ctx: ctx, Objects: []types.CloudObject{"dir-c.txt", "missing"}})
+		c.Assert(err, qt.IsNil)
+		c.Assert(results, qt.HasLen, 2)
+		c.Assert(results[0], qt.Equals, types.RemoveResult{Object: "dir-c.txt"})
+		c.Assert(results[1], qt.Equals, types.RemoveResult{Object: "missing", Err: types.ErrObjectNotExist})
+	})
+
+	c.Run("abort", func(c *qt.C) {
+		w, err := bkt.Upload(types.UploadData{Ctx: ctx, Object: "aborted"})
+		c.Assert(err, qt.IsNil)
+		_, err = io.WriteString(w, "data")
+		c.Assert(err, qt.IsNil)
+		w.Abort(nil)
+
+		_, err = w.Complete()
+		c.Assert(err, qt.IsNotNil)
+		exists, err := bkt.Exists(types.ExistsData{Ctx: ctx, Object: "aborted"})
+		c.Assert(err, qt.IsNil)
+		c.Assert(exists, qt.IsFalse)
+	})
+}
+
+func upload(c *qt.C, bkt types.BucketImpl, object types.CloudObject, data string, attrs types.UploadAttrs) {
+	c.Helper()
+	w, err := bkt.Upload(types.UploadData{Ctx: context.Background(), Object: object, Attrs: attrs})
+	c.Assert(err, qt.IsNil)
+	_, err = io.WriteString(w, data)
+	c.Assert(err, qt.IsNil)
+	_, err = w.Complete()
+	c.Assert(err, qt.IsNil)
+}
+
+func download(c *qt.C, bkt types.BucketImpl, data types.DownloadData) string {
+	c.Helper()
+	r, err := bkt.Download(data)
+	c.Assert(err, qt.IsNil)
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	c.Assert(err, qt.IsNil)
+	return string(b)
+}
+
+func list(c *qt.C, bkt types.BucketImpl, data types.ListData) []string {
+	c.Helper()
+	var names []string
+	for entry, err := range bkt.List(data) {
+		c.Assert(err, qt.IsNil)
+		name := string(entry.Object)
+		if entry.IsPrefix {
+			name += " (prefix)"
+		}
+		names = append(names, name)
+	}
+	return names
+}
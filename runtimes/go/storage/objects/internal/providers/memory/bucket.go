@@ -0,0 +1,366 @@
+// Package memory implements a bucket provider that stores objects in memory,
+// for use in unit tests.
+//
+// Buckets are identified by their cloud name, so every bucket created by
+// a Manager with the same name shares the same objects.
+// Objects are lost when the process exits.
+package memory
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"iter"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/storage/objects/internal/types"
+)
+
+type Manager struct {
+	ctx     context.Context
+	runtime *config.Runtime
+
+	mu      sync.Mutex
+	buckets map[string]*Bucket // keyed by cloud name
+}
+
+func NewManager(ctx context.Context, runtime *config.Runtime) *Manager {
+	return &Manager{ctx: ctx, runtime: runtime, buckets: make(map[string]*Bucket)}
+}
+
+func (mgr *Manager) ProviderName() string { return "memory" }
+
+func (mgr *Manager) Matches(cfg *config.BucketProvider) bool {
+	return cfg.Memory != nil
+}
+
+func (mgr *Manager) NewBucket(provider *config.BucketProvider, runtimeCfg *config.Bucket) types.BucketImpl {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	if b, ok := mgr.buckets[runtimeCfg.CloudName]; ok {
+		return b
+	}
+	b := NewBucket(runtimeCfg)
+	mgr.buckets[runtimeCfg.CloudName] = b
+	return b
+}
+
+// NewBucket returns an empty in-memory bucket.
+func NewBucket(cfg *config.Bucket) *Bucket {
+	return &Bucket{cfg: cfg, objects: make(map[types.CloudObject]*object)}
+}
+
+var _ types.BucketImpl = (*Bucket)(nil)
+
+// Bucket is an in-memory bucket. It's safe for concurrent use.
+type Bucket struct {
+	cfg *config.Bucket
+
+	mu      sync.Mutex
+	objects map[types.CloudObject]*object
+}
+
+// object is a stored object. Its fields are never modified after
+// the object is stored, so it can be read without holding the lock.
+type object struct {
+	data     []byte
+	attrs    types.UploadAttrs
+	etag     string
+	modified time.Time
+}
+
+func newObject(data []byte, attrs types.UploadAttrs) *object {
+	sum := md5.Sum(data)
+	return &object{data: data, attrs: attrs, etag: hex.EncodeToString(sum[:]), modified: time.Now()}
+}
+
+func (o *object) objectAttrs(name types.CloudObject) *types.ObjectAttrs {
+	return &types.ObjectAttrs{
+		Object:      name,
+		ContentType: o.attrs.ContentType,
+		Size:        int64(len(o.data)),
+		ETag:        o.etag,
+	}
+}
+
+var errVersioning = fmt.Errorf("%w: memory buckets don't support versioning", types.ErrInvalidArgument)
+
+// Seed stores an object with the given contents, replacing any existing object.
+func (b *Bucket) Seed(name string, data []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.objects[types.CloudObject(name)] = newObject(bytes.Clone(data), types.UploadAttrs{})
+}
+
+// Dump returns a copy of the contents of every object in the bucket.
+func (b *Bucket) Dump() map[string][]byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	dump := make(map[string][]byte, len(b.objects))
+	for name, obj := range b.objects {
+		dump[string(name)] = bytes.Clone(obj.data)
+	}
+	return dump
+}
+
+func (b *Bucket) get(name types.CloudObject) (*object, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	obj, ok := b.objects[name]
+	if !ok {
+		return nil, types.ErrObjectNotExist
+	}
+	return obj, nil
+}
+
+func (b *Bucket) Download(data types.DownloadData) (types.Downloader, error) {
+	if data.Version != "" {
+		return nil, errVersioning
+	}
+	obj, err := b.get(data.Object)
+	if err != nil {
+		return nil, err
+	}
+	if data.Conditions.NotModified(obj.etag, obj.modified) {
+		return nil, types.ErrNotModified
+	}
+	contents := obj.data[min(data.Offset, int64(len(obj.data))):]
+	if data.Length > 0 {
+		contents = contents[:min(data.Length, int64(len(contents)))]
+	}
+	return struct {
+		*bytes.Reader
+		nopCloser
+	}{bytes.NewReader(contents), nopCloser{}}, nil
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+func (b *Bucket) Upload(data types.UploadData) (types.Uploader, error) {
+	if data.Object == "" {
+		return nil, fmt.Errorf("%w: empty object name", types.ErrInvalidArgument)
+	}
+	return &uploader{bkt: b, data: data}, nil
+}
+
+func (b *Bucket) List(data types.ListData) iter.Seq2[*types.ListEntry, error] {
+	return func(yield func(*types.ListEntry, error) bool) {
+		for i, entry := range b.listEntries(data) {
+			if data.Limit != nil && int64(i) >= *data.Limit {
+				return
+			}
+			if err := data.Ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(entry, nil) {
+				return
+			}
+		}
+	}
+}
+
+// listEntries returns the entries matching the query, sorted by name.
+func (b *Bucket) listEntries(data types.ListData) []*types.ListEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var (
+		entries  []*types.ListEntry
+		prefixes = make(map[string]bool)
+	)
+	for _, name := range slices.Sorted(maps.Keys(b.objects)) {
+		s := string(name)
+		if !strings.HasPrefix(s, data.Prefix) {
+			continue
+		}
+		if data.Delimiter != "" {
+			rest := s[len(data.Prefix):]
+			if idx := strings.Index(rest, data.Delimiter); idx >= 0 {
+				prefix := s[:len(data.Prefix)+idx+len(data.Delimiter)]
+				if !prefixes[prefix] {
+					prefixes[prefix] = true
+					entries = append(entries, &types.ListEntry{
+						Object:   types.CloudObject(prefix),
+						IsPrefix: true,
+					})
+				}
+				continue
+			}
+		}
+		obj := b.objects[name]
+		entries = append(entries, &types.ListEntry{
+			Object: name,
+			Size:   int64(len(obj.data)),
+			ETag:   obj.etag,
+		})
+	}
+	return entries
+}
+
+func (b *Bucket) Remove(data types.RemoveData) error {
+	if data.Version != "" {
+		return errVersioning
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.objects[data.Object]; !ok {
+		return types.ErrObjectNotExist
+	}
+	delete(b.objects, data.Object)
+	return nil
+}
+
+func (b *Bucket) RemoveAll(data types.RemoveAllData) ([]types.RemoveResult, error) {
+	results := make([]types.RemoveResult, 0, len(data.Objects))
+	for _, obj := range data.Objects {
+		if err := data.Ctx.Err(); err != nil {
+			return results, err
+		}
+		err := b.Remove(types.RemoveData{Ctx: data.Ctx, Object: obj})
+		results = append(results, types.RemoveResult{Object: obj, Err: err})
+	}
+	return results, nil
+}
+
+func (b *Bucket) Attrs(data types.AttrsData) (*types.ObjectAttrs, error) {
+	if data.Version != "" {
+		return nil, errVersioning
+	}
+	obj, err := b.get(data.Object)
+	if err != nil {
+		return nil, err
+	}
+	attrs := obj.objectAttrs(data.Object)
+	attrs.LastModified = obj.modified
+	attrs.Metadata = maps.Clone(obj.attrs.Metadata)
+	return attrs, nil
+}
+
+func (b *Bucket) Exists(data types.ExistsData) (bool, error) {
+	_, err := b.Attrs(types.AttrsData{Ctx: data.Ctx, Object: data.Object, Version: data.Version})
+	if errors.Is(err, types.ErrObjectNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (b *Bucket) SetTags(data types.SetTagsData) error {
+	if data.Version != "" {
+		return errVersioning
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	obj, ok := b.objects[data.Object]
+	if !ok {
+		return types.ErrObjectNotExist
+	}
+	attrs := obj.attrs
+	attrs.Tags = maps.Clone(data.Tags)
+	b.objects[data.Object] = &object{data: obj.data, attrs: attrs, etag: obj.etag, modified: obj.modified}
+	return nil
+}
+
+func (b *Bucket) Copy(data types.CopyData) (*types.ObjectAttrs, error) {
+	if data.Version != "" {
+		return nil, errVersioning
+	}
+	dst := b
+	if data.DstBucket != nil {
+		d, ok := data.DstBucket.(*Bucket)
+		if !ok {
+			return nil, fmt.Errorf("%w: cannot copy objects between providers", types.ErrInvalidArgument)
+		}
+		dst = d
+	}
+
+	src, err := b.get(data.Object)
+	if err != nil {
+		return nil, err
+	}
+	attrs := src.attrs
+	if data.Attrs != nil {
+		attrs = *data.Attrs
+	}
+	// The contents are never modified, so they can be shared.
+	obj := &object{data: src.data, attrs: attrs, etag: src.etag, modified: time.Now()}
+
+	dst.mu.Lock()
+	defer dst.mu.Unlock()
+	dst.objects[data.DstObject] = obj
+	return obj.objectAttrs(data.DstObject), nil
+}
+
+func (b *Bucket) SignedUploadURL(data types.UploadURLData) (*types.SignedURL, error) {
+	return nil, fmt.Errorf("signed URLs are not supported by memory buckets")
+}
+
+func (b *Bucket) SignedDownloadURL(data types.DownloadURLData) (*types.SignedURL, error) {
+	return nil, fmt.Errorf("signed URLs are not supported by memory buckets")
+}
+
+// uploader buffers an object's contents in memory,
+// storing it in the bucket when the upload completes.
+// This means readers never observe partially written objects.
+type uploader struct {
+	bkt  *Bucket
+	data types.UploadData
+
+	buf bytes.Buffer
+	err error
+}
+
+func (u *uploader) Write(p []byte) (int, error) {
+	if u.err != nil {
+		return 0, u.err
+	} else if err := u.data.Ctx.Err(); err != nil {
+		u.Abort(err)
+		return 0, err
+	}
+	n, _ := u.buf.Write(p)
+	if u.data.Progress != nil {
+		u.data.Progress(int64(u.buf.Len()), -1)
+	}
+	return n, nil
+}
+
+func (u *uploader) Abort(err error) {
+	if u.err != nil {
+		return
+	}
+	if err == nil {
+		err = errors.New("upload aborted")
+	}
+	u.err = err
+	u.buf = bytes.Buffer{}
+}
+
+func (u *uploader) Complete() (*types.ObjectAttrs, error) {
+	if u.err != nil {
+		return nil, u.err
+	}
+	attrs := u.data.Attrs
+	attrs.Metadata = maps.Clone(attrs.Metadata)
+	attrs.Tags = maps.Clone(attrs.Tags)
+	obj := newObject(u.buf.Bytes(), attrs)
+
+	u.bkt.mu.Lock()
+	defer u.bkt.mu.Unlock()
+	if _, exists := u.bkt.objects[u.data.Object]; exists && u.data.Pre.NotExists {
+		u.Abort(types.ErrObjectExists)
+		return nil, types.ErrObjectExists
+	}
+	u.bkt.objects[u.data.Object] = obj
+	u.err = errors.New("upload already completed")
+	return obj.objectAttrs(u.data.Object), nil
+}
@@ -7,6 +7,8 @@ import (
 	"encore.dev/storage/objects/internal/types"
 )
 
+var _ types.BucketImpl = (*BucketImpl)(nil)
+
 type BucketImpl struct {
 	EncoreName string
 }
@@ -29,14 +31,30 @@ func (b *BucketImpl) Remove(data types.RemoveData) error {
 	return fmt.Errorf("cannot remove from noop bucket")
 }
 
+func (b *BucketImpl) RemoveAll(data types.RemoveAllData) ([]types.RemoveResult, error) {
+	return nil, fmt.Errorf("cannot remove from noop bucket")
+}
+
 func (b *BucketImpl) Attrs(data types.AttrsData) (*types.ObjectAttrs, error) {
 	return nil, fmt.Errorf("cannot get attributes from noop bucket")
 }
 
-func (b *BucketImpl) SignedUploadURL(data types.UploadURLData) (string, error) {
-	return "", fmt.Errorf("cannot get upload url from noop bucket")
+func (b *BucketImpl) Exists(data types.ExistsData) (bool, error) {
+	return false, fmt.Errorf("cannot check existence in noop bucket")
+}
+
+func (b *BucketImpl) SetTags(data types.SetTagsData) error {
+	return fmt.Errorf("cannot set tags in noop bucket")
+}
+
+func (b *BucketImpl) Copy(data types.CopyData) (*types.ObjectAttrs, error) {
+	return nil, fmt.Errorf("cannot copy objects in noop bucket")
+}
+
+func (b *BucketImpl) SignedUploadURL(data types.UploadURLData) (*types.SignedURL, error) {
+	return nil, fmt.Errorf("cannot get upload url from noop bucket")
 }
 
-func (b *BucketImpl) SignedDownloadURL(data types.DownloadURLData) (string, error) {
-	return "", fmt.Errorf("cannot get download url from noop bucket")
+func (b *BucketImpl) SignedDownloadURL(data types.DownloadURLData) (*types.SignedURL, error) {
+	return nil, fmt.Errorf("cannot get download url from noop bucket")
 }
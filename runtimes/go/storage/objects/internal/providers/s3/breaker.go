@@ -0,0 +1,216 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ErrCircuitOpen is reported for requests that weren't sent to S3
+// because the circuit breaker configured by WithCircuitBreaker is open.
+var ErrCircuitOpen = errors.New("s3: circuit breaker is open")
+
+// CircuitBreaker configures a circuit breaker, which makes requests fail
+// fast during a sustained S3 outage instead of piling up until they time out.
+//
+// The breaker trips after a number of consecutive requests fail with
+// transient errors such as throttling, 5xx responses, timeouts and network
+// errors. Other errors, like a missing object, show that S3 is available.
+// While it's open requests fail with ErrCircuitOpen. Once the cooldown
+// has elapsed a single probe request is let through: if it succeeds the
+// breaker closes, and otherwise it stays open for another cooldown.
+type CircuitBreaker struct {
+	// FailureThreshold is the number of consecutive failed requests
+	// that trip the breaker. If zero, 5 is used.
+	FailureThreshold int
+
+	// Cooldown is how long the breaker stays open before letting
+	// a probe request through. If zero, 30s is used.
+	Cooldown time.Duration
+}
+
+// Circuit breaker defaults.
+const (
+	defaultFailureThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+)
+
+// WithCircuitBreaker guards requests to S3 with a circuit breaker.
+// It's shared by all operations on the bucket.
+func WithCircuitBreaker(cb CircuitBreaker) Option {
+	return func(o *bucketOptions) { o.breaker = &cb }
+}
+
+// breaker is the state of a circuit breaker.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	failures int       // consecutive failures
+	openedAt time.Time // zero if closed
+	probing  bool      // whether a probe request is in flight
+}
+
+func newBreaker(cb CircuitBreaker) *breaker {
+	threshold, cooldown := cb.FailureThreshold, cb.Cooldown
+	if threshold <= 0 {
+		threshold = defaultFailureThreshold
+	}
+	if cooldown <= 0 {
+		cooldown = defaultBreakerCooldown
+	}
+	return &breaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// allow reports whether a request may be sent,
+// marking it as the probe request if the breaker is open.
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case b.openedAt.IsZero():
+		return nil
+	case b.probing || b.now().Sub(b.openedAt) < b.cooldown:
+		return ErrCircuitOpen
+	default:
+		b.probing = true
+		return nil
+	}
+}
+
+// record records the outcome of a request sent with ctx.
+func (b *breaker) record(ctx context.Context, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case err != nil && ctx.Err() != nil:
+		// The caller gave up, which says nothing about S3.
+		// Let another request probe it.
+		b.probing = false
+		return
+	case err == nil || !isOutage(err):
+		// S3 responded.
+		b.failures, b.openedAt, b.probing = 0, time.Time{}, false
+		return
+	}
+	b.failures++
+	if b.probing || b.failures >= b.threshold {
+		b.openedAt, b.probing = b.now(), false
+	}
+}
+
+// isOutage reports whether err suggests S3 is unavailable.
+func isOutage(err error) bool {
+	var netErr net.Error
+	return isRetryable(err) || errors.As(err, &netErr)
+}
+
+// breakerClient is an s3Client whose requests are guarded by a circuit breaker.
+type breakerClient struct {
+	s3Client
+	breaker *breaker
+}
+
+// callWithBreaker calls fn unless the breaker is open, recording the outcome.
+func callWithBreaker[In, Out any](ctx context.Context, b *breaker,
+	fn func(context.Context, In, ...func(*s3.Options)) (Out, error), in In, optFns []func(*s3.Options)) (Out, error) {
+	if err := b.allow(); err != nil {
+		var zero Out
+		return zero, err
+	}
+	out, err := fn(ctx, in, optFns...)
+	b.record(ctx, err)
+	return out, err
+}
+
+func (c *breakerClient) PutObject(ctx context.Context, in *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	return callWithBreaker(ctx, c.breaker, c.s3Client.PutObject, in, optFns)
+}
+
+func (c *breakerClient) CreateMultipartUpload(ctx context.Context, in *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	return callWithBreaker(ctx, c.breaker, c.s3Client.CreateMultipartUpload, in, optFns)
+}
+
+func (c *breakerClient) UploadPart(ctx context.Context, in *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	return callWithBreaker(ctx, c.breaker, c.s3Client.UploadPart, in, optFns)
+}
+
+func (c *breakerClient) CompleteMultipartUpload(ctx context.Context, in *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	return callWithBreaker(ctx, c.breaker, c.s3Client.CompleteMultipartUpload, in, optFns)
+}
+
+func (c *breakerClient) UploadPartCopy(ctx context.Context, in *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error) {
+	return callWithBreaker(ctx, c.breaker, c.s3Client.UploadPartCopy, in, optFns)
+}
+
+func (c *breakerClient) AbortMultipartUpload(ctx context.Context, in *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	return callWithBreaker(ctx, c.breaker, c.s3Client.AbortMultipartUpload, in, optFns)
+}
+
+func (c *breakerClient) ListParts(ctx context.Context, in *s3.ListPartsInput, optFns ...func(*s3.Options)) (*s3.ListPartsOutput, error) {
+	return callWithBreaker(ctx, c.breaker, c.s3Client.ListParts, in, optFns)
+}
+
+func (c *breakerClient) ListMultipartUploads(ctx context.Context, in *s3.ListMultipartUploadsInput, optFns ...func(*s3.Options)) (*s3.ListMultipartUploadsOutput, error) {
+	return callWithBreaker(ctx, c.breaker, c.s3Client.ListMultipartUploads, in, optFns)
+}
+
+// GetObject records the outcome once the response starts,
+// not while reading the object's contents.
+func (c *breakerClient) GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	return callWithBreaker(ctx, c.breaker, c.s3Client.GetObject, in, optFns)
+}
+
+func (c *breakerClient) HeadObject(ctx context.Context, in *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return callWithBreaker(ctx, c.breaker, c.s3Client.HeadObject, in, optFns)
+}
+
+func (c *breakerClient) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	return callWithBreaker(ctx, c.breaker, c.s3Client.ListObjectsV2, in, optFns)
+}
+
+func (c *breakerClient) PutObjectTagging(ctx context.Context, in *s3.PutObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.PutObjectTaggingOutput, error) {
+	return callWithBreaker(ctx, c.breaker, c.s3Client.PutObjectTagging, in, optFns)
+}
+
+func (c *breakerClient) CopyObject(ctx context.Context, in *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	return callWithBreaker(ctx, c.breaker, c.s3Client.CopyObject, in, optFns)
+}
+
+func (c *breakerClient) DeleteObject(ctx context.Context, in *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	return callWithBreaker(ctx, c.breaker, c.s3Client.DeleteObject, in, optFns)
+}
+
+func (c *breakerClient) CreateBucket(ctx context.Context, in *s3.CreateBucketInput, optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
+	return callWithBreaker(ctx, c.breaker, c.s3Client.CreateBucket, in, optFns)
+}
+
+func (c *breakerClient) HeadBucket(ctx context.Context, in *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	return callWithBreaker(ctx, c.breaker, c.s3Client.HeadBucket, in, optFns)
+}
+
+func (c *breakerClient) DeleteObjects(ctx context.Context, in *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	return callWithBreaker(ctx, c.breaker, c.s3Client.DeleteObjects, in, optFns)
+}
+
+func (c *breakerClient) GetObjectTagging(ctx context.Context, in *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error) {
+	return callWithBreaker(ctx, c.breaker, c.s3Client.GetObjectTagging, in, optFns)
+}
+
+func (c *breakerClient) GetBucketVersioning(ctx context.Context, in *s3.GetBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.GetBucketVersioningOutput, error) {
+	return callWithBreaker(ctx, c.breaker, c.s3Client.GetBucketVersioning, in, optFns)
+}
+
+func (c *breakerClient) GetObjectLockConfiguration(ctx context.Context, in *s3.GetObjectLockConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetObjectLockConfigurationOutput, error) {
+	return callWithBreaker(ctx, c.breaker, c.s3Client.GetObjectLockConfiguration, in, optFns)
+}
+
+func (c *breakerClient) GetBucketEncryption(ctx context.Context, in *s3.GetBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.GetBucketEncryptionOutput, error) {
+	return callWithBreaker(ctx, c.breaker, c.s3Client.GetBucketEncryption, in, optFns)
+}
@@ -0,0 +1,88 @@
+package s3
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	qt "github.com/frankban/quicktest"
+	"github.com/golang/mock/gomock"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/storage/objects/internal/types"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"},
+		WithCircuitBreaker(CircuitBreaker{FailureThreshold: 2, Cooldown: time.Minute}))
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	bkt.(*bucket).client.(*breakerClient).breaker.now = func() time.Time { return now }
+
+	exists := func() error {
+		_, err := bkt.Exists(types.ExistsData{Ctx: context.Background(), Object: "object"})
+		return err
+	}
+	unavailable := &smithy.GenericAPIError{Code: "ServiceUnavailable"}
+
+	// Errors that show S3 is available don't count as failures.
+	gomock.InOrder(
+		client.EXPECT().HeadObject(gomock.Any(), gomock.Any()).Return(nil, unavailable),
+		client.EXPECT().HeadObject(gomock.Any(), gomock.Any()).Return(nil, &s3types.NotFound{}),
+		client.EXPECT().HeadObject(gomock.Any(), gomock.Any()).Return(nil, unavailable),
+		client.EXPECT().HeadObject(gomock.Any(), gomock.Any()).Return(nil, unavailable),
+	)
+	c.Assert(exists(), qt.Equals, unavailable)
+	c.Assert(exists(), qt.IsNil)
+	c.Assert(exists(), qt.Equals, unavailable)
+	c.Assert(exists(), qt.Equals, unavailable)
+
+	// The breaker has tripped; requests fail without being sent.
+	c.Assert(exists(), qt.Equals, ErrCircuitOpen)
+	_, err := bkt.Download(types.DownloadData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.Equals, ErrCircuitOpen)
+
+	// After the cooldown a failed probe keeps it open.
+	now = now.Add(time.Minute)
+	client.EXPECT().HeadObject(gomock.Any(), gomock.Any()).Return(nil, unavailable)
+	c.Assert(exists(), qt.Equals, unavailable)
+	c.Assert(exists(), qt.Equals, ErrCircuitOpen)
+
+	// A successful probe closes it.
+	now = now.Add(time.Minute)
+	client.EXPECT().HeadObject(gomock.Any(), gomock.Any()).Return(&s3.HeadObjectOutput{}, nil).Times(2)
+	c.Assert(exists(), qt.IsNil)
+	c.Assert(exists(), qt.IsNil)
+}
+
+func TestCircuitBreaker_Probe(t *testing.T) {
+	c := qt.New(t)
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	b := newBreaker(CircuitBreaker{FailureThreshold: 1})
+	b.now = func() time.Time { return now }
+	c.Assert(b.cooldown, qt.Equals, defaultBreakerCooldown)
+
+	b.record(context.Background(), &smithy.GenericAPIError{Code: "InternalError"})
+	c.Assert(b.allow(), qt.Equals, ErrCircuitOpen)
+
+	// Only a single probe is let through at a time.
+	now = now.Add(defaultBreakerCooldown)
+	c.Assert(b.allow(), qt.IsNil)
+	c.Assert(b.allow(), qt.Equals, ErrCircuitOpen)
+
+	// A probe abandoned by its caller lets another request probe,
+	// without closing the breaker.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b.record(ctx, context.Canceled)
+	c.Assert(b.allow(), qt.IsNil)
+	c.Assert(b.allow(), qt.Equals, ErrCircuitOpen)
+}
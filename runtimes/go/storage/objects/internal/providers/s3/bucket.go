@@ -5,17 +5,23 @@ import (
 	"errors"
 	"fmt"
 	"iter"
+	"slices"
 	"sync"
+	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	awsConfig "github.com/aws/aws-sdk-go-v2/config"
-	awsCreds "github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/aws/smithy-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 
 	"encore.dev/appruntime/exported/config"
+	"encore.dev/appruntime/shared/reqtrack"
+	"encore.dev/metrics"
 	"encore.dev/storage/objects/internal/types"
 )
 
@@ -26,21 +32,157 @@ type Manager struct {
 
 	cfgOnce          sync.Once
 	awsDefaultConfig aws.Config
+
+	// rt tracks the requests operations are made from. It may be nil.
+	rt *reqtrack.RequestTracker
+
+	// metrics reports the operations of buckets configured to be measured.
+	// It's nil if there's no metrics registry.
+	metrics Metrics
 }
 
-func NewManager(ctx context.Context, runtime *config.Runtime) *Manager {
-	return &Manager{ctx: ctx, runtime: runtime, clients: make(map[*config.BucketProvider]*clientSet)}
+// NewManager returns a manager for S3 buckets. The operations of buckets
+// configured to be measured are reported to reg, if non-nil.
+func NewManager(ctx context.Context, runtime *config.Runtime, rt *reqtrack.RequestTracker, reg *metrics.Registry) *Manager {
+	mgr := &Manager{ctx: ctx, runtime: runtime, rt: rt, clients: make(map[*config.BucketProvider]*clientSet)}
+	if reg != nil {
+		mgr.metrics = newRegistryMetrics(reg)
+	}
+	return mgr
 }
 
+var _ types.BucketImpl = (*bucket)(nil)
+
 type bucket struct {
-	client        *s3.Client
-	presignClient *s3.PresignClient
+	client        s3Client
+	presignClient *s3.PresignClient // nil if client is not an *s3.Client
 	cfg           *config.Bucket
+	uploadOpts    UploadOptions
+	downloadOpts  DownloadOptions
+
+	// requestPayer is set on requests to requester-pays buckets.
+	requestPayer s3types.RequestPayer
+
+	// rejectControlChars rejects writes to keys with control characters.
+	rejectControlChars bool
+
+	// softDelete moves removed objects to the trash; see WithSoftDelete.
+	softDelete bool
+
+	// contextMetadata, if non-nil, returns metadata to add to uploads;
+	// see WithContextMetadata.
+	contextMetadata func(context.Context) map[string]string
+
+	// cache, if non-nil, caches the contents of small objects;
+	// see WithReadCache.
+	cache *readCache
+
+	// retry is the retry policy for requests other than uploads and copies,
+	// which are retried according to uploadOpts.
+	retry RetryPolicy
+
+	tracer  trace.Tracer // nil if tracing is disabled
+	metrics Metrics      // never nil
+
+	// awsEndpoint is whether the client sends requests to AWS
+	// rather than to an S3-compatible service, if known.
+	awsEndpoint bool
+
+	// region is the region of the client, if known.
+	// Buckets are created in it by EnsureBucket.
+	region string
+
+	caps capabilitiesCache // see Capabilities
 }
 
 type clientSet struct {
-	client        *s3.Client
-	presignClient *s3.PresignClient
+	client *s3.Client
+}
+
+// Option configures a bucket created by NewBucketWithClient.
+type Option func(*bucketOptions)
+
+type bucketOptions struct {
+	endpoint       *string
+	pathStyle      bool
+	requestTimeout time.Duration
+	uploadOpts     UploadOptions
+	downloadOpts   DownloadOptions
+	requesterPays  bool
+	rejectControl  bool
+	softDelete     bool
+	retryPolicy    *RetryPolicy
+	breaker        *CircuitBreaker
+	tracer         trace.Tracer
+	metrics        Metrics
+
+	contextMetadata func(context.Context) map[string]string
+	readCache       *CacheOptions
+}
+
+// WithEndpoint overrides the endpoint of the client, for example to use
+// an S3-compatible store like MinIO or Google Cloud Storage's XML API.
+// It only has an effect if the client is an *s3.Client.
+func WithEndpoint(endpoint string) Option {
+	return func(o *bucketOptions) { o.endpoint = &endpoint }
+}
+
+// WithPathStyle addresses buckets in the path of the URL (endpoint/bucket/key)
+// rather than in the host name, as required by MinIO and some other
+// S3-compatible stores. It only has an effect if the client is an *s3.Client.
+func WithPathStyle() Option {
+	return func(o *bucketOptions) { o.pathStyle = true }
+}
+
+// WithRequestTimeout bounds how long each request to S3 may take.
+// Requests that time out are retried like other transient errors.
+//
+// For downloads the timeout applies until the response starts,
+// not while reading the object's contents.
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(o *bucketOptions) { o.requestTimeout = timeout }
+}
+
+// WithTracer configures the bucket to create OpenTelemetry spans
+// for uploads, downloads, listings and removals using tracer.
+// Parts of multipart uploads are traced as child spans of the upload.
+// If tracer is nil, operations aren't traced.
+func WithTracer(tracer trace.Tracer) Option {
+	return func(o *bucketOptions) { o.tracer = tracer }
+}
+
+// WithRetryPolicy sets the policy for retrying requests that fail with
+// a transient error, for all operations on the bucket: uploads, copies,
+// downloads, listings and removals. It takes precedence over the MaxRetries
+// and RetryBackoff upload options. Without a retry policy only uploads,
+// copies and bulk removals are retried, according to the upload options.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(o *bucketOptions) { o.retryPolicy = &policy }
+}
+
+// WithUploadOptions configures how objects are uploaded to the bucket.
+func WithUploadOptions(opts UploadOptions) Option {
+	return func(o *bucketOptions) { o.uploadOpts = opts }
+}
+
+// WithDownloadOptions configures how objects are downloaded from the bucket.
+func WithDownloadOptions(opts DownloadOptions) Option {
+	return func(o *bucketOptions) { o.downloadOpts = opts }
+}
+
+// WithRequesterPays marks the bucket as a requester-pays bucket,
+// acknowledging that the caller is charged for reading from it.
+// S3 rejects reads and deletes from such buckets with 403 Forbidden
+// unless the request says the requester pays.
+func WithRequesterPays() Option {
+	return func(o *bucketOptions) { o.requesterPays = true }
+}
+
+// WithControlCharsRejected makes uploads and copies reject object keys
+// containing control characters, in addition to the checks made by validateKey.
+// S3 accepts such keys, but many tools can't display or address them.
+func WithControlCharsRejected() Option {
+	return func(o *bucketOptions) { o.rejectControl = true }
 }
 
 func (mgr *Manager) ProviderName() string { return "s3" }
@@ -51,28 +193,189 @@ func (mgr *Manager) Matches(cfg *config.BucketProvider) bool {
 
 func (mgr *Manager) NewBucket(provider *config.BucketProvider, runtimeCfg *config.Bucket) types.BucketImpl {
 	clients := mgr.clientForProvider(provider)
-	return &bucket{
-		client:        clients.client,
-		presignClient: clients.presignClient,
-		cfg:           runtimeCfg,
+	return NewBucketWithClient(clients.client, runtimeCfg, mgr.providerOptions(provider.S3)...)
+}
+
+// providerOptions returns the options for buckets of a provider
+// configured in the runtime config.
+func (mgr *Manager) providerOptions(cfg *config.S3BucketProvider) []Option {
+	var opts []Option
+	if cfg.Endpoint != nil {
+		opts = append(opts, WithEndpoint(*cfg.Endpoint))
+	}
+	if cfg.UsePathStyle {
+		opts = append(opts, WithPathStyle())
+	}
+	if cfg.Upload != nil {
+		opts = append(opts, WithUploadOptions(uploadOptionsFromConfig(cfg.Upload)))
+	}
+	if d := cfg.Download; d != nil {
+		opts = append(opts, WithDownloadOptions(DownloadOptions{Concurrency: d.Concurrency, ChunkSize: d.ChunkSize}))
 	}
+	if cfg.RequesterPays {
+		opts = append(opts, WithRequesterPays())
+	}
+	if cfg.RequestTimeout > 0 {
+		opts = append(opts, WithRequestTimeout(cfg.RequestTimeout))
+	}
+	if cfg.Tracing {
+		opts = append(opts, WithTracer(otel.Tracer(tracerName)))
+	}
+	if cfg.Metrics && mgr.metrics != nil {
+		opts = append(opts, WithMetrics(mgr.metrics))
+	}
+	if cfg.RejectControlChars {
+		opts = append(opts, WithControlCharsRejected())
+	}
+	if r := cfg.Retry; r != nil {
+		opts = append(opts, WithRetryPolicy(RetryPolicy{
+			MaxAttempts: r.MaxAttempts,
+			BaseDelay:   r.BaseDelay,
+			MaxDelay:    r.MaxDelay,
+			Jitter:      r.Jitter,
+		}))
+	}
+	if cb := cfg.CircuitBreaker; cb != nil {
+		opts = append(opts, WithCircuitBreaker(CircuitBreaker{FailureThreshold: cb.FailureThreshold, Cooldown: cb.Cooldown}))
+	}
+	if cfg.SoftDelete {
+		opts = append(opts, WithSoftDelete())
+	}
+	if cfg.ContextMetadata && mgr.rt != nil {
+		opts = append(opts, WithContextMetadata(requestMetadata(mgr.rt)))
+	}
+	if rc := cfg.ReadCache; rc != nil {
+		opts = append(opts, WithReadCache(CacheOptions{MaxBytes: rc.MaxBytes, MaxObjectSize: rc.MaxObjectSize}))
+	}
+	return opts
+}
+
+// NewBucketWithClient returns a bucket implementation that uses an
+// already configured client. This makes it possible to use the provider
+// against S3-compatible stores without going through the runtime config.
+//
+// Signed URLs are only supported if client is an *s3.Client.
+func NewBucketWithClient(client s3Client, cfg *config.Bucket, opts ...Option) types.BucketImpl {
+	o := bucketOptions{uploadOpts: defaultUploadOptions, metrics: noopMetrics{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	var retry RetryPolicy
+	if p := o.retryPolicy; p != nil {
+		retry = *p
+		o.uploadOpts.MaxRetries = p.maxRetries()
+		o.uploadOpts.RetryBackoff = p.backoff
+	}
+
+	b := &bucket{
+		client:       client,
+		cfg:          cfg,
+		uploadOpts:   o.uploadOpts,
+		downloadOpts: o.downloadOpts,
+		retry:        retry,
+		tracer:       o.tracer,
+		metrics:      o.metrics,
+
+		rejectControlChars: o.rejectControl,
+		softDelete:         o.softDelete,
+		contextMetadata:    o.contextMetadata,
+	}
+	if o.requesterPays {
+		b.requestPayer = s3types.RequestPayerRequester
+	}
+	if o.readCache != nil && o.readCache.MaxBytes > 0 {
+		b.cache = newReadCache(*o.readCache)
+	}
+	if c, ok := client.(*s3.Client); ok {
+		if o.endpoint != nil || o.pathStyle {
+			c = s3.New(c.Options(), func(opts *s3.Options) {
+				if o.endpoint != nil {
+					opts.BaseEndpoint = o.endpoint
+				}
+				if o.pathStyle {
+					opts.UsePathStyle = true
+				}
+			})
+			b.client = c
+		}
+		b.presignClient = s3.NewPresignClient(c)
+		b.awsEndpoint = isAWSEndpoint(c.Options().BaseEndpoint)
+		b.region = c.Options().Region
+	}
+	if o.requestTimeout > 0 {
+		b.client = &timeoutClient{s3Client: b.client, timeout: o.requestTimeout}
+	}
+	if o.breaker != nil {
+		// Wrap the timeout client so that timeouts count as failures.
+		b.client = &breakerClient{s3Client: b.client, breaker: newBreaker(*o.breaker)}
+	}
+	return b
 }
 
 func (b *bucket) Download(data types.DownloadData) (types.Downloader, error) {
-	object := string(data.Object)
-	resp, err := b.client.GetObject(data.Ctx, &s3.GetObjectInput{
-		Bucket:    &b.cfg.CloudName,
-		Key:       &object,
-		VersionId: ptrOrNil(data.Version),
-	})
+	if !b.instrumented() {
+		return b.download(data)
+	}
+	ctx, op := b.startOp(data.Ctx, "Download", data.Object)
+	data.Ctx = ctx
+	r, err := b.download(data)
 	if err != nil {
-		return nil, mapErr(err)
+		op.end(err, 0)
+		return nil, err
 	}
-	return resp.Body, nil
+	return &tracedDownloader{Downloader: r, op: op}, nil
+}
+
+func (b *bucket) download(data types.DownloadData) (types.Downloader, error) {
+	if b.cache != nil && data.Offset == 0 && data.Length == 0 && data.Conditions == (types.DownloadConditions{}) {
+		return b.cachedDownload(data)
+	}
+	if b.downloadOpts.Concurrency > 1 {
+		return b.parallelDownload(data)
+	}
+	return b.getObjectBody(data)
 }
 
 func (b *bucket) Upload(data types.UploadData) (types.Uploader, error) {
-	return newUploader(b.client, b.cfg.CloudName, data), nil
+	if err := validateKey(string(data.Object), b.rejectControlChars); err != nil {
+		return nil, err
+	}
+	if err := validatePartSize(data.PartSize); err != nil {
+		return nil, err
+	}
+	if err := b.uploadOpts.Encryption.validate(); err != nil {
+		return nil, err
+	}
+	if err := b.uploadOpts.validateStorageClass(); err != nil {
+		return nil, err
+	}
+	if err := b.uploadOpts.validateACL(); err != nil {
+		return nil, err
+	}
+	if err := b.uploadOpts.ObjectLock.validate(); err != nil {
+		return nil, err
+	}
+	if err := validateTags(data.Attrs.Tags); err != nil {
+		return nil, err
+	}
+	data.Attrs.Metadata = b.withContextMetadata(data.Ctx, data.Attrs.Metadata)
+	if b.uploadOpts.CompressGzip {
+		if err := setGzipEncoding(&data.Attrs); err != nil {
+			return nil, err
+		}
+	}
+	ctx, op := b.startOp(data.Ctx, "Upload", data.Object)
+	data.Ctx = ctx
+	client := b.client
+	if b.uploadOpts.DryRun {
+		client = &dryRunClient{}
+	}
+	u := newUploader(client, b.cfg.CloudName, data, b.uploadOptsFor(data.StateStore))
+	u.tracer, u.op = b.tracer, op
+	if b.uploadOpts.CompressGzip {
+		return newGzipUploader(u), nil
+	}
+	return u, nil
 }
 
 func mapListEntry(attrs *storage.ObjectAttrs) *types.ListEntry {
@@ -83,8 +386,24 @@ func mapListEntry(attrs *storage.ObjectAttrs) *types.ListEntry {
 	}
 }
 
+// maxListKeys is the maximum number of keys ListObjectsV2 returns per request.
+const maxListKeys = 1000
+
 func (b *bucket) List(data types.ListData) iter.Seq2[*types.ListEntry, error] {
+	if b.instrumented() {
+		return b.tracedList(data)
+	}
+	return b.list(data)
+}
+
+func (b *bucket) list(data types.ListData) iter.Seq2[*types.ListEntry, error] {
 	return func(yield func(*types.ListEntry, error) bool) {
+		pageSize := int64(maxListKeys)
+		if data.PageSize > 0 {
+			pageSize = min(int64(data.PageSize), maxListKeys)
+		}
+		hideTrash := b.hidesTrash(data.Prefix)
+
 		var n int64
 		var continuationToken string
 		for data.Limit == nil || n < *data.Limit {
@@ -94,27 +413,33 @@ func (b *bucket) List(data types.ListData) iter.Seq2[*types.ListEntry, error] {
 				return
 			}
 
-			maxKeys := int32(1000)
+			maxKeys := int32(pageSize)
 			if data.Limit != nil {
-				maxKeys = min(int32(*data.Limit-n), 1000)
+				maxKeys = int32(min(*data.Limit-n, pageSize))
 			}
-			resp, err := b.client.ListObjectsV2(data.Ctx, &s3.ListObjectsV2Input{
-				Bucket:            &b.cfg.CloudName,
-				MaxKeys:           &maxKeys,
-				ContinuationToken: ptrOrNil(continuationToken),
-				Prefix:            ptrOrNil(data.Prefix),
+			resp, err := withRetry(data.Ctx, b.retry, func() (*s3.ListObjectsV2Output, error) {
+				return b.client.ListObjectsV2(data.Ctx, &s3.ListObjectsV2Input{
+					Bucket:            &b.cfg.CloudName,
+					MaxKeys:           &maxKeys,
+					ContinuationToken: ptrOrNil(continuationToken),
+					Prefix:            ptrOrNil(data.Prefix),
+					Delimiter:         ptrOrNil(data.Delimiter),
+					RequestPayer:      b.requestPayer,
+				})
 			})
 			if err != nil {
 				yield(nil, mapErr(err))
 				return
 			}
 
-			for _, obj := range resp.Contents {
-				if !yield(&types.ListEntry{
-					Object: types.CloudObject(*obj.Key),
-					Size:   *obj.Size,
-					ETag:   *obj.ETag,
-				}, nil) {
+			for _, entry := range listEntries(resp) {
+				if data.Limit != nil && n >= *data.Limit {
+					return
+				}
+				if hideTrash && inTrash(entry) {
+					continue
+				}
+				if !yield(entry, nil) {
 					return
 				}
 				n++
@@ -129,23 +454,124 @@ func (b *bucket) List(data types.ListData) iter.Seq2[*types.ListEntry, error] {
 	}
 }
 
-func (b *bucket) Remove(data types.RemoveData) error {
+// listEntries returns the objects and common prefixes in a page of
+// listing results, merged in lexicographical order like S3 sorts them.
+func listEntries(resp *s3.ListObjectsV2Output) []*types.ListEntry {
+	entries := make([]*types.ListEntry, 0, len(resp.Contents)+len(resp.CommonPrefixes))
+	objs, prefixes := resp.Contents, resp.CommonPrefixes
+	for len(objs) > 0 || len(prefixes) > 0 {
+		if len(prefixes) == 0 || (len(objs) > 0 && valOrZero(objs[0].Key) < valOrZero(prefixes[0].Prefix)) {
+			obj := objs[0]
+			objs = objs[1:]
+			entries = append(entries, &types.ListEntry{
+				Object: types.CloudObject(valOrZero(obj.Key)),
+				Size:   valOrZero(obj.Size),
+				ETag:   valOrZero(obj.ETag),
+			})
+		} else {
+			prefix := prefixes[0]
+			prefixes = prefixes[1:]
+			entries = append(entries, &types.ListEntry{
+				Object:   types.CloudObject(valOrZero(prefix.Prefix)),
+				IsPrefix: true,
+			})
+		}
+	}
+	return entries
+}
+
+func (b *bucket) Remove(data types.RemoveData) (err error) {
+	ctx, op := b.startOp(data.Ctx, "Remove", data.Object)
+	defer func() { op.end(err, -1) }()
+
+	if b.softDelete {
+		if err := b.moveToTrash(ctx, data.Object, data.Version); err != nil {
+			return err
+		}
+	}
+
 	object := string(data.Object)
-	_, err := b.client.DeleteObject(data.Ctx, &s3.DeleteObjectInput{
-		Bucket:    &b.cfg.CloudName,
-		Key:       &object,
-		VersionId: ptrOrNil(data.Version),
+	_, err = withRetry(ctx, b.retry, func() (*s3.DeleteObjectOutput, error) {
+		return b.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket:       &b.cfg.CloudName,
+			Key:          &object,
+			VersionId:    ptrOrNil(data.Version),
+			RequestPayer: b.requestPayer,
+		})
 	})
 	return mapErr(err)
 }
 
+// maxDeleteObjects is the maximum number of objects
+// that can be removed in a single DeleteObjects request.
+const maxDeleteObjects = 1000
+
+func (b *bucket) RemoveAll(data types.RemoveAllData) (results []types.RemoveResult, err error) {
+	ctx, op := b.startOp(data.Ctx, "RemoveAll", "")
+	op.span.SetAttributes(attrCount.Int(len(data.Objects)))
+	defer func() { op.end(err, -1) }()
+	data.Ctx = ctx
+
+	results = make([]types.RemoveResult, 0, len(data.Objects))
+	for batch := range slices.Chunk(data.Objects, maxDeleteObjects) {
+		// Objects that couldn't be moved to the trash are kept.
+		var trashErrs map[types.CloudObject]error
+		if b.softDelete {
+			trashErrs = b.moveAllToTrash(data.Ctx, batch)
+		}
+		ids := make([]s3types.ObjectIdentifier, 0, len(batch))
+		for _, obj := range batch {
+			if trashErrs[obj] == nil {
+				ids = append(ids, s3types.ObjectIdentifier{Key: ptr(string(obj))})
+			}
+		}
+		if len(ids) == 0 {
+			for _, obj := range batch {
+				results = append(results, types.RemoveResult{Object: obj, Err: trashErrs[obj]})
+			}
+			continue
+		}
+
+		// In quiet mode S3 only reports the objects that failed to be removed.
+		resp, err := withRetry(data.Ctx, b.uploadOpts, func() (*s3.DeleteObjectsOutput, error) {
+			return b.client.DeleteObjects(data.Ctx, &s3.DeleteObjectsInput{
+				Bucket:       &b.cfg.CloudName,
+				Delete:       &s3types.Delete{Objects: ids, Quiet: ptr(true)},
+				RequestPayer: b.requestPayer,
+			})
+		})
+		if err != nil {
+			return results, mapErr(err)
+		}
+
+		errs := make(map[string]error, len(resp.Errors))
+		for _, e := range resp.Errors {
+			errs[valOrZero(e.Key)] = mapErr(&smithy.GenericAPIError{
+				Code:    valOrZero(e.Code),
+				Message: valOrZero(e.Message),
+			})
+		}
+		for _, obj := range batch {
+			err := errs[string(obj)]
+			if trashErr := trashErrs[obj]; trashErr != nil {
+				err = trashErr
+			}
+			results = append(results, types.RemoveResult{Object: obj, Err: err})
+		}
+	}
+	return results, nil
+}
+
 func (b *bucket) Attrs(data types.AttrsData) (*types.ObjectAttrs, error) {
 	object := string(data.Object)
-	resp, err := b.client.HeadObject(data.Ctx, &s3.HeadObjectInput{
-		Bucket:    &b.cfg.CloudName,
-		Key:       &object,
-		VersionId: ptrOrNil(data.Version),
-	})
+	in := &s3.HeadObjectInput{
+		Bucket:       &b.cfg.CloudName,
+		Key:          &object,
+		VersionId:    ptrOrNil(data.Version),
+		RequestPayer: b.requestPayer,
+	}
+	b.uploadOpts.Encryption.setHead(in)
+	resp, err := b.headObject(data.Ctx, in)
 	if err != nil {
 		return nil, mapErr(err)
 	}
@@ -155,57 +581,127 @@ func (b *bucket) Attrs(data types.AttrsData) (*types.ObjectAttrs, error) {
 		ContentType: valOrZero(resp.ContentType),
 		Size:        valOrZero(resp.ContentLength),
 		ETag:        valOrZero(resp.ETag),
+
+		LastModified: valOrZero(resp.LastModified),
+		Metadata:     resp.Metadata,
 	}, nil
 }
 
-func (b *bucket) SignedUploadURL(data types.UploadURLData) (string, error) {
+func (b *bucket) Exists(data types.ExistsData) (bool, error) {
 	object := string(data.Object)
-	params := s3.PutObjectInput{
-		Bucket: &b.cfg.CloudName,
-		Key:    &object,
+	in := &s3.HeadObjectInput{
+		Bucket:       &b.cfg.CloudName,
+		Key:          &object,
+		VersionId:    ptrOrNil(data.Version),
+		RequestPayer: b.requestPayer,
 	}
-	sign_opts := func(opts *s3.PresignOptions) {
-		opts.Expires = data.TTL
+	b.uploadOpts.Encryption.setHead(in)
+	_, err := b.headObject(data.Ctx, in)
+	if err = mapErr(err); errors.Is(err, types.ErrObjectNotExist) {
+		return false, nil
 	}
-	req, err := b.presignClient.PresignPutObject(data.Ctx, &params, sign_opts)
-	if err != nil {
-		return "", mapErr(err)
+	return err == nil, err
+}
+
+func (b *bucket) headObject(ctx context.Context, in *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+	return withRetry(ctx, b.retry, func() (*s3.HeadObjectOutput, error) {
+		return b.client.HeadObject(ctx, in)
+	})
+}
+
+func (b *bucket) SetTags(data types.SetTagsData) error {
+	if err := validateTags(data.Tags); err != nil {
+		return err
+	}
+	_, err := withRetry(data.Ctx, b.retry, func() (*s3.PutObjectTaggingOutput, error) {
+		return b.client.PutObjectTagging(data.Ctx, &s3.PutObjectTaggingInput{
+			Bucket:    &b.cfg.CloudName,
+			Key:       ptr(string(data.Object)),
+			VersionId: ptrOrNil(data.Version),
+			Tagging:   &s3types.Tagging{TagSet: tagSet(data.Tags)},
+		})
+	})
+	return mapErr(err)
+}
+
+// maxPresignTTL is the longest duration a SigV4 presigned URL can be valid for.
+const maxPresignTTL = 7 * 24 * time.Hour
+
+func validatePresignTTL(ttl time.Duration) error {
+	if ttl <= 0 || ttl > maxPresignTTL {
+		return fmt.Errorf("%w: signed URL TTL must be between 0 and %v, got %v",
+			types.ErrInvalidArgument, maxPresignTTL, ttl)
 	}
-	// TODO: add check/warn against unexpected method and headers
-	// (we expect PUT and host:<> but nothing else.)
-	return req.URL, nil
+	return nil
 }
 
-func (b *bucket) SignedDownloadURL(data types.DownloadURLData) (string, error) {
+func (b *bucket) SignedUploadURL(data types.UploadURLData) (*types.SignedURL, error) {
+	if b.presignClient == nil {
+		return nil, errNoPresignClient
+	} else if err := validatePresignTTL(data.TTL); err != nil {
+		return nil, err
+	}
 	object := string(data.Object)
-	params := s3.GetObjectInput{
+	params := &s3.PutObjectInput{
 		Bucket: &b.cfg.CloudName,
 		Key:    &object,
 	}
-	sign_opts := func(opts *s3.PresignOptions) {
-		opts.Expires = data.TTL
+	// Encryption headers are signed, so clients must send them with the upload.
+	b.uploadOpts.Encryption.setPut(params)
+	req, err := b.presignClient.PresignPutObject(data.Ctx, params, s3.WithPresignExpires(data.TTL))
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	return mapSignedURL(req), nil
+}
+
+func (b *bucket) SignedDownloadURL(data types.DownloadURLData) (*types.SignedURL, error) {
+	if b.presignClient == nil {
+		return nil, errNoPresignClient
+	} else if err := validatePresignTTL(data.TTL); err != nil {
+		return nil, err
+	}
+	object := string(data.Object)
+	params := &s3.GetObjectInput{
+		Bucket:       &b.cfg.CloudName,
+		Key:          &object,
+		RequestPayer: b.requestPayer,
 	}
-	req, err := b.presignClient.PresignGetObject(data.Ctx, &params, sign_opts)
+	b.uploadOpts.Encryption.setGet(params)
+	req, err := b.presignClient.PresignGetObject(data.Ctx, params, s3.WithPresignExpires(data.TTL))
 	if err != nil {
-		return "", mapErr(err)
+		return nil, mapErr(err)
+	}
+	return mapSignedURL(req), nil
+}
+
+// mapSignedURL returns the signed URL for a presigned request.
+// The Host header is omitted since HTTP clients set it from the URL.
+func mapSignedURL(req *v4.PresignedHTTPRequest) *types.SignedURL {
+	headers := req.SignedHeader.Clone()
+	headers.Del("Host")
+	if len(headers) == 0 {
+		headers = nil
+	}
+	return &types.SignedURL{
+		URL:     req.URL,
+		Method:  req.Method,
+		Headers: headers,
 	}
-	// TODO: add check/warn against unexpected method and headers
-	// (we expect PUT and host:<> but nothing else.)
-	return req.URL, nil
 }
 
+var errNoPresignClient = errors.New("s3: signed URLs require an *s3.Client")
+
 func (mgr *Manager) clientForProvider(prov *config.BucketProvider) *clientSet {
 	if cs, ok := mgr.clients[prov]; ok {
 		return cs
 	}
 
-	// If we have a custom access key and secret, use them instead of the default config.
+	// If the provider configures where credentials come from, use it instead of the default config.
 	var cfg aws.Config
-	if prov.S3.AccessKeyID != nil && prov.S3.SecretAccessKey != nil {
+	if opts := credentialOptions(prov.S3); len(opts) > 0 {
 		var err error
-		cfg, err = awsConfig.LoadDefaultConfig(context.Background(),
-			awsConfig.WithCredentialsProvider(awsCreds.NewStaticCredentialsProvider(*prov.S3.AccessKeyID, *prov.S3.SecretAccessKey, "")),
-		)
+		cfg, err = LoadConfig(context.Background(), append(opts, WithRegion(prov.S3.Region))...)
 		if err != nil {
 			panic(fmt.Sprintf("unable to load AWS config: %v", err))
 		}
@@ -213,15 +709,17 @@ func (mgr *Manager) clientForProvider(prov *config.BucketProvider) *clientSet {
 		cfg = mgr.defaultConfig()
 	}
 
-	client := s3.New(s3.Options{
-		Region:       prov.S3.Region,
-		BaseEndpoint: prov.S3.Endpoint,
-		Credentials:  cfg.Credentials,
+	// The endpoint and addressing style are set per bucket; see providerOptions.
+	client, err := NewClient(aws.Config{Region: prov.S3.Region, Credentials: cfg.Credentials}, ClientOptions{
+		UseAccelerate: prov.S3.UseAccelerate,
+		UseDualStack:  prov.S3.UseDualStack,
 	})
+	if err != nil {
+		panic(fmt.Sprintf("unable to create S3 client: %v", err))
+	}
 
 	clients := &clientSet{
-		client:        client,
-		presignClient: s3.NewPresignClient(client),
+		client: client,
 	}
 
 	mgr.clients[prov] = clients
@@ -243,16 +741,33 @@ func (mgr *Manager) defaultConfig() aws.Config {
 func mapErr(err error) error {
 	var (
 		noSuchKey *s3types.NoSuchKey
+		notFound  *s3types.NotFound
 		generic   smithy.APIError
 	)
 	switch {
 	case err == nil:
 		return nil
-	case errors.As(err, &noSuchKey):
+	case errors.As(err, &noSuchKey), errors.As(err, &notFound):
+		// HeadObject reports missing objects as NotFound since it has no body.
 		return types.ErrObjectNotExist
+	case isUnsupported(err):
+		return fmt.Errorf("%w: %v", types.ErrUnsupportedByProvider, err)
 	case errors.As(err, &generic):
-		if generic.ErrorCode() == "PreconditionFailed" {
+		switch generic.ErrorCode() {
+		case "PreconditionFailed":
 			return types.ErrPreconditionFailed
+		case "NotModified":
+			return types.ErrNotModified
+		case "NoSuchKey":
+			// Operations that don't model NoSuchKey, like PutObjectTagging,
+			// report it as a generic API error.
+			return types.ErrObjectNotExist
+		case "AccessControlListNotSupported":
+			return fmt.Errorf("%w: bucket has ACLs disabled by its BucketOwnerEnforced Object Ownership setting; "+
+				"remove the ACL upload option or use \"bucket-owner-full-control\": %v", types.ErrInvalidArgument, err)
+		}
+		if isObjectLockNotEnabled(err) {
+			return fmt.Errorf("%w: bucket does not have S3 Object Lock enabled: %v", types.ErrInvalidArgument, err)
 		}
 		return err
 	default:
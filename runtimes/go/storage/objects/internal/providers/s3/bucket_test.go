@@ -0,0 +1,730 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	awsCreds "github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	qt "github.com/frankban/quicktest"
+	"github.com/golang/mock/gomock"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/storage/objects/internal/types"
+)
+
+func TestNewBucketWithClient(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"})
+
+	client.EXPECT().GetObject(gomock.Any(), &s3.GetObjectInput{
+		Bucket: ptr("bucket"),
+		Key:    ptr("object"),
+	}).Return(&s3.GetObjectOutput{
+		Body: io.NopCloser(strings.NewReader("test")),
+	}, nil)
+
+	r, err := bkt.Download(types.DownloadData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.IsNil)
+	data, err := io.ReadAll(r)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(data), qt.Equals, "test")
+
+	// Signed URLs require a real client.
+	_, err = bkt.SignedDownloadURL(types.DownloadURLData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.Equals, errNoPresignClient)
+}
+
+func TestNewBucketWithClient_Endpoint(t *testing.T) {
+	c := qt.New(t)
+
+	client := s3.New(s3.Options{Region: "us-east-1"})
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"},
+		WithEndpoint("http://localhost:9000"))
+
+	b := bkt.(*bucket)
+	c.Assert(b.presignClient, qt.IsNotNil)
+	c.Assert(valOrZero(b.client.(*s3.Client).Options().BaseEndpoint), qt.Equals, "http://localhost:9000")
+}
+
+func TestNewBucketWithClient_PathStyle(t *testing.T) {
+	c := qt.New(t)
+
+	client := s3.New(s3.Options{
+		Region:      "us-east-1",
+		Credentials: awsCreds.NewStaticCredentialsProvider("AKID", "secret", ""),
+	})
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"},
+		WithEndpoint("http://localhost:9000"), WithPathStyle())
+
+	u, err := bkt.SignedDownloadURL(types.DownloadURLData{Ctx: context.Background(), Object: "object", TTL: time.Hour})
+	c.Assert(err, qt.IsNil)
+	c.Assert(u.URL, qt.Matches, `http://localhost:9000/bucket/object\?.*`)
+}
+
+func TestManager_NewBucket(t *testing.T) {
+	c := qt.New(t)
+
+	b := newConfigBucket(c, &config.S3BucketProvider{
+		Endpoint:     ptr("http://localhost:9000"),
+		UsePathStyle: true,
+	})
+	u, err := b.SignedDownloadURL(types.DownloadURLData{Ctx: context.Background(), Object: "object", TTL: time.Hour})
+	c.Assert(err, qt.IsNil)
+	c.Assert(u.URL, qt.Matches, `http://localhost:9000/bucket/object\?.*`)
+}
+
+func TestManager_NewBucket_Endpoints(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		cfg  *config.S3BucketProvider
+		want string
+	}{
+		{cfg: &config.S3BucketProvider{UseAccelerate: true}, want: `https://bucket\.s3-accelerate\.amazonaws\.com/object\?.*`},
+		{cfg: &config.S3BucketProvider{UseDualStack: true}, want: `https://bucket\.s3\.dualstack\.us-east-1\.amazonaws\.com/object\?.*`},
+	}
+	for _, tt := range tests {
+		b := newConfigBucket(c, tt.cfg)
+		u, err := b.SignedDownloadURL(types.DownloadURLData{Ctx: context.Background(), Object: "object", TTL: time.Hour})
+		c.Assert(err, qt.IsNil)
+		c.Assert(u.URL, qt.Matches, tt.want)
+	}
+}
+
+func TestManager_NewBucket_UploadOptions(t *testing.T) {
+	c := qt.New(t)
+
+	b := newConfigBucket(c, &config.S3BucketProvider{})
+	c.Assert(b.uploadOpts.MaxRetries, qt.Equals, defaultUploadOptions.MaxRetries)
+
+	b = newConfigBucket(c, &config.S3BucketProvider{Upload: &config.S3UploadOptions{
+		MaxRetries:   ptr(5),
+		Concurrency:  8,
+		Checksum:     "sha256",
+		Encryption:   &config.S3Encryption{Mode: "kms", KMSKeyID: "key"},
+		StorageClass: "STANDARD_IA",
+		DryRun:       true,
+		ObjectLock:   &config.S3ObjectLock{Mode: "governance", RetainFor: time.Hour},
+		CompressGzip: true,
+		ACL:          "bucket-owner-full-control",
+		Verify:       true,
+	}})
+	c.Assert(b.uploadOpts.MaxRetries, qt.Equals, 5)
+	c.Assert(b.uploadOpts.Concurrency, qt.Equals, 8)
+	c.Assert(b.uploadOpts.Checksum, qt.Equals, ChecksumSHA256)
+	c.Assert(b.uploadOpts.Encryption, qt.DeepEquals, Encryption{Mode: EncryptionKMS, KMSKeyID: "key"})
+	c.Assert(b.uploadOpts.StorageClass, qt.Equals, "STANDARD_IA")
+	c.Assert(b.uploadOpts.DryRun, qt.IsTrue)
+	c.Assert(b.uploadOpts.ObjectLock, qt.Equals, ObjectLock{Mode: ObjectLockGovernance, RetainFor: time.Hour})
+	c.Assert(b.uploadOpts.CompressGzip, qt.IsTrue)
+	c.Assert(b.uploadOpts.ACL, qt.Equals, "bucket-owner-full-control")
+	c.Assert(b.uploadOpts.VerifyUpload, qt.IsTrue)
+}
+
+func TestManager_NewBucket_Options(t *testing.T) {
+	c := qt.New(t)
+
+	b := newConfigBucket(c, &config.S3BucketProvider{
+		Download:           &config.S3DownloadOptions{Concurrency: 3, ChunkSize: 1024},
+		RequesterPays:      true,
+		RequestTimeout:     time.Minute,
+		Tracing:            true,
+		RejectControlChars: true,
+		Retry:              &config.S3RetryPolicy{MaxAttempts: 5, BaseDelay: time.Second, Jitter: 0.5},
+		CircuitBreaker:     &config.S3CircuitBreaker{FailureThreshold: 3},
+		SoftDelete:         true,
+		ReadCache:          &config.S3ReadCache{MaxBytes: 1 << 20},
+	})
+	c.Assert(b.downloadOpts, qt.Equals, DownloadOptions{Concurrency: 3, ChunkSize: 1024})
+	c.Assert(b.requestPayer, qt.Equals, s3types.RequestPayerRequester)
+	c.Assert(b.client.(*breakerClient).s3Client.(*timeoutClient).timeout, qt.Equals, time.Minute)
+	c.Assert(b.tracer, qt.IsNotNil)
+	c.Assert(b.rejectControlChars, qt.IsTrue)
+	c.Assert(b.retry, qt.Equals, RetryPolicy{MaxAttempts: 5, BaseDelay: time.Second, Jitter: 0.5})
+	c.Assert(b.client.(*breakerClient).breaker.threshold, qt.Equals, 3)
+	c.Assert(b.softDelete, qt.IsTrue)
+	c.Assert(b.cache, qt.IsNotNil)
+	c.Assert(b.cache.opts, qt.Equals, CacheOptions{MaxBytes: 1 << 20})
+}
+
+// newConfigBucket returns the bucket a Manager creates for a provider
+// with the given config, using static credentials.
+func newConfigBucket(c *qt.C, cfg *config.S3BucketProvider) *bucket {
+	c.Helper()
+	return newManagerBucket(c, NewManager(context.Background(), &config.Runtime{}, nil, nil), cfg)
+}
+
+// newManagerBucket is like newConfigBucket but uses the given Manager.
+func newManagerBucket(c *qt.C, mgr *Manager, cfg *config.S3BucketProvider) *bucket {
+	c.Helper()
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	cfg.AccessKeyID, cfg.SecretAccessKey = ptr("AKID"), ptr("secret")
+	return mgr.NewBucket(&config.BucketProvider{S3: cfg}, &config.Bucket{CloudName: "bucket"}).(*bucket)
+}
+
+func TestDownload_ByteRange(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"})
+
+	tests := []struct {
+		offset, length int64
+		want           *string
+	}{
+		{offset: 0, length: 0, want: nil},
+		{offset: 10, length: 0, want: ptr("bytes=10-")},
+		{offset: 10, length: 5, want: ptr("bytes=10-14")},
+		{offset: 0, length: 1, want: ptr("bytes=0-0")},
+	}
+	for _, test := range tests {
+		client.EXPECT().GetObject(gomock.Any(), &s3.GetObjectInput{
+			Bucket: ptr("bucket"),
+			Key:    ptr("object"),
+			Range:  test.want,
+		}).Return(&s3.GetObjectOutput{
+			Body: io.NopCloser(strings.NewReader("test")),
+		}, nil)
+
+		_, err := bkt.Download(types.DownloadData{
+			Ctx:    context.Background(),
+			Object: "object",
+			Offset: test.offset,
+			Length: test.length,
+		})
+		c.Assert(err, qt.IsNil)
+	}
+}
+
+func TestDownload_NotModified(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"})
+
+	since := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	client.EXPECT().GetObject(gomock.Any(), &s3.GetObjectInput{
+		Bucket:          ptr("bucket"),
+		Key:             ptr("object"),
+		IfNoneMatch:     ptr(`"etag"`),
+		IfModifiedSince: &since,
+	}).Return(nil, &smithy.GenericAPIError{Code: "NotModified"})
+
+	_, err := bkt.Download(types.DownloadData{
+		Ctx:    context.Background(),
+		Object: "object",
+		Conditions: types.DownloadConditions{
+			IfNoneMatch:     `"etag"`,
+			IfModifiedSince: since,
+		},
+	})
+	c.Assert(err, qt.Equals, types.ErrNotModified)
+}
+
+func TestDownload_Parallel(t *testing.T) {
+	c := qt.New(t)
+
+	const content = "hello, parallel world"
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"},
+		WithDownloadOptions(DownloadOptions{Concurrency: 3, ChunkSize: 5}))
+
+	// Complete the third chunk before the second one
+	// to check the chunks are reassembled in order.
+	thirdDone := make(chan struct{})
+	client.EXPECT().GetObject(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+			var start, end int64
+			_, err := fmt.Sscanf(*in.Range, "bytes=%d-%d", &start, &end)
+			c.Check(err, qt.IsNil)
+			end = min(end, int64(len(content)-1))
+
+			if start == 0 {
+				c.Check(in.IfMatch, qt.IsNil)
+			} else {
+				c.Check(valOrZero(in.IfMatch), qt.Equals, `"etag"`)
+				switch start {
+				case 5:
+					<-thirdDone
+				case 10:
+					defer close(thirdDone)
+				}
+			}
+
+			return &s3.GetObjectOutput{
+				Body:         io.NopCloser(strings.NewReader(content[start : end+1])),
+				ContentRange: ptr(fmt.Sprintf("bytes %d-%d/%d", start, end, len(content))),
+				ETag:         ptr(`"etag"`),
+			}, nil
+		}).Times(5)
+
+	r, err := bkt.Download(types.DownloadData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.IsNil)
+	data, err := io.ReadAll(r)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(data), qt.Equals, content)
+	c.Assert(r.Close(), qt.IsNil)
+}
+
+func TestDownload_ParallelError(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"},
+		WithDownloadOptions(DownloadOptions{Concurrency: 2, ChunkSize: 5}))
+
+	client.EXPECT().GetObject(gomock.Any(), gomock.Any()).Return(&s3.GetObjectOutput{
+		Body:         io.NopCloser(strings.NewReader("hello")),
+		ContentRange: ptr("bytes 0-4/10"),
+		ETag:         ptr(`"etag"`),
+	}, nil)
+	client.EXPECT().GetObject(gomock.Any(), gomock.Any()).Return(nil, &smithy.GenericAPIError{
+		Code: "PreconditionFailed",
+	})
+
+	r, err := bkt.Download(types.DownloadData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.IsNil)
+	data, err := io.ReadAll(r)
+	c.Assert(err, qt.Equals, types.ErrPreconditionFailed)
+	c.Assert(string(data), qt.Equals, "hello")
+}
+
+func TestDownloadWithResult(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"},
+		WithDownloadOptions(DownloadOptions{Concurrency: 2, ChunkSize: 5})).(*bucket)
+
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	client.EXPECT().GetObject(gomock.Any(), &s3.GetObjectInput{
+		Bucket: ptr("bucket"),
+		Key:    ptr("object"),
+	}).Return(&s3.GetObjectOutput{
+		Body:          io.NopCloser(strings.NewReader("hello world")),
+		ContentLength: ptr(int64(11)),
+		ContentType:   ptr("text/plain"),
+		ETag:          ptr(`"etag"`),
+		LastModified:  &modified,
+		VersionId:     ptr("v1"),
+		Metadata:      map[string]string{"key": "value"},
+	}, nil)
+
+	res, err := bkt.DownloadWithResult(types.DownloadData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.IsNil)
+	data, err := io.ReadAll(res.Body)
+	c.Assert(err, qt.IsNil)
+	c.Assert(res.Body.Close(), qt.IsNil)
+	c.Assert(string(data), qt.Equals, "hello world")
+	c.Assert(res.Attrs, qt.DeepEquals, &types.ObjectAttrs{
+		Object:       "object",
+		Version:      "v1",
+		ContentType:  "text/plain",
+		Size:         11,
+		ETag:         `"etag"`,
+		LastModified: modified,
+		Metadata:     map[string]string{"key": "value"},
+	})
+
+	client.EXPECT().GetObject(gomock.Any(), gomock.Any()).Return(nil, &s3types.NoSuchKey{})
+	_, err = bkt.DownloadWithResult(types.DownloadData{Ctx: context.Background(), Object: "missing"})
+	c.Assert(err, qt.Equals, types.ErrObjectNotExist)
+}
+
+func TestExists(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"})
+
+	tests := []struct {
+		name    string
+		err     error
+		want    bool
+		wantErr error
+	}{
+		{name: "exists", want: true},
+		{name: "not_found", err: &s3types.NotFound{}, want: false},
+		{name: "no_such_key", err: &s3types.NoSuchKey{}, want: false},
+		{name: "access_denied", err: &smithy.GenericAPIError{Code: "AccessDenied"}, wantErr: &smithy.GenericAPIError{Code: "AccessDenied"}},
+	}
+	for _, test := range tests {
+		c.Run(test.name, func(c *qt.C) {
+			var out *s3.HeadObjectOutput
+			if test.err == nil {
+				out = &s3.HeadObjectOutput{}
+			}
+			client.EXPECT().HeadObject(gomock.Any(), &s3.HeadObjectInput{
+				Bucket: ptr("bucket"),
+				Key:    ptr("object"),
+			}).Return(out, test.err)
+
+			got, err := bkt.Exists(types.ExistsData{Ctx: context.Background(), Object: "object"})
+			if test.wantErr != nil {
+				c.Assert(err, qt.DeepEquals, test.wantErr)
+			} else {
+				c.Assert(err, qt.IsNil)
+			}
+			c.Assert(got, qt.Equals, test.want)
+		})
+	}
+}
+
+func TestAttrs(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"})
+
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	client.EXPECT().HeadObject(gomock.Any(), &s3.HeadObjectInput{
+		Bucket: ptr("bucket"),
+		Key:    ptr("object"),
+	}).Return(&s3.HeadObjectOutput{
+		VersionId:     ptr("v1"),
+		ContentType:   ptr("text/plain"),
+		ContentLength: ptr(int64(5)),
+		ETag:          ptr(`"etag"`),
+		LastModified:  &modified,
+		Metadata:      map[string]string{"k": "v"},
+	}, nil)
+	attrs, err := bkt.Attrs(types.AttrsData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(attrs, qt.DeepEquals, &types.ObjectAttrs{
+		Object:       "object",
+		Version:      "v1",
+		ContentType:  "text/plain",
+		Size:         5,
+		ETag:         `"etag"`,
+		LastModified: modified,
+		Metadata:     map[string]string{"k": "v"},
+	})
+
+	client.EXPECT().HeadObject(gomock.Any(), gomock.Any()).Return(nil, &s3types.NotFound{})
+	_, err = bkt.Attrs(types.AttrsData{Ctx: context.Background(), Object: "missing"})
+	c.Assert(err, qt.Equals, types.ErrObjectNotExist)
+}
+
+func TestSetTags(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"})
+
+	client.EXPECT().PutObjectTagging(gomock.Any(), &s3.PutObjectTaggingInput{
+		Bucket:    ptr("bucket"),
+		Key:       ptr("object"),
+		VersionId: ptr("v1"),
+		Tagging: &s3types.Tagging{TagSet: []s3types.Tag{
+			{Key: ptr("a"), Value: ptr("1")},
+			{Key: ptr("b"), Value: ptr("2")},
+		}},
+	}).Return(&s3.PutObjectTaggingOutput{}, nil)
+	err := bkt.SetTags(types.SetTagsData{
+		Ctx:     context.Background(),
+		Object:  "object",
+		Version: "v1",
+		Tags:    map[string]string{"b": "2", "a": "1"},
+	})
+	c.Assert(err, qt.IsNil)
+
+	client.EXPECT().PutObjectTagging(gomock.Any(), gomock.Any()).Return(nil, &smithy.GenericAPIError{Code: "NoSuchKey"})
+	err = bkt.SetTags(types.SetTagsData{Ctx: context.Background(), Object: "missing"})
+	c.Assert(err, qt.Equals, types.ErrObjectNotExist)
+}
+
+func TestRemoveAll(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"})
+
+	objects := make([]types.CloudObject, 1500)
+	for i := range objects {
+		objects[i] = types.CloudObject(fmt.Sprintf("obj-%d", i))
+	}
+
+	// The objects are removed in two batches, with some failures in each.
+	var batches []int
+	client.EXPECT().DeleteObjects(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.DeleteObjectsInput, _ ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+			c.Check(valOrZero(in.Bucket), qt.Equals, "bucket")
+			c.Check(valOrZero(in.Delete.Quiet), qt.IsTrue)
+			batches = append(batches, len(in.Delete.Objects))
+
+			var out s3.DeleteObjectsOutput
+			for _, obj := range in.Delete.Objects {
+				if key := valOrZero(obj.Key); key == "obj-10" || key == "obj-1200" {
+					out.Errors = append(out.Errors, s3types.Error{
+						Key:     obj.Key,
+						Code:    ptr("AccessDenied"),
+						Message: ptr("Access Denied"),
+					})
+				}
+			}
+			return &out, nil
+		}).Times(2)
+
+	results, err := bkt.RemoveAll(types.RemoveAllData{Ctx: context.Background(), Objects: objects})
+	c.Assert(err, qt.IsNil)
+	c.Assert(batches, qt.DeepEquals, []int{1000, 500})
+	c.Assert(results, qt.HasLen, len(objects))
+
+	for i, res := range results {
+		c.Assert(res.Object, qt.Equals, objects[i])
+		if i == 10 || i == 1200 {
+			var apiErr smithy.APIError
+			c.Assert(errors.As(res.Err, &apiErr), qt.IsTrue)
+			c.Assert(apiErr.ErrorCode(), qt.Equals, "AccessDenied")
+		} else {
+			c.Assert(res.Err, qt.IsNil)
+		}
+	}
+}
+
+func TestRemoveAll_RequestError(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"})
+
+	objects := make([]types.CloudObject, 1001)
+	for i := range objects {
+		objects[i] = types.CloudObject(fmt.Sprintf("obj-%d", i))
+	}
+
+	gomock.InOrder(
+		client.EXPECT().DeleteObjects(gomock.Any(), gomock.Any()).Return(&s3.DeleteObjectsOutput{}, nil),
+		client.EXPECT().DeleteObjects(gomock.Any(), gomock.Any()).Return(nil, &smithy.GenericAPIError{Code: "AccessDenied"}),
+	)
+
+	// The results cover the batch that was removed before the failure.
+	results, err := bkt.RemoveAll(types.RemoveAllData{Ctx: context.Background(), Objects: objects})
+	c.Assert(err, qt.ErrorMatches, ".*AccessDenied.*")
+	c.Assert(results, qt.HasLen, 1000)
+}
+
+func TestList(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"})
+
+	gomock.InOrder(
+		client.EXPECT().ListObjectsV2(gomock.Any(), &s3.ListObjectsV2Input{
+			Bucket:    ptr("bucket"),
+			MaxKeys:   ptr(int32(3)),
+			Prefix:    ptr("dir/"),
+			Delimiter: ptr("/"),
+		}).Return(&s3.ListObjectsV2Output{
+			Contents: []s3types.Object{
+				{Key: ptr("dir/a"), Size: ptr(int64(1)), ETag: ptr("a")},
+				{Key: ptr("dir/c"), Size: ptr(int64(2)), ETag: ptr("c")},
+			},
+			CommonPrefixes:        []s3types.CommonPrefix{{Prefix: ptr("dir/b/")}},
+			IsTruncated:           ptr(true),
+			NextContinuationToken: ptr("token"),
+		}, nil),
+		client.EXPECT().ListObjectsV2(gomock.Any(), &s3.ListObjectsV2Input{
+			Bucket:            ptr("bucket"),
+			MaxKeys:           ptr(int32(3)),
+			Prefix:            ptr("dir/"),
+			Delimiter:         ptr("/"),
+			ContinuationToken: ptr("token"),
+		}).Return(&s3.ListObjectsV2Output{
+			CommonPrefixes: []s3types.CommonPrefix{{Prefix: ptr("dir/d/")}},
+		}, nil),
+	)
+
+	var got []types.ListEntry
+	for entry, err := range bkt.List(types.ListData{
+		Ctx:       context.Background(),
+		Prefix:    "dir/",
+		Delimiter: "/",
+		PageSize:  3,
+	}) {
+		c.Assert(err, qt.IsNil)
+		got = append(got, *entry)
+	}
+	c.Assert(got, qt.DeepEquals, []types.ListEntry{
+		{Object: "dir/a", Size: 1, ETag: "a"},
+		{Object: "dir/b/", IsPrefix: true},
+		{Object: "dir/c", Size: 2, ETag: "c"},
+		{Object: "dir/d/", IsPrefix: true},
+	})
+}
+
+func TestList_Lazy(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"})
+
+	// Only the first page is fetched, since the caller stops before the end of it.
+	client.EXPECT().ListObjectsV2(gomock.Any(), gomock.Any()).Return(&s3.ListObjectsV2Output{
+		Contents: []s3types.Object{
+			{Key: ptr("a"), Size: ptr(int64(1)), ETag: ptr("a")},
+			{Key: ptr("b"), Size: ptr(int64(1)), ETag: ptr("b")},
+		},
+		IsTruncated:           ptr(true),
+		NextContinuationToken: ptr("token"),
+	}, nil)
+
+	for entry, err := range bkt.List(types.ListData{Ctx: context.Background()}) {
+		c.Assert(err, qt.IsNil)
+		c.Assert(entry.Object, qt.Equals, types.CloudObject("a"))
+		break
+	}
+}
+
+func TestListPage(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"}).(*bucket)
+	ctx := context.Background()
+	data := types.ListPageData{Ctx: ctx, Prefix: "dir/", PageSize: 2}
+
+	gomock.InOrder(
+		client.EXPECT().ListObjectsV2(gomock.Any(), &s3.ListObjectsV2Input{
+			Bucket:  ptr("bucket"),
+			MaxKeys: ptr(int32(2)),
+			Prefix:  ptr("dir/"),
+		}).Return(&s3.ListObjectsV2Output{
+			Contents: []s3types.Object{
+				{Key: ptr("dir/a"), Size: ptr(int64(1)), ETag: ptr("a")},
+				{Key: ptr("dir/b"), Size: ptr(int64(2)), ETag: ptr("b")},
+			},
+			IsTruncated:           ptr(true),
+			NextContinuationToken: ptr("token"),
+		}, nil),
+		client.EXPECT().ListObjectsV2(gomock.Any(), &s3.ListObjectsV2Input{
+			Bucket:            ptr("bucket"),
+			MaxKeys:           ptr(int32(2)),
+			Prefix:            ptr("dir/"),
+			ContinuationToken: ptr("token"),
+		}).Return(&s3.ListObjectsV2Output{
+			Contents: []s3types.Object{{Key: ptr("dir/c"), Size: ptr(int64(3)), ETag: ptr("c")}},
+		}, nil),
+	)
+
+	entries, cursor, err := bkt.ListPage(data)
+	c.Assert(err, qt.IsNil)
+	c.Assert(entries, qt.DeepEquals, []*types.ListEntry{
+		{Object: "dir/a", Size: 1, ETag: "a"},
+		{Object: "dir/b", Size: 2, ETag: "b"},
+	})
+	c.Assert(cursor, qt.Not(qt.Equals), "")
+	c.Assert(strings.Contains(cursor, "token"), qt.IsFalse)
+
+	// The cursor can't continue a different listing.
+	_, _, err = bkt.ListPage(types.ListPageData{Ctx: ctx, Prefix: "other/", Cursor: cursor})
+	c.Assert(err, qt.ErrorIs, types.ErrInvalidArgument)
+	_, _, err = bkt.ListPage(types.ListPageData{Ctx: ctx, Prefix: "dir/", Cursor: "not a cursor"})
+	c.Assert(err, qt.ErrorIs, types.ErrInvalidArgument)
+
+	data.Cursor = cursor
+	entries, cursor, err = bkt.ListPage(data)
+	c.Assert(err, qt.IsNil)
+	c.Assert(entries, qt.DeepEquals, []*types.ListEntry{{Object: "dir/c", Size: 3, ETag: "c"}})
+	c.Assert(cursor, qt.Equals, "")
+}
+
+func TestSignedURLs(t *testing.T) {
+	c := qt.New(t)
+
+	client := s3.New(s3.Options{
+		Region:      "us-east-1",
+		Credentials: awsCreds.NewStaticCredentialsProvider("key", "secret", ""),
+	})
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"},
+		WithUploadOptions(UploadOptions{Encryption: Encryption{Mode: EncryptionKMS, KMSKeyID: "key-id"}}))
+
+	up, err := bkt.SignedUploadURL(types.UploadURLData{Ctx: context.Background(), Object: "object", TTL: time.Hour})
+	c.Assert(err, qt.IsNil)
+	c.Assert(up.Method, qt.Equals, "PUT")
+	c.Assert(up.URL, qt.Contains, "X-Amz-Expires=3600")
+	c.Assert(up.Headers.Get("X-Amz-Server-Side-Encryption"), qt.Equals, "aws:kms")
+	c.Assert(up.Headers.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"), qt.Equals, "key-id")
+	c.Assert(up.Headers.Get("Host"), qt.Equals, "")
+
+	down, err := bkt.SignedDownloadURL(types.DownloadURLData{Ctx: context.Background(), Object: "object", TTL: time.Minute})
+	c.Assert(err, qt.IsNil)
+	c.Assert(down.Method, qt.Equals, "GET")
+	c.Assert(down.URL, qt.Contains, "X-Amz-Expires=60")
+	c.Assert(down.Headers, qt.IsNil)
+
+	for _, ttl := range []time.Duration{0, 7*24*time.Hour + time.Second} {
+		_, err := bkt.SignedDownloadURL(types.DownloadURLData{Ctx: context.Background(), Object: "object", TTL: ttl})
+		c.Assert(err, qt.ErrorIs, types.ErrInvalidArgument)
+	}
+}
+
+func TestRequesterPays(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"}, WithRequesterPays())
+
+	client.EXPECT().GetObject(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+			c.Check(in.RequestPayer, qt.Equals, s3types.RequestPayerRequester)
+			return &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader("data"))}, nil
+		})
+	r, err := bkt.Download(types.DownloadData{Ctx: ctx, Object: "object"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(r.Close(), qt.IsNil)
+
+	client.EXPECT().HeadObject(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.HeadObjectInput, _ ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+			c.Check(in.RequestPayer, qt.Equals, s3types.RequestPayerRequester)
+			return &s3.HeadObjectOutput{}, nil
+		})
+	_, err = bkt.Attrs(types.AttrsData{Ctx: ctx, Object: "object"})
+	c.Assert(err, qt.IsNil)
+
+	client.EXPECT().ListObjectsV2(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.ListObjectsV2Input, _ ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			c.Check(in.RequestPayer, qt.Equals, s3types.RequestPayerRequester)
+			return &s3.ListObjectsV2Output{}, nil
+		})
+	for _, err := range bkt.List(types.ListData{Ctx: ctx}) {
+		c.Assert(err, qt.IsNil)
+	}
+
+	client.EXPECT().DeleteObjects(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.DeleteObjectsInput, _ ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+			c.Check(in.RequestPayer, qt.Equals, s3types.RequestPayerRequester)
+			return &s3.DeleteObjectsOutput{}, nil
+		})
+	_, err = bkt.RemoveAll(types.RemoveAllData{Ctx: ctx, Objects: []types.CloudObject{"object"}})
+	c.Assert(err, qt.IsNil)
+}
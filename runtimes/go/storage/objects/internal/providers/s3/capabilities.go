@@ -0,0 +1,147 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+
+	"encore.dev/storage/objects/internal/types"
+)
+
+var _ types.CapabilityProber = (*bucket)(nil)
+
+// capabilitiesProbeKey is the key of the object used to probe for
+// object tagging support. It's never created.
+const capabilitiesProbeKey = ".encore-capabilities-probe"
+
+// capabilitiesCache holds the capabilities of a bucket once probed.
+type capabilitiesCache struct {
+	mu   sync.Mutex
+	caps *types.Capabilities // nil until probed successfully
+}
+
+// Capabilities reports which optional features are available for the
+// bucket. This allows adapting to S3-compatible services like MinIO and
+// Ceph that don't implement every S3 API.
+//
+// The features are probed using read-only requests the first time
+// Capabilities is called for the bucket, and the result is reused
+// after that. A feature is reported as unavailable if the service
+// responds that it isn't implemented; other errors, such as from missing
+// permissions, are returned, and the features are probed again on the
+// next call.
+func (b *bucket) Capabilities(ctx context.Context) (types.Capabilities, error) {
+	b.caps.mu.Lock()
+	defer b.caps.mu.Unlock()
+	if b.caps.caps != nil {
+		return *b.caps.caps, nil
+	}
+	caps, err := b.probeCapabilities(ctx)
+	if err != nil {
+		return types.Capabilities{}, err
+	}
+	b.caps.caps = &caps
+	return caps, nil
+}
+
+func (b *bucket) probeCapabilities(ctx context.Context) (caps types.Capabilities, err error) {
+	bucketName := &b.cfg.CloudName
+
+	_, err = withRetry(ctx, b.retry, func() (*s3.ListMultipartUploadsOutput, error) {
+		return b.client.ListMultipartUploads(ctx, &s3.ListMultipartUploadsInput{Bucket: bucketName, MaxUploads: ptr(int32(1))})
+	})
+	if caps.Multipart, err = probeResult(err); err != nil {
+		return caps, fmt.Errorf("probe multipart uploads: %w", err)
+	}
+
+	_, err = withRetry(ctx, b.retry, func() (*s3.GetObjectTaggingOutput, error) {
+		return b.client.GetObjectTagging(ctx, &s3.GetObjectTaggingInput{Bucket: bucketName, Key: ptr(capabilitiesProbeKey)})
+	})
+	if errors.Is(mapErr(err), types.ErrObjectNotExist) {
+		err = nil // the request for the tags was understood
+	}
+	if caps.Tagging, err = probeResult(err); err != nil {
+		return caps, fmt.Errorf("probe object tagging: %w", err)
+	}
+
+	_, err = withRetry(ctx, b.retry, func() (*s3.GetBucketVersioningOutput, error) {
+		return b.client.GetBucketVersioning(ctx, &s3.GetBucketVersioningInput{Bucket: bucketName})
+	})
+	if caps.Versioning, err = probeResult(err); err != nil {
+		return caps, fmt.Errorf("probe versioning: %w", err)
+	}
+
+	lock, err := withRetry(ctx, b.retry, func() (*s3.GetObjectLockConfigurationOutput, error) {
+		return b.client.GetObjectLockConfiguration(ctx, &s3.GetObjectLockConfigurationInput{Bucket: bucketName})
+	})
+	if hasErrorCode(err, "ObjectLockConfigurationNotFoundError") {
+		err = nil // supported, but not enabled for the bucket
+	} else if err == nil {
+		caps.ObjectLock = lock.ObjectLockConfiguration != nil &&
+			lock.ObjectLockConfiguration.ObjectLockEnabled == s3types.ObjectLockEnabledEnabled
+	}
+	if _, err = probeResult(err); err != nil {
+		return caps, fmt.Errorf("probe object lock: %w", err)
+	}
+
+	if b.awsEndpoint {
+		caps.SSEKMS = true
+		return caps, nil
+	}
+	enc, err := withRetry(ctx, b.retry, func() (*s3.GetBucketEncryptionOutput, error) {
+		return b.client.GetBucketEncryption(ctx, &s3.GetBucketEncryptionInput{Bucket: bucketName})
+	})
+	if hasErrorCode(err, "ServerSideEncryptionConfigurationNotFoundError") {
+		err = nil // no default encryption, so KMS support is unknown
+	} else if err == nil && enc.ServerSideEncryptionConfiguration != nil {
+		for _, rule := range enc.ServerSideEncryptionConfiguration.Rules {
+			if d := rule.ApplyServerSideEncryptionByDefault; d != nil && strings.HasPrefix(string(d.SSEAlgorithm), "aws:kms") {
+				caps.SSEKMS = true
+			}
+		}
+	}
+	if _, err = probeResult(err); err != nil {
+		return caps, fmt.Errorf("probe encryption: %w", err)
+	}
+	return caps, nil
+}
+
+// probeResult reports whether a probe's request succeeded,
+// treating unimplemented APIs as unsupported rather than as an error.
+func probeResult(err error) (supported bool, _ error) {
+	switch {
+	case err == nil:
+		return true, nil
+	case isUnsupported(err):
+		return false, nil
+	default:
+		return false, mapErr(err)
+	}
+}
+
+// hasErrorCode reports whether err is an S3 error with the given code.
+func hasErrorCode(err error, code string) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == code
+}
+
+// isAWSEndpoint reports whether a client with the given base endpoint
+// sends requests to AWS rather than to an S3-compatible service.
+func isAWSEndpoint(endpoint *string) bool {
+	if endpoint == nil {
+		return true // resolved from the region
+	}
+	u, err := url.Parse(*endpoint)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	return strings.HasSuffix(host, ".amazonaws.com") || strings.HasSuffix(host, ".amazonaws.com.cn")
+}
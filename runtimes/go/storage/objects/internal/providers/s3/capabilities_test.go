@@ -0,0 +1,85 @@
+package s3
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	qt "github.com/frankban/quicktest"
+	"github.com/golang/mock/gomock"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/storage/objects/internal/types"
+)
+
+func TestCapabilities(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"}).(*bucket)
+	ctx := context.Background()
+	notImplemented := &smithy.GenericAPIError{Code: "NotImplemented"}
+
+	// A failed probe is reported, and not remembered.
+	client.EXPECT().ListMultipartUploads(gomock.Any(), gomock.Any()).
+		Return(nil, &smithy.GenericAPIError{Code: "AccessDenied"})
+	_, err := bkt.Capabilities(ctx)
+	c.Assert(err, qt.ErrorMatches, "probe multipart uploads: .*AccessDenied.*")
+
+	client.EXPECT().ListMultipartUploads(gomock.Any(), &s3.ListMultipartUploadsInput{Bucket: ptr("bucket"), MaxUploads: ptr(int32(1))}).
+		Return(&s3.ListMultipartUploadsOutput{}, nil)
+	client.EXPECT().GetObjectTagging(gomock.Any(), &s3.GetObjectTaggingInput{Bucket: ptr("bucket"), Key: ptr(capabilitiesProbeKey)}).
+		Return(nil, &s3types.NoSuchKey{})
+	client.EXPECT().GetBucketVersioning(gomock.Any(), gomock.Any()).Return(&s3.GetBucketVersioningOutput{}, nil)
+	client.EXPECT().GetObjectLockConfiguration(gomock.Any(), gomock.Any()).Return(nil, notImplemented)
+	client.EXPECT().GetBucketEncryption(gomock.Any(), gomock.Any()).Return(&s3.GetBucketEncryptionOutput{
+		ServerSideEncryptionConfiguration: &s3types.ServerSideEncryptionConfiguration{
+			Rules: []s3types.ServerSideEncryptionRule{{
+				ApplyServerSideEncryptionByDefault: &s3types.ServerSideEncryptionByDefault{SSEAlgorithm: s3types.ServerSideEncryptionAwsKms},
+			}},
+		},
+	}, nil)
+	want := types.Capabilities{Multipart: true, Tagging: true, Versioning: true, SSEKMS: true}
+	caps, err := bkt.Capabilities(ctx)
+	c.Assert(err, qt.IsNil)
+	c.Assert(caps, qt.Equals, want)
+
+	// The capabilities are only probed once.
+	caps, err = bkt.Capabilities(ctx)
+	c.Assert(err, qt.IsNil)
+	c.Assert(caps, qt.Equals, want)
+}
+
+func TestCapabilities_Unsupported(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"}).(*bucket)
+	notImplemented := &smithy.GenericAPIError{Code: "NotImplemented"}
+
+	client.EXPECT().ListMultipartUploads(gomock.Any(), gomock.Any()).Return(nil, notImplemented)
+	client.EXPECT().GetObjectTagging(gomock.Any(), gomock.Any()).Return(nil, notImplemented)
+	client.EXPECT().GetBucketVersioning(gomock.Any(), gomock.Any()).Return(nil, notImplemented)
+	client.EXPECT().GetObjectLockConfiguration(gomock.Any(), gomock.Any()).Return(&s3.GetObjectLockConfigurationOutput{
+		ObjectLockConfiguration: &s3types.ObjectLockConfiguration{ObjectLockEnabled: s3types.ObjectLockEnabledEnabled},
+	}, nil)
+	client.EXPECT().GetBucketEncryption(gomock.Any(), gomock.Any()).
+		Return(nil, &smithy.GenericAPIError{Code: "ServerSideEncryptionConfigurationNotFoundError"})
+
+	caps, err := bkt.Capabilities(context.Background())
+	c.Assert(err, qt.IsNil)
+	c.Assert(caps, qt.Equals, types.Capabilities{ObjectLock: true})
+}
+
+func TestIsAWSEndpoint(t *testing.T) {
+	c := qt.New(t)
+	c.Assert(isAWSEndpoint(nil), qt.IsTrue)
+	c.Assert(isAWSEndpoint(ptr("https://s3.eu-west-1.amazonaws.com")), qt.IsTrue)
+	c.Assert(isAWSEndpoint(ptr("https://s3.cn-north-1.amazonaws.com.cn")), qt.IsTrue)
+	c.Assert(isAWSEndpoint(ptr("http://localhost:9000")), qt.IsFalse)
+	c.Assert(isAWSEndpoint(ptr("https://amazonaws.com.example.org")), qt.IsFalse)
+}
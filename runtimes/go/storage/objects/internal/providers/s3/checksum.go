@@ -0,0 +1,165 @@
+package s3
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"encore.dev/storage/objects/internal/types"
+)
+
+// ChecksumAlgorithm is the algorithm used to compute checksums
+// that S3 verifies when receiving object data.
+type ChecksumAlgorithm int
+
+const (
+	// ChecksumNone disables additional checksums.
+	// Data is still verified using Content-MD5.
+	ChecksumNone ChecksumAlgorithm = iota
+
+	// ChecksumCRC32 uses CRC-32 (IEEE) checksums.
+	ChecksumCRC32
+
+	// ChecksumSHA256 uses SHA-256 checksums.
+	ChecksumSHA256
+)
+
+// checksumFromConfig returns the checksum algorithm
+// with the given name in the runtime config.
+func checksumFromConfig(name string) ChecksumAlgorithm {
+	switch name {
+	case "":
+		return ChecksumNone
+	case "crc32":
+		return ChecksumCRC32
+	case "sha256":
+		return ChecksumSHA256
+	default:
+		panic(fmt.Sprintf("s3: unknown checksum algorithm %q", name))
+	}
+}
+
+func (a ChecksumAlgorithm) s3Algorithm() s3types.ChecksumAlgorithm {
+	switch a {
+	case ChecksumCRC32:
+		return s3types.ChecksumAlgorithmCrc32
+	case ChecksumSHA256:
+		return s3types.ChecksumAlgorithmSha256
+	default:
+		return ""
+	}
+}
+
+func (a ChecksumAlgorithm) newHash() hash.Hash {
+	switch a {
+	case ChecksumCRC32:
+		return crc32.NewIEEE()
+	case ChecksumSHA256:
+		return sha256.New()
+	default:
+		return nil
+	}
+}
+
+// sum computes the base64-encoded checksum of data.
+// It returns "" if no checksum algorithm is configured.
+func (a ChecksumAlgorithm) sum(data []byte) string {
+	h := a.newHash()
+	if h == nil {
+		return ""
+	}
+	h.Write(data)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// composite computes the checksum S3 reports for a multipart upload,
+// which is the checksum of the concatenated part checksums
+// suffixed with the number of parts.
+func (a ChecksumAlgorithm) composite(partSums []string) (string, error) {
+	h := a.newHash()
+	if h == nil {
+		return "", nil
+	}
+	for _, sum := range partSums {
+		raw, err := base64.StdEncoding.DecodeString(sum)
+		if err != nil {
+			return "", fmt.Errorf("invalid part checksum %q: %w", sum, err)
+		}
+		h.Write(raw)
+	}
+	return fmt.Sprintf("%s-%d", base64.StdEncoding.EncodeToString(h.Sum(nil)), len(partSums)), nil
+}
+
+// MultipartETag computes the ETag S3 reports for an object uploaded with
+// a multipart upload, given the MD5 digests of its parts in order: the hex MD5
+// of the concatenated digests suffixed with the number of parts.
+// The ETag is returned unquoted.
+//
+// Objects encrypted with SSE-KMS or SSE-C have ETags that aren't based on MD5.
+func MultipartETag(partMD5s [][]byte) string {
+	h := md5.New()
+	for _, sum := range partMD5s {
+		h.Write(sum)
+	}
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(h.Sum(nil)), len(partMD5s))
+}
+
+// fields returns the CRC32 and SHA256 checksum fields to set
+// on a request for the given checksum.
+func (a ChecksumAlgorithm) fields(sum string) (crc, sha *string) {
+	switch a {
+	case ChecksumCRC32:
+		return &sum, nil
+	case ChecksumSHA256:
+		return nil, &sum
+	default:
+		return nil, nil
+	}
+}
+
+// fromOutput returns the checksum for this algorithm
+// from the CRC32 and SHA256 checksum fields of a response.
+func (a ChecksumAlgorithm) fromOutput(crc, sha *string) string {
+	switch a {
+	case ChecksumCRC32:
+		return valOrZero(crc)
+	case ChecksumSHA256:
+		return valOrZero(sha)
+	default:
+		return ""
+	}
+}
+
+func (a ChecksumAlgorithm) setPut(in *s3.PutObjectInput, sum string) {
+	if a == ChecksumNone {
+		return
+	}
+	in.ChecksumAlgorithm = a.s3Algorithm()
+	in.ChecksumCRC32, in.ChecksumSHA256 = a.fields(sum)
+}
+
+func (a ChecksumAlgorithm) setPart(in *s3.UploadPartInput, part *s3types.CompletedPart, sum string) {
+	if a == ChecksumNone {
+		return
+	}
+	in.ChecksumAlgorithm = a.s3Algorithm()
+	in.ChecksumCRC32, in.ChecksumSHA256 = a.fields(sum)
+	part.ChecksumCRC32, part.ChecksumSHA256 = a.fields(sum)
+}
+
+// verify checks that the checksum returned by S3 matches the expected one.
+// S3 omits checksums for algorithms it wasn't asked to compute, in which
+// case there is nothing to verify against.
+func (a ChecksumAlgorithm) verify(expected, got string) error {
+	if a == ChecksumNone || got == "" || got == expected {
+		return nil
+	}
+	return fmt.Errorf("%w: expected %s, got %s", types.ErrChecksumMismatch, expected, got)
+}
@@ -0,0 +1,78 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"encore.dev/storage/objects/internal/types"
+)
+
+var _ types.UploadCleaner = (*bucket)(nil)
+
+// CleanupIncompleteUploads aborts the multipart uploads to the bucket
+// that were initiated more than data.OlderThan ago and never completed.
+// S3 keeps the parts of such uploads, and charges for storing them,
+// until they're aborted. It returns the number of uploads aborted.
+//
+// A lifecycle rule with AbortIncompleteMultipartUpload achieves the same
+// without client involvement, where the bucket's configuration allows it.
+func (b *bucket) CleanupIncompleteUploads(data types.CleanupUploadsData) (aborted int, err error) {
+	return cleanupIncompleteUploads(data.Ctx, b.client, b.cfg.CloudName, data.Prefix, data.OlderThan)
+}
+
+// cleanupIncompleteUploads aborts the multipart uploads to objects
+// starting with prefix that were initiated more than olderThan ago.
+//
+// Uploads that fail to be aborted don't stop the cleanup;
+// their errors are joined and returned once all uploads are processed.
+func cleanupIncompleteUploads(ctx context.Context, client s3Client, bucket, prefix string, olderThan time.Duration) (aborted int, err error) {
+	cutoff := time.Now().Add(-olderThan)
+
+	var (
+		errs           []error
+		keyMarker      *string
+		uploadIDMarker *string
+	)
+	for {
+		resp, err := client.ListMultipartUploads(ctx, &s3.ListMultipartUploadsInput{
+			Bucket:         &bucket,
+			Prefix:         ptrOrNil(prefix),
+			KeyMarker:      keyMarker,
+			UploadIdMarker: uploadIDMarker,
+		})
+		if err != nil {
+			return aborted, errors.Join(append(errs, err)...)
+		}
+
+		for _, upload := range resp.Uploads {
+			if upload.Initiated == nil || !upload.Initiated.Before(cutoff) {
+				continue
+			}
+			_, err := client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+				Bucket:   &bucket,
+				Key:      upload.Key,
+				UploadId: upload.UploadId,
+			})
+			var noSuchUpload *s3types.NoSuchUpload
+			switch {
+			case err == nil:
+				aborted++
+			case errors.As(err, &noSuchUpload):
+				// Completed or aborted since it was listed.
+			default:
+				errs = append(errs, fmt.Errorf("abort upload %s of %q: %w",
+					valOrZero(upload.UploadId), valOrZero(upload.Key), err))
+			}
+		}
+
+		if !valOrZero(resp.IsTruncated) {
+			return aborted, errors.Join(errs...)
+		}
+		keyMarker, uploadIDMarker = resp.NextKeyMarker, resp.NextUploadIdMarker
+	}
+}
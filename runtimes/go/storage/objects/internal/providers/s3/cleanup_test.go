@@ -0,0 +1,105 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	qt "github.com/frankban/quicktest"
+	"github.com/golang/mock/gomock"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/storage/objects/internal/types"
+)
+
+func TestCleanupIncompleteUploads(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	old := time.Now().Add(-48 * time.Hour)
+	recent := time.Now().Add(-time.Hour)
+
+	client.EXPECT().ListMultipartUploads(gomock.Any(), &s3.ListMultipartUploadsInput{Bucket: ptr("bucket")}).
+		Return(&s3.ListMultipartUploadsOutput{
+			Uploads: []s3types.MultipartUpload{
+				{Key: ptr("a"), UploadId: ptr("1"), Initiated: &old},
+				{Key: ptr("b"), UploadId: ptr("2"), Initiated: &recent},
+			},
+			IsTruncated:        ptr(true),
+			NextKeyMarker:      ptr("b"),
+			NextUploadIdMarker: ptr("2"),
+		}, nil)
+	client.EXPECT().ListMultipartUploads(gomock.Any(), &s3.ListMultipartUploadsInput{
+		Bucket:         ptr("bucket"),
+		KeyMarker:      ptr("b"),
+		UploadIdMarker: ptr("2"),
+	}).Return(&s3.ListMultipartUploadsOutput{
+		Uploads: []s3types.MultipartUpload{
+			{Key: ptr("c"), UploadId: ptr("3"), Initiated: &old},
+			{Key: ptr("d"), UploadId: ptr("4"), Initiated: &old},
+		},
+	}, nil)
+
+	client.EXPECT().AbortMultipartUpload(gomock.Any(), &s3.AbortMultipartUploadInput{
+		Bucket: ptr("bucket"), Key: ptr("a"), UploadId: ptr("1"),
+	}).Return(&s3.AbortMultipartUploadOutput{}, nil)
+	client.EXPECT().AbortMultipartUpload(gomock.Any(), &s3.AbortMultipartUploadInput{
+		Bucket: ptr("bucket"), Key: ptr("c"), UploadId: ptr("3"),
+	}).Return(nil, &s3types.NoSuchUpload{})
+	client.EXPECT().AbortMultipartUpload(gomock.Any(), &s3.AbortMultipartUploadInput{
+		Bucket: ptr("bucket"), Key: ptr("d"), UploadId: ptr("4"),
+	}).Return(&s3.AbortMultipartUploadOutput{}, nil)
+
+	n, err := cleanupIncompleteUploads(ctx, client, "bucket", "", 24*time.Hour)
+	c.Assert(err, qt.IsNil)
+	c.Assert(n, qt.Equals, 2)
+}
+
+func TestCleanupIncompleteUploads_Errors(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	old := time.Now().Add(-48 * time.Hour)
+
+	client.EXPECT().ListMultipartUploads(gomock.Any(), gomock.Any()).Return(&s3.ListMultipartUploadsOutput{
+		Uploads: []s3types.MultipartUpload{
+			{Key: ptr("a"), UploadId: ptr("1"), Initiated: &old},
+			{Key: ptr("b"), UploadId: ptr("2"), Initiated: &old},
+		},
+	}, nil)
+	client.EXPECT().AbortMultipartUpload(gomock.Any(), gomock.Any()).Return(nil, errors.New("access denied"))
+	client.EXPECT().AbortMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.AbortMultipartUploadOutput{}, nil)
+
+	n, err := cleanupIncompleteUploads(ctx, client, "bucket", "", time.Hour)
+	c.Assert(err, qt.ErrorMatches, `abort upload 1 of "a": access denied`)
+	c.Assert(n, qt.Equals, 1)
+
+	client.EXPECT().ListMultipartUploads(gomock.Any(), gomock.Any()).Return(nil, errors.New("list failed"))
+	_, err = cleanupIncompleteUploads(ctx, client, "bucket", "", time.Hour)
+	c.Assert(err, qt.ErrorMatches, "list failed")
+}
+
+func TestBucket_CleanupIncompleteUploads(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"}).(*bucket)
+
+	// Only uploads to objects under the prefix are cleaned up.
+	client.EXPECT().ListMultipartUploads(gomock.Any(), &s3.ListMultipartUploadsInput{
+		Bucket: ptr("bucket"),
+		Prefix: ptr("dir/"),
+	}).Return(&s3.ListMultipartUploadsOutput{}, nil)
+	n, err := bkt.CleanupIncompleteUploads(types.CleanupUploadsData{Ctx: ctx, Prefix: "dir/", OlderThan: time.Hour})
+	c.Assert(err, qt.IsNil)
+	c.Assert(n, qt.Equals, 0)
+}
@@ -0,0 +1,57 @@
+package s3
+
+import (
+	"context"
+	"maps"
+	"strings"
+
+	"encore.dev/appruntime/shared/reqtrack"
+)
+
+// WithContextMetadata adds user metadata derived from the context of each
+// upload to the uploaded object, for example to record the trace or request
+// ID of the request that wrote it so S3 access logs can be correlated with
+// request traces.
+//
+// fn is called with the context of every upload and returns the metadata
+// to add, keyed by name without the "x-amz-meta-" prefix. It returns nil
+// or an empty map if the context has no values to add, in which case the
+// upload's metadata is left as is. Metadata set on the upload itself takes
+// precedence over metadata with the same name returned by fn.
+func WithContextMetadata(fn func(ctx context.Context) map[string]string) Option {
+	return func(o *bucketOptions) { o.contextMetadata = fn }
+}
+
+// requestMetadata returns a function for WithContextMetadata that adds
+// the trace and span IDs of the request an upload is made from, if any,
+// as the "encore-trace-id" and "encore-span-id" metadata.
+func requestMetadata(rt *reqtrack.RequestTracker) func(context.Context) map[string]string {
+	return func(context.Context) map[string]string {
+		req := rt.Current().Req
+		if req == nil || req.TraceID.IsZero() {
+			return nil
+		}
+		return map[string]string{
+			"encore-trace-id": req.TraceID.String(),
+			"encore-span-id":  req.SpanID.String(),
+		}
+	}
+}
+
+// withContextMetadata returns md with the metadata for ctx added,
+// without modifying md.
+func (b *bucket) withContextMetadata(ctx context.Context, md map[string]string) map[string]string {
+	if b.contextMetadata == nil {
+		return md
+	}
+	merged := userMetadata(b.contextMetadata(ctx))
+	if len(merged) == 0 {
+		return md
+	}
+	for k, v := range userMetadata(md) {
+		// Metadata names are case-insensitive.
+		maps.DeleteFunc(merged, func(name, _ string) bool { return strings.EqualFold(name, k) })
+		merged[k] = v
+	}
+	return merged
+}
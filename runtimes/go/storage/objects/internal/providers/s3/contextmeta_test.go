@@ -0,0 +1,78 @@
+package s3
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	qt "github.com/frankban/quicktest"
+	"github.com/golang/mock/gomock"
+	"github.com/rs/zerolog"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/appruntime/exported/model"
+	"encore.dev/appruntime/shared/reqtrack"
+	"encore.dev/storage/objects/internal/types"
+)
+
+type traceIDKey struct{}
+
+func TestWithContextMetadata(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"},
+		WithContextMetadata(func(ctx context.Context) map[string]string {
+			id, _ := ctx.Value(traceIDKey{}).(string)
+			if id == "" {
+				return nil
+			}
+			return map[string]string{"trace-id": id, "owner": "from-context"}
+		}))
+
+	upload := func(ctx context.Context, md map[string]string) (sent map[string]string) {
+		client.EXPECT().PutObject(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+				sent = in.Metadata
+				return &s3.PutObjectOutput{}, nil
+			})
+		u, err := bkt.Upload(types.UploadData{Ctx: ctx, Object: "object", Attrs: types.UploadAttrs{Metadata: md}})
+		c.Assert(err, qt.IsNil)
+		_, err = u.Write([]byte("data"))
+		c.Assert(err, qt.IsNil)
+		_, err = u.Complete()
+		c.Assert(err, qt.IsNil)
+		return sent
+	}
+
+	ctx := context.WithValue(context.Background(), traceIDKey{}, "trace")
+	md := map[string]string{"X-Amz-Meta-Owner": "alice"}
+	c.Assert(upload(ctx, md), qt.DeepEquals, map[string]string{"trace-id": "trace", "Owner": "alice"})
+	c.Assert(md, qt.DeepEquals, map[string]string{"X-Amz-Meta-Owner": "alice"}, qt.Commentf("metadata modified"))
+
+	// Nothing is added when the context has no values.
+	c.Assert(upload(context.Background(), nil), qt.IsNil)
+}
+
+func TestRequestMetadata(t *testing.T) {
+	c := qt.New(t)
+	rt := reqtrack.New(zerolog.Nop(), nil, nil)
+	mgr := NewManager(context.Background(), &config.Runtime{}, rt, nil)
+
+	// Buckets only record request metadata if configured to.
+	c.Assert(newManagerBucket(c, mgr, &config.S3BucketProvider{}).contextMetadata, qt.IsNil)
+	fn := newManagerBucket(c, mgr, &config.S3BucketProvider{ContextMetadata: true}).contextMetadata
+	c.Assert(fn, qt.IsNotNil)
+
+	// Outside of a request there's nothing to record.
+	c.Assert(fn(context.Background()), qt.IsNil)
+
+	req := &model.Request{TraceID: model.TraceID{1}, SpanID: model.SpanID{2}}
+	rt.BeginRequest(req)
+	defer rt.FinishRequest(false)
+	c.Assert(fn(context.Background()), qt.DeepEquals, map[string]string{
+		"encore-trace-id": req.TraceID.String(),
+		"encore-span-id":  req.SpanID.String(),
+	})
+}
@@ -0,0 +1,237 @@
+package s3
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"golang.org/x/sync/errgroup"
+
+	"encore.dev/storage/objects/internal/types"
+)
+
+// maxCopyObjectSize is the largest object CopyObject can copy.
+// Larger objects must be copied using a multipart upload.
+const maxCopyObjectSize = 5 * 1024 * 1024 * 1024
+
+// copyPartSize is the size of each part when copying
+// an object using a multipart upload.
+// It's a variable for testing purposes.
+var copyPartSize int64 = 512 * 1024 * 1024
+
+func (b *bucket) Copy(data types.CopyData) (*types.ObjectAttrs, error) {
+	dst := b
+	if data.DstBucket != nil {
+		d, ok := data.DstBucket.(*bucket)
+		if !ok {
+			return nil, fmt.Errorf("%w: cannot copy objects between providers", types.ErrInvalidArgument)
+		}
+		dst = d
+	}
+	if err := validateKey(string(data.DstObject), dst.rejectControlChars); err != nil {
+		return nil, err
+	}
+	if err := dst.uploadOpts.Encryption.validate(); err != nil {
+		return nil, err
+	}
+	if err := dst.uploadOpts.validateStorageClass(); err != nil {
+		return nil, err
+	}
+	if err := dst.uploadOpts.validateACL(); err != nil {
+		return nil, err
+	}
+	if data.Attrs != nil {
+		if err := validateTags(data.Attrs.Tags); err != nil {
+			return nil, err
+		}
+	}
+
+	// Look up the source object to determine how to copy it.
+	object := string(data.Object)
+	head := &s3.HeadObjectInput{
+		Bucket:       &b.cfg.CloudName,
+		Key:          &object,
+		VersionId:    ptrOrNil(data.Version),
+		RequestPayer: b.requestPayer,
+	}
+	b.uploadOpts.Encryption.setHead(head)
+	src, err := b.headObject(data.Ctx, head)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+
+	source := copySource(b.cfg.CloudName, object, valOrZero(src.VersionId))
+	if valOrZero(src.ContentLength) <= maxCopyObjectSize {
+		return dst.copyObject(b, data, source, src)
+	}
+	return dst.multipartCopy(b, data, source, src)
+}
+
+// copySource returns the URL-encoded CopySource for an object.
+func copySource(bucket, object, version string) string {
+	segments := strings.Split(object, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	source := bucket + "/" + strings.Join(segments, "/")
+	if version != "" {
+		source += "?versionId=" + url.QueryEscape(version)
+	}
+	return source
+}
+
+// copyObject copies an object from src using a single CopyObject request.
+func (b *bucket) copyObject(src *bucket, data types.CopyData, source string, head *s3.HeadObjectOutput) (*types.ObjectAttrs, error) {
+	in := &s3.CopyObjectInput{
+		Bucket:       &b.cfg.CloudName,
+		Key:          ptr(string(data.DstObject)),
+		CopySource:   &source,
+		StorageClass: s3types.StorageClass(b.uploadOpts.StorageClass),
+		ACL:          s3types.ObjectCannedACL(b.uploadOpts.ACL),
+	}
+	if attrs := data.Attrs; attrs != nil {
+		in.MetadataDirective = s3types.MetadataDirectiveReplace
+		in.ContentType = ptrOrNil(attrs.ContentType)
+		in.CacheControl = ptrOrNil(attrs.CacheControl)
+		in.ContentEncoding = ptrOrNil(attrs.ContentEncoding)
+		in.Metadata = userMetadata(attrs.Metadata)
+		if attrs.Tags != nil {
+			in.TaggingDirective = s3types.TaggingDirectiveReplace
+			in.Tagging = tagging(attrs.Tags)
+		}
+	}
+	b.uploadOpts.Encryption.setCopy(in)
+	src.uploadOpts.Encryption.setCopySource(in)
+
+	resp, err := withRetry(data.Ctx, b.uploadOpts, func() (*s3.CopyObjectOutput, error) {
+		return b.client.CopyObject(data.Ctx, in)
+	})
+	if err != nil {
+		return nil, mapErr(err)
+	}
+
+	attrs := &types.ObjectAttrs{
+		Object:      data.DstObject,
+		Version:     valOrZero(resp.VersionId),
+		ContentType: valOrZero(head.ContentType),
+		Size:        valOrZero(head.ContentLength),
+	}
+	if data.Attrs != nil {
+		attrs.ContentType = data.Attrs.ContentType
+	}
+	if res := resp.CopyObjectResult; res != nil {
+		attrs.ETag = valOrZero(res.ETag)
+	}
+	return attrs, nil
+}
+
+// multipartCopy copies an object from src that is too large
+// for CopyObject, by copying byte ranges of it in parallel.
+func (b *bucket) multipartCopy(src *bucket, data types.CopyData, source string, head *s3.HeadObjectOutput) (attrs *types.ObjectAttrs, err error) {
+	key := ptr(string(data.DstObject))
+
+	// Multipart uploads don't copy the source's attributes,
+	// so set them explicitly.
+	create := &s3.CreateMultipartUploadInput{
+		Bucket:       &b.cfg.CloudName,
+		Key:          key,
+		StorageClass: s3types.StorageClass(b.uploadOpts.StorageClass),
+		ACL:          s3types.ObjectCannedACL(b.uploadOpts.ACL),
+	}
+	if a := data.Attrs; a != nil {
+		create.ContentType = ptrOrNil(a.ContentType)
+		create.CacheControl = ptrOrNil(a.CacheControl)
+		create.ContentEncoding = ptrOrNil(a.ContentEncoding)
+		create.Metadata = userMetadata(a.Metadata)
+		create.Tagging = tagging(a.Tags)
+	} else {
+		create.ContentType = head.ContentType
+		create.CacheControl = head.CacheControl
+		create.ContentEncoding = head.ContentEncoding
+		create.Metadata = head.Metadata
+	}
+	b.uploadOpts.Encryption.setCreate(create)
+
+	resp, err := b.client.CreateMultipartUpload(data.Ctx, create)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	uploadID := valOrZero(resp.UploadId)
+
+	defer func() {
+		if err != nil {
+			go abortMultipart(b.client, b.cfg.CloudName, key, uploadID)
+		}
+	}()
+
+	var (
+		mu    sync.Mutex
+		parts = make(map[int32]s3types.CompletedPart)
+	)
+	g, ctx := errgroup.WithContext(data.Ctx)
+	g.SetLimit(b.uploadOpts.concurrency())
+
+	size := valOrZero(head.ContentLength)
+	for off, partNum := int64(0), int32(1); off < size; off, partNum = off+copyPartSize, partNum+1 {
+		in := &s3.UploadPartCopyInput{
+			Bucket:          &b.cfg.CloudName,
+			Key:             key,
+			UploadId:        &uploadID,
+			PartNumber:      ptr(partNum),
+			CopySource:      &source,
+			CopySourceRange: ptr(fmt.Sprintf("bytes=%d-%d", off, min(off+copyPartSize, size)-1)),
+
+			// Make sure every part is copied from the same object.
+			CopySourceIfMatch: head.ETag,
+		}
+		b.uploadOpts.Encryption.setPartCopy(in)
+		src.uploadOpts.Encryption.setPartCopySource(in)
+
+		g.Go(func() error {
+			if err := ctx.Err(); err != nil {
+				return err // another part failed; don't start copying more
+			}
+			resp, err := withRetry(ctx, b.uploadOpts, func() (*s3.UploadPartCopyOutput, error) {
+				return b.client.UploadPartCopy(ctx, in)
+			})
+			if err != nil {
+				return mapErr(err)
+			}
+			part := s3types.CompletedPart{PartNumber: in.PartNumber}
+			if res := resp.CopyPartResult; res != nil {
+				part.ETag = res.ETag
+			}
+			mu.Lock()
+			parts[partNum] = part
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	complete, err := withRetry(data.Ctx, b.uploadOpts, func() (*s3.CompleteMultipartUploadOutput, error) {
+		return b.client.CompleteMultipartUpload(data.Ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:   &b.cfg.CloudName,
+			Key:      key,
+			UploadId: &uploadID,
+			MultipartUpload: &s3types.CompletedMultipartUpload{
+				Parts: sortedParts(parts),
+			},
+		})
+	})
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	return &types.ObjectAttrs{
+		Object:      data.DstObject,
+		Version:     valOrZero(complete.VersionId),
+		ETag:        valOrZero(complete.ETag),
+		ContentType: valOrZero(create.ContentType),
+		Size:        size,
+	}, nil
+}
@@ -0,0 +1,349 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	qt "github.com/frankban/quicktest"
+	"github.com/golang/mock/gomock"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/storage/objects/internal/types"
+)
+
+func TestCopy(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"})
+
+	client.EXPECT().HeadObject(gomock.Any(), &s3.HeadObjectInput{
+		Bucket: ptr("bucket"),
+		Key:    ptr("dir/a b.txt"),
+	}).Return(&s3.HeadObjectOutput{
+		ContentLength: ptr(int64(10)),
+		ContentType:   ptr("text/plain"),
+		VersionId:     ptr("v1"),
+	}, nil)
+	client.EXPECT().CopyObject(gomock.Any(), &s3.CopyObjectInput{
+		Bucket:     ptr("bucket"),
+		Key:        ptr("dst"),
+		CopySource: ptr("bucket/dir/a%20b.txt?versionId=v1"),
+	}).Return(&s3.CopyObjectOutput{
+		VersionId:        ptr("v2"),
+		CopyObjectResult: &s3types.CopyObjectResult{ETag: ptr(`"etag"`)},
+	}, nil)
+
+	attrs, err := bkt.Copy(types.CopyData{
+		Ctx:       context.Background(),
+		Object:    "dir/a b.txt",
+		DstObject: "dst",
+	})
+	c.Assert(err, qt.IsNil)
+	c.Assert(attrs, qt.DeepEquals, &types.ObjectAttrs{
+		Object:      "dst",
+		Version:     "v2",
+		ContentType: "text/plain",
+		Size:        10,
+		ETag:        `"etag"`,
+	})
+}
+
+func TestCopy_ReplaceAttrs(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	src := NewBucketWithClient(client, &config.Bucket{CloudName: "src"})
+	dst := NewBucketWithClient(client, &config.Bucket{CloudName: "dst"})
+
+	client.EXPECT().HeadObject(gomock.Any(), gomock.Any()).Return(&s3.HeadObjectOutput{
+		ContentLength: ptr(int64(10)),
+		ContentType:   ptr("text/plain"),
+	}, nil)
+	client.EXPECT().CopyObject(gomock.Any(), &s3.CopyObjectInput{
+		Bucket:            ptr("dst"),
+		Key:               ptr("object"),
+		CopySource:        ptr("src/object"),
+		MetadataDirective: s3types.MetadataDirectiveReplace,
+		ContentType:       ptr("application/json"),
+		Metadata:          map[string]string{"foo": "bar"},
+	}).Return(&s3.CopyObjectOutput{}, nil)
+
+	attrs, err := src.Copy(types.CopyData{
+		Ctx:       context.Background(),
+		Object:    "object",
+		DstBucket: dst,
+		DstObject: "object",
+		Attrs: &types.UploadAttrs{
+			ContentType: "application/json",
+			Metadata:    map[string]string{"x-amz-meta-foo": "bar"},
+		},
+	})
+	c.Assert(err, qt.IsNil)
+	c.Assert(attrs.ContentType, qt.Equals, "application/json")
+}
+
+func TestCopy_Multipart(t *testing.T) {
+	c := qt.New(t)
+	withCopyPartSize(c, 2*1024*1024*1024)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"})
+
+	const size = maxCopyObjectSize + 1
+	client.EXPECT().HeadObject(gomock.Any(), gomock.Any()).Return(&s3.HeadObjectOutput{
+		ContentLength: ptr(int64(size)),
+		ContentType:   ptr("text/plain"),
+		Metadata:      map[string]string{"foo": "bar"},
+		ETag:          ptr(`"src"`),
+	}, nil)
+	client.EXPECT().CreateMultipartUpload(gomock.Any(), &s3.CreateMultipartUploadInput{
+		Bucket:      ptr("bucket"),
+		Key:         ptr("dst"),
+		ContentType: ptr("text/plain"),
+		Metadata:    map[string]string{"foo": "bar"},
+	}).Return(&s3.CreateMultipartUploadOutput{UploadId: ptr("upload")}, nil)
+
+	var (
+		mu     sync.Mutex
+		ranges = make(map[int32]string)
+	)
+	client.EXPECT().UploadPartCopy(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.UploadPartCopyInput, _ ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error) {
+			c.Check(valOrZero(in.CopySource), qt.Equals, "bucket/src")
+			c.Check(valOrZero(in.CopySourceIfMatch), qt.Equals, `"src"`)
+			mu.Lock()
+			ranges[*in.PartNumber] = *in.CopySourceRange
+			mu.Unlock()
+			return &s3.UploadPartCopyOutput{
+				CopyPartResult: &s3types.CopyPartResult{ETag: ptr(fmt.Sprintf("etag%d", *in.PartNumber))},
+			}, nil
+		}).Times(3)
+	client.EXPECT().CompleteMultipartUpload(gomock.Any(), &s3.CompleteMultipartUploadInput{
+		Bucket:   ptr("bucket"),
+		Key:      ptr("dst"),
+		UploadId: ptr("upload"),
+		MultipartUpload: &s3types.CompletedMultipartUpload{
+			Parts: []s3types.CompletedPart{
+				{PartNumber: ptr(int32(1)), ETag: ptr("etag1")},
+				{PartNumber: ptr(int32(2)), ETag: ptr("etag2")},
+				{PartNumber: ptr(int32(3)), ETag: ptr("etag3")},
+			},
+		},
+	}).Return(&s3.CompleteMultipartUploadOutput{ETag: ptr(`"dst"`)}, nil)
+
+	attrs, err := bkt.Copy(types.CopyData{
+		Ctx:       context.Background(),
+		Object:    "src",
+		DstObject: "dst",
+	})
+	c.Assert(err, qt.IsNil)
+	c.Assert(attrs.Size, qt.Equals, int64(size))
+	c.Assert(ranges, qt.DeepEquals, map[int32]string{
+		1: "bytes=0-2147483647",
+		2: "bytes=2147483648-4294967295",
+		3: "bytes=4294967296-5368709120",
+	})
+}
+
+func TestCopy_MultipartAborts(t *testing.T) {
+	c := qt.New(t)
+	withCopyPartSize(c, 2*1024*1024*1024)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"},
+		WithUploadOptions(UploadOptions{Concurrency: 1}))
+
+	client.EXPECT().HeadObject(gomock.Any(), gomock.Any()).Return(&s3.HeadObjectOutput{
+		ContentLength: ptr(int64(maxCopyObjectSize + 1)),
+	}, nil)
+	client.EXPECT().CreateMultipartUpload(gomock.Any(), gomock.Any()).Return(
+		&s3.CreateMultipartUploadOutput{UploadId: ptr("upload")}, nil)
+	client.EXPECT().UploadPartCopy(gomock.Any(), gomock.Any()).Return(
+		nil, &smithy.GenericAPIError{Code: "AccessDenied"})
+
+	aborted := make(chan struct{})
+	client.EXPECT().AbortMultipartUpload(gomock.Any(), &s3.AbortMultipartUploadInput{
+		Bucket:   ptr("bucket"),
+		Key:      ptr("dst"),
+		UploadId: ptr("upload"),
+	}).DoAndReturn(
+		func(ctx context.Context, _ *s3.AbortMultipartUploadInput, _ ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+			close(aborted)
+			return &s3.AbortMultipartUploadOutput{}, nil
+		})
+
+	_, err := bkt.Copy(types.CopyData{
+		Ctx:       context.Background(),
+		Object:    "src",
+		DstObject: "dst",
+	})
+	c.Assert(err, qt.ErrorMatches, ".*AccessDenied.*")
+	waitFor(c, aborted)
+}
+
+func TestCopy_AcrossProviders(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	bkt := NewBucketWithClient(NewMocks3Client(ctrl), &config.Bucket{CloudName: "bucket"})
+
+	_, err := bkt.Copy(types.CopyData{
+		Ctx:       context.Background(),
+		Object:    "src",
+		DstBucket: otherBucket{},
+		DstObject: "dst",
+	})
+	c.Assert(err, qt.ErrorIs, types.ErrInvalidArgument)
+}
+
+func TestCopyParts(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"}).(*bucket)
+	other := NewBucketWithClient(client, &config.Bucket{CloudName: "other"})
+
+	// The size of objects copied whole is looked up.
+	client.EXPECT().HeadObject(gomock.Any(), &s3.HeadObjectInput{
+		Bucket: ptr("other"),
+		Key:    ptr("b"),
+	}).Return(&s3.HeadObjectOutput{ContentLength: ptr(int64(minPartSize)), ETag: ptr(`"b"`)}, nil)
+	client.EXPECT().CreateMultipartUpload(gomock.Any(), &s3.CreateMultipartUploadInput{
+		Bucket:      ptr("bucket"),
+		Key:         ptr("dst"),
+		ContentType: ptr("text/plain"),
+	}).Return(&s3.CreateMultipartUploadOutput{UploadId: ptr("upload")}, nil)
+
+	var (
+		mu     sync.Mutex
+		copies = make(map[int32]*s3.UploadPartCopyInput)
+	)
+	client.EXPECT().UploadPartCopy(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.UploadPartCopyInput, _ ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error) {
+			mu.Lock()
+			copies[*in.PartNumber] = in
+			mu.Unlock()
+			return &s3.UploadPartCopyOutput{
+				CopyPartResult: &s3types.CopyPartResult{ETag: ptr(fmt.Sprintf("etag%d", *in.PartNumber))},
+			}, nil
+		}).Times(3)
+	client.EXPECT().CompleteMultipartUpload(gomock.Any(), &s3.CompleteMultipartUploadInput{
+		Bucket:   ptr("bucket"),
+		Key:      ptr("dst"),
+		UploadId: ptr("upload"),
+		MultipartUpload: &s3types.CompletedMultipartUpload{
+			Parts: []s3types.CompletedPart{
+				{PartNumber: ptr(int32(1)), ETag: ptr("etag1")},
+				{PartNumber: ptr(int32(2)), ETag: ptr("etag2")},
+				{PartNumber: ptr(int32(3)), ETag: ptr("etag3")},
+			},
+		},
+	}).Return(&s3.CompleteMultipartUploadOutput{ETag: ptr(`"dst"`), VersionId: ptr("v1")}, nil)
+
+	attrs, err := bkt.CopyParts(types.CopyPartsData{
+		Ctx:    context.Background(),
+		Object: "dst",
+		Attrs:  types.UploadAttrs{ContentType: "text/plain"},
+		Parts: []types.CopyPart{
+			{Object: "a", Offset: 10, Length: minPartSize, ETag: `"a"`},
+			{Bucket: other, Object: "b"},
+			{Object: "c", Version: "v2", Offset: 0, Length: 3},
+		},
+	})
+	c.Assert(err, qt.IsNil)
+	c.Assert(attrs, qt.DeepEquals, &types.ObjectAttrs{
+		Object:      "dst",
+		Version:     "v1",
+		ETag:        `"dst"`,
+		ContentType: "text/plain",
+		Size:        2*minPartSize + 3,
+	})
+
+	c.Assert(valOrZero(copies[1].CopySource), qt.Equals, "bucket/a")
+	c.Assert(valOrZero(copies[1].CopySourceRange), qt.Equals, fmt.Sprintf("bytes=10-%d", minPartSize+9))
+	c.Assert(valOrZero(copies[1].CopySourceIfMatch), qt.Equals, `"a"`)
+	c.Assert(valOrZero(copies[2].CopySource), qt.Equals, "other/b")
+	c.Assert(copies[2].CopySourceRange, qt.IsNil)
+	c.Assert(valOrZero(copies[2].CopySourceIfMatch), qt.Equals, `"b"`)
+	c.Assert(valOrZero(copies[3].CopySource), qt.Equals, "bucket/c?versionId=v2")
+	c.Assert(valOrZero(copies[3].CopySourceRange), qt.Equals, "bytes=0-2")
+	c.Assert(copies[3].CopySourceIfMatch, qt.IsNil)
+}
+
+func TestCopyParts_Aborts(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"},
+		WithUploadOptions(UploadOptions{Concurrency: 1})).(*bucket)
+
+	client.EXPECT().CreateMultipartUpload(gomock.Any(), gomock.Any()).Return(
+		&s3.CreateMultipartUploadOutput{UploadId: ptr("upload")}, nil)
+	client.EXPECT().UploadPartCopy(gomock.Any(), gomock.Any()).Return(
+		nil, &smithy.GenericAPIError{Code: "PreconditionFailed"})
+
+	aborted := make(chan struct{})
+	client.EXPECT().AbortMultipartUpload(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, _ *s3.AbortMultipartUploadInput, _ ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+			close(aborted)
+			return &s3.AbortMultipartUploadOutput{}, nil
+		})
+
+	_, err := bkt.CopyParts(types.CopyPartsData{
+		Ctx:    context.Background(),
+		Object: "dst",
+		Parts: []types.CopyPart{
+			{Object: "a", Length: minPartSize, ETag: `"stale"`},
+			{Object: "b", Length: 1},
+		},
+	})
+	c.Assert(err, qt.ErrorMatches, "copy part 1 from a: .*")
+	waitFor(c, aborted)
+}
+
+func TestCopyParts_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		parts []types.CopyPart
+	}{
+		{name: "no_parts"},
+		{name: "too_many", parts: make([]types.CopyPart, maxParts+1)},
+		{name: "negative", parts: []types.CopyPart{{Object: "a", Offset: -1, Length: 1}}},
+		{name: "offset_without_length", parts: []types.CopyPart{{Object: "a", Offset: 1}}},
+		{name: "too_small", parts: []types.CopyPart{{Object: "a", Length: 1}, {Object: "b", Length: 1}}},
+		{name: "too_large", parts: []types.CopyPart{{Object: "a", Length: maxPartSize + 1}}},
+		{name: "other_provider", parts: []types.CopyPart{{Bucket: otherBucket{}, Object: "a", Length: 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			ctrl := gomock.NewController(c)
+			bkt := NewBucketWithClient(NewMocks3Client(ctrl), &config.Bucket{CloudName: "bucket"}).(*bucket)
+
+			_, err := bkt.CopyParts(types.CopyPartsData{Ctx: context.Background(), Object: "dst", Parts: tt.parts})
+			c.Assert(err, qt.ErrorIs, types.ErrInvalidArgument)
+		})
+	}
+}
+
+func withCopyPartSize(c *qt.C, n int64) {
+	orig := copyPartSize
+	copyPartSize = n
+	c.Cleanup(func() { copyPartSize = orig })
+}
+
+// otherBucket is a bucket implementation from another provider.
+type otherBucket struct{ types.BucketImpl }
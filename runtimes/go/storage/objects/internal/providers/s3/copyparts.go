@@ -0,0 +1,205 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"golang.org/x/sync/errgroup"
+
+	"encore.dev/storage/objects/internal/types"
+)
+
+// maxPartSize is the maximum size of a part in a multipart upload.
+const maxPartSize = 5 * 1024 * 1024 * 1024
+
+var _ types.PartCopier = (*bucket)(nil)
+
+// CopyParts creates an object by concatenating byte ranges of existing
+// objects in order. The parts are copied within S3 using UploadPartCopy,
+// so large objects can be concatenated without downloading them.
+//
+// Every part except the last must be at least 5 MiB, and at most 5 GiB.
+// Objects copied whole are looked up first to determine their size.
+func (b *bucket) CopyParts(data types.CopyPartsData) (_ *types.ObjectAttrs, err error) {
+	ctx, key, attrs, parts := data.Ctx, string(data.Object), data.Attrs, data.Parts
+	switch {
+	case len(parts) == 0:
+		return nil, fmt.Errorf("%w: no parts to copy", types.ErrInvalidArgument)
+	case len(parts) > maxParts:
+		return nil, fmt.Errorf("%w: %d parts exceeds the S3 maximum of %d", types.ErrInvalidArgument, len(parts), maxParts)
+	}
+	if err := validateKey(key, b.rejectControlChars); err != nil {
+		return nil, err
+	}
+	if err := b.uploadOpts.Encryption.validate(); err != nil {
+		return nil, err
+	}
+	if err := b.uploadOpts.validateStorageClass(); err != nil {
+		return nil, err
+	}
+	if err := b.uploadOpts.validateACL(); err != nil {
+		return nil, err
+	}
+	if err := validateTags(attrs.Tags); err != nil {
+		return nil, err
+	}
+
+	sources, size, err := b.copyPartSources(ctx, parts)
+	if err != nil {
+		return nil, err
+	}
+
+	create := &s3.CreateMultipartUploadInput{
+		Bucket:          &b.cfg.CloudName,
+		Key:             &key,
+		ContentType:     ptrOrNil(attrs.ContentType),
+		CacheControl:    ptrOrNil(attrs.CacheControl),
+		ContentEncoding: ptrOrNil(attrs.ContentEncoding),
+		Metadata:        userMetadata(attrs.Metadata),
+		Tagging:         tagging(attrs.Tags),
+		StorageClass:    s3types.StorageClass(b.uploadOpts.StorageClass),
+		ACL:             s3types.ObjectCannedACL(b.uploadOpts.ACL),
+	}
+	b.uploadOpts.Encryption.setCreate(create)
+	resp, err := b.client.CreateMultipartUpload(ctx, create)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	uploadID := valOrZero(resp.UploadId)
+
+	defer func() {
+		if err != nil {
+			go abortMultipart(b.client, b.cfg.CloudName, &key, uploadID)
+		}
+	}()
+
+	var (
+		mu        sync.Mutex
+		completed = make(map[int32]s3types.CompletedPart, len(sources))
+	)
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(b.uploadOpts.concurrency())
+	for i, src := range sources {
+		in := &s3.UploadPartCopyInput{
+			Bucket:            &b.cfg.CloudName,
+			Key:               &key,
+			UploadId:          &uploadID,
+			PartNumber:        ptr(int32(i + 1)),
+			CopySource:        ptr(copySource(src.bucket.cfg.CloudName, string(src.part.Object), src.part.Version)),
+			CopySourceRange:   src.rangeHeader(),
+			CopySourceIfMatch: ptrOrNil(src.part.ETag),
+		}
+		b.uploadOpts.Encryption.setPartCopy(in)
+		src.bucket.uploadOpts.Encryption.setPartCopySource(in)
+
+		g.Go(func() error {
+			if err := gctx.Err(); err != nil {
+				return err // another part failed; don't start copying more
+			}
+			resp, err := withRetry(gctx, b.uploadOpts, func() (*s3.UploadPartCopyOutput, error) {
+				return b.client.UploadPartCopy(gctx, in)
+			})
+			if err != nil {
+				return fmt.Errorf("copy part %d from %s: %w", i+1, src.part.Object, mapErr(err))
+			}
+			part := s3types.CompletedPart{PartNumber: in.PartNumber}
+			if res := resp.CopyPartResult; res != nil {
+				part.ETag = res.ETag
+			}
+			mu.Lock()
+			completed[*in.PartNumber] = part
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err = g.Wait(); err != nil {
+		return nil, err
+	}
+
+	complete, err := withRetry(ctx, b.uploadOpts, func() (*s3.CompleteMultipartUploadOutput, error) {
+		return b.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:   &b.cfg.CloudName,
+			Key:      &key,
+			UploadId: &uploadID,
+			MultipartUpload: &s3types.CompletedMultipartUpload{
+				Parts: sortedParts(completed),
+			},
+		})
+	})
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	return &types.ObjectAttrs{
+		Object:      types.CloudObject(key),
+		Version:     valOrZero(complete.VersionId),
+		ETag:        valOrZero(complete.ETag),
+		ContentType: attrs.ContentType,
+		Size:        size,
+	}, nil
+}
+
+// copyPartSource is a part to copy, with its source bucket and size resolved.
+type copyPartSource struct {
+	part   types.CopyPart
+	bucket *bucket
+	size   int64
+}
+
+// rangeHeader returns the CopySourceRange of the part,
+// or nil to copy the whole object.
+func (s copyPartSource) rangeHeader() *string {
+	if s.part.Length == 0 {
+		return nil
+	}
+	return rangeHeader(s.part.Offset, s.part.Length)
+}
+
+// copyPartSources validates the parts, looking up the size of objects copied whole.
+// It returns the parts along with the total size of the resulting object.
+func (b *bucket) copyPartSources(ctx context.Context, parts []types.CopyPart) (sources []copyPartSource, size int64, err error) {
+	sources = make([]copyPartSource, len(parts))
+	for i, p := range parts {
+		src := b
+		if p.Bucket != nil {
+			s, ok := p.Bucket.(*bucket)
+			if !ok {
+				return nil, 0, fmt.Errorf("%w: cannot copy objects between providers", types.ErrInvalidArgument)
+			}
+			src = s
+		}
+
+		partSize := p.Length
+		switch {
+		case p.Offset < 0 || p.Length < 0:
+			return nil, 0, fmt.Errorf("%w: part %d has a negative byte range", types.ErrInvalidArgument, i+1)
+		case p.Length == 0 && p.Offset != 0:
+			return nil, 0, fmt.Errorf("%w: part %d has an offset but no length", types.ErrInvalidArgument, i+1)
+		case p.Length == 0:
+			// The whole object is copied; look up its size.
+			attrs, err := src.Attrs(types.AttrsData{Ctx: ctx, Object: p.Object, Version: p.Version})
+			if err != nil {
+				return nil, 0, fmt.Errorf("part %d: %w", i+1, err)
+			}
+			partSize = attrs.Size
+
+			// Make sure the object isn't replaced before it's copied.
+			if p.ETag == "" {
+				p.ETag = attrs.ETag
+			}
+		}
+
+		if partSize > maxPartSize {
+			return nil, 0, fmt.Errorf("%w: part %d is %d bytes, above the S3 maximum of %d",
+				types.ErrInvalidArgument, i+1, partSize, maxPartSize)
+		} else if partSize < minPartSize && i < len(parts)-1 {
+			return nil, 0, fmt.Errorf("%w: part %d is %d bytes, below the S3 minimum of %d",
+				types.ErrInvalidArgument, i+1, partSize, minPartSize)
+		}
+		sources[i] = copyPartSource{part: p, bucket: src, size: partSize}
+		size += partSize
+	}
+	return sources, size, nil
+}
@@ -0,0 +1,140 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsConfig "github.com/aws/aws-sdk-go-v2/config"
+	awsCreds "github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+
+	"encore.dev/appruntime/exported/config"
+)
+
+// ConfigOption configures how LoadConfig obtains credentials.
+type ConfigOption func(*configOptions)
+
+type configOptions struct {
+	region string
+
+	// At most one source of credentials may be set.
+	static      *aws.Credentials
+	profile     string
+	webIdentity *webIdentity
+
+	assumeRoleARN string
+}
+
+type webIdentity struct {
+	roleARN, tokenFile string
+}
+
+// WithStaticCredentials uses the given access key, for example
+// for an S3-compatible store that doesn't support IAM.
+// The session token is only needed for temporary credentials.
+func WithStaticCredentials(accessKeyID, secretAccessKey, sessionToken string) ConfigOption {
+	return func(o *configOptions) {
+		o.static = &aws.Credentials{
+			AccessKeyID:     accessKeyID,
+			SecretAccessKey: secretAccessKey,
+			SessionToken:    sessionToken,
+			Source:          awsCreds.StaticCredentialsName,
+		}
+	}
+}
+
+// WithProfile uses the named profile from the shared config
+// and credentials files, instead of the default profile.
+func WithProfile(name string) ConfigOption {
+	return func(o *configOptions) { o.profile = name }
+}
+
+// WithWebIdentity assumes the role by exchanging the web identity token
+// in tokenFile, as used by IAM Roles for Service Accounts (IRSA) on EKS.
+//
+// When running with IRSA the default credential chain already does this
+// based on the AWS_ROLE_ARN and AWS_WEB_IDENTITY_TOKEN_FILE environment
+// variables; this option is for configuring it explicitly.
+func WithWebIdentity(roleARN, tokenFile string) ConfigOption {
+	return func(o *configOptions) { o.webIdentity = &webIdentity{roleARN: roleARN, tokenFile: tokenFile} }
+}
+
+// WithAssumeRole assumes the role with the given ARN, using the credentials
+// from the other options (or the default credential chain) to call STS.
+// The temporary credentials are refreshed automatically before they expire.
+func WithAssumeRole(roleARN string) ConfigOption {
+	return func(o *configOptions) { o.assumeRoleARN = roleARN }
+}
+
+// WithRegion sets the region, overriding the region from
+// the environment and shared config.
+func WithRegion(region string) ConfigOption {
+	return func(o *configOptions) { o.region = region }
+}
+
+// credentialOptions returns the options for obtaining the credentials
+// of a provider configured in the runtime config.
+func credentialOptions(cfg *config.S3BucketProvider) []ConfigOption {
+	var opts []ConfigOption
+	if cfg.AccessKeyID != nil && cfg.SecretAccessKey != nil {
+		opts = append(opts, WithStaticCredentials(*cfg.AccessKeyID, *cfg.SecretAccessKey, ""))
+	}
+	if cfg.Profile != "" {
+		opts = append(opts, WithProfile(cfg.Profile))
+	}
+	if w := cfg.WebIdentity; w != nil {
+		opts = append(opts, WithWebIdentity(w.RoleARN, w.TokenFile))
+	}
+	if cfg.AssumeRoleARN != "" {
+		opts = append(opts, WithAssumeRole(cfg.AssumeRoleARN))
+	}
+	return opts
+}
+
+// LoadConfig loads the AWS config for creating an S3 client,
+// for use with NewBucketWithClient. Without options it's equivalent
+// to the config Encore uses for buckets without explicit credentials.
+func LoadConfig(ctx context.Context, opts ...ConfigOption) (aws.Config, error) {
+	var o configOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	sources := 0
+	for _, set := range []bool{o.static != nil, o.profile != "", o.webIdentity != nil} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return aws.Config{}, errors.New("s3: at most one of static credentials, a profile and a web identity can be used")
+	}
+
+	var loadOpts []func(*awsConfig.LoadOptions) error
+	if o.region != "" {
+		loadOpts = append(loadOpts, awsConfig.WithRegion(o.region))
+	}
+	if o.static != nil {
+		loadOpts = append(loadOpts, awsConfig.WithCredentialsProvider(awsCreds.StaticCredentialsProvider{Value: *o.static}))
+	}
+	if o.profile != "" {
+		loadOpts = append(loadOpts, awsConfig.WithSharedConfigProfile(o.profile))
+	}
+	cfg, err := awsConfig.LoadDefaultConfig(ctx, loadOpts...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("s3: load AWS config: %w", err)
+	}
+
+	if w := o.webIdentity; w != nil {
+		provider := stscreds.NewWebIdentityRoleProvider(sts.NewFromConfig(cfg), w.roleARN, stscreds.IdentityTokenFile(w.tokenFile))
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+	if o.assumeRoleARN != "" {
+		provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), o.assumeRoleARN)
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,112 @@
+package s3
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	qt "github.com/frankban/quicktest"
+
+	"encore.dev/appruntime/exported/config"
+)
+
+// isolateAWSEnv makes the AWS config independent of the environment
+// and shared config files of the user running the tests.
+func isolateAWSEnv(c *qt.C) string {
+	dir := c.TempDir()
+	for _, name := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_PROFILE", "AWS_ROLE_ARN", "AWS_WEB_IDENTITY_TOKEN_FILE"} {
+		c.Setenv(name, "")
+	}
+	c.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	c.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	return dir
+}
+
+func TestLoadConfig_Static(t *testing.T) {
+	c := qt.New(t)
+	isolateAWSEnv(c)
+
+	cfg, err := LoadConfig(context.Background(), WithRegion("eu-west-1"), WithStaticCredentials("AKID", "secret", ""))
+	c.Assert(err, qt.IsNil)
+	c.Assert(cfg.Region, qt.Equals, "eu-west-1")
+	creds, err := cfg.Credentials.Retrieve(context.Background())
+	c.Assert(err, qt.IsNil)
+	c.Assert(creds.AccessKeyID, qt.Equals, "AKID")
+	c.Assert(creds.SecretAccessKey, qt.Equals, "secret")
+}
+
+func TestLoadConfig_Profile(t *testing.T) {
+	c := qt.New(t)
+	dir := isolateAWSEnv(c)
+
+	err := os.WriteFile(filepath.Join(dir, "credentials"), []byte("[other]\naws_access_key_id = OTHER\naws_secret_access_key = othersecret\n"), 0600)
+	c.Assert(err, qt.IsNil)
+	err = os.WriteFile(filepath.Join(dir, "config"), []byte("[profile other]\nregion = ap-south-1\n"), 0600)
+	c.Assert(err, qt.IsNil)
+
+	cfg, err := LoadConfig(context.Background(), WithProfile("other"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(cfg.Region, qt.Equals, "ap-south-1")
+	creds, err := cfg.Credentials.Retrieve(context.Background())
+	c.Assert(err, qt.IsNil)
+	c.Assert(creds.AccessKeyID, qt.Equals, "OTHER")
+
+	_, err = LoadConfig(context.Background(), WithProfile("missing"))
+	c.Assert(err, qt.ErrorMatches, "s3: load AWS config: .*")
+}
+
+func TestLoadConfig_AssumeRole(t *testing.T) {
+	c := qt.New(t)
+	isolateAWSEnv(c)
+
+	cfg, err := LoadConfig(context.Background(), WithRegion("us-east-1"),
+		WithStaticCredentials("AKID", "secret", ""),
+		WithAssumeRole("arn:aws:iam::123456789012:role/uploader"))
+	c.Assert(err, qt.IsNil)
+	cache, ok := cfg.Credentials.(*aws.CredentialsCache)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(cache.IsCredentialsProvider(&stscreds.AssumeRoleProvider{}), qt.IsTrue)
+
+	cfg, err = LoadConfig(context.Background(), WithRegion("us-east-1"),
+		WithWebIdentity("arn:aws:iam::123456789012:role/uploader", "/var/run/token"))
+	c.Assert(err, qt.IsNil)
+	cache, ok = cfg.Credentials.(*aws.CredentialsCache)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(cache.IsCredentialsProvider(&stscreds.WebIdentityRoleProvider{}), qt.IsTrue)
+}
+
+func TestLoadConfig_ConflictingSources(t *testing.T) {
+	c := qt.New(t)
+	isolateAWSEnv(c)
+
+	_, err := LoadConfig(context.Background(), WithStaticCredentials("AKID", "secret", ""), WithProfile("other"))
+	c.Assert(err, qt.ErrorMatches, "s3: at most one of .*")
+}
+
+func TestManager_NewBucket_Credentials(t *testing.T) {
+	c := qt.New(t)
+	dir := isolateAWSEnv(c)
+	err := os.WriteFile(filepath.Join(dir, "credentials"), []byte("[other]\naws_access_key_id = OTHER\naws_secret_access_key = othersecret\n"), 0600)
+	c.Assert(err, qt.IsNil)
+
+	mgr := NewManager(context.Background(), &config.Runtime{}, nil, nil)
+	credentials := func(cfg *config.S3BucketProvider) aws.CredentialsProvider {
+		cfg.Region = "us-east-1"
+		b := mgr.NewBucket(&config.BucketProvider{S3: cfg}, &config.Bucket{CloudName: "bucket"}).(*bucket)
+		return b.client.(*s3.Client).Options().Credentials
+	}
+
+	creds, err := credentials(&config.S3BucketProvider{Profile: "other"}).Retrieve(context.Background())
+	c.Assert(err, qt.IsNil)
+	c.Assert(creds.AccessKeyID, qt.Equals, "OTHER")
+
+	provider := credentials(&config.S3BucketProvider{Profile: "other", AssumeRoleARN: "arn:aws:iam::123456789012:role/app"})
+	c.Assert(provider.(*aws.CredentialsCache).IsCredentialsProvider(&stscreds.AssumeRoleProvider{}), qt.IsTrue)
+
+	provider = credentials(&config.S3BucketProvider{WebIdentity: &config.S3WebIdentity{RoleARN: "arn:aws:iam::123456789012:role/app", TokenFile: "token"}})
+	c.Assert(provider.(*aws.CredentialsCache).IsCredentialsProvider(&stscreds.WebIdentityRoleProvider{}), qt.IsTrue)
+}
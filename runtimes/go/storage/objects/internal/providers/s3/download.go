@@ -0,0 +1,251 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+
+	"encore.dev/storage/objects/internal/types"
+)
+
+// DownloadOptions configures how the bucket downloads objects.
+type DownloadOptions struct {
+	// Concurrency is the number of chunks of an object to download in parallel.
+	// If less than 2, objects are downloaded using a single request.
+	Concurrency int
+
+	// ChunkSize is the size of each chunk when downloading in parallel.
+	// If zero, defaultChunkSize is used.
+	ChunkSize int64
+}
+
+// defaultChunkSize is the default chunk size for parallel downloads.
+const defaultChunkSize = 8 * 1024 * 1024
+
+func (o DownloadOptions) chunkSize() int64 {
+	if o.ChunkSize > 0 {
+		return o.ChunkSize
+	}
+	return defaultChunkSize
+}
+
+// rangeHeader returns the value of the Range header for downloading
+// length bytes starting at offset. A zero length means the rest of the object.
+func rangeHeader(offset, length int64) *string {
+	switch {
+	case offset == 0 && length == 0:
+		return nil
+	case length == 0:
+		return ptr(fmt.Sprintf("bytes=%d-", offset))
+	default:
+		return ptr(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	}
+}
+
+// parseContentRange returns the total size of the object
+// from a Content-Range header like "bytes 0-99/1000".
+func parseContentRange(val string) (total int64, err error) {
+	idx := strings.LastIndexByte(val, '/')
+	if idx < 0 {
+		return 0, fmt.Errorf("invalid content range %q", val)
+	}
+	total, err = strconv.ParseInt(val[idx+1:], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid content range %q: %w", val, err)
+	}
+	return total, nil
+}
+
+// parallelDownload downloads an object by fetching chunks concurrently,
+// returning a reader that yields the chunks in order.
+func (b *bucket) parallelDownload(data types.DownloadData) (types.Downloader, error) {
+	chunkSize := b.downloadOpts.chunkSize()
+	start := data.Offset
+
+	// Fetch the first chunk, which also tells us the size of the object.
+	firstLen := chunkSize
+	if data.Length > 0 {
+		firstLen = min(firstLen, data.Length)
+	}
+	first, err := b.getObject(data, start, firstLen, nil)
+	if err != nil {
+		// Empty objects can't satisfy any range; fall back to a plain request.
+		if start == 0 && isInvalidRange(err) {
+			return b.getObjectBody(data)
+		}
+		return nil, err
+	}
+	total, err := parseContentRange(valOrZero(first.ContentRange))
+	if err != nil {
+		_ = first.Body.Close()
+		return nil, err
+	}
+	end := total
+	if data.Length > 0 {
+		end = min(end, start+data.Length)
+	}
+
+	ctx, cancel := context.WithCancel(data.Ctx)
+	r := &parallelReader{
+		cancel: cancel,
+		curr:   first.Body,
+		chunks: make(chan chan chunkResult, b.downloadOpts.Concurrency-1),
+	}
+
+	// Pin the remaining chunks to the version we started downloading,
+	// which already satisfied the conditions.
+	data.Ctx = ctx
+	data.Conditions = types.DownloadConditions{}
+	etag := first.ETag
+	go func() {
+		defer close(r.chunks)
+		for off := start + firstLen; off < end; off += chunkSize {
+			ch := make(chan chunkResult, 1)
+			select {
+			case r.chunks <- ch:
+			case <-ctx.Done():
+				return
+			}
+
+			go func(off, n int64) {
+				resp, err := b.getObject(data, off, n, etag)
+				if err != nil {
+					ch <- chunkResult{err: err}
+					return
+				}
+				defer resp.Body.Close()
+				buf, err := io.ReadAll(resp.Body)
+				ch <- chunkResult{data: buf, err: err}
+			}(off, min(chunkSize, end-off))
+		}
+	}()
+
+	return r, nil
+}
+
+var _ types.ResultDownloader = (*bucket)(nil)
+
+// DownloadWithResult downloads an object along with its attributes from
+// the GetObject response, without a separate HeadObject request for them.
+// Unlike Download the object is always downloaded with a single request,
+// regardless of the bucket's download concurrency.
+func (b *bucket) DownloadWithResult(data types.DownloadData) (*types.DownloadResult, error) {
+	var op *operation
+	if b.instrumented() {
+		data.Ctx, op = b.startOp(data.Ctx, "Download", data.Object)
+	}
+	resp, err := b.getObject(data, data.Offset, data.Length, nil)
+	if err != nil {
+		if op != nil {
+			op.end(err, 0)
+		}
+		return nil, err
+	}
+
+	var body types.Downloader = resp.Body
+	if op != nil {
+		body = &tracedDownloader{Downloader: resp.Body, op: op}
+	}
+	return &types.DownloadResult{
+		Body: body,
+		Attrs: &types.ObjectAttrs{
+			Object:       data.Object,
+			Version:      valOrZero(resp.VersionId),
+			ContentType:  valOrZero(resp.ContentType),
+			Size:         valOrZero(resp.ContentLength),
+			ETag:         valOrZero(resp.ETag),
+			LastModified: valOrZero(resp.LastModified),
+			Metadata:     resp.Metadata,
+		},
+	}, nil
+}
+
+// getObjectBody downloads the requested range of an object using a single request.
+func (b *bucket) getObjectBody(data types.DownloadData) (types.Downloader, error) {
+	resp, err := b.getObject(data, data.Offset, data.Length, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (b *bucket) getObject(data types.DownloadData, offset, length int64, ifMatch *string) (*s3.GetObjectOutput, error) {
+	object := string(data.Object)
+	in := &s3.GetObjectInput{
+		Bucket:       &b.cfg.CloudName,
+		Key:          &object,
+		VersionId:    ptrOrNil(data.Version),
+		Range:        rangeHeader(offset, length),
+		IfMatch:      ifMatch,
+		RequestPayer: b.requestPayer,
+
+		IfNoneMatch:     ptrOrNil(data.Conditions.IfNoneMatch),
+		IfModifiedSince: ptrOrNil(data.Conditions.IfModifiedSince),
+	}
+	b.uploadOpts.Encryption.setGet(in)
+	resp, err := withRetry(data.Ctx, b.retry, func() (*s3.GetObjectOutput, error) {
+		return b.client.GetObject(data.Ctx, in)
+	})
+	return resp, mapErr(err)
+}
+
+func isInvalidRange(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "InvalidRange"
+}
+
+type chunkResult struct {
+	data []byte
+	err  error
+}
+
+// parallelReader reads chunks of an object that are downloaded concurrently.
+type parallelReader struct {
+	cancel context.CancelFunc
+	chunks chan chan chunkResult // in order
+	curr   io.ReadCloser         // the chunk being read
+	err    error
+}
+
+func (r *parallelReader) Read(p []byte) (int, error) {
+	for r.err == nil {
+		n, err := r.curr.Read(p)
+		if err == io.EOF {
+			_ = r.curr.Close()
+			err = r.next()
+		}
+		if err != nil {
+			r.err = err
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+	return 0, r.err
+}
+
+// next advances to the next chunk, or returns io.EOF if there are none.
+func (r *parallelReader) next() error {
+	ch, ok := <-r.chunks
+	if !ok {
+		return io.EOF
+	}
+	res := <-ch
+	if res.err != nil {
+		return res.err
+	}
+	r.curr = io.NopCloser(bytes.NewReader(res.data))
+	return nil
+}
+
+func (r *parallelReader) Close() error {
+	r.cancel()
+	return r.curr.Close()
+}
@@ -0,0 +1,109 @@
+package s3
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"encore.dev/storage/objects/internal/types"
+)
+
+// DryRunRequests are the requests an upload would have sent to S3
+// when UploadOptions.DryRun is set. Request bodies are not retained.
+type DryRunRequests struct {
+	// PutObject is set if the object would have been uploaded in a single request.
+	PutObject *s3.PutObjectInput
+
+	// CreateMultipartUpload, UploadParts and CompleteMultipartUpload are set
+	// if the object would have been uploaded using a multipart upload.
+	// UploadParts is ordered by part number.
+	CreateMultipartUpload   *s3.CreateMultipartUploadInput
+	UploadParts             []*s3.UploadPartInput
+	CompleteMultipartUpload *s3.CompleteMultipartUploadInput
+}
+
+// DryRunRequestsOf returns the requests recorded by an upload made
+// to a bucket with UploadOptions.DryRun set. It reports false if u
+// is not a dry-run upload from this provider.
+//
+// The requests are complete once the upload's Complete method has returned.
+func DryRunRequestsOf(u types.Uploader) (*DryRunRequests, bool) {
+	if gz, ok := u.(*gzipUploader); ok {
+		u = gz.uploader
+	}
+	up, ok := u.(*uploader)
+	if !ok {
+		return nil, false
+	}
+	dr, ok := up.client.(*dryRunClient)
+	if !ok {
+		return nil, false
+	}
+	return dr.requests(), true
+}
+
+// dryRunClient records upload requests instead of sending them.
+// Other requests are not expected during an upload and panic,
+// with the exception of the cleanup requests, which do nothing.
+type dryRunClient struct {
+	s3Client // nil
+
+	mu  sync.Mutex
+	req DryRunRequests
+}
+
+func (c *dryRunClient) requests() *DryRunRequests {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	req := c.req
+	req.UploadParts = slices.Clone(req.UploadParts)
+	slices.SortFunc(req.UploadParts, func(a, b *s3.UploadPartInput) int {
+		return cmp.Compare(*a.PartNumber, *b.PartNumber)
+	})
+	return &req
+}
+
+func (c *dryRunClient) PutObject(ctx context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	rec := *in
+	rec.Body = nil
+	c.mu.Lock()
+	c.req.PutObject = &rec
+	c.mu.Unlock()
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (c *dryRunClient) CreateMultipartUpload(ctx context.Context, in *s3.CreateMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	rec := *in
+	c.mu.Lock()
+	c.req.CreateMultipartUpload = &rec
+	c.mu.Unlock()
+	return &s3.CreateMultipartUploadOutput{UploadId: ptr("dry-run")}, nil
+}
+
+func (c *dryRunClient) UploadPart(ctx context.Context, in *s3.UploadPartInput, _ ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	rec := *in
+	rec.Body = nil
+	c.mu.Lock()
+	c.req.UploadParts = append(c.req.UploadParts, &rec)
+	c.mu.Unlock()
+	return &s3.UploadPartOutput{}, nil
+}
+
+func (c *dryRunClient) CompleteMultipartUpload(ctx context.Context, in *s3.CompleteMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	rec := *in
+	c.mu.Lock()
+	c.req.CompleteMultipartUpload = &rec
+	c.mu.Unlock()
+	return &s3.CompleteMultipartUploadOutput{}, nil
+}
+
+func (c *dryRunClient) AbortMultipartUpload(ctx context.Context, in *s3.AbortMultipartUploadInput, _ ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func (c *dryRunClient) DeleteObject(ctx context.Context, in *s3.DeleteObjectInput, _ ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	return &s3.DeleteObjectOutput{}, nil
+}
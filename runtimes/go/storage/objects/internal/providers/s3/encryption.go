@@ -0,0 +1,142 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/storage/objects/internal/types"
+)
+
+// EncryptionMode is the kind of server-side encryption to use.
+type EncryptionMode int
+
+const (
+	// EncryptionDefault uses the bucket's default encryption settings.
+	EncryptionDefault EncryptionMode = iota
+
+	// EncryptionS3 uses S3-managed keys (SSE-S3).
+	EncryptionS3
+
+	// EncryptionKMS uses keys stored in AWS KMS (SSE-KMS).
+	EncryptionKMS
+
+	// EncryptionCustomer uses a key provided by the customer (SSE-C).
+	// The key must be provided on every request that reads or writes
+	// object data, including each part of a multipart upload.
+	EncryptionCustomer
+)
+
+// Encryption describes the server-side encryption settings for objects.
+type Encryption struct {
+	Mode EncryptionMode
+
+	// KMSKeyID is the ID or ARN of the KMS key to use with EncryptionKMS.
+	// If empty, the AWS managed key for S3 is used.
+	KMSKeyID string
+
+	// CustomerKey is the 256-bit AES key to use with EncryptionCustomer.
+	CustomerKey []byte
+}
+
+// encryptionFromConfig returns the encryption settings
+// configured for a provider in the runtime config.
+func encryptionFromConfig(cfg *config.S3Encryption) Encryption {
+	enc := Encryption{KMSKeyID: cfg.KMSKeyID, CustomerKey: cfg.CustomerKey}
+	switch cfg.Mode {
+	case "s3":
+		enc.Mode = EncryptionS3
+	case "kms":
+		enc.Mode = EncryptionKMS
+	case "customer":
+		enc.Mode = EncryptionCustomer
+	default:
+		panic(fmt.Sprintf("s3: unknown encryption mode %q", cfg.Mode))
+	}
+	return enc
+}
+
+// sseCustomerAlgorithm is the only algorithm S3 supports for SSE-C.
+const sseCustomerAlgorithm = "AES256"
+
+func (e Encryption) validate() error {
+	switch e.Mode {
+	case EncryptionDefault, EncryptionS3, EncryptionKMS:
+		return nil
+	case EncryptionCustomer:
+		if len(e.CustomerKey) != 32 {
+			return fmt.Errorf("%w: SSE-C key must be 32 bytes, got %d",
+				types.ErrInvalidArgument, len(e.CustomerKey))
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown encryption mode %d", types.ErrInvalidArgument, e.Mode)
+	}
+}
+
+// customerKey returns the SSE-C request fields, or nils if SSE-C isn't used.
+func (e Encryption) customerKey() (algorithm, key, keyMD5 *string) {
+	if e.Mode != EncryptionCustomer {
+		return nil, nil, nil
+	}
+	sum := md5.Sum(e.CustomerKey)
+	return ptr(sseCustomerAlgorithm),
+		ptr(base64.StdEncoding.EncodeToString(e.CustomerKey)),
+		ptr(base64.StdEncoding.EncodeToString(sum[:]))
+}
+
+// serverSide returns the SSE-S3 and SSE-KMS request fields.
+func (e Encryption) serverSide() (sse s3types.ServerSideEncryption, kmsKeyID *string) {
+	switch e.Mode {
+	case EncryptionS3:
+		return s3types.ServerSideEncryptionAes256, nil
+	case EncryptionKMS:
+		return s3types.ServerSideEncryptionAwsKms, ptrOrNil(e.KMSKeyID)
+	default:
+		return "", nil
+	}
+}
+
+func (e Encryption) setPut(in *s3.PutObjectInput) {
+	in.ServerSideEncryption, in.SSEKMSKeyId = e.serverSide()
+	in.SSECustomerAlgorithm, in.SSECustomerKey, in.SSECustomerKeyMD5 = e.customerKey()
+}
+
+func (e Encryption) setCreate(in *s3.CreateMultipartUploadInput) {
+	in.ServerSideEncryption, in.SSEKMSKeyId = e.serverSide()
+	in.SSECustomerAlgorithm, in.SSECustomerKey, in.SSECustomerKeyMD5 = e.customerKey()
+}
+
+func (e Encryption) setPart(in *s3.UploadPartInput) {
+	in.SSECustomerAlgorithm, in.SSECustomerKey, in.SSECustomerKeyMD5 = e.customerKey()
+}
+
+func (e Encryption) setGet(in *s3.GetObjectInput) {
+	in.SSECustomerAlgorithm, in.SSECustomerKey, in.SSECustomerKeyMD5 = e.customerKey()
+}
+
+func (e Encryption) setHead(in *s3.HeadObjectInput) {
+	in.SSECustomerAlgorithm, in.SSECustomerKey, in.SSECustomerKeyMD5 = e.customerKey()
+}
+
+func (e Encryption) setCopy(in *s3.CopyObjectInput) {
+	in.ServerSideEncryption, in.SSEKMSKeyId = e.serverSide()
+	in.SSECustomerAlgorithm, in.SSECustomerKey, in.SSECustomerKeyMD5 = e.customerKey()
+}
+
+func (e Encryption) setPartCopy(in *s3.UploadPartCopyInput) {
+	in.SSECustomerAlgorithm, in.SSECustomerKey, in.SSECustomerKeyMD5 = e.customerKey()
+}
+
+// setCopySource sets the SSE-C fields needed to read the source of a copy.
+func (e Encryption) setCopySource(in *s3.CopyObjectInput) {
+	in.CopySourceSSECustomerAlgorithm, in.CopySourceSSECustomerKey, in.CopySourceSSECustomerKeyMD5 = e.customerKey()
+}
+
+func (e Encryption) setPartCopySource(in *s3.UploadPartCopyInput) {
+	in.CopySourceSSECustomerAlgorithm, in.CopySourceSSECustomerKey, in.CopySourceSSECustomerKeyMD5 = e.customerKey()
+}
@@ -0,0 +1,40 @@
+package s3
+
+import (
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ClientOptions configures how NewClient addresses S3.
+type ClientOptions struct {
+	// UseAccelerate sends requests through S3 Transfer Acceleration,
+	// which routes them over the AWS network from the nearest edge location.
+	// Acceleration must be enabled on the bucket, and bucket names
+	// must be DNS-compatible and not contain dots.
+	UseAccelerate bool
+
+	// UseDualStack uses dual-stack endpoints, which support IPv6 as well as IPv4.
+	UseDualStack bool
+
+	// UsePathStyle addresses buckets in the path of the URL rather than
+	// in the host name, as required by some S3-compatible stores.
+	// It can't be combined with UseAccelerate.
+	UsePathStyle bool
+}
+
+// NewClient returns an S3 client for cfg, such as one returned by LoadConfig,
+// for use with NewBucketWithClient.
+func NewClient(cfg aws.Config, opts ClientOptions) (*s3.Client, error) {
+	if opts.UseAccelerate && opts.UsePathStyle {
+		return nil, errors.New("s3: transfer acceleration can't be used with path-style addressing")
+	}
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.UseAccelerate = opts.UseAccelerate
+		o.UsePathStyle = opts.UsePathStyle
+		if opts.UseDualStack {
+			o.EndpointOptions.UseDualStackEndpoint = aws.DualStackEndpointStateEnabled
+		}
+	}), nil
+}
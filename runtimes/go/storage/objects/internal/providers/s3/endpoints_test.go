@@ -0,0 +1,49 @@
+package s3
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	qt "github.com/frankban/quicktest"
+)
+
+func TestNewClient(t *testing.T) {
+	c := qt.New(t)
+	cfg := aws.Config{Region: "us-west-2", Credentials: credentials.NewStaticCredentialsProvider("AKID", "secret", "")}
+
+	tests := []struct {
+		name     string
+		opts     ClientOptions
+		wantHost string
+		wantPath string
+	}{
+		{name: "default", wantHost: "bucket.s3.us-west-2.amazonaws.com", wantPath: "/object"},
+		{name: "accelerate", opts: ClientOptions{UseAccelerate: true}, wantHost: "bucket.s3-accelerate.amazonaws.com", wantPath: "/object"},
+		{name: "dualstack", opts: ClientOptions{UseDualStack: true}, wantHost: "bucket.s3.dualstack.us-west-2.amazonaws.com", wantPath: "/object"},
+		{name: "path_style", opts: ClientOptions{UsePathStyle: true}, wantHost: "s3.us-west-2.amazonaws.com", wantPath: "/bucket/object"},
+	}
+	for _, tt := range tests {
+		c.Run(tt.name, func(c *qt.C) {
+			client, err := NewClient(cfg, tt.opts)
+			c.Assert(err, qt.IsNil)
+
+			// Presigning resolves the endpoint without sending a request.
+			req, err := s3.NewPresignClient(client).PresignGetObject(context.Background(), &s3.GetObjectInput{
+				Bucket: ptr("bucket"),
+				Key:    ptr("object"),
+			})
+			c.Assert(err, qt.IsNil)
+			u, err := url.Parse(req.URL)
+			c.Assert(err, qt.IsNil)
+			c.Assert(u.Host, qt.Equals, tt.wantHost)
+			c.Assert(u.Path, qt.Equals, tt.wantPath)
+		})
+	}
+
+	_, err := NewClient(cfg, ClientOptions{UseAccelerate: true, UsePathStyle: true})
+	c.Assert(err, qt.ErrorMatches, "s3: transfer acceleration can't be used with path-style addressing")
+}
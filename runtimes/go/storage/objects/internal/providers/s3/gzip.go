@@ -0,0 +1,81 @@
+package s3
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+
+	"encore.dev/storage/objects/internal/types"
+)
+
+// gzipEncoding is the Content-Encoding of gzip-compressed objects.
+const gzipEncoding = "gzip"
+
+// setGzipEncoding marks the object as gzip-compressed. It fails if
+// the upload already specifies a different content encoding.
+func setGzipEncoding(attrs *types.UploadAttrs) error {
+	if enc := attrs.ContentEncoding; enc != "" && enc != gzipEncoding {
+		return fmt.Errorf("%w: cannot gzip an object with content encoding %q",
+			types.ErrInvalidArgument, enc)
+	}
+	attrs.ContentEncoding = gzipEncoding
+	return nil
+}
+
+// gzipUploader compresses the data written to it before uploading it.
+type gzipUploader struct {
+	*uploader
+	gz *gzip.Writer
+
+	// head is the start of the uncompressed data, while the content type
+	// is being detected from it. detected is set once it has been.
+	head     []byte
+	detected bool
+}
+
+// sniffLen is the number of bytes http.DetectContentType considers.
+const sniffLen = 512
+
+func newGzipUploader(u *uploader) *gzipUploader {
+	return &gzipUploader{uploader: u, gz: gzip.NewWriter(u)}
+}
+
+func (u *gzipUploader) Write(p []byte) (int, error) {
+	if !u.detected {
+		u.head = append(u.head, p[:min(len(p), sniffLen-len(u.head))]...)
+		if len(u.head) == sniffLen {
+			u.detectContentType()
+		}
+	}
+	return u.gz.Write(p)
+}
+
+// ReadFrom compresses the data from r rather than uploading it as is,
+// since the compressed size isn't known up front.
+func (u *gzipUploader) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(writerOnly{u}, r)
+}
+
+// detectContentType detects the content type from the uncompressed data,
+// since the uploader only sees the compressed data. It's called before
+// the first part is sent, as 512 bytes never compress to a full part.
+func (u *gzipUploader) detectContentType() {
+	u.detected = true
+	if u.data.Attrs.ContentType == "" && len(u.head) > 0 {
+		u.data.Attrs.ContentType = http.DetectContentType(u.head)
+	}
+	u.head = nil
+}
+
+// Complete flushes the compressed data and completes the upload.
+// The reported size is that of the compressed object.
+func (u *gzipUploader) Complete() (*types.ObjectAttrs, error) {
+	if !u.detected {
+		u.detectContentType()
+	}
+	if err := u.gz.Close(); err != nil {
+		u.uploader.Abort(err)
+	}
+	return u.uploader.Complete()
+}
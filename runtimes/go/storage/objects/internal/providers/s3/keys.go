@@ -0,0 +1,39 @@
+package s3
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"encore.dev/storage/objects/internal/types"
+)
+
+// maxKeyLength is the maximum length of an S3 object key, in bytes.
+const maxKeyLength = 1024
+
+// validateKey reports whether key is a valid S3 object key:
+// it must be non-empty valid UTF-8 of at most 1024 bytes.
+// If rejectControl is set it must also not contain control characters.
+// Errors wrap types.ErrInvalidArgument.
+//
+// Uploads and copies validate the object keys they write to,
+// so objects can't be written under keys that can't be read back.
+func validateKey(key string, rejectControl bool) error {
+	switch {
+	case key == "":
+		return fmt.Errorf("%w: empty object key", types.ErrInvalidArgument)
+	case len(key) > maxKeyLength:
+		return fmt.Errorf("%w: object key is %d bytes, exceeding the S3 maximum of %d",
+			types.ErrInvalidArgument, len(key), maxKeyLength)
+	case !utf8.ValidString(key):
+		return fmt.Errorf("%w: object key %q is not valid UTF-8", types.ErrInvalidArgument, key)
+	}
+	if rejectControl {
+		if idx := strings.IndexFunc(key, unicode.IsControl); idx >= 0 {
+			return fmt.Errorf("%w: object key %q contains a control character at byte %d",
+				types.ErrInvalidArgument, key, idx)
+		}
+	}
+	return nil
+}
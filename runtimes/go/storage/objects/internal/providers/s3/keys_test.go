@@ -0,0 +1,58 @@
+package s3
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/golang/mock/gomock"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/storage/objects/internal/types"
+)
+
+func TestValidateKey(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		key           string
+		rejectControl bool
+		wantErr       string
+	}{
+		{key: "a/b.txt"},
+		{key: "ünïcode/日本"},
+		{key: strings.Repeat("a", maxKeyLength)},
+		{key: "tab\there"},
+		{key: "", wantErr: ".*empty object key"},
+		{key: strings.Repeat("a", maxKeyLength+1), wantErr: ".*1025 bytes.*"},
+		{key: "bad\xffutf8", wantErr: ".*not valid UTF-8"},
+		{key: "tab\there", rejectControl: true, wantErr: ".*control character at byte 3"},
+		{key: "nul\x00", rejectControl: true, wantErr: ".*control character at byte 3"},
+	}
+	for _, tt := range tests {
+		err := validateKey(tt.key, tt.rejectControl)
+		if tt.wantErr == "" {
+			c.Check(err, qt.IsNil, qt.Commentf("key %q", tt.key))
+		} else {
+			c.Check(err, qt.ErrorMatches, tt.wantErr, qt.Commentf("key %q", tt.key))
+			c.Check(err, qt.ErrorIs, types.ErrInvalidArgument)
+		}
+	}
+}
+
+func TestWriteValidatesKey(t *testing.T) {
+	c := qt.New(t)
+
+	// No calls are expected on the client.
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"}, WithControlCharsRejected())
+
+	_, err := bkt.Upload(types.UploadData{Ctx: context.Background(), Object: ""})
+	c.Assert(err, qt.ErrorIs, types.ErrInvalidArgument)
+	_, err = bkt.Upload(types.UploadData{Ctx: context.Background(), Object: "line\nbreak"})
+	c.Assert(err, qt.ErrorIs, types.ErrInvalidArgument)
+	_, err = bkt.Copy(types.CopyData{Ctx: context.Background(), Object: "src", DstObject: types.CloudObject(strings.Repeat("a", maxKeyLength+1))})
+	c.Assert(err, qt.ErrorIs, types.ErrInvalidArgument)
+}
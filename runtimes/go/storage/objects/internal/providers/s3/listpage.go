@@ -0,0 +1,101 @@
+package s3
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"encore.dev/storage/objects/internal/types"
+)
+
+var _ types.PageLister = (*bucket)(nil)
+
+// listCursor is the decoded form of a cursor returned by ListPage.
+// The listing options are included so a cursor can't be used to
+// continue a different listing.
+type listCursor struct {
+	Token     string `json:"t"`
+	Prefix    string `json:"p,omitempty"`
+	Delimiter string `json:"d,omitempty"`
+}
+
+// ListPage returns a single page of the objects in the bucket,
+// for paging through listings across requests.
+//
+// Cursors are opaque strings that are safe to hand to clients, and encode
+// the prefix and delimiter so they can't be used to continue a different
+// listing. The page size is at most 1000, which is also the default.
+func (b *bucket) ListPage(data types.ListPageData) (entries []*types.ListEntry, nextCursor string, err error) {
+	ctx, cursor := data.Ctx, data.Cursor
+	if data.PageSize < 0 || data.PageSize > maxListKeys {
+		return nil, "", fmt.Errorf("%w: page size must be between 0 and %d", types.ErrInvalidArgument, maxListKeys)
+	}
+
+	var token string
+	if cursor != "" {
+		c, err := decodeListCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		if c.Prefix != data.Prefix || c.Delimiter != data.Delimiter {
+			return nil, "", fmt.Errorf("%w: cursor is for a different listing", types.ErrInvalidArgument)
+		}
+		token = c.Token
+	}
+
+	ctx, op := b.startOp(ctx, "ListPage", "")
+	defer func() { op.end(err, -1) }()
+
+	maxKeys := int32(maxListKeys)
+	if data.PageSize > 0 {
+		maxKeys = int32(data.PageSize)
+	}
+	resp, err := withRetry(ctx, b.retry, func() (*s3.ListObjectsV2Output, error) {
+		return b.client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+			Bucket:            &b.cfg.CloudName,
+			MaxKeys:           &maxKeys,
+			ContinuationToken: ptrOrNil(token),
+			Prefix:            ptrOrNil(data.Prefix),
+			Delimiter:         ptrOrNil(data.Delimiter),
+			RequestPayer:      b.requestPayer,
+		})
+	})
+	if err != nil {
+		return nil, "", mapErr(err)
+	}
+
+	entries = listEntries(resp)
+	if b.hidesTrash(data.Prefix) {
+		entries = slices.DeleteFunc(entries, inTrash)
+	}
+	if next := valOrZero(resp.NextContinuationToken); valOrZero(resp.IsTruncated) && next != "" {
+		nextCursor, err = encodeListCursor(listCursor{Token: next, Prefix: data.Prefix, Delimiter: data.Delimiter})
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	return entries, nextCursor, nil
+}
+
+func encodeListCursor(c listCursor) (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeListCursor(cursor string) (listCursor, error) {
+	var c listCursor
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil {
+		err = json.Unmarshal(data, &c)
+	}
+	if err != nil || c.Token == "" {
+		return listCursor{}, fmt.Errorf("%w: invalid cursor", types.ErrInvalidArgument)
+	}
+	return c, nil
+}
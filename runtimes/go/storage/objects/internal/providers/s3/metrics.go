@@ -0,0 +1,152 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"encore.dev/metrics"
+	"encore.dev/storage/objects/internal/types"
+)
+
+// Metrics receives measurements of a bucket's operations, for example
+// to record them as Encore metrics or export them to Prometheus.
+// Implementations must be safe for concurrent use.
+type Metrics interface {
+	// ObserveOperation is called once for each operation when it completes.
+	ObserveOperation(Observation)
+}
+
+// Observation describes a completed operation.
+type Observation struct {
+	// Operation is the name of the operation:
+	// "Upload", "Download", "List", "Remove" or "RemoveAll".
+	Operation string
+
+	// Bucket is the cloud name of the bucket.
+	Bucket string
+
+	// Bytes is the number of bytes uploaded or downloaded,
+	// or zero for other operations.
+	Bytes int64
+
+	// Duration is how long the operation took. For downloads and listings
+	// it includes the time until the caller closed the reader or stopped
+	// iterating.
+	Duration time.Duration
+
+	// Err is the error the operation failed with, if any.
+	Err error
+}
+
+// Result summarizes the outcome of the operation as a metric label:
+// "success", "not_found", "precondition_failed" or "error".
+func (o Observation) Result() string {
+	switch {
+	case o.Err == nil:
+		return "success"
+	case errors.Is(o.Err, types.ErrObjectNotExist):
+		return "not_found"
+	case errors.Is(o.Err, types.ErrPreconditionFailed):
+		return "precondition_failed"
+	default:
+		return "error"
+	}
+}
+
+// WithMetrics configures the bucket to report uploads, downloads,
+// listings and removals to m. If m is nil, nothing is reported,
+// which is the default.
+func WithMetrics(m Metrics) Option {
+	return func(o *bucketOptions) {
+		if m == nil {
+			m = noopMetrics{}
+		}
+		o.metrics = m
+	}
+}
+
+// registryMetrics reports operations as Encore metrics,
+// for buckets configured to be measured in the runtime config.
+type registryMetrics struct {
+	operations *metrics.CounterGroup[operationLabels, uint64]
+	bytes      *metrics.CounterGroup[operationLabels, uint64]
+}
+
+type operationLabels struct {
+	bucket    string // Cloud name of the bucket.
+	operation string // Name of the operation.
+	result    string // Outcome of the operation; see Observation.Result.
+}
+
+func newRegistryMetrics(reg *metrics.Registry) *registryMetrics {
+	cfg := metrics.CounterConfig{
+		EncoreInternal_LabelMapper: func(labels operationLabels) []metrics.KeyValue {
+			return []metrics.KeyValue{
+				{Key: "bucket", Value: labels.bucket},
+				{Key: "operation", Value: labels.operation},
+				{Key: "result", Value: labels.result},
+			}
+		},
+	}
+	return &registryMetrics{
+		operations: metrics.NewCounterGroupInternal[operationLabels, uint64](reg, "e_objects_operations_total", cfg),
+		bytes:      metrics.NewCounterGroupInternal[operationLabels, uint64](reg, "e_objects_bytes_total", cfg),
+	}
+}
+
+func (m *registryMetrics) ObserveOperation(o Observation) {
+	labels := operationLabels{bucket: o.Bucket, operation: o.Operation, result: o.Result()}
+	m.operations.With(labels).Increment()
+	if o.Bytes > 0 {
+		m.bytes.With(labels).Add(uint64(o.Bytes))
+	}
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) ObserveOperation(Observation) {}
+
+// operation tracks an operation that's traced and measured.
+type operation struct {
+	name    string
+	bucket  string
+	span    trace.Span
+	metrics Metrics
+	start   time.Time
+}
+
+// instrumented reports whether operations on the bucket are traced or measured,
+// so the cost of tracking them can be avoided when they're not.
+func (b *bucket) instrumented() bool {
+	_, noop := b.metrics.(noopMetrics)
+	return b.tracer != nil || !noop
+}
+
+// startOp starts the operation on the given object,
+// which may be empty for operations on the bucket as a whole.
+func (b *bucket) startOp(ctx context.Context, name string, object types.CloudObject) (context.Context, *operation) {
+	ctx, span := startSpan(ctx, b.tracer, name, b.cfg.CloudName, object)
+	return ctx, &operation{
+		name:    name,
+		bucket:  b.cfg.CloudName,
+		span:    span,
+		metrics: b.metrics,
+		start:   time.Now(),
+	}
+}
+
+// end ends the operation's span and reports it to the bucket's metrics.
+// Unless bytes is negative it's recorded as the number of bytes transferred.
+func (op *operation) end(err error, bytes int64) {
+	endSpan(op.span, err, bytes)
+	op.metrics.ObserveOperation(Observation{
+		Operation: op.name,
+		Bucket:    op.bucket,
+		Bytes:     max(bytes, 0),
+		Duration:  time.Since(op.start),
+		Err:       err,
+	})
+}
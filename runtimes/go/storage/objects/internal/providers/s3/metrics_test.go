@@ -0,0 +1,155 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	qt "github.com/frankban/quicktest"
+	"github.com/golang/mock/gomock"
+	"github.com/rs/zerolog"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/appruntime/exported/model"
+	"encore.dev/appruntime/shared/reqtrack"
+	"encore.dev/metrics"
+	"encore.dev/storage/objects/internal/types"
+)
+
+type recordingMetrics struct {
+	mu  sync.Mutex
+	obs []Observation
+}
+
+func (m *recordingMetrics) ObserveOperation(o Observation) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.obs = append(m.obs, o)
+}
+
+func (m *recordingMetrics) observations() []Observation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Observation(nil), m.obs...)
+}
+
+func newMeasuredBucket(c *qt.C) (*Mocks3Client, types.BucketImpl, *recordingMetrics) {
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	m := &recordingMetrics{}
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"}, WithMetrics(m))
+	return client, bkt, m
+}
+
+func TestMetrics_Download(t *testing.T) {
+	c := qt.New(t)
+	client, bkt, m := newMeasuredBucket(c)
+
+	client.EXPECT().GetObject(gomock.Any(), gomock.Any()).Return(&s3.GetObjectOutput{
+		Body: io.NopCloser(strings.NewReader("hello")),
+	}, nil)
+	r, err := bkt.Download(types.DownloadData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.IsNil)
+	_, err = io.ReadAll(r)
+	c.Assert(err, qt.IsNil)
+	c.Assert(m.observations(), qt.HasLen, 0, qt.Commentf("observed before the download was closed"))
+	c.Assert(r.Close(), qt.IsNil)
+
+	obs := m.observations()
+	c.Assert(obs, qt.HasLen, 1)
+	c.Assert(obs[0].Operation, qt.Equals, "Download")
+	c.Assert(obs[0].Bucket, qt.Equals, "bucket")
+	c.Assert(obs[0].Bytes, qt.Equals, int64(5))
+	c.Assert(obs[0].Result(), qt.Equals, "success")
+	c.Assert(obs[0].Duration > 0, qt.IsTrue)
+}
+
+func TestMetrics_Upload(t *testing.T) {
+	c := qt.New(t)
+	client, bkt, m := newMeasuredBucket(c)
+
+	client.EXPECT().PutObject(gomock.Any(), gomock.Any()).Return(&s3.PutObjectOutput{}, nil)
+	u, err := bkt.Upload(types.UploadData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.IsNil)
+	_, err = u.Write([]byte("hello"))
+	c.Assert(err, qt.IsNil)
+	_, err = u.Complete()
+	c.Assert(err, qt.IsNil)
+
+	obs := m.observations()
+	c.Assert(obs, qt.HasLen, 1)
+	c.Assert(obs[0].Operation, qt.Equals, "Upload")
+	c.Assert(obs[0].Bytes, qt.Equals, int64(5))
+	c.Assert(obs[0].Result(), qt.Equals, "success")
+}
+
+func TestMetrics_Error(t *testing.T) {
+	c := qt.New(t)
+	client, bkt, m := newMeasuredBucket(c)
+
+	client.EXPECT().DeleteObject(gomock.Any(), gomock.Any()).Return(nil, &s3types.NoSuchKey{})
+	err := bkt.Remove(types.RemoveData{Ctx: context.Background(), Object: "missing"})
+	c.Assert(err, qt.Equals, types.ErrObjectNotExist)
+
+	obs := m.observations()
+	c.Assert(obs, qt.HasLen, 1)
+	c.Assert(obs[0].Operation, qt.Equals, "Remove")
+	c.Assert(obs[0].Err, qt.Equals, types.ErrObjectNotExist)
+	c.Assert(obs[0].Result(), qt.Equals, "not_found")
+}
+
+func TestMetrics_List(t *testing.T) {
+	c := qt.New(t)
+	client, bkt, m := newMeasuredBucket(c)
+
+	client.EXPECT().ListObjectsV2(gomock.Any(), gomock.Any()).Return(&s3.ListObjectsV2Output{
+		Contents: []s3types.Object{{Key: ptr("a")}, {Key: ptr("b")}},
+	}, nil)
+	var n int
+	for _, err := range bkt.List(types.ListData{Ctx: context.Background()}) {
+		c.Assert(err, qt.IsNil)
+		n++
+	}
+	c.Assert(n, qt.Equals, 2)
+
+	obs := m.observations()
+	c.Assert(obs, qt.HasLen, 1)
+	c.Assert(obs[0].Operation, qt.Equals, "List")
+	c.Assert(obs[0].Bytes, qt.Equals, int64(0))
+	c.Assert(obs[0].Result(), qt.Equals, "success")
+}
+
+func TestRegistryMetrics(t *testing.T) {
+	c := qt.New(t)
+	rt := reqtrack.New(zerolog.Nop(), nil, nil)
+	reg := metrics.NewRegistry(rt, 1)
+	mgr := NewManager(context.Background(), &config.Runtime{}, rt, reg)
+
+	// Buckets are only measured if configured to be.
+	c.Assert(newManagerBucket(c, mgr, &config.S3BucketProvider{}).metrics, qt.Equals, Metrics(noopMetrics{}))
+	b := newManagerBucket(c, mgr, &config.S3BucketProvider{Metrics: true})
+	c.Assert(b.metrics, qt.Equals, mgr.metrics)
+
+	rt.BeginRequest(&model.Request{SvcNum: 1})
+	defer rt.FinishRequest(false)
+	b.metrics.ObserveOperation(Observation{Operation: "Upload", Bucket: "bucket", Bytes: 5})
+	b.metrics.ObserveOperation(Observation{Operation: "Upload", Bucket: "bucket", Bytes: 3})
+
+	values := make(map[string]uint64)
+	for _, m := range reg.Collect() {
+		labels := make(map[string]string)
+		for _, kv := range m.Labels {
+			labels[kv.Key] = kv.Value
+		}
+		c.Assert(labels, qt.DeepEquals, map[string]string{"bucket": "bucket", "operation": "Upload", "result": "success"})
+		values[m.Info.Name()] = m.Val.([]uint64)[0]
+	}
+	c.Assert(values, qt.DeepEquals, map[string]uint64{
+		"e_objects_operations_total": 2,
+		"e_objects_bytes_total":      8,
+	})
+}
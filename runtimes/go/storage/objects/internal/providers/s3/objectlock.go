@@ -0,0 +1,115 @@
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/storage/objects/internal/types"
+)
+
+// ObjectLockMode is the S3 Object Lock retention mode of an object.
+type ObjectLockMode string
+
+const (
+	// ObjectLockGovernance prevents the object from being overwritten or
+	// deleted, except by users with the s3:BypassGovernanceRetention permission.
+	ObjectLockGovernance ObjectLockMode = "GOVERNANCE"
+
+	// ObjectLockCompliance prevents the object from being overwritten or
+	// deleted by any user, including the root user, until the retention
+	// period ends.
+	ObjectLockCompliance ObjectLockMode = "COMPLIANCE"
+)
+
+// ObjectLock describes the S3 Object Lock settings for uploaded objects,
+// which provide write-once-read-many (WORM) semantics.
+// Object Lock must be enabled on the bucket; uploads to other buckets fail
+// with an error wrapping types.ErrInvalidArgument.
+type ObjectLock struct {
+	// Mode is the retention mode. If empty, no retention is set.
+	Mode ObjectLockMode
+
+	// RetainUntil is when the retention period ends.
+	// Either it or RetainFor must be set if and only if Mode is set.
+	RetainUntil time.Time
+
+	// RetainFor is the length of the retention period, starting when each
+	// object is uploaded. It's only used if RetainUntil is zero.
+	RetainFor time.Duration
+
+	// LegalHold places a legal hold on the object, which prevents it from
+	// being overwritten or deleted until the hold is removed,
+	// independently of the retention period.
+	LegalHold bool
+}
+
+// objectLockFromConfig returns the Object Lock settings
+// configured for a provider in the runtime config.
+func objectLockFromConfig(cfg *config.S3ObjectLock) ObjectLock {
+	lock := ObjectLock{RetainFor: cfg.RetainFor, LegalHold: cfg.LegalHold}
+	switch cfg.Mode {
+	case "":
+	case "governance":
+		lock.Mode = ObjectLockGovernance
+	case "compliance":
+		lock.Mode = ObjectLockCompliance
+	default:
+		panic(fmt.Sprintf("s3: unknown object lock mode %q", cfg.Mode))
+	}
+	return lock
+}
+
+func (l ObjectLock) validate() error {
+	switch l.Mode {
+	case "":
+		if !l.RetainUntil.IsZero() || l.RetainFor != 0 {
+			return fmt.Errorf("%w: object lock retention requires a mode", types.ErrInvalidArgument)
+		}
+		return nil
+	case ObjectLockGovernance, ObjectLockCompliance:
+		if l.RetainUntil.IsZero() && l.RetainFor <= 0 {
+			return fmt.Errorf("%w: object lock mode %s requires a retention period", types.ErrInvalidArgument, l.Mode)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown object lock mode %q", types.ErrInvalidArgument, l.Mode)
+	}
+}
+
+// fields returns the Object Lock request fields.
+func (l ObjectLock) fields() (mode s3types.ObjectLockMode, until *time.Time, hold s3types.ObjectLockLegalHoldStatus) {
+	if l.Mode != "" {
+		mode, until = s3types.ObjectLockMode(l.Mode), ptr(l.RetainUntil)
+		if l.RetainUntil.IsZero() {
+			until = ptr(time.Now().Add(l.RetainFor))
+		}
+	}
+	if l.LegalHold {
+		hold = s3types.ObjectLockLegalHoldStatusOn
+	}
+	return mode, until, hold
+}
+
+func (l ObjectLock) setPut(in *s3.PutObjectInput) {
+	in.ObjectLockMode, in.ObjectLockRetainUntilDate, in.ObjectLockLegalHoldStatus = l.fields()
+}
+
+func (l ObjectLock) setCreate(in *s3.CreateMultipartUploadInput) {
+	in.ObjectLockMode, in.ObjectLockRetainUntilDate, in.ObjectLockLegalHoldStatus = l.fields()
+}
+
+// isObjectLockNotEnabled reports whether err is S3 rejecting a request
+// that sets Object Lock parameters because the bucket doesn't have
+// Object Lock enabled.
+func isObjectLockNotEnabled(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "InvalidRequest" &&
+		strings.Contains(apiErr.ErrorMessage(), "Object Lock")
+}
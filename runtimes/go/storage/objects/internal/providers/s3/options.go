@@ -0,0 +1,150 @@
+package s3
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/storage/objects/internal/types"
+)
+
+// UploadOptions configures how the uploader transfers data to S3.
+type UploadOptions struct {
+	// MaxRetries is the maximum number of times a request that failed
+	// with a retryable error is retried before giving up.
+	// Each part of a multipart upload is retried independently,
+	// as is completing the multipart upload.
+	// Zero means requests are not retried.
+	// It's overridden by a bucket's retry policy; see WithRetryPolicy.
+	MaxRetries int
+
+	// RetryBackoff reports how long to wait before the given retry attempt,
+	// starting at 1. If nil, defaultRetryBackoff is used.
+	RetryBackoff func(attempt int) time.Duration
+
+	// Concurrency is the maximum number of parts of a multipart upload
+	// that are uploaded in parallel. If zero, defaultConcurrency is used.
+	Concurrency int
+
+	// Checksum is the algorithm used to compute additional checksums
+	// of the uploaded data, which S3 verifies on receipt.
+	// The aggregate checksum reported by S3 once the upload completes
+	// is verified against the locally computed one.
+	Checksum ChecksumAlgorithm
+
+	// Encryption configures server-side encryption of uploaded objects.
+	// When using customer-provided keys, the same key is needed to
+	// download the object, and is sent with any download from the bucket.
+	Encryption Encryption
+
+	// StorageClass is the S3 storage class of uploaded and copied objects,
+	// such as "STANDARD_IA" or "INTELLIGENT_TIERING". If empty, S3 uses STANDARD.
+	//
+	// Objects in archival storage classes like "GLACIER" and "DEEP_ARCHIVE"
+	// must be restored before they can be downloaded.
+	StorageClass string
+
+	// ACL is the canned ACL of uploaded and copied objects, such as
+	// "bucket-owner-full-control" for writes to a bucket in another account.
+	// If empty, no ACL is sent and S3 applies the bucket's default.
+	//
+	// Buckets whose Object Ownership is set to BucketOwnerEnforced have ACLs
+	// disabled, and reject any ACL other than "bucket-owner-full-control",
+	// which has no effect there since the bucket owner already owns every object.
+	ACL string
+
+	// ObjectLock configures S3 Object Lock retention and legal holds
+	// for uploaded objects.
+	ObjectLock ObjectLock
+
+	// DryRun validates uploads and builds their requests without sending them.
+	// The requests that would have been sent can be retrieved with
+	// DryRunRequestsOf, which makes it possible to check a configuration
+	// or a set of object keys before writing to the bucket.
+	DryRun bool
+
+	// CompressGzip compresses uploaded objects with gzip and sets their
+	// Content-Encoding to "gzip", so that clients such as browsers
+	// decompress them transparently. Uploads that specify a different
+	// content encoding are rejected.
+	CompressGzip bool
+
+	// StateStore, if set, persists the state of multipart uploads so they
+	// can be resumed with ResumeUpload if interrupted. Multipart uploads
+	// that fail are then kept rather than aborted, unless they're aborted
+	// explicitly; see CleanupIncompleteUploads for removing stale ones.
+	StateStore types.UploadStateStore
+
+	// VerifyUpload looks up each object with HeadObject once its upload
+	// completes, and checks that its size and ETag match what was uploaded.
+	// The ETag of multipart uploads is also checked against the one computed
+	// from the parts' MD5 digests; see MultipartETag.
+	// Mismatches, such as an object silently truncated on the way to S3,
+	// are reported as ErrVerifyFailed. The object is left as is, since
+	// another upload may have replaced it in the meantime.
+	VerifyUpload bool
+}
+
+// validateStorageClass reports whether the storage class is known to S3.
+func (o UploadOptions) validateStorageClass() error {
+	if o.StorageClass == "" {
+		return nil
+	}
+	if !slices.Contains(s3types.StorageClass("").Values(), s3types.StorageClass(o.StorageClass)) {
+		return fmt.Errorf("%w: unknown S3 storage class %q", types.ErrInvalidArgument, o.StorageClass)
+	}
+	return nil
+}
+
+// validateACL reports whether the ACL is a canned ACL known to S3.
+func (o UploadOptions) validateACL() error {
+	if o.ACL == "" {
+		return nil
+	}
+	if !slices.Contains(s3types.ObjectCannedACL("").Values(), s3types.ObjectCannedACL(o.ACL)) {
+		return fmt.Errorf("%w: unknown S3 canned ACL %q", types.ErrInvalidArgument, o.ACL)
+	}
+	return nil
+}
+
+// defaultConcurrency is the default number of parts uploaded in parallel.
+const defaultConcurrency = 4
+
+func (o UploadOptions) concurrency() int {
+	if o.Concurrency > 0 {
+		return o.Concurrency
+	}
+	return defaultConcurrency
+}
+
+// defaultUploadOptions are the upload options used by buckets
+// unless otherwise configured.
+var defaultUploadOptions = UploadOptions{
+	MaxRetries: 3,
+}
+
+// uploadOptionsFromConfig returns the upload options
+// configured for a provider in the runtime config.
+func uploadOptionsFromConfig(cfg *config.S3UploadOptions) UploadOptions {
+	opts := defaultUploadOptions
+	if cfg.MaxRetries != nil {
+		opts.MaxRetries = *cfg.MaxRetries
+	}
+	opts.Concurrency = cfg.Concurrency
+	opts.Checksum = checksumFromConfig(cfg.Checksum)
+	if enc := cfg.Encryption; enc != nil {
+		opts.Encryption = encryptionFromConfig(enc)
+	}
+	opts.StorageClass = cfg.StorageClass
+	opts.DryRun = cfg.DryRun
+	if lock := cfg.ObjectLock; lock != nil {
+		opts.ObjectLock = objectLockFromConfig(lock)
+	}
+	opts.CompressGzip = cfg.CompressGzip
+	opts.ACL = cfg.ACL
+	opts.VerifyUpload = cfg.Verify
+	return opts
+}
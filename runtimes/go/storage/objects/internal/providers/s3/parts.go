@@ -0,0 +1,179 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"encore.dev/storage/objects/internal/types"
+)
+
+var _ types.PartUploader = (*bucket)(nil)
+
+// InitUpload starts a multipart upload of the object.
+//
+// Unlike Upload, the steps of the upload can be split across processes:
+// the parts are uploaded with UploadPart, in any order and concurrently,
+// and the upload is then completed with CompleteUpload or aborted with
+// AbortUpload. Uploads that are never completed or aborted keep their
+// parts, which are billed for; see CleanupIncompleteUploads.
+//
+// The bucket's upload options apply as they would to Upload, except that
+// the content type isn't detected and uploads can't be compressed.
+func (b *bucket) InitUpload(data types.InitUploadData) (types.MultipartUpload, error) {
+	ctx, key, attrs := data.Ctx, string(data.Object), data.Attrs
+	if b.uploadOpts.CompressGzip {
+		return types.MultipartUpload{}, fmt.Errorf("%w: compressed uploads can't be split into parts", types.ErrInvalidArgument)
+	}
+	if err := validateKey(key, b.rejectControlChars); err != nil {
+		return types.MultipartUpload{}, err
+	}
+	if err := b.uploadOpts.Encryption.validate(); err != nil {
+		return types.MultipartUpload{}, err
+	}
+	if err := b.uploadOpts.validateStorageClass(); err != nil {
+		return types.MultipartUpload{}, err
+	}
+	if err := b.uploadOpts.validateACL(); err != nil {
+		return types.MultipartUpload{}, err
+	}
+	if err := b.uploadOpts.ObjectLock.validate(); err != nil {
+		return types.MultipartUpload{}, err
+	}
+	if err := validateTags(attrs.Tags); err != nil {
+		return types.MultipartUpload{}, err
+	}
+	attrs.Metadata = b.withContextMetadata(ctx, attrs.Metadata)
+
+	u := b.partUploader(ctx, key, attrs)
+	resp, err := withRetry(ctx, b.uploadOpts, func() (*s3.CreateMultipartUploadOutput, error) {
+		return b.client.CreateMultipartUpload(ctx, u.createMultipartUploadInput())
+	})
+	if err != nil {
+		return types.MultipartUpload{}, mapErr(err)
+	}
+	return types.MultipartUpload{Bucket: b.cfg.CloudName, Key: key, UploadID: valOrZero(resp.UploadId)}, nil
+}
+
+// UploadPart uploads a part of the upload, which must have been started
+// with InitUpload for the bucket. Part numbers range
+// from 1 to 10,000, and the parts make up the object in order of their
+// numbers. Every part except the last must be at least 5 MiB.
+//
+// Uploading a part again with the same number replaces it.
+// The returned part must be passed to CompleteUpload.
+func (b *bucket) UploadPart(data types.UploadPartData) (types.UploadedPart, error) {
+	ctx, upload, partNum, contents := data.Ctx, data.Upload, data.PartNumber, data.Data
+	if err := b.checkUpload(upload); err != nil {
+		return types.UploadedPart{}, err
+	}
+	if partNum < 1 || partNum > maxParts {
+		return types.UploadedPart{}, fmt.Errorf("%w: part number %d is not between 1 and %d", types.ErrInvalidArgument, partNum, maxParts)
+	}
+
+	md5sum := md5.Sum(contents)
+	checksum := b.uploadOpts.Checksum.sum(contents)
+	in := &s3.UploadPartInput{
+		Bucket:        &upload.Bucket,
+		Key:           &upload.Key,
+		UploadId:      &upload.UploadID,
+		PartNumber:    &partNum,
+		ContentLength: ptr(int64(len(contents))),
+		ContentMD5:    ptr(base64.StdEncoding.EncodeToString(md5sum[:])),
+	}
+	var completed s3types.CompletedPart
+	b.uploadOpts.Checksum.setPart(in, &completed, checksum)
+	b.uploadOpts.Encryption.setPart(in)
+
+	resp, err := withRetry(ctx, b.uploadOpts, func() (*s3.UploadPartOutput, error) {
+		in.Body = bytes.NewReader(contents)
+		return b.client.UploadPart(ctx, in)
+	})
+	if err != nil {
+		return types.UploadedPart{}, mapErr(err)
+	}
+	return types.UploadedPart{
+		Number:   partNum,
+		ETag:     valOrZero(resp.ETag),
+		Size:     int64(len(contents)),
+		Checksum: checksum,
+	}, nil
+}
+
+// CompleteUpload completes the upload, which must have been started with
+// InitUpload for the bucket, assembling the object from the given parts as
+// returned by UploadPart. The parts may be in any order, but their numbers
+// must be unique. Parts that were uploaded but aren't listed are discarded.
+//
+// If the bucket verifies uploads the object is checked against the sizes
+// of the parts, but the combined ETag isn't, since the parts' contents
+// aren't known.
+func (b *bucket) CompleteUpload(data types.CompleteUploadData) (*types.ObjectAttrs, error) {
+	ctx, upload, parts := data.Ctx, data.Upload, data.Parts
+	if err := b.checkUpload(upload); err != nil {
+		return nil, err
+	}
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("%w: no parts to complete the upload with", types.ErrInvalidArgument)
+	}
+
+	completed := make(map[int32]s3types.CompletedPart, len(parts))
+	var size int64
+	for _, p := range parts {
+		if _, ok := completed[p.Number]; ok {
+			return nil, fmt.Errorf("%w: part %d is listed more than once", types.ErrInvalidArgument, p.Number)
+		}
+		part := s3types.CompletedPart{PartNumber: ptr(p.Number), ETag: ptrOrNil(p.ETag)}
+		part.ChecksumCRC32, part.ChecksumSHA256 = b.uploadOpts.Checksum.fields(p.Checksum)
+		completed[p.Number] = part
+		size += p.Size
+	}
+
+	u := b.partUploader(ctx, upload.Key, types.UploadAttrs{})
+	return u.run(func() (*types.ObjectAttrs, error) {
+		return u.completeMultipart(&upload.Key, upload.UploadID, completed, nil, size)
+	})
+}
+
+// AbortUpload aborts the upload, which must have been started with
+// InitUpload for the bucket, discarding the parts uploaded so far.
+func (b *bucket) AbortUpload(ctx context.Context, upload types.MultipartUpload) error {
+	if err := b.checkUpload(upload); err != nil {
+		return err
+	}
+	_, err := withRetry(ctx, b.uploadOpts, func() (*s3.AbortMultipartUploadOutput, error) {
+		return b.client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+			Bucket:   &upload.Bucket,
+			Key:      &upload.Key,
+			UploadId: &upload.UploadID,
+		})
+	})
+	return mapErr(err)
+}
+
+// checkUpload checks that upload is a multipart upload to the bucket.
+func (b *bucket) checkUpload(upload types.MultipartUpload) error {
+	switch {
+	case upload.Bucket != b.cfg.CloudName:
+		return fmt.Errorf("%w: upload is to bucket %q, not %q",
+			types.ErrInvalidArgument, upload.Bucket, b.cfg.CloudName)
+	case upload.Key == "" || upload.UploadID == "":
+		return fmt.Errorf("%w: incomplete upload", types.ErrInvalidArgument)
+	}
+	return nil
+}
+
+// partUploader returns an uploader for the object, for building
+// and completing multipart uploads split across processes.
+func (b *bucket) partUploader(ctx context.Context, key string, attrs types.UploadAttrs) *uploader {
+	return newUploader(b.client, b.cfg.CloudName, types.UploadData{
+		Ctx:    ctx,
+		Object: types.CloudObject(key),
+		Attrs:  attrs,
+	}, b.uploadOpts)
+}
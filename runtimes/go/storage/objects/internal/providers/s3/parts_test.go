@@ -0,0 +1,90 @@
+package s3
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	qt "github.com/frankban/quicktest"
+	"github.com/golang/mock/gomock"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/storage/objects/internal/types"
+)
+
+func TestSplitUpload(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"},
+		WithUploadOptions(UploadOptions{Checksum: ChecksumCRC32, VerifyUpload: true})).(*bucket)
+	ctx := context.Background()
+
+	client.EXPECT().CreateMultipartUpload(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, in *s3.CreateMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+			c.Check(valOrZero(in.Key), qt.Equals, "object")
+			c.Check(valOrZero(in.ContentType), qt.Equals, "text/plain")
+			c.Check(in.ChecksumAlgorithm, qt.Equals, s3types.ChecksumAlgorithmCrc32)
+			return &s3.CreateMultipartUploadOutput{UploadId: ptr("uploadID")}, nil
+		})
+	upload, err := bkt.InitUpload(types.InitUploadData{Ctx: ctx, Object: "object", Attrs: types.UploadAttrs{ContentType: "text/plain"}})
+	c.Assert(err, qt.IsNil)
+	c.Assert(upload, qt.Equals, types.MultipartUpload{Bucket: "bucket", Key: "object", UploadID: "uploadID"})
+
+	// Parts can be uploaded in any order, such as by different workers.
+	client.EXPECT().UploadPart(gomock.Any(), &partMatcher{num: 2, data: "world"}).
+		Return(&s3.UploadPartOutput{ETag: ptr(`"etag2"`)}, nil)
+	client.EXPECT().UploadPart(gomock.Any(), &partMatcher{num: 1, data: "hello "}).
+		Return(&s3.UploadPartOutput{ETag: ptr(`"etag1"`)}, nil)
+	part2, err := bkt.UploadPart(types.UploadPartData{Ctx: ctx, Upload: upload, PartNumber: 2, Data: []byte("world")})
+	c.Assert(err, qt.IsNil)
+	part1, err := bkt.UploadPart(types.UploadPartData{Ctx: ctx, Upload: upload, PartNumber: 1, Data: []byte("hello ")})
+	c.Assert(err, qt.IsNil)
+	c.Assert(part1.Checksum, qt.Equals, ChecksumCRC32.sum([]byte("hello ")))
+
+	checksum, err := ChecksumCRC32.composite([]string{part1.Checksum, part2.Checksum})
+	c.Assert(err, qt.IsNil)
+	client.EXPECT().CompleteMultipartUpload(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, in *s3.CompleteMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+			c.Check(valOrZero(in.UploadId), qt.Equals, "uploadID")
+			parts := in.MultipartUpload.Parts
+			c.Assert(parts, qt.HasLen, 2)
+			c.Check(valOrZero(parts[0].PartNumber), qt.Equals, int32(1))
+			c.Check(valOrZero(parts[0].ETag), qt.Equals, `"etag1"`)
+			c.Check(valOrZero(parts[0].ChecksumCRC32), qt.Equals, part1.Checksum)
+			c.Check(valOrZero(parts[1].PartNumber), qt.Equals, int32(2))
+			return &s3.CompleteMultipartUploadOutput{ETag: ptr(`"etag-2"`), ChecksumCRC32: &checksum}, nil
+		})
+	client.EXPECT().HeadObject(gomock.Any(), gomock.Any()).
+		Return(&s3.HeadObjectOutput{ContentLength: ptr(int64(11)), ETag: ptr(`"etag-2"`)}, nil)
+	attrs, err := bkt.CompleteUpload(types.CompleteUploadData{Ctx: ctx, Upload: upload, Parts: []types.UploadedPart{part2, part1}})
+	c.Assert(err, qt.IsNil)
+	c.Assert(attrs.Size, qt.Equals, int64(11))
+	c.Assert(attrs.ETag, qt.Equals, `"etag-2"`)
+}
+
+func TestSplitUpload_Invalid(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"}).(*bucket)
+	ctx := context.Background()
+	upload := types.MultipartUpload{Bucket: "bucket", Key: "object", UploadID: "uploadID"}
+
+	_, err := bkt.UploadPart(types.UploadPartData{Ctx: ctx, Upload: types.MultipartUpload{Bucket: "other", Key: "object", UploadID: "uploadID"}, PartNumber: 1})
+	c.Assert(err, qt.ErrorIs, types.ErrInvalidArgument)
+	_, err = bkt.UploadPart(types.UploadPartData{Ctx: ctx, Upload: upload})
+	c.Assert(err, qt.ErrorIs, types.ErrInvalidArgument)
+	_, err = bkt.CompleteUpload(types.CompleteUploadData{Ctx: ctx, Upload: upload})
+	c.Assert(err, qt.ErrorIs, types.ErrInvalidArgument)
+	_, err = bkt.CompleteUpload(types.CompleteUploadData{Ctx: ctx, Upload: upload, Parts: []types.UploadedPart{{Number: 1}, {Number: 1}}})
+	c.Assert(err, qt.ErrorMatches, ".*part 1 is listed more than once")
+
+	client.EXPECT().AbortMultipartUpload(gomock.Any(), &s3.AbortMultipartUploadInput{
+		Bucket: ptr("bucket"), Key: ptr("object"), UploadId: ptr("uploadID"),
+	}).Return(&s3.AbortMultipartUploadOutput{}, nil)
+	c.Assert(bkt.AbortUpload(ctx, upload), qt.IsNil)
+}
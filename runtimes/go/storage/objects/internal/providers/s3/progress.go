@@ -0,0 +1,52 @@
+package s3
+
+// progressReporter reports upload progress to a callback.
+// Callbacks are invoked sequentially from a single goroutine,
+// even when parts are uploaded concurrently.
+type progressReporter struct {
+	fn    func(uploaded, total int64)
+	total int64
+	ch    chan int64
+	done  chan struct{}
+}
+
+// newProgressReporter starts a progress reporter.
+// It returns nil if fn is nil; the methods on a nil reporter are no-ops.
+func newProgressReporter(fn func(uploaded, total int64), total int64) *progressReporter {
+	if fn == nil {
+		return nil
+	}
+	p := &progressReporter{
+		fn:    fn,
+		total: total,
+		ch:    make(chan int64, 16),
+		done:  make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// add records that n more bytes have been uploaded.
+func (p *progressReporter) add(n int64) {
+	if p != nil {
+		p.ch <- n
+	}
+}
+
+// close stops the reporter, waiting for pending callbacks to complete.
+// It must be called after all calls to add have returned.
+func (p *progressReporter) close() {
+	if p != nil {
+		close(p.ch)
+		<-p.done
+	}
+}
+
+func (p *progressReporter) run() {
+	defer close(p.done)
+	var uploaded int64
+	for n := range p.ch {
+		uploaded += n
+		p.fn(uploaded, p.total)
+	}
+}
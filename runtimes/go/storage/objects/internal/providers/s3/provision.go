@@ -0,0 +1,56 @@
+package s3
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"encore.dev/storage/objects/internal/types"
+)
+
+var _ types.Provisioner = (*bucket)(nil)
+
+// EnsureBucket creates the bucket in the client's region,
+// unless it already exists and is owned by the caller.
+func (b *bucket) EnsureBucket(ctx context.Context) error {
+	return mapErr(ensureBucket(ctx, b.client, b.cfg.CloudName, b.region))
+}
+
+// BucketExists reports whether the bucket exists and is accessible to the caller.
+func (b *bucket) BucketExists(ctx context.Context) (bool, error) {
+	exists, err := bucketExists(ctx, b.client, b.cfg.CloudName)
+	return exists, mapErr(err)
+}
+
+// ensureBucket creates the bucket with the given name in region,
+// unless it already exists and is owned by the caller.
+// An empty region uses the client's default.
+func ensureBucket(ctx context.Context, client s3Client, name, region string) error {
+	in := &s3.CreateBucketInput{Bucket: &name}
+	// us-east-1 is the default location and must not be specified explicitly.
+	if region != "" && region != "us-east-1" {
+		in.CreateBucketConfiguration = &s3types.CreateBucketConfiguration{
+			LocationConstraint: s3types.BucketLocationConstraint(region),
+		}
+	}
+
+	_, err := client.CreateBucket(ctx, in)
+	var owned *s3types.BucketAlreadyOwnedByYou
+	if errors.As(err, &owned) {
+		return nil
+	}
+	return err
+}
+
+// bucketExists reports whether the bucket with the given name exists
+// and is accessible to the caller.
+func bucketExists(ctx context.Context, client s3Client, name string) (bool, error) {
+	_, err := client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: &name})
+	var notFound *s3types.NotFound
+	if errors.As(err, &notFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,88 @@
+package s3
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	qt "github.com/frankban/quicktest"
+	"github.com/golang/mock/gomock"
+
+	"encore.dev/appruntime/exported/config"
+)
+
+func TestEnsureBucket(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+
+	client.EXPECT().CreateBucket(gomock.Any(), &s3.CreateBucketInput{
+		Bucket: ptr("bucket"),
+		CreateBucketConfiguration: &s3types.CreateBucketConfiguration{
+			LocationConstraint: s3types.BucketLocationConstraintEuWest1,
+		},
+	}).Return(&s3.CreateBucketOutput{}, nil)
+	c.Assert(ensureBucket(ctx, client, "bucket", "eu-west-1"), qt.IsNil)
+
+	// us-east-1 must not be sent as a location constraint.
+	client.EXPECT().CreateBucket(gomock.Any(), &s3.CreateBucketInput{Bucket: ptr("bucket")}).
+		Return(nil, &s3types.BucketAlreadyOwnedByYou{})
+	c.Assert(ensureBucket(ctx, client, "bucket", "us-east-1"), qt.IsNil)
+
+	client.EXPECT().CreateBucket(gomock.Any(), gomock.Any()).Return(nil, &s3types.BucketAlreadyExists{})
+	c.Assert(ensureBucket(ctx, client, "bucket", ""), qt.ErrorAs, new(*s3types.BucketAlreadyExists))
+}
+
+func TestBucketExists(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+
+	client.EXPECT().HeadBucket(gomock.Any(), &s3.HeadBucketInput{Bucket: ptr("bucket")}).Return(&s3.HeadBucketOutput{}, nil)
+	exists, err := bucketExists(ctx, client, "bucket")
+	c.Assert(err, qt.IsNil)
+	c.Assert(exists, qt.IsTrue)
+
+	client.EXPECT().HeadBucket(gomock.Any(), gomock.Any()).Return(nil, &s3types.NotFound{})
+	exists, err = bucketExists(ctx, client, "missing")
+	c.Assert(err, qt.IsNil)
+	c.Assert(exists, qt.IsFalse)
+
+	denied := &smithy.GenericAPIError{Code: "Forbidden"}
+	client.EXPECT().HeadBucket(gomock.Any(), gomock.Any()).Return(nil, denied)
+	_, err = bucketExists(ctx, client, "forbidden")
+	c.Assert(err, qt.Equals, error(denied))
+}
+
+func TestBucket_EnsureBucket(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	b := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"}).(*bucket)
+	b.region = "eu-west-1"
+
+	client.EXPECT().CreateBucket(gomock.Any(), &s3.CreateBucketInput{
+		Bucket: ptr("bucket"),
+		CreateBucketConfiguration: &s3types.CreateBucketConfiguration{
+			LocationConstraint: s3types.BucketLocationConstraintEuWest1,
+		},
+	}).Return(&s3.CreateBucketOutput{}, nil)
+	c.Assert(b.EnsureBucket(ctx), qt.IsNil)
+
+	client.EXPECT().HeadBucket(gomock.Any(), &s3.HeadBucketInput{Bucket: ptr("bucket")}).Return(&s3.HeadBucketOutput{}, nil)
+	exists, err := b.BucketExists(ctx)
+	c.Assert(err, qt.IsNil)
+	c.Assert(exists, qt.IsTrue)
+
+	// The region is taken from *s3.Client clients.
+	b = NewBucketWithClient(s3.New(s3.Options{Region: "eu-north-1"}), &config.Bucket{CloudName: "bucket"}).(*bucket)
+	c.Assert(b.region, qt.Equals, "eu-north-1")
+}
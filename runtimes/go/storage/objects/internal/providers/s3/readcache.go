@@ -0,0 +1,145 @@
+package s3
+
+import (
+	"bytes"
+	"container/list"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"encore.dev/storage/objects/internal/types"
+)
+
+// CacheOptions configures the read-through cache enabled by WithReadCache.
+type CacheOptions struct {
+	// MaxBytes is the memory budget for the contents of cached objects.
+	// The least recently used objects are evicted to stay within it.
+	MaxBytes int64
+
+	// MaxObjectSize is the size of the largest object to cache.
+	// If zero, defaultMaxCachedObjectSize is used.
+	MaxObjectSize int64
+}
+
+// defaultMaxCachedObjectSize is the default size of the largest object to cache.
+const defaultMaxCachedObjectSize = 1024 * 1024
+
+func (o CacheOptions) maxObjectSize() int64 {
+	size := o.MaxObjectSize
+	if size <= 0 {
+		size = defaultMaxCachedObjectSize
+	}
+	return min(size, o.MaxBytes)
+}
+
+// WithReadCache caches the contents of small objects downloaded from the
+// bucket in memory, for objects that are read often but rarely change,
+// such as configuration files.
+//
+// Cached objects are still requested from S3 on every download, but with
+// the ETag of the cached contents so that S3 responds with 304 Not Modified
+// and no body if the object hasn't changed, in which case the cached
+// contents are used. Only downloads of whole objects are cached, and they're
+// made with a single request rather than in parallel. Downloads of a byte
+// range or with DownloadConditions bypass the cache.
+func WithReadCache(opts CacheOptions) Option {
+	return func(o *bucketOptions) { o.readCache = &opts }
+}
+
+// readCache is an LRU cache of object contents, keyed by object and version.
+type readCache struct {
+	opts CacheOptions
+
+	mu      sync.Mutex
+	size    int64                      // total size of the cached contents
+	lru     *list.List                 // of *cacheEntry, most recently used first
+	entries map[cacheKey]*list.Element // of *cacheEntry
+}
+
+type cacheKey struct {
+	object  types.CloudObject
+	version string
+}
+
+type cacheEntry struct {
+	key  cacheKey
+	etag string
+	data []byte
+}
+
+func newReadCache(opts CacheOptions) *readCache {
+	return &readCache{opts: opts, lru: list.New(), entries: make(map[cacheKey]*list.Element)}
+}
+
+// get returns the cached entry for key, if any, marking it as recently used.
+func (c *readCache) get(key cacheKey) (*cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*cacheEntry), true
+}
+
+// put caches the contents of the object, evicting the least recently
+// used objects as needed to stay within the memory budget.
+func (c *readCache) put(e *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[e.key]; ok {
+		c.removeElem(elem)
+	}
+	c.entries[e.key] = c.lru.PushFront(e)
+	c.size += int64(len(e.data))
+	for c.size > c.opts.MaxBytes {
+		c.removeElem(c.lru.Back())
+	}
+}
+
+// remove removes the entry for key, if any.
+func (c *readCache) remove(key cacheKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.removeElem(elem)
+	}
+}
+
+func (c *readCache) removeElem(elem *list.Element) {
+	e := c.lru.Remove(elem).(*cacheEntry)
+	delete(c.entries, e.key)
+	c.size -= int64(len(e.data))
+}
+
+// cachedDownload downloads the whole object using the read cache.
+func (b *bucket) cachedDownload(data types.DownloadData) (types.Downloader, error) {
+	key := cacheKey{object: data.Object, version: data.Version}
+	cached, ok := b.cache.get(key)
+	if ok {
+		data.Conditions = types.DownloadConditions{IfNoneMatch: cached.etag}
+	}
+	resp, err := b.getObject(data, 0, 0, nil)
+	if ok && errors.Is(err, types.ErrNotModified) {
+		return io.NopCloser(bytes.NewReader(cached.data)), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	size := valOrZero(resp.ContentLength)
+	if resp.ContentLength == nil || size > b.cache.opts.maxObjectSize() || resp.ETag == nil {
+		b.cache.remove(key)
+		return resp.Body, nil
+	}
+	defer resp.Body.Close()
+	buf, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	} else if int64(len(buf)) != size {
+		return nil, fmt.Errorf("read object: got %d bytes, want %d: %w", len(buf), size, io.ErrUnexpectedEOF)
+	}
+	b.cache.put(&cacheEntry{key: key, etag: *resp.ETag, data: buf})
+	return io.NopCloser(bytes.NewReader(buf)), nil
+}
@@ -0,0 +1,88 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	qt "github.com/frankban/quicktest"
+	"github.com/golang/mock/gomock"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/storage/objects/internal/types"
+)
+
+func TestReadCache(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"},
+		WithReadCache(CacheOptions{MaxBytes: 10, MaxObjectSize: 6}))
+	ctx := context.Background()
+
+	object := func(etag, data string) *s3.GetObjectOutput {
+		return &s3.GetObjectOutput{
+			Body:          io.NopCloser(strings.NewReader(data)),
+			ContentLength: ptr(int64(len(data))),
+			ETag:          ptr(etag),
+		}
+	}
+	expectGet := func(key, ifNoneMatch string) *gomock.Call {
+		return client.EXPECT().GetObject(gomock.Any(), &s3.GetObjectInput{
+			Bucket:      ptr("bucket"),
+			Key:         ptr(key),
+			IfNoneMatch: ptrOrNil(ifNoneMatch),
+		})
+	}
+	notModified := &smithy.GenericAPIError{Code: "NotModified"}
+	download := func(key string) string {
+		r, err := bkt.Download(types.DownloadData{Ctx: ctx, Object: types.CloudObject(key)})
+		c.Assert(err, qt.IsNil)
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		c.Assert(err, qt.IsNil)
+		return string(data)
+	}
+
+	// The first download is cached, and later ones are served from
+	// the cache as long as the object hasn't changed.
+	expectGet("a", "").Return(object(`"a1"`, "aaaa"), nil)
+	c.Assert(download("a"), qt.Equals, "aaaa")
+	expectGet("a", `"a1"`).Return(nil, notModified)
+	c.Assert(download("a"), qt.Equals, "aaaa")
+
+	// Changed objects are downloaded again.
+	expectGet("a", `"a1"`).Return(object(`"a2"`, "AAAA"), nil)
+	c.Assert(download("a"), qt.Equals, "AAAA")
+	expectGet("a", `"a2"`).Return(nil, notModified)
+	c.Assert(download("a"), qt.Equals, "AAAA")
+
+	// Objects larger than MaxObjectSize aren't cached.
+	for range 2 {
+		expectGet("big", "").Return(object(`"big"`, "bigobject"), nil)
+		c.Assert(download("big"), qt.Equals, "bigobject")
+	}
+
+	// The least recently used objects are evicted to stay within MaxBytes.
+	expectGet("b", "").Return(object(`"b1"`, "bbbb"), nil)
+	c.Assert(download("b"), qt.Equals, "bbbb")
+	expectGet("a", `"a2"`).Return(nil, notModified)
+	c.Assert(download("a"), qt.Equals, "AAAA") // a is now more recently used than b
+	expectGet("c", "").Return(object(`"c1"`, "cccc"), nil)
+	c.Assert(download("c"), qt.Equals, "cccc") // evicts b
+	expectGet("b", "").Return(object(`"b1"`, "bbbb"), nil)
+	c.Assert(download("b"), qt.Equals, "bbbb")
+
+	// Range downloads bypass the cache.
+	client.EXPECT().GetObject(gomock.Any(), &s3.GetObjectInput{Bucket: ptr("bucket"), Key: ptr("b"), Range: ptr("bytes=1-2")}).
+		Return(object(`"b1"`, "bb"), nil)
+	r, err := bkt.Download(types.DownloadData{Ctx: ctx, Object: "b", Offset: 1, Length: 2})
+	c.Assert(err, qt.IsNil)
+	data, err := io.ReadAll(r)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(data), qt.Equals, "bb")
+}
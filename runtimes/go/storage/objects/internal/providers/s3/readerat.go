@@ -0,0 +1,180 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"golang.org/x/sync/errgroup"
+
+	"encore.dev/storage/objects/internal/types"
+)
+
+var _ types.ReaderAtUploader = (*bucket)(nil)
+
+// UploadReaderAt uploads the first data.Size bytes of data.Reader.
+//
+// Parts of a multipart upload are read from their own ranges of r as they're
+// uploaded in parallel, rather than being copied into buffers first, so r
+// must be safe for concurrent use. Objects that fit in a single part are read
+// into memory and uploaded with a single PutObject. The part size is increased
+// from the default if needed to stay within the S3 limit on the number of parts.
+func (b *bucket) UploadReaderAt(data types.UploadReaderAtData) (*types.ObjectAttrs, error) {
+	r, size := data.Reader, data.Size
+	if size < 0 {
+		return nil, fmt.Errorf("%w: negative size %d", types.ErrInvalidArgument, size)
+	}
+
+	if data.PartSize == 0 {
+		data.PartSize = filePartSize(size)
+	}
+	up, err := b.Upload(data.UploadData)
+	if err != nil {
+		return nil, err
+	}
+	u, ok := up.(*uploader)
+	if !ok {
+		// The data is transformed, such as by compression, so the
+		// parts don't correspond to ranges of r; upload it as a stream.
+		if _, err := io.Copy(up, io.NewSectionReader(r, 0, size)); err != nil {
+			up.Abort(err)
+			return nil, err
+		}
+		return up.Complete()
+	}
+	return u.run(func() (*types.ObjectAttrs, error) {
+		return u.uploadReaderAt(r, size)
+	})
+}
+
+// uploadReaderAt uploads the object from r, in place of the buffered upload.
+func (u *uploader) uploadReaderAt(r io.ReaderAt, size int64) (*types.ObjectAttrs, error) {
+	if size < int64(u.partSize()) {
+		buf := make([]byte, size)
+		if n, err := r.ReadAt(buf, 0); n < len(buf) {
+			return nil, fmt.Errorf("read object: %w", err)
+		}
+		u.detectContentType(buf)
+		return u.singlePartUpload(buf)
+	}
+
+	// Detect the content type before the upload is created with it.
+	head := make([]byte, sniffLen)
+	n, err := r.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("read object: %w", err)
+	}
+	u.detectContentType(head[:n])
+	return u.multiPartUploadReaderAt(r, size)
+}
+
+// multiPartUploadReaderAt uploads the object from r using a multipart upload,
+// with each part read from its range of r.
+func (u *uploader) multiPartUploadReaderAt(r io.ReaderAt, size int64) (attrs *types.ObjectAttrs, err error) {
+	key := ptr(u.data.Object.String())
+	partSize := int64(u.partSize())
+	numParts := (size + partSize - 1) / partSize
+	if numParts > maxParts {
+		return nil, fmt.Errorf("%w: object exceeds %d parts of %d bytes; use a larger part size",
+			types.ErrInvalidArgument, maxParts, partSize)
+	}
+
+	resp, err := u.client.CreateMultipartUpload(u.ctx, u.createMultipartUploadInput())
+	if err != nil {
+		return nil, err
+	}
+	uploadID := valOrZero(resp.UploadId)
+	defer func() {
+		if err != nil {
+			go abortMultipart(u.client, u.bucket, key, uploadID)
+		}
+	}()
+
+	var (
+		partsMu  sync.Mutex
+		parts    = make(map[int32]s3types.CompletedPart, numParts)
+		partMD5s = make(map[int32][]byte, numParts)
+	)
+	progress := newProgressReporter(u.data.Progress, size)
+	defer progress.close()
+
+	g, groupCtx := errgroup.WithContext(u.ctx)
+	g.SetLimit(u.opts.concurrency())
+	for i := range numParts {
+		part := int32(i + 1)
+		offset := i * partSize
+		length := min(partSize, size-offset)
+		g.Go(func() error {
+			if err := groupCtx.Err(); err != nil {
+				return err // another part failed; don't start uploading more
+			}
+
+			// Read the range once to compute its digests,
+			// and again for each attempt to upload it.
+			md5h, sumh := md5.New(), u.opts.Checksum.newHash()
+			w := io.Writer(md5h)
+			if sumh != nil {
+				w = io.MultiWriter(md5h, sumh)
+			}
+			if n, err := io.Copy(w, io.NewSectionReader(r, offset, length)); err != nil {
+				return fmt.Errorf("read part %d: %w", part, err)
+			} else if n < length {
+				return fmt.Errorf("read part %d: %w", part, io.ErrUnexpectedEOF)
+			}
+			md5sum := md5h.Sum(nil)
+			var checksum string
+			if sumh != nil {
+				checksum = base64.StdEncoding.EncodeToString(sumh.Sum(nil))
+			}
+
+			in := &s3.UploadPartInput{
+				Bucket:        &u.bucket,
+				Key:           key,
+				UploadId:      &uploadID,
+				PartNumber:    &part,
+				ContentLength: &length,
+				ContentMD5:    ptr(base64.StdEncoding.EncodeToString(md5sum)),
+			}
+			completed := s3types.CompletedPart{PartNumber: ptr(part)}
+			u.opts.Checksum.setPart(in, &completed, checksum)
+			u.opts.Encryption.setPart(in)
+
+			partCtx, span := startSpan(groupCtx, u.tracer, "UploadPart", u.bucket, u.data.Object)
+			span.SetAttributes(attrPartNumber.Int(int(part)))
+			resp, err := withRetry(partCtx, u.opts, func() (*s3.UploadPartOutput, error) {
+				in.Body = io.NewSectionReader(r, offset, length)
+				return u.client.UploadPart(partCtx, in)
+			})
+			endSpan(span, mapErr(err), length)
+			if err != nil {
+				return err
+			}
+			completed.ETag = resp.ETag
+
+			partsMu.Lock()
+			parts[part] = completed
+			partMD5s[part] = md5sum
+			partsMu.Unlock()
+			progress.add(length)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return u.completeMultipart(key, uploadID, parts, partMD5s, size)
+}
+
+// filePartSize returns the part size for uploading a file of the given size,
+// or zero to use the default part size.
+func filePartSize(size int64) int64 {
+	if n := (size + maxParts - 1) / maxParts; n > int64(bufSize) {
+		return n
+	}
+	return 0
+}
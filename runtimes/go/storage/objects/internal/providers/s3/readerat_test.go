@@ -0,0 +1,103 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	qt "github.com/frankban/quicktest"
+	"github.com/golang/mock/gomock"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/storage/objects/internal/types"
+)
+
+func TestUploadReaderAt(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"}).(*bucket)
+
+	client.EXPECT().PutObject(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+			c.Check(valOrZero(in.ContentLength), qt.Equals, int64(5))
+			data, err := io.ReadAll(in.Body)
+			c.Check(err, qt.IsNil)
+			c.Check(string(data), qt.Equals, "hello")
+			return &s3.PutObjectOutput{ETag: ptr("etag")}, nil
+		})
+
+	// Only the first size bytes are uploaded.
+	attrs, err := bkt.UploadReaderAt(uploadReaderAtData("key", strings.NewReader("hello world"), 5))
+	c.Assert(err, qt.IsNil)
+	c.Assert(attrs.Size, qt.Equals, int64(5))
+	c.Assert(attrs.ContentType, qt.Equals, "text/plain; charset=utf-8")
+}
+
+func TestUploadReaderAt_Multipart(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"}).(*bucket)
+
+	withBufSize(c, 4)
+	client.EXPECT().CreateMultipartUpload(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.CreateMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+			c.Check(valOrZero(in.ContentType), qt.Equals, "text/plain; charset=utf-8")
+			return &s3.CreateMultipartUploadOutput{UploadId: ptr("upload")}, nil
+		})
+	for i, data := range []string{"abcd", "efgh", "ij"} {
+		client.EXPECT().UploadPart(gomock.Any(), &partMatcher{num: i + 1, data: data}).
+			Return(&s3.UploadPartOutput{ETag: ptr("etag")}, nil)
+	}
+	client.EXPECT().CompleteMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CompleteMultipartUploadOutput{}, nil)
+
+	attrs, err := bkt.UploadReaderAt(uploadReaderAtData("key", strings.NewReader("abcdefghij"), 10))
+	c.Assert(err, qt.IsNil)
+	c.Assert(attrs.Size, qt.Equals, int64(10))
+}
+
+func TestUploadReaderAt_ShortReader(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"}).(*bucket)
+
+	// The reader ends before the given size; the upload is aborted.
+	withBufSize(c, 4)
+	waitAbort := make(chan struct{})
+	client.EXPECT().CreateMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CreateMultipartUploadOutput{UploadId: ptr("upload")}, nil)
+	client.EXPECT().UploadPart(gomock.Any(), gomock.Any()).Return(&s3.UploadPartOutput{ETag: ptr("etag")}, nil).AnyTimes()
+	client.EXPECT().AbortMultipartUpload(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.AbortMultipartUploadInput, _ ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+			close(waitAbort)
+			return &s3.AbortMultipartUploadOutput{}, nil
+		})
+
+	_, err := bkt.UploadReaderAt(uploadReaderAtData("key", strings.NewReader("abcdefghij"), 12))
+	c.Assert(err, qt.ErrorIs, io.ErrUnexpectedEOF)
+	waitFor(c, waitAbort)
+
+	_, err = bkt.UploadReaderAt(uploadReaderAtData("key", strings.NewReader(""), -1))
+	c.Assert(err, qt.ErrorIs, types.ErrInvalidArgument)
+}
+
+func uploadReaderAtData(key string, r io.ReaderAt, size int64) types.UploadReaderAtData {
+	return types.UploadReaderAtData{
+		UploadData: types.UploadData{Ctx: context.Background(), Object: types.CloudObject(key)},
+		Reader:     r,
+		Size:       size,
+	}
+}
+
+func TestFilePartSize(t *testing.T) {
+	c := qt.New(t)
+	c.Assert(filePartSize(0), qt.Equals, int64(0))
+	c.Assert(filePartSize(int64(bufSize)*maxParts), qt.Equals, int64(0))
+	c.Assert(filePartSize(int64(bufSize)*maxParts+1), qt.Equals, int64(bufSize)+1)
+}
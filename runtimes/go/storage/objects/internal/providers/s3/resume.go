@@ -0,0 +1,133 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"encore.dev/storage/objects/internal/types"
+)
+
+var _ types.UploadResumer = (*bucket)(nil)
+
+// resumePoint describes where a resumed upload continues from.
+type resumePoint struct {
+	uploadID string
+	parts    []s3types.CompletedPart // contiguous from part 1
+	size     int64                   // total size of parts
+}
+
+// ResumeUpload continues an interrupted multipart upload to the bucket.
+// The parts that have already been uploaded are determined by listing them,
+// rather than trusting state.Parts, since the state may be stale.
+//
+// It returns an uploader along with the offset into the object's
+// contents to continue writing from. The caller must write the contents
+// from that offset onwards and complete the upload as usual.
+// Any parts after the offset are uploaded again.
+func (b *bucket) ResumeUpload(data types.ResumeUploadData) (u types.Uploader, offset int64, err error) {
+	ctx, state := data.Ctx, data.State
+	switch {
+	case state.Bucket != b.cfg.CloudName:
+		return nil, 0, fmt.Errorf("%w: upload is to bucket %q, not %q",
+			types.ErrInvalidArgument, state.Bucket, b.cfg.CloudName)
+	case state.UploadID == "" || state.PartSize <= 0:
+		return nil, 0, fmt.Errorf("%w: incomplete upload state", types.ErrInvalidArgument)
+	case b.uploadOpts.CompressGzip:
+		return nil, 0, fmt.Errorf("%w: compressed uploads can't be resumed", types.ErrInvalidArgument)
+	}
+
+	parts, err := b.listParts(ctx, state)
+	if err != nil {
+		return nil, 0, err
+	}
+	resume := &resumePoint{uploadID: state.UploadID}
+	for i, p := range parts {
+		// Only a prefix of full-sized parts can be kept; any part after
+		// a gap, or after a short part, is uploaded again.
+		if valOrZero(p.PartNumber) != int32(i+1) || valOrZero(p.Size) != state.PartSize {
+			break
+		}
+		resume.parts = append(resume.parts, s3types.CompletedPart{
+			PartNumber:     p.PartNumber,
+			ETag:           p.ETag,
+			ChecksumCRC32:  p.ChecksumCRC32,
+			ChecksumSHA256: p.ChecksumSHA256,
+		})
+		resume.size += state.PartSize
+	}
+
+	upload := types.UploadData{
+		Ctx:      ctx,
+		Object:   types.CloudObject(state.Key),
+		PartSize: state.PartSize,
+		Pre:      types.Preconditions{NotExists: state.NotExists},
+	}
+	ctx, op := b.startOp(ctx, "Upload", upload.Object)
+	upload.Ctx = ctx
+	up := newUploader(b.client, b.cfg.CloudName, upload, b.uploadOptsFor(data.StateStore))
+	up.tracer, up.op, up.resume = b.tracer, op, resume
+	return up, resume.size, nil
+}
+
+// uploadOptsFor returns the bucket's upload options, with the state
+// of multipart uploads persisted to store instead, if non-nil.
+func (b *bucket) uploadOptsFor(store types.UploadStateStore) UploadOptions {
+	opts := b.uploadOpts
+	if store != nil {
+		opts.StateStore = store
+	}
+	return opts
+}
+
+// listParts lists the parts of the multipart upload, ordered by part number.
+func (b *bucket) listParts(ctx context.Context, state types.UploadState) ([]s3types.Part, error) {
+	var (
+		parts  []s3types.Part
+		marker *string
+	)
+	for {
+		resp, err := b.client.ListParts(ctx, &s3.ListPartsInput{
+			Bucket:           &state.Bucket,
+			Key:              &state.Key,
+			UploadId:         &state.UploadID,
+			PartNumberMarker: marker,
+			RequestPayer:     b.requestPayer,
+		})
+		var noSuchUpload *s3types.NoSuchUpload
+		if errors.As(err, &noSuchUpload) {
+			return nil, fmt.Errorf("%w: upload %s no longer exists; it was completed or aborted",
+				types.ErrInvalidArgument, state.UploadID)
+		} else if err != nil {
+			return nil, mapErr(err)
+		}
+		parts = append(parts, resp.Parts...)
+		if !valOrZero(resp.IsTruncated) {
+			break
+		}
+		marker = resp.NextPartNumberMarker
+	}
+	slices.SortFunc(parts, func(a, b s3types.Part) int {
+		return int(valOrZero(a.PartNumber) - valOrZero(b.PartNumber))
+	})
+	return parts, nil
+}
+
+// uploadState returns the state of the multipart upload with the given parts.
+func (u *uploader) uploadState(uploadID string, parts map[int32]s3types.CompletedPart) types.UploadState {
+	state := types.UploadState{
+		Bucket:    u.bucket,
+		Key:       u.data.Object.String(),
+		UploadID:  uploadID,
+		PartSize:  int64(u.partSize()),
+		NotExists: u.data.Pre.NotExists,
+	}
+	for _, p := range sortedParts(parts) {
+		state.Parts = append(state.Parts, types.UploadedPart{Number: *p.PartNumber, ETag: valOrZero(p.ETag)})
+	}
+	return state
+}
@@ -0,0 +1,180 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	qt "github.com/frankban/quicktest"
+	"github.com/golang/mock/gomock"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/storage/objects/internal/types"
+)
+
+type memStateStore struct {
+	mu      sync.Mutex
+	saved   []types.UploadState
+	deleted []types.UploadState
+}
+
+func (s *memStateStore) SaveUploadState(ctx context.Context, state types.UploadState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saved = append(s.saved, state)
+	return nil
+}
+
+func (s *memStateStore) DeleteUploadState(ctx context.Context, state types.UploadState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleted = append(s.deleted, state)
+	return nil
+}
+
+func (s *memStateStore) last() types.UploadState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.saved[len(s.saved)-1]
+}
+
+func TestUploader_StateStore(t *testing.T) {
+	c := qt.New(t)
+	withBufSize(c, 5)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	store := &memStateStore{}
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"},
+		WithUploadOptions(UploadOptions{StateStore: store}))
+
+	client.EXPECT().CreateMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CreateMultipartUploadOutput{UploadId: ptr("uploadID")}, nil)
+	client.EXPECT().UploadPart(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.UploadPartInput, _ ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+			return &s3.UploadPartOutput{ETag: ptr("etag" + string('0'+byte(*in.PartNumber)))}, nil
+		}).Times(2)
+	client.EXPECT().CompleteMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CompleteMultipartUploadOutput{}, nil)
+
+	u, err := bkt.Upload(types.UploadData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.IsNil)
+	_, err = u.Write([]byte("abcdefghij"))
+	c.Assert(err, qt.IsNil)
+	_, err = u.Complete()
+	c.Assert(err, qt.IsNil)
+
+	c.Assert(store.saved, qt.HasLen, 3)
+	c.Assert(store.saved[0], qt.DeepEquals, types.UploadState{Bucket: "bucket", Key: "object", UploadID: "uploadID", PartSize: 5})
+	c.Assert(store.last().Parts, qt.DeepEquals, []types.UploadedPart{{Number: 1, ETag: "etag1"}, {Number: 2, ETag: "etag2"}})
+	c.Assert(store.deleted, qt.HasLen, 1)
+	c.Assert(store.deleted[0].UploadID, qt.Equals, "uploadID")
+}
+
+func TestUploader_StateStoreKeepsFailedUpload(t *testing.T) {
+	c := qt.New(t)
+	withBufSize(c, 5)
+
+	// AbortMultipartUpload is not expected.
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	store := &memStateStore{}
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"},
+		WithUploadOptions(UploadOptions{Concurrency: 1}))
+
+	client.EXPECT().CreateMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CreateMultipartUploadOutput{UploadId: ptr("uploadID")}, nil)
+	client.EXPECT().UploadPart(gomock.Any(), gomock.Any()).Return(&s3.UploadPartOutput{ETag: ptr("etag1")}, nil)
+	client.EXPECT().UploadPart(gomock.Any(), gomock.Any()).Return(nil, errors.New("connection lost"))
+
+	// The store can also be set for a single upload.
+	u, err := bkt.Upload(types.UploadData{Ctx: context.Background(), Object: "object", StateStore: store})
+	c.Assert(err, qt.IsNil)
+	_, _ = u.Write([]byte("abcdefghij"))
+	_, err = u.Complete()
+	c.Assert(err, qt.ErrorMatches, "connection lost")
+
+	c.Assert(store.last().Parts, qt.DeepEquals, []types.UploadedPart{{Number: 1, ETag: "etag1"}})
+	c.Assert(store.deleted, qt.HasLen, 0)
+}
+
+func TestResumeUpload(t *testing.T) {
+	c := qt.New(t)
+	withBufSize(c, 5)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	store := &memStateStore{}
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"},
+		WithUploadOptions(UploadOptions{StateStore: store})).(*bucket)
+	state := types.UploadState{Bucket: "bucket", Key: "object", UploadID: "uploadID", PartSize: 5}
+
+	// Part 3 completed before the interruption, but part 2 didn't,
+	// so the upload continues from part 2.
+	client.EXPECT().ListParts(gomock.Any(), &s3.ListPartsInput{
+		Bucket: ptr("bucket"), Key: ptr("object"), UploadId: ptr("uploadID"),
+	}).Return(&s3.ListPartsOutput{
+		Parts:                []s3types.Part{{PartNumber: ptr(int32(1)), ETag: ptr("etag1"), Size: ptr(int64(5))}},
+		IsTruncated:          ptr(true),
+		NextPartNumberMarker: ptr("1"),
+	}, nil)
+	client.EXPECT().ListParts(gomock.Any(), &s3.ListPartsInput{
+		Bucket: ptr("bucket"), Key: ptr("object"), UploadId: ptr("uploadID"), PartNumberMarker: ptr("1"),
+	}).Return(&s3.ListPartsOutput{
+		Parts: []s3types.Part{{PartNumber: ptr(int32(3)), ETag: ptr("stale"), Size: ptr(int64(5))}},
+	}, nil)
+
+	var (
+		mu       sync.Mutex
+		uploaded = make(map[int32]string)
+	)
+	client.EXPECT().UploadPart(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.UploadPartInput, _ ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+			data, _ := io.ReadAll(in.Body)
+			mu.Lock()
+			uploaded[*in.PartNumber] = string(data)
+			mu.Unlock()
+			return &s3.UploadPartOutput{ETag: ptr("new" + string('0'+byte(*in.PartNumber)))}, nil
+		}).Times(2)
+	client.EXPECT().CompleteMultipartUpload(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.CompleteMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+			var etags []string
+			for _, p := range in.MultipartUpload.Parts {
+				etags = append(etags, valOrZero(p.ETag))
+			}
+			c.Check(etags, qt.DeepEquals, []string{"etag1", "new2", "new3"})
+			return &s3.CompleteMultipartUploadOutput{}, nil
+		})
+
+	u, offset, err := bkt.ResumeUpload(types.ResumeUploadData{Ctx: context.Background(), State: state})
+	c.Assert(err, qt.IsNil)
+	c.Assert(offset, qt.Equals, int64(5))
+	_, err = u.Write([]byte("abcdefghijkl")[offset:])
+	c.Assert(err, qt.IsNil)
+	attrs, err := u.Complete()
+	c.Assert(err, qt.IsNil)
+	c.Assert(attrs.Size, qt.Equals, int64(12))
+	c.Assert(uploaded, qt.DeepEquals, map[int32]string{2: "fghij", 3: "kl"})
+	c.Assert(store.deleted, qt.HasLen, 1)
+}
+
+func TestResumeUpload_Invalid(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"}).(*bucket)
+	state := types.UploadState{Bucket: "bucket", Key: "object", UploadID: "uploadID", PartSize: 5}
+
+	other := state
+	other.Bucket = "other"
+	_, _, err := bkt.ResumeUpload(types.ResumeUploadData{Ctx: ctx, State: other})
+	c.Assert(err, qt.ErrorIs, types.ErrInvalidArgument)
+
+	client.EXPECT().ListParts(gomock.Any(), gomock.Any()).Return(nil, &s3types.NoSuchUpload{})
+	_, _, err = bkt.ResumeUpload(types.ResumeUploadData{Ctx: ctx, State: state})
+	c.Assert(err, qt.ErrorIs, types.ErrInvalidArgument)
+	c.Assert(err, qt.ErrorMatches, ".*upload uploadID no longer exists.*")
+}
@@ -0,0 +1,126 @@
+package s3
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+)
+
+// RetryPolicy configures how requests that fail with a transient error
+// are retried, for all operations on a bucket. See WithRetryPolicy.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of each request,
+	// including the first. Less than 2 means requests aren't retried.
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry, which doubles
+	// with each subsequent retry. If zero, 100ms is used.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the delay between retries. If zero, 10s is used.
+	MaxDelay time.Duration
+
+	// Jitter is the fraction of each delay that's randomized, so that
+	// clients failing at the same time don't retry in lockstep.
+	// With a jitter of 0.5 the delay is chosen uniformly between half
+	// the computed delay and the full delay. It's clamped to [0, 1].
+	Jitter float64
+}
+
+// Default retry delays.
+const (
+	defaultBaseDelay = 100 * time.Millisecond
+	defaultMaxDelay  = 10 * time.Second
+)
+
+func (p RetryPolicy) maxRetries() int {
+	return max(p.MaxAttempts-1, 0)
+}
+
+// backoff reports how long to wait before the given retry attempt, starting at 1.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	base, maxDelay := cmp.Or(p.BaseDelay, defaultBaseDelay), cmp.Or(p.MaxDelay, defaultMaxDelay)
+	if attempt < 1 {
+		attempt = 1
+	}
+	d := base << (attempt - 1)
+	if d <= 0 || d > maxDelay || d>>(attempt-1) != base {
+		d = maxDelay
+	}
+	if jitter := min(max(p.Jitter, 0), 1); jitter > 0 {
+		d -= time.Duration(jitter * rand.Float64() * float64(d))
+	}
+	return d
+}
+
+func (o UploadOptions) maxRetries() int {
+	return o.MaxRetries
+}
+
+func (o UploadOptions) backoff(attempt int) time.Duration {
+	if o.RetryBackoff != nil {
+		return o.RetryBackoff(attempt)
+	}
+	return defaultRetryBackoff(attempt)
+}
+
+// defaultRetryBackoff is an exponential backoff starting at 100ms,
+// capped at 10s.
+func defaultRetryBackoff(attempt int) time.Duration {
+	return RetryPolicy{}.backoff(attempt)
+}
+
+// retrier configures how withRetry retries requests.
+// It's implemented by RetryPolicy and UploadOptions.
+type retrier interface {
+	maxRetries() int
+	backoff(attempt int) time.Duration
+}
+
+// withRetry calls fn until it succeeds, fails with a non-retryable error,
+// or the maximum number of retries has been reached.
+func withRetry[T any](ctx context.Context, r retrier, fn func() (T, error)) (T, error) {
+	for attempt := 0; ; attempt++ {
+		res, err := fn()
+		if err == nil || attempt >= r.maxRetries() || !isRetryable(err) {
+			return res, err
+		}
+
+		t := time.NewTimer(r.backoff(attempt + 1))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return res, err
+		case <-t.C:
+		}
+	}
+}
+
+// isRetryable reports whether err is a transient error
+// for which retrying the request may succeed.
+func isRetryable(err error) bool {
+	if errors.Is(err, errRequestTimeout) {
+		return true
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "SlowDown", "RequestTimeout", "InternalError", "ServiceUnavailable", "Throttling", "ThrottlingException":
+			return true
+		}
+	}
+
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		// Retrying an operation the service doesn't implement won't help.
+		return respErr.HTTPStatusCode() >= 500 && respErr.HTTPStatusCode() != http.StatusNotImplemented
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	qt "github.com/frankban/quicktest"
+	"github.com/golang/mock/gomock"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/storage/objects/internal/types"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	c := qt.New(t)
+
+	var def RetryPolicy
+	c.Assert(def.backoff(1), qt.Equals, 100*time.Millisecond)
+	c.Assert(def.backoff(3), qt.Equals, 400*time.Millisecond)
+	c.Assert(def.backoff(100), qt.Equals, 10*time.Second)
+	c.Assert(defaultRetryBackoff(2), qt.Equals, 200*time.Millisecond)
+
+	p := RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	c.Assert(p.backoff(0), qt.Equals, time.Second)
+	c.Assert(p.backoff(2), qt.Equals, 2*time.Second)
+	c.Assert(p.backoff(4), qt.Equals, 5*time.Second)
+
+	p.Jitter = 0.5
+	for range 100 {
+		d := p.backoff(2)
+		c.Assert(d >= time.Second && d <= 2*time.Second, qt.IsTrue, qt.Commentf("delay %v", d))
+	}
+
+	// Out of range jitter is clamped.
+	p.Jitter = 2
+	for range 100 {
+		d := p.backoff(2)
+		c.Assert(d >= 0 && d <= 2*time.Second, qt.IsTrue, qt.Commentf("delay %v", d))
+	}
+
+	c.Assert(RetryPolicy{}.maxRetries(), qt.Equals, 0)
+	c.Assert(RetryPolicy{MaxAttempts: 1}.maxRetries(), qt.Equals, 0)
+	c.Assert(RetryPolicy{MaxAttempts: 3}.maxRetries(), qt.Equals, 2)
+}
+
+func TestWithRetryPolicy(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	policy := RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"},
+		WithRetryPolicy(policy), WithUploadOptions(UploadOptions{MaxRetries: 10}))
+	transient := &smithy.GenericAPIError{Code: "InternalError"}
+
+	// The policy overrides the upload options.
+	b := bkt.(*bucket)
+	c.Assert(b.uploadOpts.MaxRetries, qt.Equals, 1)
+	c.Assert(b.uploadOpts.backoff(1), qt.Equals, time.Millisecond)
+
+	gomock.InOrder(
+		client.EXPECT().GetObject(gomock.Any(), gomock.Any()).Return(nil, transient),
+		client.EXPECT().GetObject(gomock.Any(), gomock.Any()).Return(&s3.GetObjectOutput{
+			Body: io.NopCloser(strings.NewReader("data")),
+		}, nil),
+	)
+	r, err := bkt.Download(types.DownloadData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(r.Close(), qt.IsNil)
+
+	gomock.InOrder(
+		client.EXPECT().HeadObject(gomock.Any(), gomock.Any()).Return(nil, transient),
+		client.EXPECT().HeadObject(gomock.Any(), gomock.Any()).Return(&s3.HeadObjectOutput{}, nil),
+	)
+	exists, err := bkt.Exists(types.ExistsData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(exists, qt.IsTrue)
+
+	gomock.InOrder(
+		client.EXPECT().ListObjectsV2(gomock.Any(), gomock.Any()).Return(nil, transient),
+		client.EXPECT().ListObjectsV2(gomock.Any(), gomock.Any()).Return(&s3.ListObjectsV2Output{}, nil),
+	)
+	for _, err := range bkt.List(types.ListData{Ctx: context.Background()}) {
+		c.Assert(err, qt.IsNil)
+	}
+
+	// Requests are attempted at most MaxAttempts times.
+	client.EXPECT().DeleteObject(gomock.Any(), gomock.Any()).Return(nil, transient).Times(2)
+	err = bkt.Remove(types.RemoveData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.ErrorMatches, ".*InternalError.*")
+
+	// Non-retryable errors aren't retried.
+	client.EXPECT().DeleteObject(gomock.Any(), gomock.Any()).Return(nil, &smithy.GenericAPIError{Code: "AccessDenied"})
+	err = bkt.Remove(types.RemoveData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.ErrorMatches, ".*AccessDenied.*")
+}
+
+func TestWithoutRetryPolicy(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"})
+
+	// Without a policy, only uploads, copies and bulk removals are retried.
+	client.EXPECT().GetObject(gomock.Any(), gomock.Any()).Return(nil, &smithy.GenericAPIError{Code: "InternalError"})
+	_, err := bkt.Download(types.DownloadData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.ErrorMatches, ".*InternalError.*")
+}
@@ -0,0 +1,118 @@
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"encore.dev/storage/objects/internal/types"
+)
+
+var _ types.SeekDownloader = (*bucket)(nil)
+
+// DownloadSeeker opens an object for random access. This suits file formats
+// like zip and Parquet that are read starting from a footer, without
+// downloading the whole object.
+//
+// The object's size is read with a single HeadObject request when opening it.
+// Reads after each seek then download the rest of the object with a ranged
+// GetObject request, which is only made once the caller reads.
+// Reads are pinned to the version of the object that was opened:
+// if it's replaced in the meantime they fail with ErrPreconditionFailed.
+func (b *bucket) DownloadSeeker(data types.DownloadSeekerData) (io.ReadSeekCloser, error) {
+	attrs, err := b.Attrs(types.AttrsData{Ctx: data.Ctx, Object: data.Object, Version: data.Version})
+	if err != nil {
+		return nil, err
+	}
+	return &seeker{
+		b:    b,
+		data: types.DownloadData{Ctx: data.Ctx, Object: attrs.Object, Version: attrs.Version},
+		etag: ptrOrNil(attrs.ETag),
+		size: attrs.Size,
+	}, nil
+}
+
+// seeker reads an object from its current offset,
+// reopening it whenever the offset changes.
+type seeker struct {
+	b    *bucket
+	data types.DownloadData
+	etag *string
+	size int64
+
+	offset int64
+	body   io.ReadCloser // nil until read from offset
+	closed bool
+}
+
+func (s *seeker) Read(p []byte) (int, error) {
+	switch {
+	case s.closed:
+		return 0, os.ErrClosed
+	case s.offset >= s.size:
+		return 0, io.EOF
+	case len(p) == 0:
+		return 0, nil
+	}
+
+	if s.body == nil {
+		resp, err := s.b.getObject(s.data, s.offset, 0, s.etag)
+		if err != nil {
+			return 0, err
+		}
+		s.body = resp.Body
+	}
+
+	n, err := s.body.Read(p)
+	s.offset += int64(n)
+	if errors.Is(err, io.EOF) {
+		s.closeBody()
+		if s.offset < s.size {
+			err = io.ErrUnexpectedEOF
+		} else if n > 0 {
+			err = nil
+		}
+	}
+	return n, err
+}
+
+func (s *seeker) Seek(offset int64, whence int) (int64, error) {
+	if s.closed {
+		return 0, os.ErrClosed
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.offset
+	case io.SeekEnd:
+		offset += s.size
+	default:
+		return 0, fmt.Errorf("s3: invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, errors.New("s3: negative position")
+	}
+	if offset != s.offset {
+		s.closeBody()
+		s.offset = offset
+	}
+	return offset, nil
+}
+
+func (s *seeker) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.closeBody()
+}
+
+func (s *seeker) closeBody() error {
+	if s.body == nil {
+		return nil
+	}
+	err := s.body.Close()
+	s.body = nil
+	return err
+}
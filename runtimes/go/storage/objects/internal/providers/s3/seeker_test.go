@@ -0,0 +1,141 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	qt "github.com/frankban/quicktest"
+	"github.com/golang/mock/gomock"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/storage/objects/internal/types"
+)
+
+func TestDownloadSeeker(t *testing.T) {
+	c := qt.New(t)
+
+	const content = "0123456789abcdefFOOT"
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"}).(*bucket)
+
+	client.EXPECT().HeadObject(gomock.Any(), &s3.HeadObjectInput{
+		Bucket: ptr("bucket"),
+		Key:    ptr("object"),
+	}).Return(&s3.HeadObjectOutput{
+		ContentLength: ptr(int64(len(content))),
+		ETag:          ptr(`"etag"`),
+	}, nil)
+
+	// expectRange expects a request for the rest of the object from offset.
+	expectRange := func(offset int) {
+		client.EXPECT().GetObject(gomock.Any(), &s3.GetObjectInput{
+			Bucket:  ptr("bucket"),
+			Key:     ptr("object"),
+			Range:   rangeHeader(int64(offset), 0),
+			IfMatch: ptr(`"etag"`),
+		}).Return(&s3.GetObjectOutput{
+			Body: io.NopCloser(strings.NewReader(content[offset:])),
+		}, nil)
+	}
+
+	r, err := bkt.DownloadSeeker(types.DownloadSeekerData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.IsNil)
+	defer r.Close()
+
+	// Seeking alone doesn't download anything.
+	pos, err := r.Seek(-4, io.SeekEnd)
+	c.Assert(err, qt.IsNil)
+	c.Assert(pos, qt.Equals, int64(16))
+
+	expectRange(16)
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(r, buf)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(buf), qt.Equals, "FOOT")
+	_, err = r.Read(buf)
+	c.Assert(err, qt.Equals, io.EOF)
+
+	pos, err = r.Seek(2, io.SeekStart)
+	c.Assert(err, qt.IsNil)
+	c.Assert(pos, qt.Equals, int64(2))
+
+	expectRange(2)
+	_, err = io.ReadFull(r, buf[:3])
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(buf[:3]), qt.Equals, "234")
+
+	// Seeking to the current position keeps reading the same response.
+	pos, err = r.Seek(0, io.SeekCurrent)
+	c.Assert(err, qt.IsNil)
+	c.Assert(pos, qt.Equals, int64(5))
+	_, err = io.ReadFull(r, buf[:2])
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(buf[:2]), qt.Equals, "56")
+
+	expectRange(10)
+	pos, err = r.Seek(3, io.SeekCurrent)
+	c.Assert(err, qt.IsNil)
+	c.Assert(pos, qt.Equals, int64(10))
+	rest, err := io.ReadAll(r)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(rest), qt.Equals, "abcdefFOOT")
+
+	// Reading past the end doesn't make a request.
+	_, err = r.Seek(100, io.SeekStart)
+	c.Assert(err, qt.IsNil)
+	_, err = r.Read(buf)
+	c.Assert(err, qt.Equals, io.EOF)
+
+	_, err = r.Seek(-1, io.SeekStart)
+	c.Assert(err, qt.ErrorMatches, "s3: negative position")
+}
+
+func TestDownloadSeeker_Empty(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"}).(*bucket)
+
+	client.EXPECT().HeadObject(gomock.Any(), &s3.HeadObjectInput{
+		Bucket:    ptr("bucket"),
+		Key:       ptr("object"),
+		VersionId: ptr("v1"),
+	}).Return(&s3.HeadObjectOutput{
+		ContentLength: ptr(int64(0)),
+	}, nil)
+
+	r, err := bkt.DownloadSeeker(types.DownloadSeekerData{Ctx: context.Background(), Object: "object", Version: "v1"})
+	c.Assert(err, qt.IsNil)
+	data, err := io.ReadAll(r)
+	c.Assert(err, qt.IsNil)
+	c.Assert(data, qt.HasLen, 0)
+	c.Assert(r.Close(), qt.IsNil)
+
+	_, err = r.Read(make([]byte, 1))
+	c.Assert(err, qt.ErrorMatches, ".*file already closed")
+}
+
+func TestDownloadSeeker_Replaced(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"}).(*bucket)
+
+	client.EXPECT().HeadObject(gomock.Any(), gomock.Any()).Return(&s3.HeadObjectOutput{
+		ContentLength: ptr(int64(10)),
+		ETag:          ptr(`"etag"`),
+	}, nil)
+	client.EXPECT().GetObject(gomock.Any(), gomock.Any()).Return(nil, &smithy.GenericAPIError{Code: "PreconditionFailed"})
+
+	r, err := bkt.DownloadSeeker(types.DownloadSeekerData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.IsNil)
+	_, err = r.Read(make([]byte, 1))
+	c.Assert(err, qt.Equals, types.ErrPreconditionFailed)
+}
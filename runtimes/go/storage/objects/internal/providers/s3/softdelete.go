@@ -0,0 +1,151 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"encore.dev/storage/objects/internal/types"
+)
+
+// trashPrefix is the prefix objects are moved under when soft-deleted.
+const trashPrefix = ".trash/"
+
+// trashTimeFormat is the format of deletion times in trash keys.
+// It's fixed-width, so the trash is listed in the order objects were deleted.
+const trashTimeFormat = "20060102T150405.000000000Z"
+
+// WithSoftDelete configures the bucket to move objects to the trash when
+// they're removed, rather than deleting them permanently. Trashed objects
+// are copied to ".trash/<deletion time>/<key>" before the original is
+// removed, and can be restored by copying them back. Trashed objects
+// are only listed when the listing's prefix starts with ".trash/".
+//
+// Objects already in the trash are deleted permanently when removed.
+// Use PurgeTrash to delete the objects trashed before a given age.
+func WithSoftDelete() Option {
+	return func(o *bucketOptions) { o.softDelete = true }
+}
+
+// trashKey returns the key an object deleted at the given time is moved to.
+func trashKey(object types.CloudObject, deleted time.Time) types.CloudObject {
+	return types.CloudObject(trashPrefix + deleted.UTC().Format(trashTimeFormat) + "/" + string(object))
+}
+
+// parseTrashKey returns the object a trash key was moved from, and the
+// time it was deleted. It reports false if key isn't a trash key.
+func parseTrashKey(key types.CloudObject) (object types.CloudObject, deleted time.Time, ok bool) {
+	rest, ok := strings.CutPrefix(string(key), trashPrefix)
+	if !ok {
+		return "", time.Time{}, false
+	}
+	ts, orig, ok := strings.Cut(rest, "/")
+	if !ok {
+		return "", time.Time{}, false
+	}
+	deleted, err := time.Parse(trashTimeFormat, ts)
+	return types.CloudObject(orig), deleted, err == nil
+}
+
+// hidesTrash reports whether listing objects under prefix skips the trash.
+// The trash is only listed when the prefix explicitly targets it.
+func (b *bucket) hidesTrash(prefix string) bool {
+	return b.softDelete && !strings.HasPrefix(prefix, trashPrefix)
+}
+
+// inTrash reports whether the entry is in the trash.
+func inTrash(entry *types.ListEntry) bool {
+	return strings.HasPrefix(string(entry.Object), trashPrefix)
+}
+
+// moveToTrash copies the object to the trash ahead of it being removed.
+// Objects that don't exist have nothing to keep, and are skipped.
+func (b *bucket) moveToTrash(ctx context.Context, object types.CloudObject, version string) error {
+	if strings.HasPrefix(string(object), trashPrefix) {
+		return nil
+	}
+	_, err := b.Copy(types.CopyData{
+		Ctx:       ctx,
+		Object:    object,
+		Version:   version,
+		DstObject: trashKey(object, time.Now()),
+	})
+	if err != nil && !errors.Is(err, types.ErrObjectNotExist) {
+		return fmt.Errorf("move %s to trash: %w", object, err)
+	}
+	return nil
+}
+
+// moveAllToTrash moves the objects to the trash in parallel,
+// returning the errors of those that couldn't be moved.
+func (b *bucket) moveAllToTrash(ctx context.Context, objects []types.CloudObject) map[types.CloudObject]error {
+	var (
+		mu   sync.Mutex
+		errs = make(map[types.CloudObject]error)
+	)
+	var g errgroup.Group
+	g.SetLimit(b.uploadOpts.concurrency())
+	for _, obj := range objects {
+		g.Go(func() error {
+			if err := b.moveToTrash(ctx, obj, ""); err != nil {
+				mu.Lock()
+				errs[obj] = err
+				mu.Unlock()
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+	return errs
+}
+
+var _ types.TrashPurger = (*bucket)(nil)
+
+// PurgeTrash permanently deletes the objects that were moved to the trash
+// by soft deletes more than data.OlderThan ago, and returns the number of
+// objects deleted. Purging stops at the first object that fails to be deleted.
+func (b *bucket) PurgeTrash(data types.PurgeTrashData) (purged int, err error) {
+	ctx := data.Ctx
+	cutoff := time.Now().Add(-data.OlderThan)
+
+	var batch []types.CloudObject
+	purge := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		results, err := b.RemoveAll(types.RemoveAllData{Ctx: ctx, Objects: batch})
+		for _, res := range results {
+			if res.Err != nil {
+				return fmt.Errorf("purge %s: %w", res.Object, res.Err)
+			}
+			purged++
+		}
+		batch = batch[:0]
+		return err
+	}
+
+	for entry, err := range b.List(types.ListData{Ctx: ctx, Prefix: trashPrefix}) {
+		if err != nil {
+			return purged, err
+		}
+		object, deleted, ok := parseTrashKey(entry.Object)
+		if !ok {
+			continue // not put there by a soft delete
+		} else if !deleted.Before(cutoff) {
+			break // the rest were deleted later
+		} else if !strings.HasPrefix(string(object), data.Prefix) {
+			continue
+		}
+		if batch = append(batch, entry.Object); len(batch) == maxDeleteObjects {
+			if err := purge(); err != nil {
+				return purged, err
+			}
+		}
+	}
+	return purged, purge()
+}
@@ -0,0 +1,179 @@
+package s3
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	qt "github.com/frankban/quicktest"
+	"github.com/golang/mock/gomock"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/storage/objects/internal/types"
+)
+
+func TestSoftDelete_Remove(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"}, WithSoftDelete())
+	ctx := context.Background()
+
+	// The object is copied to the trash before it's deleted.
+	start := time.Now()
+	gomock.InOrder(
+		client.EXPECT().HeadObject(gomock.Any(), gomock.Any()).Return(&s3.HeadObjectOutput{ContentLength: ptr(int64(1))}, nil),
+		client.EXPECT().CopyObject(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, in *s3.CopyObjectInput, _ ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+				_, deleted, ok := parseTrashKey(types.CloudObject(valOrZero(in.Key)))
+				c.Check(ok, qt.IsTrue)
+				c.Check(deleted.Before(start.Add(-time.Second)), qt.IsFalse)
+				c.Check(strings.HasSuffix(valOrZero(in.Key), "/dir/object"), qt.IsTrue)
+				c.Check(valOrZero(in.CopySource), qt.Equals, "bucket/dir/object")
+				return &s3.CopyObjectOutput{}, nil
+			}),
+		client.EXPECT().DeleteObject(gomock.Any(), &s3.DeleteObjectInput{Bucket: ptr("bucket"), Key: ptr("dir/object")}).
+			Return(&s3.DeleteObjectOutput{}, nil),
+	)
+	c.Assert(bkt.Remove(types.RemoveData{Ctx: ctx, Object: "dir/object"}), qt.IsNil)
+
+	// Objects that don't exist have nothing to keep.
+	gomock.InOrder(
+		client.EXPECT().HeadObject(gomock.Any(), gomock.Any()).Return(nil, &s3types.NotFound{}),
+		client.EXPECT().DeleteObject(gomock.Any(), gomock.Any()).Return(&s3.DeleteObjectOutput{}, nil),
+	)
+	c.Assert(bkt.Remove(types.RemoveData{Ctx: ctx, Object: "missing"}), qt.IsNil)
+
+	// Objects in the trash are deleted permanently.
+	client.EXPECT().DeleteObject(gomock.Any(), gomock.Any()).Return(&s3.DeleteObjectOutput{}, nil)
+	c.Assert(bkt.Remove(types.RemoveData{Ctx: ctx, Object: trashKey("object", start)}), qt.IsNil)
+
+	// The object is kept if it can't be moved to the trash.
+	client.EXPECT().HeadObject(gomock.Any(), gomock.Any()).Return(nil, &smithy.GenericAPIError{Code: "AccessDenied"})
+	c.Assert(bkt.Remove(types.RemoveData{Ctx: ctx, Object: "object"}), qt.ErrorMatches, "move object to trash: .*AccessDenied.*")
+}
+
+func TestSoftDelete_RemoveAll(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"}, WithSoftDelete())
+
+	client.EXPECT().HeadObject(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.HeadObjectInput, _ ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+			if valOrZero(in.Key) == "denied" {
+				return nil, &smithy.GenericAPIError{Code: "AccessDenied"}
+			}
+			return &s3.HeadObjectOutput{ContentLength: ptr(int64(1))}, nil
+		}).Times(2)
+	client.EXPECT().CopyObject(gomock.Any(), gomock.Any()).Return(&s3.CopyObjectOutput{}, nil)
+	client.EXPECT().DeleteObjects(gomock.Any(), &s3.DeleteObjectsInput{
+		Bucket: ptr("bucket"),
+		Delete: &s3types.Delete{Objects: []s3types.ObjectIdentifier{{Key: ptr("ok")}}, Quiet: ptr(true)},
+	}).Return(&s3.DeleteObjectsOutput{}, nil)
+
+	results, err := bkt.RemoveAll(types.RemoveAllData{Ctx: context.Background(), Objects: []types.CloudObject{"denied", "ok"}})
+	c.Assert(err, qt.IsNil)
+	c.Assert(results, qt.HasLen, 2)
+	c.Assert(results[0].Object, qt.Equals, types.CloudObject("denied"))
+	c.Assert(results[0].Err, qt.ErrorMatches, ".*AccessDenied.*")
+	c.Assert(results[1], qt.DeepEquals, types.RemoveResult{Object: "ok"})
+}
+
+func TestPurgeTrash(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"}, WithSoftDelete()).(*bucket)
+
+	now := time.Now()
+	old1, old2 := trashKey("dir/a", now.Add(-72*time.Hour)), trashKey("dir/b", now.Add(-48*time.Hour))
+	other := trashKey("other/a", now.Add(-48*time.Hour))
+	recent := trashKey("dir/c", now.Add(-time.Hour))
+
+	client.EXPECT().ListObjectsV2(gomock.Any(), gomock.Any()).Return(&s3.ListObjectsV2Output{
+		Contents: []s3types.Object{
+			{Key: ptr(string(old1))},
+			{Key: ptr(string(old2))},
+			{Key: ptr(string(other))},
+			{Key: ptr(string(recent))},
+		},
+	}, nil)
+	client.EXPECT().DeleteObjects(gomock.Any(), &s3.DeleteObjectsInput{
+		Bucket: ptr("bucket"),
+		Delete: &s3types.Delete{
+			Objects: []s3types.ObjectIdentifier{{Key: ptr(string(old1))}, {Key: ptr(string(old2))}},
+			Quiet:   ptr(true),
+		},
+	}).Return(&s3.DeleteObjectsOutput{}, nil)
+
+	// Only objects trashed from under the prefix are purged.
+	purged, err := bkt.PurgeTrash(types.PurgeTrashData{Ctx: context.Background(), Prefix: "dir/", OlderThan: 24 * time.Hour})
+	c.Assert(err, qt.IsNil)
+	c.Assert(purged, qt.Equals, 2)
+}
+
+func TestParseTrashKey(t *testing.T) {
+	c := qt.New(t)
+
+	deleted := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	key := trashKey("dir/object", deleted)
+	c.Assert(key, qt.Equals, types.CloudObject(".trash/20240102T030405.000000006Z/dir/object"))
+	object, got, ok := parseTrashKey(key)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(object, qt.Equals, types.CloudObject("dir/object"))
+	c.Assert(got.Equal(deleted), qt.IsTrue)
+
+	for _, key := range []types.CloudObject{"dir/object", ".trash/object", ".trash/invalid/object"} {
+		_, _, ok := parseTrashKey(key)
+		c.Assert(ok, qt.IsFalse, qt.Commentf("key %q", key))
+	}
+}
+
+func TestSoftDelete_ListHidesTrash(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"}, WithSoftDelete()).(*bucket)
+	ctx := context.Background()
+
+	trashed := trashKey("object", time.Now())
+	client.EXPECT().ListObjectsV2(gomock.Any(), gomock.Any()).Return(&s3.ListObjectsV2Output{
+		Contents: []s3types.Object{
+			{Key: ptr(string(trashed))},
+			{Key: ptr("dir/object")},
+			{Key: ptr("object")},
+		},
+	}, nil).Times(4)
+
+	list := func(prefix string) []types.CloudObject {
+		var objects []types.CloudObject
+		for entry, err := range bkt.List(types.ListData{Ctx: ctx, Prefix: prefix}) {
+			c.Assert(err, qt.IsNil)
+			objects = append(objects, entry.Object)
+		}
+		return objects
+	}
+
+	// The trash is skipped, unless the prefix targets it.
+	c.Assert(list(""), qt.DeepEquals, []types.CloudObject{"dir/object", "object"})
+	c.Assert(list(trashPrefix), qt.DeepEquals, []types.CloudObject{trashed, "dir/object", "object"})
+
+	entries, _, err := bkt.ListPage(types.ListPageData{Ctx: ctx})
+	c.Assert(err, qt.IsNil)
+	c.Assert(entries, qt.HasLen, 2)
+
+	// Watch snapshots don't report the trash either.
+	snap, err := bkt.snapshot(ctx, "")
+	c.Assert(err, qt.IsNil)
+	c.Assert(snap, qt.HasLen, 2)
+	c.Assert(snap[trashed], qt.Equals, types.ListEntry{})
+}
@@ -0,0 +1,67 @@
+package s3
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"encore.dev/storage/objects/internal/types"
+)
+
+// maxTags is the maximum number of tags S3 allows on an object.
+const maxTags = 10
+
+// validateTags reports an error if S3 would reject the tags.
+func validateTags(tags map[string]string) error {
+	if len(tags) > maxTags {
+		return fmt.Errorf("%w: at most %d tags can be set on an object, got %d",
+			types.ErrInvalidArgument, maxTags, len(tags))
+	}
+	return nil
+}
+
+// tagging encodes tags as URL query parameters, the format S3 expects
+// for the Tagging field of PutObject and CreateMultipartUpload.
+// It returns nil if there are no tags.
+func tagging(tags map[string]string) *string {
+	if len(tags) == 0 {
+		return nil
+	}
+	var sb strings.Builder
+	for i, k := range sortedKeys(tags) {
+		if i > 0 {
+			sb.WriteByte('&')
+		}
+		sb.WriteString(tagEscape(k))
+		sb.WriteByte('=')
+		sb.WriteString(tagEscape(tags[k]))
+	}
+	return ptr(sb.String())
+}
+
+// tagEscape is like url.QueryEscape but encodes spaces as "%20"
+// rather than "+", which S3 would otherwise keep as a literal plus.
+func tagEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// tagSet returns tags as a tag set, in key order.
+func tagSet(tags map[string]string) []s3types.Tag {
+	set := make([]s3types.Tag, 0, len(tags))
+	for _, k := range sortedKeys(tags) {
+		set = append(set, s3types.Tag{Key: ptr(k), Value: ptr(tags[k])})
+	}
+	return set
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,146 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// errRequestTimeout is reported when a request exceeds its timeout,
+// as configured by WithRequestTimeout.
+var errRequestTimeout = errors.New("s3: request timed out")
+
+// timeoutClient is an s3Client that bounds the duration of each request.
+type timeoutClient struct {
+	s3Client
+	timeout time.Duration
+}
+
+// callWithTimeout calls fn with a context that's canceled after timeout.
+func callWithTimeout[In, Out any](ctx context.Context, timeout time.Duration,
+	fn func(context.Context, In, ...func(*s3.Options)) (Out, error), in In, optFns []func(*s3.Options)) (Out, error) {
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	out, err := fn(reqCtx, in, optFns...)
+	return out, timeoutErr(ctx, reqCtx, err)
+}
+
+// timeoutErr marks err as a timeout if it was caused by
+// reqCtx timing out rather than ctx being done.
+func timeoutErr(ctx, reqCtx context.Context, err error) error {
+	if err != nil && ctx.Err() == nil && errors.Is(reqCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", errRequestTimeout, err)
+	}
+	return err
+}
+
+// GetObject only bounds the time until the response starts,
+// so that reading large objects isn't interrupted.
+// The request is canceled when the body is closed.
+func (c *timeoutClient) GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	reqCtx, cancel := context.WithCancelCause(ctx)
+	timer := time.AfterFunc(c.timeout, func() { cancel(context.DeadlineExceeded) })
+	out, err := c.s3Client.GetObject(reqCtx, in, optFns...)
+	if !timer.Stop() && err == nil {
+		// The timeout fired just as the response arrived.
+		err = context.DeadlineExceeded
+		_ = out.Body.Close()
+	}
+	if err != nil {
+		cancel(nil)
+		if ctx.Err() == nil && errors.Is(context.Cause(reqCtx), context.DeadlineExceeded) {
+			err = fmt.Errorf("%w: %w", errRequestTimeout, err)
+		}
+		return nil, err
+	}
+	out.Body = &cancelOnClose{ReadCloser: out.Body, cancel: func() { cancel(nil) }}
+	return out, nil
+}
+
+// cancelOnClose cancels a request's context when its body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel func()
+}
+
+func (r *cancelOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.cancel()
+	return err
+}
+
+func (c *timeoutClient) PutObject(ctx context.Context, in *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	return callWithTimeout(ctx, c.timeout, c.s3Client.PutObject, in, optFns)
+}
+
+func (c *timeoutClient) CreateMultipartUpload(ctx context.Context, in *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	return callWithTimeout(ctx, c.timeout, c.s3Client.CreateMultipartUpload, in, optFns)
+}
+
+func (c *timeoutClient) UploadPart(ctx context.Context, in *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	return callWithTimeout(ctx, c.timeout, c.s3Client.UploadPart, in, optFns)
+}
+
+func (c *timeoutClient) CompleteMultipartUpload(ctx context.Context, in *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	return callWithTimeout(ctx, c.timeout, c.s3Client.CompleteMultipartUpload, in, optFns)
+}
+
+func (c *timeoutClient) UploadPartCopy(ctx context.Context, in *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error) {
+	return callWithTimeout(ctx, c.timeout, c.s3Client.UploadPartCopy, in, optFns)
+}
+
+func (c *timeoutClient) AbortMultipartUpload(ctx context.Context, in *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	return callWithTimeout(ctx, c.timeout, c.s3Client.AbortMultipartUpload, in, optFns)
+}
+
+func (c *timeoutClient) HeadObject(ctx context.Context, in *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return callWithTimeout(ctx, c.timeout, c.s3Client.HeadObject, in, optFns)
+}
+
+func (c *timeoutClient) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	return callWithTimeout(ctx, c.timeout, c.s3Client.ListObjectsV2, in, optFns)
+}
+
+func (c *timeoutClient) PutObjectTagging(ctx context.Context, in *s3.PutObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.PutObjectTaggingOutput, error) {
+	return callWithTimeout(ctx, c.timeout, c.s3Client.PutObjectTagging, in, optFns)
+}
+
+func (c *timeoutClient) CopyObject(ctx context.Context, in *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	return callWithTimeout(ctx, c.timeout, c.s3Client.CopyObject, in, optFns)
+}
+
+func (c *timeoutClient) DeleteObject(ctx context.Context, in *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	return callWithTimeout(ctx, c.timeout, c.s3Client.DeleteObject, in, optFns)
+}
+
+func (c *timeoutClient) CreateBucket(ctx context.Context, in *s3.CreateBucketInput, optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
+	return callWithTimeout(ctx, c.timeout, c.s3Client.CreateBucket, in, optFns)
+}
+
+func (c *timeoutClient) HeadBucket(ctx context.Context, in *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	return callWithTimeout(ctx, c.timeout, c.s3Client.HeadBucket, in, optFns)
+}
+
+func (c *timeoutClient) DeleteObjects(ctx context.Context, in *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	return callWithTimeout(ctx, c.timeout, c.s3Client.DeleteObjects, in, optFns)
+}
+
+func (c *timeoutClient) GetObjectTagging(ctx context.Context, in *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error) {
+	return callWithTimeout(ctx, c.timeout, c.s3Client.GetObjectTagging, in, optFns)
+}
+
+func (c *timeoutClient) GetBucketVersioning(ctx context.Context, in *s3.GetBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.GetBucketVersioningOutput, error) {
+	return callWithTimeout(ctx, c.timeout, c.s3Client.GetBucketVersioning, in, optFns)
+}
+
+func (c *timeoutClient) GetObjectLockConfiguration(ctx context.Context, in *s3.GetObjectLockConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetObjectLockConfigurationOutput, error) {
+	return callWithTimeout(ctx, c.timeout, c.s3Client.GetObjectLockConfiguration, in, optFns)
+}
+
+func (c *timeoutClient) GetBucketEncryption(ctx context.Context, in *s3.GetBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.GetBucketEncryptionOutput, error) {
+	return callWithTimeout(ctx, c.timeout, c.s3Client.GetBucketEncryption, in, optFns)
+}
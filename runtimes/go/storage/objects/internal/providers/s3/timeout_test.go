@@ -0,0 +1,81 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	qt "github.com/frankban/quicktest"
+	"github.com/golang/mock/gomock"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/storage/objects/internal/types"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"},
+		WithRequestTimeout(10*time.Millisecond))
+
+	client.EXPECT().HeadObject(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.HeadObjectInput, _ ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+	_, err := bkt.Attrs(types.AttrsData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.ErrorIs, errRequestTimeout)
+	c.Assert(err, qt.ErrorIs, context.DeadlineExceeded)
+	c.Assert(isRetryable(err), qt.IsTrue)
+
+	// A canceled parent context is not reported as a timeout.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client.EXPECT().HeadObject(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.HeadObjectInput, _ ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+			return nil, ctx.Err()
+		})
+	_, err = bkt.Attrs(types.AttrsData{Ctx: ctx, Object: "object"})
+	c.Assert(err, qt.ErrorIs, context.Canceled)
+	c.Assert(err, qt.Not(qt.ErrorIs), errRequestTimeout)
+}
+
+func TestRequestTimeout_Download(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"},
+		WithRequestTimeout(10*time.Millisecond))
+
+	// Reading the body may take longer than the timeout.
+	var reqCtx context.Context
+	client.EXPECT().GetObject(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+			reqCtx = ctx
+			return &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader("data"))}, nil
+		})
+	r, err := bkt.Download(types.DownloadData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.IsNil)
+	time.Sleep(20 * time.Millisecond)
+	c.Assert(reqCtx.Err(), qt.IsNil)
+	data, err := io.ReadAll(r)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(data), qt.Equals, "data")
+	c.Assert(r.Close(), qt.IsNil)
+	c.Assert(reqCtx.Err(), qt.IsNotNil)
+
+	// Waiting for the response may not.
+	client.EXPECT().GetObject(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+	_, err = bkt.Download(types.DownloadData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.ErrorIs, errRequestTimeout)
+}
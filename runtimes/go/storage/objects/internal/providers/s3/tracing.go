@@ -0,0 +1,109 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"io"
+	"iter"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"encore.dev/storage/objects/internal/types"
+)
+
+// tracerName is the name of the tracer used for buckets
+// configured to be traced in the runtime config.
+const tracerName = "encore.dev/storage/objects/s3"
+
+// Span attribute keys for object operations.
+const (
+	attrOperation  = attribute.Key("objects.operation")
+	attrBucket     = attribute.Key("objects.bucket")
+	attrKey        = attribute.Key("objects.key")
+	attrBytes      = attribute.Key("objects.bytes")
+	attrCount      = attribute.Key("objects.count")
+	attrPartNumber = attribute.Key("objects.part_number")
+)
+
+// startSpan starts a span for the operation on the given object,
+// which may be empty for operations on the bucket as a whole.
+// If tracer is nil it returns ctx and a span that records nothing.
+func startSpan(ctx context.Context, tracer trace.Tracer, op, bucket string, object types.CloudObject) (context.Context, trace.Span) {
+	if tracer == nil {
+		return ctx, noop.Span{}
+	}
+	attrs := []attribute.KeyValue{attrOperation.String(op), attrBucket.String(bucket)}
+	if object != "" {
+		attrs = append(attrs, attrKey.String(string(object)))
+	}
+	return tracer.Start(ctx, "s3."+op, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// endSpan ends the span, recording err as its status.
+// Unless bytes is negative it's recorded as the number of bytes transferred.
+func endSpan(span trace.Span, err error, bytes int64) {
+	if bytes >= 0 {
+		span.SetAttributes(attrBytes.Int64(bytes))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// tracedList wraps the listing of a bucket in an operation,
+// which ends once the caller stops iterating.
+func (b *bucket) tracedList(data types.ListData) iter.Seq2[*types.ListEntry, error] {
+	return func(yield func(*types.ListEntry, error) bool) {
+		ctx, op := b.startOp(data.Ctx, "List", "")
+		data.Ctx = ctx
+
+		var (
+			n   int64
+			err error
+		)
+		for entry, entryErr := range b.list(data) {
+			if entryErr != nil {
+				err = entryErr
+			} else {
+				n++
+			}
+			if !yield(entry, entryErr) {
+				break
+			}
+		}
+		op.span.SetAttributes(attrCount.Int64(n))
+		op.end(err, -1)
+	}
+}
+
+// tracedDownloader ends a download's operation once it's closed,
+// recording the number of bytes read.
+type tracedDownloader struct {
+	types.Downloader
+	op  *operation
+	n   int64
+	err error
+}
+
+func (d *tracedDownloader) Read(p []byte) (int, error) {
+	n, err := d.Downloader.Read(p)
+	d.n += int64(n)
+	if err != nil && !errors.Is(err, io.EOF) {
+		d.err = err
+	}
+	return n, err
+}
+
+func (d *tracedDownloader) Close() error {
+	err := d.Downloader.Close()
+	if d.err == nil {
+		d.err = err
+	}
+	d.op.end(d.err, d.n)
+	return err
+}
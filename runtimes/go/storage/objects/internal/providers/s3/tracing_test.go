@@ -0,0 +1,128 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	qt "github.com/frankban/quicktest"
+	"github.com/golang/mock/gomock"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/storage/objects/internal/types"
+)
+
+func newTracedBucket(c *qt.C) (*Mocks3Client, types.BucketImpl, *tracetest.SpanRecorder) {
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"}, WithTracer(tp.Tracer("test")))
+	return client, bkt, rec
+}
+
+func spanAttr(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracing_Download(t *testing.T) {
+	c := qt.New(t)
+	client, bkt, rec := newTracedBucket(c)
+
+	client.EXPECT().GetObject(gomock.Any(), gomock.Any()).Return(&s3.GetObjectOutput{
+		Body: io.NopCloser(strings.NewReader("hello")),
+	}, nil)
+	r, err := bkt.Download(types.DownloadData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.IsNil)
+	_, err = io.ReadAll(r)
+	c.Assert(err, qt.IsNil)
+	c.Assert(rec.Ended(), qt.HasLen, 0, qt.Commentf("span ended before the download was closed"))
+	c.Assert(r.Close(), qt.IsNil)
+
+	spans := rec.Ended()
+	c.Assert(spans, qt.HasLen, 1)
+	c.Assert(spans[0].Name(), qt.Equals, "s3.Download")
+	c.Assert(spanAttr(spans[0], attrBucket).AsString(), qt.Equals, "bucket")
+	c.Assert(spanAttr(spans[0], attrKey).AsString(), qt.Equals, "object")
+	c.Assert(spanAttr(spans[0], attrBytes).AsInt64(), qt.Equals, int64(5))
+	c.Assert(spans[0].Status().Code, qt.Equals, codes.Unset)
+}
+
+func TestTracing_Error(t *testing.T) {
+	c := qt.New(t)
+	client, bkt, rec := newTracedBucket(c)
+
+	client.EXPECT().DeleteObject(gomock.Any(), gomock.Any()).Return(nil, &s3types.NoSuchKey{})
+	err := bkt.Remove(types.RemoveData{Ctx: context.Background(), Object: "missing"})
+	c.Assert(err, qt.Equals, types.ErrObjectNotExist)
+
+	spans := rec.Ended()
+	c.Assert(spans, qt.HasLen, 1)
+	c.Assert(spans[0].Name(), qt.Equals, "s3.Remove")
+	c.Assert(spans[0].Status().Code, qt.Equals, codes.Error)
+	c.Assert(spans[0].Status().Description, qt.Equals, types.ErrObjectNotExist.Error())
+}
+
+func TestTracing_MultipartUpload(t *testing.T) {
+	c := qt.New(t)
+	withBufSize(c, 5)
+	client, bkt, rec := newTracedBucket(c)
+
+	client.EXPECT().CreateMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CreateMultipartUploadOutput{UploadId: ptr("uploadID")}, nil)
+	client.EXPECT().UploadPart(gomock.Any(), gomock.Any()).Return(&s3.UploadPartOutput{}, nil)
+	client.EXPECT().UploadPart(gomock.Any(), gomock.Any()).Return(nil, errors.New("part failed"))
+	client.EXPECT().AbortMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.AbortMultipartUploadOutput{}, nil).AnyTimes()
+
+	u, err := bkt.Upload(types.UploadData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.IsNil)
+	_, _ = u.Write([]byte("abcdefghij"))
+	_, err = u.Complete()
+	c.Assert(err, qt.ErrorMatches, "part failed")
+
+	var upload sdktrace.ReadOnlySpan
+	var parts []sdktrace.ReadOnlySpan
+	for _, span := range rec.Ended() {
+		switch span.Name() {
+		case "s3.Upload":
+			upload = span
+		case "s3.UploadPart":
+			parts = append(parts, span)
+		}
+	}
+	c.Assert(upload, qt.IsNotNil)
+	c.Assert(upload.Status().Code, qt.Equals, codes.Error)
+	c.Assert(parts, qt.HasLen, 2)
+	for _, part := range parts {
+		c.Assert(part.Parent().SpanID(), qt.Equals, upload.SpanContext().SpanID())
+		c.Assert(spanAttr(part, attrBytes).AsInt64(), qt.Equals, int64(5))
+	}
+}
+
+func TestTracing_Disabled(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"}, WithTracer(nil))
+
+	client.EXPECT().GetObject(gomock.Any(), gomock.Any()).Return(&s3.GetObjectOutput{
+		Body: io.NopCloser(strings.NewReader("hello")),
+	}, nil)
+	r, err := bkt.Download(types.DownloadData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.IsNil)
+	_, isTraced := r.(*tracedDownloader)
+	c.Assert(isTraced, qt.IsFalse)
+}
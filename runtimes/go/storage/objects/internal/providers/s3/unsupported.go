@@ -0,0 +1,22 @@
+package s3
+
+import (
+	"errors"
+	"net/http"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+)
+
+// isUnsupported reports whether err is the storage service rejecting
+// an operation it doesn't implement. S3-compatible services like MinIO
+// and Ceph report this with a NotImplemented error code, and others
+// only with a 501 Not Implemented status.
+func isUnsupported(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "NotImplemented" {
+		return true
+	}
+	var respErr *awshttp.ResponseError
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotImplemented
+}
@@ -0,0 +1,79 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	qt "github.com/frankban/quicktest"
+	"github.com/golang/mock/gomock"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/storage/objects/internal/types"
+)
+
+func TestUnsupportedByProvider(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"},
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}))
+	ctx := context.Background()
+
+	// MinIO and Ceph report unsupported APIs with a NotImplemented error code.
+	client.EXPECT().PutObjectTagging(gomock.Any(), gomock.Any()).
+		Return(nil, &smithy.GenericAPIError{Code: "NotImplemented", Message: "A header you provided implies functionality that is not implemented"})
+	err := bkt.SetTags(types.SetTagsData{Ctx: ctx, Object: "object", Tags: map[string]string{"k": "v"}})
+	c.Assert(errors.Is(err, types.ErrUnsupportedByProvider), qt.IsTrue, qt.Commentf("got %v", err))
+	c.Assert(err, qt.ErrorMatches, ".*not implemented.*")
+
+	// Services that only report a 501 status are detected too,
+	// and the request isn't retried.
+	notImplemented := &awshttp.ResponseError{ResponseError: &smithyhttp.ResponseError{
+		Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusNotImplemented}},
+		Err:      errors.New("not implemented"),
+	}}
+	client.EXPECT().HeadObject(gomock.Any(), gomock.Any()).Return(nil, notImplemented).Times(1)
+	_, err = bkt.Attrs(types.AttrsData{Ctx: ctx, Object: "object"})
+	c.Assert(errors.Is(err, types.ErrUnsupportedByProvider), qt.IsTrue, qt.Commentf("got %v", err))
+
+	// Other server errors are retried as before.
+	c.Assert(isRetryable(&awshttp.ResponseError{ResponseError: &smithyhttp.ResponseError{
+		Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusBadGateway}},
+	}}), qt.IsTrue)
+}
+
+func TestUnsupportedByProvider_CopyParts(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"}).(*bucket)
+
+	// Some S3-compatible services don't implement UploadPartCopy.
+	client.EXPECT().CreateMultipartUpload(gomock.Any(), gomock.Any()).Return(
+		&s3.CreateMultipartUploadOutput{UploadId: ptr("upload")}, nil)
+	client.EXPECT().UploadPartCopy(gomock.Any(), gomock.Any()).Return(
+		nil, &smithy.GenericAPIError{Code: "NotImplemented"})
+	aborted := make(chan struct{})
+	client.EXPECT().AbortMultipartUpload(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, _ *s3.AbortMultipartUploadInput, _ ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+			close(aborted)
+			return &s3.AbortMultipartUploadOutput{}, nil
+		})
+
+	_, err := bkt.CopyParts(types.CopyPartsData{
+		Ctx:    context.Background(),
+		Object: "dst",
+		Parts:  []types.CopyPart{{Object: "a", Length: 1}},
+	})
+	c.Assert(err, qt.ErrorIs, types.ErrUnsupportedByProvider)
+	waitFor(c, aborted)
+}
@@ -2,22 +2,37 @@ package s3
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"crypto/md5"
 	"encoding/base64"
 	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"encore.dev/storage/objects/internal/types"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sync/errgroup"
 )
 
+// uploader uploads an object of unknown length as it's written.
+//
+// Written data is buffered into part-sized buffers. If the upload completes
+// before the first buffer is full the object is uploaded with a single PutObject
+// (including for empty objects), and otherwise using a multipart upload.
 type uploader struct {
 	client s3Client
 	bucket string
 	data   types.UploadData
+	opts   UploadOptions
 	ctx    context.Context
 	out    chan uploadEvent
 
@@ -26,7 +41,12 @@ type uploader struct {
 	attrs *types.ObjectAttrs
 	err   error
 
-	curr *buffer
+	curr  *buffer
+	wrote bool // whether any data has been written
+
+	tracer trace.Tracer // nil if tracing is disabled
+	op     *operation   // the upload operation, if started by a bucket
+	resume *resumePoint // set when resuming an interrupted upload
 }
 
 type uploadEvent struct {
@@ -38,25 +58,66 @@ type uploadEvent struct {
 type buffer struct {
 	buf []byte
 	n   int // number of bytes in buf
+
+	// exact is set for buffers sized to the data read by ReadFrom
+	// rather than to the part size. They aren't pooled.
+	exact bool
 }
 
-func newUploader(client s3Client, bucket string, data types.UploadData) *uploader {
+func newUploader(client s3Client, bucket string, data types.UploadData, opts UploadOptions) *uploader {
 	return &uploader{
 		bucket: bucket,
 		client: client,
 		ctx:    data.Ctx,
 		data:   data,
+		opts:   opts,
 		out:    make(chan uploadEvent, 10),
 		done:   make(chan struct{}),
 	}
 }
 
+// partSize reports the size of each part in a multipart upload.
+func (u *uploader) partSize() int {
+	if u.data.PartSize > 0 {
+		return int(u.data.PartSize)
+	}
+	return bufSize
+}
+
+const (
+	// minPartSize is the minimum size of a part in a multipart upload,
+	// except for the last part.
+	minPartSize = 5 * 1024 * 1024
+
+	// maxParts is the maximum number of parts in a multipart upload.
+	maxParts = 10000
+)
+
+// validatePartSize reports whether size is a valid part size.
+// Zero means the default part size.
+func validatePartSize(size int64) error {
+	if size != 0 && size < minPartSize {
+		return fmt.Errorf("%w: part size %d is below the S3 minimum of %d bytes",
+			types.ErrInvalidArgument, size, minPartSize)
+	}
+	return nil
+}
+
 func (u *uploader) Write(p []byte) (n int, err error) {
 	u.initUpload()
+	if len(p) > 0 {
+		u.wrote = true
+	}
 	for len(p) > 0 {
 		curr := u.curr
 		if curr == nil {
-			curr = getBuf()
+			curr = getBuf(u.partSize())
+		} else if curr.exact {
+			// Move the data read by ReadFrom into a part-sized buffer,
+			// which it's smaller than.
+			grown := getBuf(u.partSize())
+			grown.n = copy(grown.buf, curr.buf[:curr.n])
+			curr = grown
 		}
 
 		copied := copy(curr.buf[curr.n:], p)
@@ -84,6 +145,67 @@ func (u *uploader) Write(p []byte) (n int, err error) {
 	return n, nil
 }
 
+// ReadFrom implements io.ReaderFrom, so uploads can dispatch on the size
+// of r when it's known, such as for files and in-memory readers.
+// It's used by io.Copy for readers that don't implement io.WriterTo,
+// including files.
+//
+// If nothing has been written yet and r is smaller than a part, its contents
+// are read into a buffer of exactly that size and uploaded with a single
+// PutObject once the upload completes. Otherwise r is copied using Write.
+func (u *uploader) ReadFrom(r io.Reader) (int64, error) {
+	size, ok := readerSize(r)
+	if !ok || size >= int64(u.partSize()) || u.wrote || u.resume != nil {
+		return io.Copy(writerOnly{u}, r)
+	}
+
+	u.initUpload()
+	u.wrote = true
+	buf := &buffer{buf: make([]byte, size), exact: true}
+	n, err := io.ReadFull(r, buf.buf)
+	buf.n = n
+	u.curr = buf
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		// The reader was shorter than its reported size.
+		return int64(n), nil
+	} else if err != nil {
+		return int64(n), err
+	}
+
+	// The reader may be longer than its reported size, for example
+	// if it's a file being appended to.
+	m, err := io.Copy(writerOnly{u}, r)
+	return int64(n) + m, err
+}
+
+// readerSize reports the number of bytes remaining in r, if known.
+func readerSize(r io.Reader) (int64, bool) {
+	switch r := r.(type) {
+	case interface{ Len() int }: // bytes.Reader, bytes.Buffer, strings.Reader
+		return int64(r.Len()), true
+	case interface {
+		io.Seeker
+		Stat() (fs.FileInfo, error)
+	}: // *os.File, including when wrapped by its WriteTo method
+		info, err := r.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return 0, false
+		}
+		off, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, false
+		}
+		return max(info.Size()-off, 0), true
+	}
+	return 0, false
+}
+
+// writerOnly hides any io.ReaderFrom implementation of the writer,
+// so io.Copy to it uses Write.
+type writerOnly struct {
+	io.Writer
+}
+
 func (u *uploader) Complete() (*types.ObjectAttrs, error) {
 	u.initUpload()
 	// If we have a current buffer, send it.
@@ -122,35 +244,106 @@ func (u *uploader) initUpload() {
 	u.init.Do(func() {
 		go func() {
 			defer close(u.done)
-			attrs, err := u.doUpload()
-			u.attrs, u.err = attrs, mapErr(err)
+			u.attrs, u.err = u.run(u.doUpload)
 		}()
 	})
 }
 
+// run runs the upload, verifying the uploaded object if configured,
+// and ends the upload operation.
+func (u *uploader) run(upload func() (*types.ObjectAttrs, error)) (*types.ObjectAttrs, error) {
+	attrs, err := upload()
+	if err == nil && u.opts.VerifyUpload && !u.opts.DryRun {
+		err = u.verifyUpload(attrs)
+	}
+	err = mapErr(err)
+	if u.op != nil {
+		var size int64
+		if attrs != nil {
+			size = attrs.Size
+		}
+		u.op.end(err, size)
+	}
+	return attrs, err
+}
+
 func (u *uploader) doUpload() (*types.ObjectAttrs, error) {
-	ev := <-u.out
-	if ev.abort != nil {
+	var ev uploadEvent
+	select {
+	case ev = <-u.out:
+	case <-u.ctx.Done():
+		return nil, u.ctx.Err()
+	}
+
+	if u.resume != nil {
+		// The multipart upload already exists; continue it.
+		return u.multiPartUpload(ev)
+	} else if ev.abort != nil {
 		// Nothing to do.
 		return nil, ev.abort
 	} else if ev.done {
 		// First buffer is the final one; we can do a single-part upload.
 		var buf []byte
 		if ev.data != nil {
+			defer putBuf(ev.data)
 			buf = ev.data.buf[:ev.data.n]
 		}
+		u.detectContentType(buf)
 		return u.singlePartUpload(buf)
 	}
 
-	return u.multiPartUpload(ev.data)
+	u.detectContentType(ev.data.buf[:ev.data.n])
+	return u.multiPartUpload(ev)
 }
 
-type s3Client interface {
-	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
-	CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
-	UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
-	CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
-	AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+// detectContentType sets the content type of the object from the first bytes
+// of its data, as GCS does, unless it was specified. The first buffer always
+// holds either the whole object or at least a part, so no extra buffering is
+// needed. Encoded objects are skipped, since their data isn't the content.
+func (u *uploader) detectContentType(data []byte) {
+	if u.data.Attrs.ContentType != "" || u.data.Attrs.ContentEncoding != "" || len(data) == 0 {
+		return
+	}
+	u.data.Attrs.ContentType = http.DetectContentType(data[:min(len(data), sniffLen)])
+}
+
+// putObjectInput returns the input for uploading the object in a single request,
+// with the object's attributes set. The input for a multipart upload is
+// built by createMultipartUploadInput, which must be kept in sync.
+func (u *uploader) putObjectInput() *s3.PutObjectInput {
+	in := &s3.PutObjectInput{
+		Bucket:          &u.bucket,
+		Key:             ptr(u.data.Object.String()),
+		ContentType:     ptrOrNil(u.data.Attrs.ContentType),
+		CacheControl:    ptrOrNil(u.data.Attrs.CacheControl),
+		Metadata:        userMetadata(u.data.Attrs.Metadata),
+		ContentEncoding: ptrOrNil(u.data.Attrs.ContentEncoding),
+		Tagging:         tagging(u.data.Attrs.Tags),
+		StorageClass:    s3types.StorageClass(u.opts.StorageClass),
+		ACL:             s3types.ObjectCannedACL(u.opts.ACL),
+	}
+	u.opts.Encryption.setPut(in)
+	u.opts.ObjectLock.setPut(in)
+	return in
+}
+
+// createMultipartUploadInput is the multipart counterpart to putObjectInput.
+func (u *uploader) createMultipartUploadInput() *s3.CreateMultipartUploadInput {
+	in := &s3.CreateMultipartUploadInput{
+		Bucket:            &u.bucket,
+		Key:               ptr(u.data.Object.String()),
+		ContentType:       ptrOrNil(u.data.Attrs.ContentType),
+		CacheControl:      ptrOrNil(u.data.Attrs.CacheControl),
+		ContentEncoding:   ptrOrNil(u.data.Attrs.ContentEncoding),
+		Metadata:          userMetadata(u.data.Attrs.Metadata),
+		Tagging:           tagging(u.data.Attrs.Tags),
+		ChecksumAlgorithm: u.opts.Checksum.s3Algorithm(),
+		StorageClass:      s3types.StorageClass(u.opts.StorageClass),
+		ACL:               s3types.ObjectCannedACL(u.opts.ACL),
+	}
+	u.opts.Encryption.setCreate(in)
+	u.opts.ObjectLock.setCreate(in)
+	return in
 }
 
 func (u *uploader) singlePartUpload(buf []byte) (*types.ObjectAttrs, error) {
@@ -163,19 +356,31 @@ func (u *uploader) singlePartUpload(buf []byte) (*types.ObjectAttrs, error) {
 		ifNoneMatch = ptr("*")
 	}
 
-	resp, err := u.client.PutObject(u.ctx, &s3.PutObjectInput{
-		Bucket:        &u.bucket,
-		Key:           key,
-		Body:          bytes.NewReader(buf),
-		ContentType:   ptrOrNil(u.data.Attrs.ContentType),
-		ContentMD5:    &contentMD5,
-		ContentLength: ptr(int64(len(buf))),
-		IfNoneMatch:   ifNoneMatch,
+	in := u.putObjectInput()
+	in.ContentMD5 = &contentMD5
+	in.ContentLength = ptr(int64(len(buf)))
+	in.IfNoneMatch = ifNoneMatch
+	checksum := u.opts.Checksum.sum(buf)
+	u.opts.Checksum.setPut(in, checksum)
+
+	resp, err := withRetry(u.ctx, u.opts, func() (*s3.PutObjectOutput, error) {
+		in.Body = bytes.NewReader(buf)
+		return u.client.PutObject(u.ctx, in)
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	got := u.opts.Checksum.fromOutput(resp.ChecksumCRC32, resp.ChecksumSHA256)
+	if err := u.opts.Checksum.verify(checksum, got); err != nil {
+		u.removeObject(key, resp.VersionId)
+		return nil, err
+	}
+
+	if u.data.Progress != nil {
+		u.data.Progress(int64(len(buf)), int64(len(buf)))
+	}
+
 	return &types.ObjectAttrs{
 		Object:      u.data.Object,
 		Version:     valOrZero(resp.VersionId),
@@ -185,40 +390,84 @@ func (u *uploader) singlePartUpload(buf []byte) (*types.ObjectAttrs, error) {
 	}, nil
 }
 
-func (u *uploader) multiPartUpload(initial *buffer) (attrs *types.ObjectAttrs, err error) {
+// multiPartUpload uploads the object using a multipart upload,
+// starting with the data of the first event.
+func (u *uploader) multiPartUpload(first uploadEvent) (attrs *types.ObjectAttrs, err error) {
 	key := ptr(u.data.Object.String())
-	resp, err := u.client.CreateMultipartUpload(u.ctx, &s3.CreateMultipartUploadInput{
-		Bucket:      &u.bucket,
-		Key:         key,
-		ContentType: ptrOrNil(u.data.Attrs.ContentType),
-	})
-	if err != nil {
-		return nil, err
+	var (
+		partsMu  sync.Mutex
+		parts    = make(map[int32]s3types.CompletedPart)
+		partMD5s = make(map[int32][]byte) // of the parts uploaded, not resumed
+	)
+	partNumber := int32(1)
+	var totalSize int64
+
+	var uploadID string
+	if r := u.resume; r != nil {
+		uploadID = r.uploadID
+		for _, p := range r.parts {
+			parts[*p.PartNumber] = p
+		}
+		partNumber += int32(len(r.parts))
+		totalSize = r.size
+	} else {
+		resp, err := u.client.CreateMultipartUpload(u.ctx, u.createMultipartUploadInput())
+		if err != nil {
+			return nil, err
+		}
+		uploadID = valOrZero(resp.UploadId)
 	}
-	uploadID := valOrZero(resp.UploadId)
 
+	store := u.opts.StateStore
+	aborted := false
 	defer func() {
-		if err != nil {
-			// The upload failed. Abort the multipart upload.
-			go func() {
-				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-				defer cancel()
-				_, _ = u.client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
-					Bucket:   &u.bucket,
-					Key:      key,
-					UploadId: &uploadID,
-				})
-			}()
+		switch {
+		case err == nil:
+			if store != nil {
+				_ = store.DeleteUploadState(context.WithoutCancel(u.ctx), u.uploadState(uploadID, nil))
+			}
+		case store == nil || aborted:
+			// The upload failed. Abort the multipart upload so the
+			// uploaded parts don't linger and incur storage costs.
+			// With a state store the upload is kept so it can be resumed,
+			// unless it was aborted explicitly.
+			go abortMultipart(u.client, u.bucket, key, uploadID)
+			if store != nil {
+				_ = store.DeleteUploadState(context.WithoutCancel(u.ctx), u.uploadState(uploadID, nil))
+			}
 		}
 	}()
+	if store != nil && u.resume == nil {
+		if err := store.SaveUploadState(u.ctx, u.uploadState(uploadID, parts)); err != nil {
+			aborted = true // nothing to resume
+			return nil, fmt.Errorf("save upload state: %w", err)
+		}
+	}
 
-	g, groupCtx := errgroup.WithContext(u.ctx)
-	partNumber := int32(1)
-	var totalSize int64
-	uploadPart := func(buf *buffer) {
+	// Cancel any in-flight part uploads if we return early.
+	ctx, cancel := context.WithCancel(u.ctx)
+	defer cancel()
+
+	g, groupCtx := errgroup.WithContext(ctx)
+	g.SetLimit(u.opts.concurrency())
+
+	// The total size isn't known until all data has been written.
+	progress := newProgressReporter(u.data.Progress, -1)
+	defer progress.close()
+	if totalSize > 0 {
+		// Report the parts uploaded before the upload was resumed.
+		progress.add(totalSize)
+	}
+
+	uploadPart := func(buf *buffer) error {
 		if buf == nil {
 			// No data to upload.
-			return
+			return nil
+		}
+		if partNumber > maxParts {
+			putBuf(buf)
+			return fmt.Errorf("%w: object exceeds %d parts of %d bytes; use a larger part size",
+				types.ErrInvalidArgument, maxParts, u.partSize())
 		}
 
 		totalSize += int64(buf.n)
@@ -230,83 +479,235 @@ func (u *uploader) multiPartUpload(initial *buffer) (attrs *types.ObjectAttrs, e
 
 			md5sum := md5.Sum(data)
 			contentMD5 := base64.StdEncoding.EncodeToString(md5sum[:])
-			_, err := u.client.UploadPart(groupCtx, &s3.UploadPartInput{
+			in := &s3.UploadPartInput{
 				Bucket:        &u.bucket,
+				Key:           key,
 				UploadId:      &uploadID,
 				PartNumber:    &part,
-				Body:          bytes.NewReader(data),
 				ContentLength: ptr(int64(len(data))),
 				ContentMD5:    ptr(contentMD5),
+			}
+			completed := s3types.CompletedPart{PartNumber: ptr(part)}
+			u.opts.Checksum.setPart(in, &completed, u.opts.Checksum.sum(data))
+			u.opts.Encryption.setPart(in)
+
+			partCtx, span := startSpan(groupCtx, u.tracer, "UploadPart", u.bucket, u.data.Object)
+			span.SetAttributes(attrPartNumber.Int(int(part)))
+			resp, err := withRetry(partCtx, u.opts, func() (*s3.UploadPartOutput, error) {
+				in.Body = bytes.NewReader(data)
+				return u.client.UploadPart(partCtx, in)
 			})
-			return err
+			endSpan(span, mapErr(err), int64(len(data)))
+			if err != nil {
+				return err
+			}
+			completed.ETag = resp.ETag
+
+			partsMu.Lock()
+			parts[part] = completed
+			partMD5s[part] = md5sum[:]
+			var saveErr error
+			if store != nil {
+				// Save while holding the lock so saves aren't reordered.
+				saveErr = store.SaveUploadState(groupCtx, u.uploadState(uploadID, parts))
+			}
+			partsMu.Unlock()
+			if saveErr != nil {
+				return fmt.Errorf("save upload state: %w", saveErr)
+			}
+
+			progress.add(int64(len(data)))
+			return nil
 		})
+		return nil
 	}
 
-	// Upload the first part, if given.
-	uploadPart(initial)
-	for {
-		ev := <-u.out
+	for ev := first; ; {
 		if ev.abort != nil {
+			aborted = true
+			cancel()
+			_ = g.Wait()
 			return nil, ev.abort
 		}
 
-		if ev.data != nil {
-			uploadPart(ev.data)
+		if err := uploadPart(ev.data); err != nil {
+			cancel()
+			_ = g.Wait()
+			return nil, err
 		}
 
 		if ev.done {
 			break
 		}
+
+		select {
+		case ev = <-u.out:
+		case <-groupCtx.Done():
+			// Either a part upload failed or the upload context
+			// was canceled; stop accepting more data.
+			if err := g.Wait(); err != nil {
+				return nil, err
+			}
+			return nil, u.ctx.Err()
+		}
 	}
 
 	// Wait for the uploads to complete.
 	if err := g.Wait(); err != nil {
 		return nil, err
 	}
+	return u.completeMultipart(key, uploadID, parts, partMD5s, totalSize)
+}
 
-	// Complete the multipart upload.
+// completeMultipart completes the multipart upload of the given parts,
+// verifying the checksum and ETag of the object if configured.
+func (u *uploader) completeMultipart(key *string, uploadID string, parts map[int32]s3types.CompletedPart, partMD5s map[int32][]byte, size int64) (*types.ObjectAttrs, error) {
 	var ifNoneMatch *string
 	if u.data.Pre.NotExists {
 		ifNoneMatch = ptr("*")
 	}
 
-	var completeResp *s3.CompleteMultipartUploadOutput
-	completeResp, err = u.client.CompleteMultipartUpload(u.ctx, &s3.CompleteMultipartUploadInput{
-		Bucket:      &u.bucket,
-		Key:         key,
-		UploadId:    &uploadID,
-		IfNoneMatch: ifNoneMatch,
+	// Completing the upload with the same parts is idempotent, so retry it
+	// rather than discarding all the uploaded parts on a transient error.
+	completedParts := sortedParts(parts)
+	completeResp, err := withRetry(u.ctx, u.opts, func() (*s3.CompleteMultipartUploadOutput, error) {
+		return u.client.CompleteMultipartUpload(u.ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:      &u.bucket,
+			Key:         key,
+			UploadId:    &uploadID,
+			IfNoneMatch: ifNoneMatch,
+			MultipartUpload: &s3types.CompletedMultipartUpload{
+				Parts: completedParts,
+			},
+		})
 	})
 	if err != nil {
 		return nil, err
 	}
+
+	if u.opts.Checksum != ChecksumNone {
+		partSums := make([]string, len(completedParts))
+		for i, p := range completedParts {
+			partSums[i] = u.opts.Checksum.fromOutput(p.ChecksumCRC32, p.ChecksumSHA256)
+		}
+		checksum, err := u.opts.Checksum.composite(partSums)
+		if err != nil {
+			return nil, err
+		}
+		got := u.opts.Checksum.fromOutput(completeResp.ChecksumCRC32, completeResp.ChecksumSHA256)
+		if err := u.opts.Checksum.verify(checksum, got); err != nil {
+			u.removeObject(key, completeResp.VersionId)
+			return nil, err
+		}
+	}
+	if u.opts.VerifyUpload {
+		if err := u.verifyMultipartETag(completeResp, partMD5s); err != nil {
+			return nil, err
+		}
+	}
 	return &types.ObjectAttrs{
 		Object:      u.data.Object,
 		Version:     valOrZero(completeResp.VersionId),
 		ContentType: u.data.Attrs.ContentType,
-		Size:        totalSize,
+		Size:        size,
 		ETag:        valOrZero(completeResp.ETag),
 	}, nil
 }
 
-// bufSize is the size of buffers allocated by bufPool.
-// It's a variable for testing purposes.
-var bufSize = 10 * 1024 * 1024
+// userMetadataPrefix is the header prefix S3 uses for user metadata.
+// The SDK adds it to the keys of the Metadata map when sending requests.
+const userMetadataPrefix = "x-amz-meta-"
 
-var bufPool = sync.Pool{
-	New: func() any {
-		return &buffer{
-			buf: make([]byte, bufSize),
+// userMetadata returns the user metadata to send to S3.
+// Keys that already include the user metadata prefix have it removed,
+// so it's not added twice.
+func userMetadata(md map[string]string) map[string]string {
+	if len(md) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(md))
+	for k, v := range md {
+		if len(k) >= len(userMetadataPrefix) && strings.EqualFold(k[:len(userMetadataPrefix)], userMetadataPrefix) {
+			k = k[len(userMetadataPrefix):]
 		}
-	},
+		out[k] = v
+	}
+	return out
 }
 
-func getBuf() *buffer {
-	buf := bufPool.Get().(*buffer)
+// abortTimeout is how long to wait for a multipart upload to be aborted.
+const abortTimeout = 30 * time.Second
+
+// abortMultipart aborts a multipart upload.
+// It uses a fresh context, since the upload is commonly
+// aborted because the upload context was canceled.
+func abortMultipart(client s3Client, bucket string, key *string, uploadID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), abortTimeout)
+	defer cancel()
+	_, _ = client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   &bucket,
+		Key:      key,
+		UploadId: &uploadID,
+	})
+}
+
+// removeObject removes an object that was written but failed verification.
+// The removal is best-effort and uses a fresh context, as the upload
+// context may already be canceled.
+func (u *uploader) removeObject(key, version *string) {
+	ctx, cancel := context.WithTimeout(context.Background(), abortTimeout)
+	defer cancel()
+	_, _ = u.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket:    &u.bucket,
+		Key:       key,
+		VersionId: version,
+	})
+}
+
+// sortedParts returns the completed parts ordered by part number,
+// as required by CompleteMultipartUpload.
+func sortedParts(parts map[int32]s3types.CompletedPart) []s3types.CompletedPart {
+	sorted := make([]s3types.CompletedPart, 0, len(parts))
+	for _, p := range parts {
+		sorted = append(sorted, p)
+	}
+	slices.SortFunc(sorted, func(a, b s3types.CompletedPart) int {
+		return cmp.Compare(*a.PartNumber, *b.PartNumber)
+	})
+	return sorted
+}
+
+// bufSize is the default part size for multipart uploads.
+// It's a variable for testing purposes.
+var bufSize = minPartSize
+
+// bufPools holds a *sync.Pool of buffers for each part size in use,
+// so buffers are reused across parts and uploads.
+var bufPools sync.Map // int -> *sync.Pool
+
+func bufPool(size int) *sync.Pool {
+	if p, ok := bufPools.Load(size); ok {
+		return p.(*sync.Pool)
+	}
+	p, _ := bufPools.LoadOrStore(size, &sync.Pool{
+		New: func() any {
+			return &buffer{buf: make([]byte, size)}
+		},
+	})
+	return p.(*sync.Pool)
+}
+
+// getBuf returns an empty buffer of the given size.
+func getBuf(size int) *buffer {
+	buf := bufPool(size).Get().(*buffer)
 	buf.n = 0
 	return buf
 }
 
+// putBuf returns buf to its pool. It must not be used afterwards.
 func putBuf(buf *buffer) {
-	bufPool.Put(buf)
+	if buf.exact {
+		return
+	}
+	bufPool(len(buf.buf)).Put(buf)
 }
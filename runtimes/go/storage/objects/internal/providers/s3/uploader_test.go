@@ -2,19 +2,32 @@ package s3
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
 	"fmt"
+	"hash/crc32"
 	"io"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"encore.dev/appruntime/exported/config"
 	"encore.dev/storage/objects/internal/types"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
 	qt "github.com/frankban/quicktest"
 	"github.com/golang/mock/gomock"
 )
 
-//go:generate mockgen -source=./uploader.go -destination ./mock_client_test.go -package s3 s3Client
+//go:generate mockgen -source=./client.go -destination ./mock_client_test.go -package s3 s3Client
 
 func TestUploader_Sync(t *testing.T) {
 	c := qt.New(t)
@@ -34,7 +47,7 @@ func TestUploader_Sync(t *testing.T) {
 			ContentType: contentType,
 		},
 		Pre: types.Preconditions{},
-	})
+	}, UploadOptions{})
 
 	const (
 		version = "version"
@@ -61,6 +74,71 @@ func TestUploader_Sync(t *testing.T) {
 	})
 }
 
+func TestUploader_Empty(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+
+	u := newUploader(client, "bucket", types.UploadData{
+		Ctx:    context.Background(),
+		Object: "object",
+	}, UploadOptions{})
+
+	client.EXPECT().PutObject(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+			c.Check(*in.ContentLength, qt.Equals, int64(0))
+			data, err := io.ReadAll(in.Body)
+			c.Check(err, qt.IsNil)
+			c.Check(data, qt.HasLen, 0)
+			return &s3.PutObjectOutput{ETag: ptr("etag")}, nil
+		})
+
+	attrs, err := u.Complete()
+	c.Assert(err, qt.IsNil)
+	c.Assert(attrs.Size, qt.Equals, int64(0))
+}
+
+func TestUploader_UnknownLengthStream(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+
+	u := newUploader(client, "bucket", types.UploadData{
+		Ctx:    context.Background(),
+		Object: "object",
+	}, UploadOptions{})
+
+	// The stream fits in a single buffer, so it's uploaded using PutObject.
+	withBufSize(c, 10)
+	client.EXPECT().PutObject(gomock.Any(), gomock.Any()).Return(&s3.PutObjectOutput{}, nil)
+	r := io.MultiReader(strings.NewReader("abcde"), strings.NewReader("fghij"))
+	_, err := io.Copy(u, r)
+	c.Assert(err, qt.IsNil)
+	attrs, err := u.Complete()
+	c.Assert(err, qt.IsNil)
+	c.Assert(attrs.Size, qt.Equals, int64(10))
+
+	// Once the stream exceeds a single buffer it switches to a multipart upload.
+	u = newUploader(client, "bucket", types.UploadData{
+		Ctx:    context.Background(),
+		Object: "object",
+	}, UploadOptions{})
+	client.EXPECT().CreateMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CreateMultipartUploadOutput{
+		UploadId: ptr("uploadID"),
+	}, nil)
+	client.EXPECT().UploadPart(gomock.Any(), &partMatcher{num: 1, data: "abcdefghij"}).Return(&s3.UploadPartOutput{}, nil)
+	client.EXPECT().UploadPart(gomock.Any(), &partMatcher{num: 2, data: "k"}).Return(&s3.UploadPartOutput{}, nil)
+	client.EXPECT().CompleteMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CompleteMultipartUploadOutput{}, nil)
+	r = io.MultiReader(strings.NewReader("abcde"), strings.NewReader("fghijk"))
+	_, err = io.Copy(u, r)
+	c.Assert(err, qt.IsNil)
+	attrs, err = u.Complete()
+	c.Assert(err, qt.IsNil)
+	c.Assert(attrs.Size, qt.Equals, int64(11))
+}
+
 func TestUploader_MultipleWrites(t *testing.T) {
 	c := qt.New(t)
 
@@ -79,7 +157,7 @@ func TestUploader_MultipleWrites(t *testing.T) {
 			ContentType: contentType,
 		},
 		Pre: types.Preconditions{},
-	})
+	}, UploadOptions{})
 
 	const (
 		version = "version"
@@ -109,6 +187,101 @@ func TestUploader_MultipleWrites(t *testing.T) {
 	})
 }
 
+func TestUploader_ReadFrom(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	withBufSize(c, 5)
+
+	// Readers smaller than a part are uploaded with a single request.
+	// io.Copy from a file uses ReadFrom.
+	path := filepath.Join(c.TempDir(), "file")
+	c.Assert(os.WriteFile(path, []byte("abcd"), 0644), qt.IsNil)
+	f, err := os.Open(path)
+	c.Assert(err, qt.IsNil)
+	defer f.Close()
+	u := newUploader(client, "bucket", types.UploadData{Ctx: context.Background(), Object: "object"}, UploadOptions{})
+	client.EXPECT().PutObject(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+			data, err := io.ReadAll(in.Body)
+			c.Check(err, qt.IsNil)
+			c.Check(string(data), qt.Equals, "abcd")
+			return &s3.PutObjectOutput{}, nil
+		})
+	n, err := io.Copy(u, f)
+	c.Assert(err, qt.IsNil)
+	c.Assert(n, qt.Equals, int64(4))
+	c.Assert(u.curr.exact, qt.IsTrue)
+	attrs, err := u.Complete()
+	c.Assert(err, qt.IsNil)
+	c.Assert(attrs.Size, qt.Equals, int64(4))
+
+	// Larger readers use a multipart upload.
+	u = newUploader(client, "bucket", types.UploadData{Ctx: context.Background(), Object: "object"}, UploadOptions{})
+	client.EXPECT().CreateMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CreateMultipartUploadOutput{UploadId: ptr("uploadID")}, nil)
+	client.EXPECT().UploadPart(gomock.Any(), &partMatcher{num: 1, data: "abcde"}).Return(&s3.UploadPartOutput{}, nil)
+	client.EXPECT().UploadPart(gomock.Any(), &partMatcher{num: 2, data: "fg"}).Return(&s3.UploadPartOutput{}, nil)
+	client.EXPECT().CompleteMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CompleteMultipartUploadOutput{}, nil)
+	n, err = u.ReadFrom(bytes.NewReader([]byte("abcdefg")))
+	c.Assert(err, qt.IsNil)
+	c.Assert(n, qt.Equals, int64(7))
+	attrs, err = u.Complete()
+	c.Assert(err, qt.IsNil)
+	c.Assert(attrs.Size, qt.Equals, int64(7))
+}
+
+func TestUploader_ReadFromThenWrite(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	withBufSize(c, 5)
+
+	// Data written after a small reader is moved into part-sized buffers.
+	u := newUploader(client, "bucket", types.UploadData{Ctx: context.Background(), Object: "object"}, UploadOptions{})
+	client.EXPECT().CreateMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CreateMultipartUploadOutput{UploadId: ptr("uploadID")}, nil)
+	client.EXPECT().UploadPart(gomock.Any(), &partMatcher{num: 1, data: "abcde"}).Return(&s3.UploadPartOutput{}, nil)
+	client.EXPECT().UploadPart(gomock.Any(), &partMatcher{num: 2, data: "fghij"}).Return(&s3.UploadPartOutput{}, nil)
+	client.EXPECT().CompleteMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CompleteMultipartUploadOutput{}, nil)
+	_, err := u.ReadFrom(strings.NewReader("abc"))
+	c.Assert(err, qt.IsNil)
+	_, err = u.Write([]byte("defghij"))
+	c.Assert(err, qt.IsNil)
+	attrs, err := u.Complete()
+	c.Assert(err, qt.IsNil)
+	c.Assert(attrs.Size, qt.Equals, int64(10))
+}
+
+func TestReaderSize(t *testing.T) {
+	c := qt.New(t)
+
+	f, err := os.CreateTemp(c.TempDir(), "file")
+	c.Assert(err, qt.IsNil)
+	defer f.Close()
+	_, err = f.WriteString("abcdef")
+	c.Assert(err, qt.IsNil)
+	_, err = f.Seek(2, io.SeekStart)
+	c.Assert(err, qt.IsNil)
+
+	tests := []struct {
+		name   string
+		r      io.Reader
+		want   int64
+		wantOK bool
+	}{
+		{name: "strings", r: strings.NewReader("abc"), want: 3, wantOK: true},
+		{name: "bytes_buffer", r: bytes.NewBufferString("abcd"), want: 4, wantOK: true},
+		{name: "file", r: f, want: 4, wantOK: true},
+		{name: "unknown", r: io.MultiReader(strings.NewReader("abc"))},
+	}
+	for _, tt := range tests {
+		size, ok := readerSize(tt.r)
+		c.Check(ok, qt.Equals, tt.wantOK, qt.Commentf(tt.name))
+		c.Check(size, qt.Equals, tt.want, qt.Commentf(tt.name))
+	}
+}
+
 func TestUploader_MultipartUpload(t *testing.T) {
 	c := qt.New(t)
 
@@ -127,7 +300,7 @@ func TestUploader_MultipartUpload(t *testing.T) {
 			ContentType: contentType,
 		},
 		Pre: types.Preconditions{},
-	})
+	}, UploadOptions{})
 
 	withBufSize(c, 10)
 	const (
@@ -166,6 +339,560 @@ func TestUploader_MultipartUpload(t *testing.T) {
 	})
 }
 
+func TestUploader_RetryTransientErrors(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+
+	u := newUploader(client, "bucket", types.UploadData{
+		Ctx:    context.Background(),
+		Object: "object",
+	}, UploadOptions{MaxRetries: 3, RetryBackoff: noBackoff})
+
+	slowDown := &smithy.GenericAPIError{Code: "SlowDown"}
+	gomock.InOrder(
+		client.EXPECT().PutObject(gomock.Any(), gomock.Any()).Return(nil, slowDown).Times(2),
+		client.EXPECT().PutObject(gomock.Any(), gomock.Any()).Return(&s3.PutObjectOutput{
+			ETag: ptr("etag"),
+		}, nil),
+	)
+
+	_, err := u.Write([]byte("test"))
+	c.Assert(err, qt.IsNil)
+	attrs, err := u.Complete()
+	c.Assert(err, qt.IsNil)
+	c.Assert(attrs.ETag, qt.Equals, "etag")
+}
+
+func TestUploader_RetryMultipartPart(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+
+	u := newUploader(client, "bucket", types.UploadData{
+		Ctx:    context.Background(),
+		Object: "object",
+	}, UploadOptions{MaxRetries: 3, RetryBackoff: noBackoff})
+
+	withBufSize(c, 5)
+	internalErr := &smithy.GenericAPIError{Code: "InternalError"}
+	client.EXPECT().CreateMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CreateMultipartUploadOutput{
+		UploadId: ptr("uploadID"),
+	}, nil)
+	client.EXPECT().UploadPart(gomock.Any(), &partMatcher{num: 1, data: "abcde"}).Return(&s3.UploadPartOutput{}, nil)
+	gomock.InOrder(
+		client.EXPECT().UploadPart(gomock.Any(), &partMatcher{num: 2, data: "fghij"}).Return(nil, internalErr).Times(2),
+		client.EXPECT().UploadPart(gomock.Any(), &partMatcher{num: 2, data: "fghij"}).Return(&s3.UploadPartOutput{}, nil),
+	)
+	client.EXPECT().CompleteMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CompleteMultipartUploadOutput{
+		ETag: ptr("etag"),
+	}, nil)
+
+	_, err := u.Write([]byte("abcdefghij"))
+	c.Assert(err, qt.IsNil)
+	attrs, err := u.Complete()
+	c.Assert(err, qt.IsNil)
+	c.Assert(attrs.Size, qt.Equals, int64(10))
+}
+
+func TestUploader_RetryComplete(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+
+	u := newUploader(client, "bucket", types.UploadData{
+		Ctx:    context.Background(),
+		Object: "object",
+	}, UploadOptions{MaxRetries: 2, RetryBackoff: noBackoff})
+
+	withBufSize(c, 5)
+	internalErr := &smithy.GenericAPIError{Code: "InternalError"}
+	client.EXPECT().CreateMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CreateMultipartUploadOutput{
+		UploadId: ptr("uploadID"),
+	}, nil)
+	client.EXPECT().UploadPart(gomock.Any(), gomock.Any()).Return(&s3.UploadPartOutput{}, nil).Times(2)
+	gomock.InOrder(
+		client.EXPECT().CompleteMultipartUpload(gomock.Any(), gomock.Any()).Return(nil, internalErr).Times(2),
+		client.EXPECT().CompleteMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CompleteMultipartUploadOutput{
+			ETag: ptr("etag"),
+		}, nil),
+	)
+
+	_, err := u.Write([]byte("abcdefghij"))
+	c.Assert(err, qt.IsNil)
+	attrs, err := u.Complete()
+	c.Assert(err, qt.IsNil)
+	c.Assert(attrs.ETag, qt.Equals, "etag")
+}
+
+func TestUploader_CompleteRetriesExhaustedAborts(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+
+	u := newUploader(client, "bucket", types.UploadData{
+		Ctx:    context.Background(),
+		Object: "object",
+	}, UploadOptions{MaxRetries: 2, RetryBackoff: noBackoff})
+
+	withBufSize(c, 5)
+	internalErr := &smithy.GenericAPIError{Code: "InternalError"}
+	aborted := make(chan struct{})
+	client.EXPECT().CreateMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CreateMultipartUploadOutput{
+		UploadId: ptr("uploadID"),
+	}, nil)
+	client.EXPECT().UploadPart(gomock.Any(), gomock.Any()).Return(&s3.UploadPartOutput{}, nil).Times(2)
+	client.EXPECT().CompleteMultipartUpload(gomock.Any(), gomock.Any()).Return(nil, internalErr).Times(3)
+	client.EXPECT().AbortMultipartUpload(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(context.Context, *s3.AbortMultipartUploadInput, ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+			close(aborted)
+			return &s3.AbortMultipartUploadOutput{}, nil
+		})
+
+	_, err := u.Write([]byte("abcdefghij"))
+	c.Assert(err, qt.IsNil)
+	_, err = u.Complete()
+	c.Assert(err, qt.ErrorMatches, ".*InternalError.*")
+	waitFor(c, aborted)
+}
+
+func TestUploader_PermanentErrorAborts(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+
+	u := newUploader(client, "bucket", types.UploadData{
+		Ctx:    context.Background(),
+		Object: "object",
+	}, UploadOptions{MaxRetries: 3, RetryBackoff: noBackoff})
+
+	withBufSize(c, 5)
+	accessDenied := &smithy.GenericAPIError{Code: "AccessDenied"}
+	aborted := make(chan struct{})
+	client.EXPECT().CreateMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CreateMultipartUploadOutput{
+		UploadId: ptr("uploadID"),
+	}, nil)
+	client.EXPECT().UploadPart(gomock.Any(), gomock.Any()).Return(nil, accessDenied).Times(1)
+	client.EXPECT().UploadPart(gomock.Any(), gomock.Any()).Return(&s3.UploadPartOutput{}, nil).AnyTimes()
+	client.EXPECT().AbortMultipartUpload(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(context.Context, *s3.AbortMultipartUploadInput, ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+			close(aborted)
+			return &s3.AbortMultipartUploadOutput{}, nil
+		})
+
+	_, _ = u.Write([]byte("abcdefghij"))
+	_, err := u.Complete()
+	c.Assert(err, qt.ErrorAs, new(smithy.APIError))
+	waitFor(c, aborted)
+}
+
+func TestUploader_PartsCompleteOutOfOrder(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+
+	u := newUploader(client, "bucket", types.UploadData{
+		Ctx:    context.Background(),
+		Object: "object",
+	}, UploadOptions{Concurrency: 3})
+
+	withBufSize(c, 5)
+	client.EXPECT().CreateMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CreateMultipartUploadOutput{
+		UploadId: ptr("uploadID"),
+	}, nil)
+
+	// Block the first part until the last part has completed.
+	lastDone := make(chan struct{})
+	client.EXPECT().UploadPart(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, in *s3.UploadPartInput, _ ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+			num := valOrZero(in.PartNumber)
+			switch num {
+			case 1:
+				waitFor(c, lastDone)
+			case 3:
+				defer close(lastDone)
+			}
+			return &s3.UploadPartOutput{ETag: ptr(fmt.Sprintf("etag-%d", num))}, nil
+		}).Times(3)
+
+	var completed []s3types.CompletedPart
+	client.EXPECT().CompleteMultipartUpload(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, in *s3.CompleteMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+			completed = in.MultipartUpload.Parts
+			return &s3.CompleteMultipartUploadOutput{}, nil
+		})
+
+	_, err := u.Write([]byte("abcdefghijklmno"))
+	c.Assert(err, qt.IsNil)
+	_, err = u.Complete()
+	c.Assert(err, qt.IsNil)
+
+	c.Assert(completed, qt.HasLen, 3)
+	for i, p := range completed {
+		c.Assert(valOrZero(p.PartNumber), qt.Equals, int32(i+1))
+		c.Assert(valOrZero(p.ETag), qt.Equals, fmt.Sprintf("etag-%d", i+1))
+	}
+}
+
+func TestUploader_PartSize(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+
+	u := newUploader(client, "bucket", types.UploadData{
+		Ctx:      context.Background(),
+		Object:   "object",
+		PartSize: 4,
+	}, UploadOptions{})
+
+	client.EXPECT().CreateMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CreateMultipartUploadOutput{
+		UploadId: ptr("uploadID"),
+	}, nil)
+	client.EXPECT().UploadPart(gomock.Any(), &partMatcher{num: 1, data: "abcd"}).Return(&s3.UploadPartOutput{}, nil)
+	client.EXPECT().UploadPart(gomock.Any(), &partMatcher{num: 2, data: "efgh"}).Return(&s3.UploadPartOutput{}, nil)
+	client.EXPECT().UploadPart(gomock.Any(), &partMatcher{num: 3, data: "ij"}).Return(&s3.UploadPartOutput{}, nil)
+	client.EXPECT().CompleteMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CompleteMultipartUploadOutput{}, nil)
+
+	_, err := u.Write([]byte("abcdefghij"))
+	c.Assert(err, qt.IsNil)
+	attrs, err := u.Complete()
+	c.Assert(err, qt.IsNil)
+	c.Assert(attrs.Size, qt.Equals, int64(10))
+}
+
+func TestValidatePartSize(t *testing.T) {
+	c := qt.New(t)
+	c.Assert(validatePartSize(0), qt.IsNil)
+	c.Assert(validatePartSize(minPartSize), qt.IsNil)
+	c.Assert(validatePartSize(minPartSize-1), qt.ErrorIs, types.ErrInvalidArgument)
+}
+
+func TestUploader_Checksum(t *testing.T) {
+	content := []byte("test")
+	sum := sha256.Sum256(content)
+	checksum := base64.StdEncoding.EncodeToString(sum[:])
+
+	tests := []struct {
+		name    string
+		got     string
+		wantErr error
+	}{
+		{name: "match", got: checksum},
+		{name: "mismatch", got: "bogus", wantErr: types.ErrChecksumMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			ctrl := gomock.NewController(c)
+			client := NewMocks3Client(ctrl)
+
+			u := newUploader(client, "bucket", types.UploadData{
+				Ctx:    context.Background(),
+				Object: "object",
+			}, UploadOptions{Checksum: ChecksumSHA256})
+
+			client.EXPECT().PutObject(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+					c.Check(in.ChecksumAlgorithm, qt.Equals, s3types.ChecksumAlgorithmSha256)
+					c.Check(valOrZero(in.ChecksumSHA256), qt.Equals, checksum)
+					return &s3.PutObjectOutput{ChecksumSHA256: ptr(tt.got), VersionId: ptr("v1")}, nil
+				})
+			if tt.wantErr != nil {
+				client.EXPECT().DeleteObject(gomock.Any(), &s3.DeleteObjectInput{
+					Bucket:    ptr("bucket"),
+					Key:       ptr("object"),
+					VersionId: ptr("v1"),
+				}).Return(&s3.DeleteObjectOutput{}, nil)
+			}
+
+			_, err := u.Write(content)
+			c.Assert(err, qt.IsNil)
+			_, err = u.Complete()
+			if tt.wantErr != nil {
+				c.Assert(err, qt.ErrorIs, tt.wantErr)
+			} else {
+				c.Assert(err, qt.IsNil)
+			}
+		})
+	}
+}
+
+func TestUploader_MultipartChecksum(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+
+	u := newUploader(client, "bucket", types.UploadData{
+		Ctx:    context.Background(),
+		Object: "object",
+	}, UploadOptions{Checksum: ChecksumCRC32})
+
+	withBufSize(c, 5)
+	partSum := func(data string) []byte {
+		return binary.BigEndian.AppendUint32(nil, crc32.ChecksumIEEE([]byte(data)))
+	}
+	composite := crc32.ChecksumIEEE(append(partSum("abcde"), partSum("fghij")...))
+	want := base64.StdEncoding.EncodeToString(binary.BigEndian.AppendUint32(nil, composite)) + "-2"
+
+	client.EXPECT().CreateMultipartUpload(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, in *s3.CreateMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+			c.Check(in.ChecksumAlgorithm, qt.Equals, s3types.ChecksumAlgorithmCrc32)
+			return &s3.CreateMultipartUploadOutput{UploadId: ptr("uploadID")}, nil
+		})
+	client.EXPECT().UploadPart(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, in *s3.UploadPartInput, _ ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+			data, _ := io.ReadAll(in.Body)
+			c.Check(valOrZero(in.ChecksumCRC32), qt.Equals, base64.StdEncoding.EncodeToString(partSum(string(data))))
+			return &s3.UploadPartOutput{}, nil
+		}).Times(2)
+	client.EXPECT().CompleteMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CompleteMultipartUploadOutput{
+		ChecksumCRC32: ptr(want),
+	}, nil)
+
+	_, err := u.Write([]byte("abcdefghij"))
+	c.Assert(err, qt.IsNil)
+	_, err = u.Complete()
+	c.Assert(err, qt.IsNil)
+}
+
+func TestUploader_Progress(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+
+	type call struct{ uploaded, total int64 }
+	var (
+		calls  []call
+		active atomic.Int32
+	)
+	u := newUploader(client, "bucket", types.UploadData{
+		Ctx:    context.Background(),
+		Object: "object",
+		Progress: func(uploaded, total int64) {
+			if active.Add(1) > 1 {
+				c.Error("progress callback called concurrently")
+			}
+			defer active.Add(-1)
+			calls = append(calls, call{uploaded, total})
+		},
+	}, UploadOptions{Concurrency: 3})
+
+	withBufSize(c, 5)
+	client.EXPECT().CreateMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CreateMultipartUploadOutput{
+		UploadId: ptr("uploadID"),
+	}, nil)
+	client.EXPECT().UploadPart(gomock.Any(), gomock.Any()).Return(&s3.UploadPartOutput{}, nil).Times(3)
+	client.EXPECT().CompleteMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CompleteMultipartUploadOutput{}, nil)
+
+	_, err := u.Write([]byte("abcdefghijklm"))
+	c.Assert(err, qt.IsNil)
+	_, err = u.Complete()
+	c.Assert(err, qt.IsNil)
+
+	// The last part is smaller, so the intermediate values depend on
+	// completion order; the final value is always the total.
+	c.Assert(calls, qt.HasLen, 3)
+	c.Assert(calls[2], qt.Equals, call{13, -1})
+}
+
+func TestUploader_CancelAborts(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	u := newUploader(client, "bucket", types.UploadData{
+		Ctx:    ctx,
+		Object: "object",
+	}, UploadOptions{})
+
+	withBufSize(c, 5)
+	client.EXPECT().CreateMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CreateMultipartUploadOutput{
+		UploadId: ptr("uploadID"),
+	}, nil)
+	client.EXPECT().UploadPart(gomock.Any(), &partMatcher{num: 1, data: "abcde"}).DoAndReturn(
+		func(context.Context, *s3.UploadPartInput, ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+			cancel()
+			return &s3.UploadPartOutput{}, nil
+		})
+	client.EXPECT().UploadPart(gomock.Any(), gomock.Any()).Return(nil, context.Canceled).AnyTimes()
+
+	aborted := make(chan struct{})
+	client.EXPECT().AbortMultipartUpload(gomock.Any(), &s3.AbortMultipartUploadInput{
+		Bucket:   ptr("bucket"),
+		Key:      ptr("object"),
+		UploadId: ptr("uploadID"),
+	}).DoAndReturn(
+		func(ctx context.Context, _ *s3.AbortMultipartUploadInput, _ ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+			// The abort must not use the canceled upload context.
+			c.Check(ctx.Err(), qt.IsNil)
+			close(aborted)
+			return &s3.AbortMultipartUploadOutput{}, nil
+		})
+
+	_, _ = u.Write([]byte("abcdefghij"))
+	_, err := u.Complete()
+	c.Assert(err, qt.ErrorIs, context.Canceled)
+	waitFor(c, aborted)
+}
+
+func TestUploader_Attrs(t *testing.T) {
+	attrs := types.UploadAttrs{
+		ContentType:  "application/json",
+		CacheControl: "max-age=60",
+		Metadata:     map[string]string{"owner": "alice", "X-Amz-Meta-Team": "core"},
+	}
+	wantMeta := map[string]string{"owner": "alice", "Team": "core"}
+
+	c := qt.New(t)
+	c.Run("single", func(c *qt.C) {
+		ctrl := gomock.NewController(c)
+		client := NewMocks3Client(ctrl)
+		u := newUploader(client, "bucket", types.UploadData{
+			Ctx:    context.Background(),
+			Object: "object",
+			Attrs:  attrs,
+		}, UploadOptions{})
+
+		client.EXPECT().PutObject(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+				c.Check(valOrZero(in.ContentType), qt.Equals, attrs.ContentType)
+				c.Check(valOrZero(in.CacheControl), qt.Equals, attrs.CacheControl)
+				c.Check(in.Metadata, qt.DeepEquals, wantMeta)
+				return &s3.PutObjectOutput{}, nil
+			})
+
+		_, err := u.Write([]byte("test"))
+		c.Assert(err, qt.IsNil)
+		_, err = u.Complete()
+		c.Assert(err, qt.IsNil)
+	})
+
+	c.Run("multipart", func(c *qt.C) {
+		ctrl := gomock.NewController(c)
+		client := NewMocks3Client(ctrl)
+		u := newUploader(client, "bucket", types.UploadData{
+			Ctx:    context.Background(),
+			Object: "object",
+			Attrs:  attrs,
+		}, UploadOptions{})
+
+		withBufSize(c, 5)
+		client.EXPECT().CreateMultipartUpload(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, in *s3.CreateMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+				c.Check(valOrZero(in.ContentType), qt.Equals, attrs.ContentType)
+				c.Check(valOrZero(in.CacheControl), qt.Equals, attrs.CacheControl)
+				c.Check(in.Metadata, qt.DeepEquals, wantMeta)
+				return &s3.CreateMultipartUploadOutput{UploadId: ptr("uploadID")}, nil
+			})
+		client.EXPECT().UploadPart(gomock.Any(), gomock.Any()).Return(&s3.UploadPartOutput{}, nil).Times(2)
+		client.EXPECT().CompleteMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CompleteMultipartUploadOutput{}, nil)
+
+		_, err := u.Write([]byte("abcdefghij"))
+		c.Assert(err, qt.IsNil)
+		_, err = u.Complete()
+		c.Assert(err, qt.IsNil)
+	})
+}
+
+func TestUploader_EncryptionKMS(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+
+	const keyARN = "arn:aws:kms:us-east-1:123456789012:key/abc"
+	u := newUploader(client, "bucket", types.UploadData{
+		Ctx:    context.Background(),
+		Object: "object",
+	}, UploadOptions{Encryption: Encryption{Mode: EncryptionKMS, KMSKeyID: keyARN}})
+
+	client.EXPECT().PutObject(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+			c.Check(in.ServerSideEncryption, qt.Equals, s3types.ServerSideEncryptionAwsKms)
+			c.Check(valOrZero(in.SSEKMSKeyId), qt.Equals, keyARN)
+			c.Check(in.SSECustomerKey, qt.IsNil)
+			return &s3.PutObjectOutput{}, nil
+		})
+
+	_, err := u.Write([]byte("test"))
+	c.Assert(err, qt.IsNil)
+	_, err = u.Complete()
+	c.Assert(err, qt.IsNil)
+}
+
+func TestUploader_EncryptionCustomerKey(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+
+	key := bytes.Repeat([]byte{0x42}, 32)
+	keySum := md5.Sum(key)
+	wantKey := base64.StdEncoding.EncodeToString(key)
+	wantMD5 := base64.StdEncoding.EncodeToString(keySum[:])
+
+	u := newUploader(client, "bucket", types.UploadData{
+		Ctx:    context.Background(),
+		Object: "object",
+	}, UploadOptions{Encryption: Encryption{Mode: EncryptionCustomer, CustomerKey: key}})
+
+	withBufSize(c, 5)
+	client.EXPECT().CreateMultipartUpload(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, in *s3.CreateMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+			c.Check(valOrZero(in.SSECustomerAlgorithm), qt.Equals, "AES256")
+			c.Check(valOrZero(in.SSECustomerKey), qt.Equals, wantKey)
+			c.Check(valOrZero(in.SSECustomerKeyMD5), qt.Equals, wantMD5)
+			c.Check(in.ServerSideEncryption, qt.Equals, s3types.ServerSideEncryption(""))
+			return &s3.CreateMultipartUploadOutput{UploadId: ptr("uploadID")}, nil
+		})
+	// The key must be echoed on every part.
+	client.EXPECT().UploadPart(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, in *s3.UploadPartInput, _ ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+			c.Check(valOrZero(in.SSECustomerAlgorithm), qt.Equals, "AES256")
+			c.Check(valOrZero(in.SSECustomerKey), qt.Equals, wantKey)
+			c.Check(valOrZero(in.SSECustomerKeyMD5), qt.Equals, wantMD5)
+			return &s3.UploadPartOutput{}, nil
+		}).Times(3)
+	client.EXPECT().CompleteMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CompleteMultipartUploadOutput{}, nil)
+
+	_, err := u.Write([]byte("abcdefghijklm"))
+	c.Assert(err, qt.IsNil)
+	_, err = u.Complete()
+	c.Assert(err, qt.IsNil)
+}
+
+func TestEncryption_Validate(t *testing.T) {
+	c := qt.New(t)
+	c.Assert(Encryption{}.validate(), qt.IsNil)
+	c.Assert(Encryption{Mode: EncryptionKMS}.validate(), qt.IsNil)
+	c.Assert(Encryption{Mode: EncryptionCustomer, CustomerKey: make([]byte, 32)}.validate(), qt.IsNil)
+	c.Assert(Encryption{Mode: EncryptionCustomer, CustomerKey: make([]byte, 16)}.validate(), qt.ErrorIs, types.ErrInvalidArgument)
+}
+
+func noBackoff(int) time.Duration { return 0 }
+
+func waitFor(c *qt.C, ch <-chan struct{}) {
+	c.Helper()
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		c.Fatal("timed out waiting")
+	}
+}
+
 func withBufSize(c *qt.C, n int) {
 	orig := bufSize
 	bufSize = n
@@ -194,3 +921,553 @@ func (m *partMatcher) Matches(x interface{}) bool {
 func (m *partMatcher) String() string {
 	return fmt.Sprintf("is part %d with data %q", m.num, m.data)
 }
+
+// BenchmarkBufPool measures allocations when getting and returning part buffers.
+// Buffers are pooled for any part size, not just the default one.
+func BenchmarkBufPool(b *testing.B) {
+	for _, size := range []int{bufSize, 8 * 1024 * 1024} {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				buf := getBuf(size)
+				buf.n = copy(buf.buf, "data")
+				putBuf(buf)
+			}
+		})
+	}
+}
+
+func TestUploader_StorageClass(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"},
+		WithUploadOptions(UploadOptions{StorageClass: "STANDARD_IA"}))
+
+	client.EXPECT().PutObject(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+			c.Check(in.StorageClass, qt.Equals, s3types.StorageClassStandardIa)
+			return &s3.PutObjectOutput{}, nil
+		})
+	u, err := bkt.Upload(types.UploadData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.IsNil)
+	_, err = u.Complete()
+	c.Assert(err, qt.IsNil)
+
+	withBufSize(c, 5)
+	client.EXPECT().CreateMultipartUpload(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.CreateMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+			c.Check(in.StorageClass, qt.Equals, s3types.StorageClassStandardIa)
+			return &s3.CreateMultipartUploadOutput{UploadId: ptr("uploadID")}, nil
+		})
+	client.EXPECT().UploadPart(gomock.Any(), gomock.Any()).Return(&s3.UploadPartOutput{}, nil).Times(2)
+	client.EXPECT().CompleteMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CompleteMultipartUploadOutput{}, nil)
+	u, err = bkt.Upload(types.UploadData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.IsNil)
+	_, err = u.Write([]byte("abcdefghij"))
+	c.Assert(err, qt.IsNil)
+	_, err = u.Complete()
+	c.Assert(err, qt.IsNil)
+}
+
+func TestUploadOptions_ValidateStorageClass(t *testing.T) {
+	c := qt.New(t)
+	for _, class := range []string{"", "STANDARD", "INTELLIGENT_TIERING", "GLACIER", "DEEP_ARCHIVE"} {
+		c.Check(UploadOptions{StorageClass: class}.validateStorageClass(), qt.IsNil, qt.Commentf("class %q", class))
+	}
+
+	ctrl := gomock.NewController(c)
+	bkt := NewBucketWithClient(NewMocks3Client(ctrl), &config.Bucket{CloudName: "bucket"},
+		WithUploadOptions(UploadOptions{StorageClass: "standard"}))
+	_, err := bkt.Upload(types.UploadData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.ErrorIs, types.ErrInvalidArgument)
+}
+
+func TestUploader_ACL(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"},
+		WithUploadOptions(UploadOptions{ACL: "bucket-owner-full-control"}))
+
+	client.EXPECT().PutObject(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+			c.Check(in.ACL, qt.Equals, s3types.ObjectCannedACLBucketOwnerFullControl)
+			return &s3.PutObjectOutput{}, nil
+		})
+	u, err := bkt.Upload(types.UploadData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.IsNil)
+	_, err = u.Complete()
+	c.Assert(err, qt.IsNil)
+
+	withBufSize(c, 5)
+	client.EXPECT().CreateMultipartUpload(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.CreateMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+			c.Check(in.ACL, qt.Equals, s3types.ObjectCannedACLBucketOwnerFullControl)
+			return &s3.CreateMultipartUploadOutput{UploadId: ptr("uploadID")}, nil
+		})
+	client.EXPECT().UploadPart(gomock.Any(), gomock.Any()).Return(&s3.UploadPartOutput{}, nil).Times(2)
+	client.EXPECT().CompleteMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CompleteMultipartUploadOutput{}, nil)
+	u, err = bkt.Upload(types.UploadData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.IsNil)
+	_, err = u.Write([]byte("abcdefghij"))
+	c.Assert(err, qt.IsNil)
+	_, err = u.Complete()
+	c.Assert(err, qt.IsNil)
+}
+
+func TestUploader_ACLNotSupported(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"},
+		WithUploadOptions(UploadOptions{ACL: "public-read"}))
+
+	client.EXPECT().PutObject(gomock.Any(), gomock.Any()).Return(nil, &smithy.GenericAPIError{
+		Code:    "AccessControlListNotSupported",
+		Message: "The bucket does not allow ACLs",
+	})
+	u, err := bkt.Upload(types.UploadData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.IsNil)
+	_, err = u.Complete()
+	c.Assert(err, qt.ErrorIs, types.ErrInvalidArgument)
+	c.Assert(err, qt.ErrorMatches, ".*bucket has ACLs disabled by its BucketOwnerEnforced Object Ownership setting.*")
+}
+
+func TestUploadOptions_ValidateACL(t *testing.T) {
+	c := qt.New(t)
+	for _, acl := range []string{"", "private", "bucket-owner-full-control", "public-read"} {
+		c.Check(UploadOptions{ACL: acl}.validateACL(), qt.IsNil, qt.Commentf("acl %q", acl))
+	}
+
+	ctrl := gomock.NewController(c)
+	bkt := NewBucketWithClient(NewMocks3Client(ctrl), &config.Bucket{CloudName: "bucket"},
+		WithUploadOptions(UploadOptions{ACL: "owner-full-control"}))
+	_, err := bkt.Upload(types.UploadData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.ErrorIs, types.ErrInvalidArgument)
+}
+
+func TestUploader_Tags(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"})
+	tags := map[string]string{"team": "core", "note": "a&b=c d"}
+
+	client.EXPECT().PutObject(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+			c.Check(valOrZero(in.Tagging), qt.Equals, "note=a%26b%3Dc%20d&team=core")
+			return &s3.PutObjectOutput{}, nil
+		})
+	u, err := bkt.Upload(types.UploadData{Ctx: context.Background(), Object: "object", Attrs: types.UploadAttrs{Tags: tags}})
+	c.Assert(err, qt.IsNil)
+	_, err = u.Complete()
+	c.Assert(err, qt.IsNil)
+
+	withBufSize(c, 5)
+	client.EXPECT().CreateMultipartUpload(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.CreateMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+			c.Check(valOrZero(in.Tagging), qt.Equals, "note=a%26b%3Dc%20d&team=core")
+			return &s3.CreateMultipartUploadOutput{UploadId: ptr("uploadID")}, nil
+		})
+	client.EXPECT().UploadPart(gomock.Any(), gomock.Any()).Return(&s3.UploadPartOutput{}, nil).Times(2)
+	client.EXPECT().CompleteMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CompleteMultipartUploadOutput{}, nil)
+	u, err = bkt.Upload(types.UploadData{Ctx: context.Background(), Object: "object", Attrs: types.UploadAttrs{Tags: tags}})
+	c.Assert(err, qt.IsNil)
+	_, err = u.Write([]byte("abcdefghij"))
+	c.Assert(err, qt.IsNil)
+	_, err = u.Complete()
+	c.Assert(err, qt.IsNil)
+}
+
+func TestUploader_TooManyTags(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	bkt := NewBucketWithClient(NewMocks3Client(ctrl), &config.Bucket{CloudName: "bucket"})
+	tags := make(map[string]string)
+	for i := range maxTags + 1 {
+		tags[fmt.Sprintf("key%d", i)] = "value"
+	}
+	_, err := bkt.Upload(types.UploadData{Ctx: context.Background(), Object: "object", Attrs: types.UploadAttrs{Tags: tags}})
+	c.Assert(err, qt.ErrorIs, types.ErrInvalidArgument)
+}
+
+func TestUploader_DryRun(t *testing.T) {
+	c := qt.New(t)
+
+	// No calls are expected on the client.
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"},
+		WithUploadOptions(UploadOptions{DryRun: true, StorageClass: "STANDARD_IA"}))
+
+	u, err := bkt.Upload(types.UploadData{Ctx: context.Background(), Object: "object", Attrs: types.UploadAttrs{ContentType: "text/plain"}})
+	c.Assert(err, qt.IsNil)
+	_, err = u.Write([]byte("hello"))
+	c.Assert(err, qt.IsNil)
+	attrs, err := u.Complete()
+	c.Assert(err, qt.IsNil)
+	c.Assert(attrs.Size, qt.Equals, int64(5))
+
+	req, ok := DryRunRequestsOf(u)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(req.PutObject, qt.IsNotNil)
+	c.Assert(req.CreateMultipartUpload, qt.IsNil)
+	c.Assert(valOrZero(req.PutObject.Key), qt.Equals, "object")
+	c.Assert(valOrZero(req.PutObject.ContentType), qt.Equals, "text/plain")
+	c.Assert(valOrZero(req.PutObject.ContentLength), qt.Equals, int64(5))
+	c.Assert(req.PutObject.StorageClass, qt.Equals, s3types.StorageClassStandardIa)
+	c.Assert(req.PutObject.Body, qt.IsNil)
+}
+
+func TestUploader_DryRunMultipart(t *testing.T) {
+	c := qt.New(t)
+	withBufSize(c, 5)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"},
+		WithUploadOptions(UploadOptions{DryRun: true}))
+
+	u, err := bkt.Upload(types.UploadData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.IsNil)
+	_, err = u.Write([]byte("abcdefghijkl"))
+	c.Assert(err, qt.IsNil)
+	_, err = u.Complete()
+	c.Assert(err, qt.IsNil)
+
+	req, ok := DryRunRequestsOf(u)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(req.PutObject, qt.IsNil)
+	c.Assert(req.CreateMultipartUpload, qt.IsNotNil)
+	c.Assert(req.UploadParts, qt.HasLen, 3)
+	for i, part := range req.UploadParts {
+		c.Assert(valOrZero(part.PartNumber), qt.Equals, int32(i+1))
+	}
+	c.Assert(valOrZero(req.UploadParts[2].ContentLength), qt.Equals, int64(2))
+	c.Assert(req.CompleteMultipartUpload.MultipartUpload.Parts, qt.HasLen, 3)
+}
+
+func TestUploader_DryRunValidates(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"},
+		WithUploadOptions(UploadOptions{DryRun: true}))
+
+	_, err := bkt.Upload(types.UploadData{Ctx: context.Background(), Object: "object", PartSize: 1})
+	c.Assert(err, qt.ErrorIs, types.ErrInvalidArgument)
+
+	u, err := bkt.Upload(types.UploadData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.IsNil)
+	_, ok := DryRunRequestsOf(u)
+	c.Assert(ok, qt.IsTrue)
+
+	live := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"})
+	u, err = live.Upload(types.UploadData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.IsNil)
+	_, ok = DryRunRequestsOf(u)
+	c.Assert(ok, qt.IsFalse)
+	u.Abort(nil)
+}
+
+func TestUploader_ObjectLock(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	until := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"},
+		WithUploadOptions(UploadOptions{ObjectLock: ObjectLock{Mode: ObjectLockCompliance, RetainUntil: until, LegalHold: true}}))
+
+	client.EXPECT().PutObject(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+			c.Check(in.ObjectLockMode, qt.Equals, s3types.ObjectLockModeCompliance)
+			c.Check(valOrZero(in.ObjectLockRetainUntilDate), qt.Equals, until)
+			c.Check(in.ObjectLockLegalHoldStatus, qt.Equals, s3types.ObjectLockLegalHoldStatusOn)
+			return &s3.PutObjectOutput{}, nil
+		})
+	u, err := bkt.Upload(types.UploadData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.IsNil)
+	_, err = u.Complete()
+	c.Assert(err, qt.IsNil)
+
+	withBufSize(c, 5)
+	client.EXPECT().CreateMultipartUpload(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.CreateMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+			c.Check(in.ObjectLockMode, qt.Equals, s3types.ObjectLockModeCompliance)
+			c.Check(valOrZero(in.ObjectLockRetainUntilDate), qt.Equals, until)
+			c.Check(in.ObjectLockLegalHoldStatus, qt.Equals, s3types.ObjectLockLegalHoldStatusOn)
+			return &s3.CreateMultipartUploadOutput{UploadId: ptr("uploadID")}, nil
+		})
+	client.EXPECT().UploadPart(gomock.Any(), gomock.Any()).Return(&s3.UploadPartOutput{}, nil).Times(2)
+	client.EXPECT().CompleteMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CompleteMultipartUploadOutput{}, nil)
+	u, err = bkt.Upload(types.UploadData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.IsNil)
+	_, err = u.Write([]byte("abcdefghij"))
+	c.Assert(err, qt.IsNil)
+	_, err = u.Complete()
+	c.Assert(err, qt.IsNil)
+}
+
+func TestObjectLock_RetainFor(t *testing.T) {
+	c := qt.New(t)
+
+	before := time.Now()
+	mode, until, _ := ObjectLock{Mode: ObjectLockGovernance, RetainFor: time.Hour}.fields()
+	c.Assert(mode, qt.Equals, s3types.ObjectLockModeGovernance)
+	c.Assert(until.Sub(before) >= time.Hour, qt.IsTrue)
+	c.Assert(until.Sub(time.Now()) <= time.Hour, qt.IsTrue)
+}
+
+func TestUploader_ObjectLockNotEnabled(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"},
+		WithUploadOptions(UploadOptions{ObjectLock: ObjectLock{LegalHold: true}}))
+
+	client.EXPECT().PutObject(gomock.Any(), gomock.Any()).Return(nil, &smithy.GenericAPIError{
+		Code:    "InvalidRequest",
+		Message: "Bucket is missing Object Lock Configuration",
+	})
+	u, err := bkt.Upload(types.UploadData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.IsNil)
+	_, err = u.Complete()
+	c.Assert(err, qt.ErrorIs, types.ErrInvalidArgument)
+	c.Assert(err, qt.ErrorMatches, ".*bucket does not have S3 Object Lock enabled.*")
+}
+
+func TestObjectLock_Validate(t *testing.T) {
+	c := qt.New(t)
+	until := time.Now().Add(time.Hour)
+
+	c.Assert(ObjectLock{}.validate(), qt.IsNil)
+	c.Assert(ObjectLock{LegalHold: true}.validate(), qt.IsNil)
+	c.Assert(ObjectLock{Mode: ObjectLockGovernance, RetainUntil: until}.validate(), qt.IsNil)
+	c.Assert(ObjectLock{Mode: ObjectLockGovernance, RetainFor: time.Hour}.validate(), qt.IsNil)
+	c.Assert(ObjectLock{Mode: ObjectLockGovernance}.validate(), qt.ErrorIs, types.ErrInvalidArgument)
+	c.Assert(ObjectLock{RetainUntil: until}.validate(), qt.ErrorIs, types.ErrInvalidArgument)
+	c.Assert(ObjectLock{RetainFor: time.Hour}.validate(), qt.ErrorIs, types.ErrInvalidArgument)
+	c.Assert(ObjectLock{Mode: "forever", RetainUntil: until}.validate(), qt.ErrorIs, types.ErrInvalidArgument)
+}
+
+func TestUploader_ContentEncoding(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"})
+	attrs := types.UploadAttrs{ContentType: "application/json", ContentEncoding: "gzip"}
+
+	client.EXPECT().PutObject(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+			c.Check(valOrZero(in.ContentEncoding), qt.Equals, "gzip")
+			return &s3.PutObjectOutput{}, nil
+		})
+	u, err := bkt.Upload(types.UploadData{Ctx: context.Background(), Object: "object", Attrs: attrs})
+	c.Assert(err, qt.IsNil)
+	_, err = u.Complete()
+	c.Assert(err, qt.IsNil)
+
+	withBufSize(c, 5)
+	client.EXPECT().CreateMultipartUpload(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.CreateMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+			c.Check(valOrZero(in.ContentEncoding), qt.Equals, "gzip")
+			return &s3.CreateMultipartUploadOutput{UploadId: ptr("uploadID")}, nil
+		})
+	client.EXPECT().UploadPart(gomock.Any(), gomock.Any()).Return(&s3.UploadPartOutput{}, nil).Times(2)
+	client.EXPECT().CompleteMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CompleteMultipartUploadOutput{}, nil)
+	u, err = bkt.Upload(types.UploadData{Ctx: context.Background(), Object: "object", Attrs: attrs})
+	c.Assert(err, qt.IsNil)
+	_, err = u.Write([]byte("abcdefghij"))
+	c.Assert(err, qt.IsNil)
+	_, err = u.Complete()
+	c.Assert(err, qt.IsNil)
+}
+
+func TestUploader_CompressGzip(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"},
+		WithUploadOptions(UploadOptions{CompressGzip: true}))
+	contents := strings.Repeat(`{"hello":"world"}`, 100)
+
+	client.EXPECT().PutObject(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+			c.Check(valOrZero(in.ContentEncoding), qt.Equals, "gzip")
+			c.Check(valOrZero(in.ContentType), qt.Equals, "text/plain; charset=utf-8") // detected before compression
+			c.Check(valOrZero(in.ContentLength) < int64(len(contents)), qt.IsTrue)
+			zr, err := gzip.NewReader(in.Body)
+			c.Assert(err, qt.IsNil)
+			got, err := io.ReadAll(zr)
+			c.Assert(err, qt.IsNil)
+			c.Check(string(got), qt.Equals, contents)
+			return &s3.PutObjectOutput{}, nil
+		})
+	u, err := bkt.Upload(types.UploadData{Ctx: context.Background(), Object: "object"})
+	c.Assert(err, qt.IsNil)
+	_, err = io.WriteString(u, contents)
+	c.Assert(err, qt.IsNil)
+	_, err = u.Complete()
+	c.Assert(err, qt.IsNil)
+
+	_, err = bkt.Upload(types.UploadData{Ctx: context.Background(), Object: "object", Attrs: types.UploadAttrs{ContentEncoding: "br"}})
+	c.Assert(err, qt.ErrorIs, types.ErrInvalidArgument)
+}
+
+func TestUploader_DetectContentType(t *testing.T) {
+	c := qt.New(t)
+	pngHeader := "\x89PNG\r\n\x1a\n"
+	tests := []struct {
+		name            string
+		attrs           types.UploadAttrs
+		contents        string
+		wantContentType string
+	}{
+		{name: "detected", contents: pngHeader + "rest of the image", wantContentType: "image/png"},
+		{name: "specified", attrs: types.UploadAttrs{ContentType: "text/csv"}, contents: "a,b", wantContentType: "text/csv"},
+		{name: "encoded", attrs: types.UploadAttrs{ContentEncoding: "br"}, contents: "compressed"},
+		{name: "empty"},
+	}
+	for _, test := range tests {
+		c.Run(test.name, func(c *qt.C) {
+			ctrl := gomock.NewController(c)
+			client := NewMocks3Client(ctrl)
+			bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"})
+
+			client.EXPECT().PutObject(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+					c.Check(valOrZero(in.ContentType), qt.Equals, test.wantContentType)
+					return &s3.PutObjectOutput{}, nil
+				})
+			u, err := bkt.Upload(types.UploadData{Ctx: context.Background(), Object: "object", Attrs: test.attrs})
+			c.Assert(err, qt.IsNil)
+			_, err = io.WriteString(u, test.contents)
+			c.Assert(err, qt.IsNil)
+			attrs, err := u.Complete()
+			c.Assert(err, qt.IsNil)
+			c.Assert(attrs.ContentType, qt.Equals, test.wantContentType)
+		})
+	}
+}
+
+func TestUploader_DetectContentTypeMultipart(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"})
+
+	client.EXPECT().CreateMultipartUpload(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.CreateMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+			c.Check(valOrZero(in.ContentType), qt.Equals, "text/html; charset=utf-8")
+			return &s3.CreateMultipartUploadOutput{UploadId: ptr("upload")}, nil
+		})
+	client.EXPECT().UploadPart(gomock.Any(), gomock.Any()).Return(&s3.UploadPartOutput{ETag: ptr("etag")}, nil).Times(2)
+	client.EXPECT().CompleteMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CompleteMultipartUploadOutput{}, nil)
+
+	u, err := bkt.Upload(types.UploadData{Ctx: context.Background(), Object: "object", PartSize: minPartSize})
+	c.Assert(err, qt.IsNil)
+	_, err = io.WriteString(u, "<!DOCTYPE html>"+strings.Repeat("x", minPartSize))
+	c.Assert(err, qt.IsNil)
+	_, err = u.Complete()
+	c.Assert(err, qt.IsNil)
+}
+
+func TestUploader_VerifyUpload(t *testing.T) {
+	c := qt.New(t)
+	contents := "hello world"
+	tests := []struct {
+		name    string
+		head    *s3.HeadObjectOutput
+		wantErr error
+	}{
+		{name: "match", head: &s3.HeadObjectOutput{ContentLength: ptr(int64(len(contents))), ETag: ptr("etag")}},
+		{name: "truncated", head: &s3.HeadObjectOutput{ContentLength: ptr(int64(5)), ETag: ptr("etag")}, wantErr: ErrVerifyFailed},
+		{name: "etag_mismatch", head: &s3.HeadObjectOutput{ContentLength: ptr(int64(len(contents))), ETag: ptr("other")}, wantErr: ErrVerifyFailed},
+	}
+	for _, test := range tests {
+		c.Run(test.name, func(c *qt.C) {
+			ctrl := gomock.NewController(c)
+			client := NewMocks3Client(ctrl)
+			bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"},
+				WithUploadOptions(UploadOptions{VerifyUpload: true}))
+
+			gomock.InOrder(
+				client.EXPECT().PutObject(gomock.Any(), gomock.Any()).Return(&s3.PutObjectOutput{ETag: ptr("etag"), VersionId: ptr("v1")}, nil),
+				client.EXPECT().HeadObject(gomock.Any(), &s3.HeadObjectInput{
+					Bucket:    ptr("bucket"),
+					Key:       ptr("object"),
+					VersionId: ptr("v1"),
+				}).Return(test.head, nil),
+			)
+			u, err := bkt.Upload(types.UploadData{Ctx: context.Background(), Object: "object"})
+			c.Assert(err, qt.IsNil)
+			_, err = io.WriteString(u, contents)
+			c.Assert(err, qt.IsNil)
+			_, err = u.Complete()
+			if test.wantErr != nil {
+				c.Assert(err, qt.ErrorIs, test.wantErr)
+			} else {
+				c.Assert(err, qt.IsNil)
+			}
+		})
+	}
+}
+
+func TestUploader_VerifyMultipartUpload(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"},
+		WithUploadOptions(UploadOptions{VerifyUpload: true}))
+	data := make([]byte, minPartSize+1)
+	part1, part2 := md5.Sum(data[:minPartSize]), md5.Sum(data[minPartSize:])
+	etag := `"` + MultipartETag([][]byte{part1[:], part2[:]}) + `"`
+
+	upload := func(completeETag string, headSize int64) error {
+		client.EXPECT().CreateMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CreateMultipartUploadOutput{UploadId: ptr("upload")}, nil)
+		client.EXPECT().UploadPart(gomock.Any(), gomock.Any()).Return(&s3.UploadPartOutput{ETag: ptr("etag")}, nil).Times(2)
+		client.EXPECT().CompleteMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.CompleteMultipartUploadOutput{ETag: &completeETag}, nil)
+		if completeETag == etag {
+			client.EXPECT().HeadObject(gomock.Any(), gomock.Any()).Return(&s3.HeadObjectOutput{ContentLength: &headSize, ETag: &completeETag}, nil)
+		} else {
+			client.EXPECT().AbortMultipartUpload(gomock.Any(), gomock.Any()).Return(&s3.AbortMultipartUploadOutput{}, nil).AnyTimes()
+		}
+
+		u, err := bkt.Upload(types.UploadData{Ctx: context.Background(), Object: "object", PartSize: minPartSize})
+		c.Assert(err, qt.IsNil)
+		_, err = u.Write(data)
+		c.Assert(err, qt.IsNil)
+		_, err = u.Complete()
+		return err
+	}
+
+	c.Assert(upload(etag, int64(len(data))), qt.IsNil)
+
+	err := upload(etag, int64(len(data)-1))
+	c.Assert(err, qt.ErrorIs, ErrVerifyFailed)
+	c.Assert(err, qt.ErrorMatches, `.*object is 5242880 bytes, expected 5242881`)
+
+	// The ETag S3 reports must match the one computed from the parts.
+	err = upload(`"other-2"`, 0)
+	c.Assert(err, qt.ErrorIs, ErrVerifyFailed)
+	c.Assert(err, qt.ErrorMatches, `.*S3 reported ETag other-2.*`)
+}
+
+func TestMultipartETag(t *testing.T) {
+	c := qt.New(t)
+	hello, world := md5.Sum([]byte("hello")), md5.Sum([]byte("world"))
+	c.Assert(MultipartETag([][]byte{hello[:], world[:]}), qt.Equals, "065947336a2f2a95ba8899f3675c3be6-2")
+}
@@ -0,0 +1,64 @@
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"encore.dev/storage/objects/internal/types"
+)
+
+// ErrVerifyFailed is reported by uploads with UploadOptions.VerifyUpload set
+// when the uploaded object doesn't match what was uploaded.
+var ErrVerifyFailed = errors.New("s3: upload verification failed")
+
+// verifyUpload looks up the uploaded object and checks it against attrs.
+// The ETag is only compared when S3 reported one for the upload.
+func (u *uploader) verifyUpload(attrs *types.ObjectAttrs) error {
+	in := &s3.HeadObjectInput{
+		Bucket:    &u.bucket,
+		Key:       ptr(u.data.Object.String()),
+		VersionId: ptrOrNil(attrs.Version),
+	}
+	u.opts.Encryption.setHead(in)
+	resp, err := withRetry(u.ctx, u.opts, func() (*s3.HeadObjectOutput, error) {
+		return u.client.HeadObject(u.ctx, in)
+	})
+	if err != nil {
+		return fmt.Errorf("verify upload: %w", mapErr(err))
+	}
+
+	if size := valOrZero(resp.ContentLength); size != attrs.Size {
+		return fmt.Errorf("%w: object is %d bytes, expected %d", ErrVerifyFailed, size, attrs.Size)
+	}
+	if etag := valOrZero(resp.ETag); attrs.ETag != "" && etag != attrs.ETag {
+		return fmt.Errorf("%w: object has ETag %s, expected %s", ErrVerifyFailed, etag, attrs.ETag)
+	}
+	return nil
+}
+
+// verifyMultipartETag checks the ETag S3 reported for a completed multipart
+// upload against the one computed from the MD5 digests of its parts.
+// It's skipped for resumed uploads, whose earlier parts' digests aren't known,
+// for uploads whose parts were uploaded separately with UploadPart, and for
+// encrypted objects whose ETags aren't based on MD5.
+func (u *uploader) verifyMultipartETag(resp *s3.CompleteMultipartUploadOutput, partMD5s map[int32][]byte) error {
+	if len(partMD5s) == 0 || u.opts.Encryption.Mode == EncryptionCustomer || strings.HasPrefix(string(resp.ServerSideEncryption), "aws:kms") {
+		return nil
+	}
+	sums := make([][]byte, len(partMD5s))
+	for part, sum := range partMD5s {
+		if part < 1 || int(part) > len(sums) {
+			return nil // some parts were uploaded before the upload was resumed
+		}
+		sums[part-1] = sum
+	}
+
+	expected := MultipartETag(sums)
+	if etag := strings.Trim(valOrZero(resp.ETag), `"`); etag != expected {
+		return fmt.Errorf("%w: S3 reported ETag %s, expected %s", ErrVerifyFailed, etag, expected)
+	}
+	return nil
+}
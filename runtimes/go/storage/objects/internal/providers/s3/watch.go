@@ -0,0 +1,102 @@
+package s3
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"encore.dev/storage/objects/internal/types"
+)
+
+var _ types.Watcher = (*bucket)(nil)
+
+// defaultWatchInterval is the default interval between listings in Watch.
+const defaultWatchInterval = 30 * time.Second
+
+// Watch reports changes to the objects whose keys start with data.Prefix.
+// It's an alternative to S3 event notifications for buckets without them set up.
+//
+// The prefix is listed periodically, and each listing is compared to the
+// previous one. Changes are only observed at that granularity: an object
+// created and deleted between two listings isn't reported. The first listing
+// is made before Watch returns, and errors listing it are returned;
+// objects that already exist then aren't reported. Later listings that fail
+// are retried at the next interval.
+//
+// The returned channel is closed once ctx is canceled.
+func (b *bucket) Watch(data types.WatchData) (<-chan types.ObjectEvent, error) {
+	ctx, prefix := data.Ctx, data.Prefix
+	interval := data.Interval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	prev, err := b.snapshot(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan types.ObjectEvent)
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			curr, err := b.snapshot(ctx, prefix)
+			if err != nil {
+				continue
+			}
+			for _, ev := range diffSnapshots(prev, curr) {
+				select {
+				case events <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+			prev = curr
+		}
+	}()
+	return events, nil
+}
+
+// snapshot lists the objects under prefix.
+func (b *bucket) snapshot(ctx context.Context, prefix string) (map[types.CloudObject]types.ListEntry, error) {
+	objects := make(map[types.CloudObject]types.ListEntry)
+	for entry, err := range b.List(types.ListData{Ctx: ctx, Prefix: prefix}) {
+		if err != nil {
+			return nil, err
+		}
+		objects[entry.Object] = *entry
+	}
+	return objects, nil
+}
+
+// diffSnapshots returns the changes between two listings, ordered by key.
+func diffSnapshots(prev, curr map[types.CloudObject]types.ListEntry) []types.ObjectEvent {
+	var events []types.ObjectEvent
+	for obj, entry := range curr {
+		old, existed := prev[obj]
+		switch {
+		case !existed:
+			events = append(events, types.ObjectEvent{Kind: types.ObjectCreated, Object: obj, Size: entry.Size, ETag: entry.ETag})
+		case old.ETag != entry.ETag || old.Size != entry.Size:
+			events = append(events, types.ObjectEvent{Kind: types.ObjectUpdated, Object: obj, Size: entry.Size, ETag: entry.ETag})
+		}
+	}
+	for obj, entry := range prev {
+		if _, exists := curr[obj]; !exists {
+			events = append(events, types.ObjectEvent{Kind: types.ObjectDeleted, Object: obj, Size: entry.Size, ETag: entry.ETag})
+		}
+	}
+	slices.SortFunc(events, func(a, b types.ObjectEvent) int {
+		return strings.Compare(string(a.Object), string(b.Object))
+	})
+	return events
+}
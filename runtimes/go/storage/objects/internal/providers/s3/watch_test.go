@@ -0,0 +1,87 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	qt "github.com/frankban/quicktest"
+	"github.com/golang/mock/gomock"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/storage/objects/internal/types"
+)
+
+func TestWatch(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"}).(*bucket)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	listing := func(objs ...s3types.Object) *s3.ListObjectsV2Output {
+		return &s3.ListObjectsV2Output{Contents: objs}
+	}
+	object := func(key, etag string, size int64) s3types.Object {
+		return s3types.Object{Key: ptr(key), ETag: ptr(etag), Size: ptr(size)}
+	}
+	gomock.InOrder(
+		client.EXPECT().ListObjectsV2(gomock.Any(), gomock.Any()).Return(listing(
+			object("dir/a", "a1", 1),
+			object("dir/b", "b1", 1),
+		), nil),
+		// Failed listings are retried, without reporting every object as deleted.
+		client.EXPECT().ListObjectsV2(gomock.Any(), gomock.Any()).Return(nil, errors.New("listing failed")),
+		client.EXPECT().ListObjectsV2(gomock.Any(), gomock.Any()).Return(listing(
+			object("dir/a", "a2", 2),
+			object("dir/c", "c1", 3),
+		), nil),
+		client.EXPECT().ListObjectsV2(gomock.Any(), gomock.Any()).Return(listing(
+			object("dir/a", "a2", 2),
+			object("dir/c", "c1", 3),
+		), nil).AnyTimes(),
+	)
+
+	events, err := bkt.Watch(types.WatchData{Ctx: ctx, Prefix: "dir/", Interval: time.Millisecond})
+	c.Assert(err, qt.IsNil)
+
+	// Objects that existed when watching started aren't reported.
+	c.Assert(nextEvent(c, events), qt.Equals, types.ObjectEvent{Kind: types.ObjectUpdated, Object: "dir/a", Size: 2, ETag: "a2"})
+	c.Assert(nextEvent(c, events), qt.Equals, types.ObjectEvent{Kind: types.ObjectDeleted, Object: "dir/b", Size: 1, ETag: "b1"})
+	c.Assert(nextEvent(c, events), qt.Equals, types.ObjectEvent{Kind: types.ObjectCreated, Object: "dir/c", Size: 3, ETag: "c1"})
+
+	// The channel is closed once the context is canceled.
+	cancel()
+	for range events {
+		c.Fatal("unexpected event after the listing stopped changing")
+	}
+}
+
+func TestWatch_InitialListingFails(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(c)
+	client := NewMocks3Client(ctrl)
+	bkt := NewBucketWithClient(client, &config.Bucket{CloudName: "bucket"}).(*bucket)
+
+	client.EXPECT().ListObjectsV2(gomock.Any(), gomock.Any()).Return(nil, errors.New("listing failed"))
+	_, err := bkt.Watch(types.WatchData{Ctx: context.Background(), Prefix: "dir/"})
+	c.Assert(err, qt.ErrorMatches, ".*listing failed.*")
+}
+
+func nextEvent(c *qt.C, events <-chan types.ObjectEvent) types.ObjectEvent {
+	c.Helper()
+	select {
+	case ev, ok := <-events:
+		c.Assert(ok, qt.IsTrue, qt.Commentf("events channel closed"))
+		return ev
+	case <-time.After(5 * time.Second):
+		c.Fatal("timed out waiting for event")
+		return types.ObjectEvent{}
+	}
+}
@@ -0,0 +1,358 @@
+package types
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// The interfaces below are implemented by providers that support
+// operations beyond those of BucketImpl. The objects package checks
+// for them at runtime and reports ErrUnsupportedByProvider otherwise.
+
+// Provisioner is implemented by providers that can create buckets.
+type Provisioner interface {
+	// EnsureBucket creates the bucket unless it already exists.
+	EnsureBucket(ctx context.Context) error
+
+	// BucketExists reports whether the bucket exists
+	// and is accessible to the caller.
+	BucketExists(ctx context.Context) (bool, error)
+}
+
+// UploadCleaner is implemented by providers that can clean up
+// multipart uploads that were never completed.
+type UploadCleaner interface {
+	// CleanupIncompleteUploads aborts incomplete uploads and
+	// returns the number of uploads aborted.
+	CleanupIncompleteUploads(data CleanupUploadsData) (int, error)
+}
+
+type CleanupUploadsData struct {
+	Ctx context.Context
+
+	// Prefix limits the cleanup to uploads of objects starting with it.
+	Prefix string
+
+	// OlderThan is how long ago uploads must have been initiated
+	// to be aborted.
+	OlderThan time.Duration
+}
+
+// UploadResumer is implemented by providers that can resume
+// interrupted multipart uploads.
+type UploadResumer interface {
+	// ResumeUpload continues the upload described by data.State.
+	// It returns an uploader along with the offset into the object's
+	// contents to continue writing from.
+	ResumeUpload(data ResumeUploadData) (u Uploader, offset int64, err error)
+}
+
+type ResumeUploadData struct {
+	Ctx   context.Context
+	State UploadState
+
+	// StateStore, if non-nil, persists the state of the resumed upload.
+	StateStore UploadStateStore
+}
+
+// UploadState is the state of an in-progress multipart upload.
+// It can be serialized as JSON and passed to UploadResumer.ResumeUpload
+// to continue the upload after it's been interrupted, for example in
+// another process.
+type UploadState struct {
+	Bucket   string `json:"bucket"`
+	Key      string `json:"key"`
+	UploadID string `json:"upload_id"`
+
+	// PartSize is the size of every part except the last.
+	PartSize int64 `json:"part_size"`
+
+	// NotExists is whether the upload requires the object not to exist.
+	NotExists bool `json:"not_exists,omitempty"`
+
+	// Parts are the parts uploaded so far, ordered by part number.
+	// Parts may complete out of order, so there may be gaps.
+	Parts []UploadedPart `json:"parts,omitempty"`
+}
+
+// UploadedPart is a part of a multipart upload that has been uploaded.
+type UploadedPart struct {
+	Number int32  `json:"number"`
+	ETag   string `json:"etag"`
+
+	// Size and Checksum are set for parts uploaded with UploadPart.
+	// The checksum is only computed if the bucket's upload options
+	// set a checksum algorithm.
+	Size     int64  `json:"size,omitempty"`
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// UploadStateStore persists the state of multipart uploads, so that
+// interrupted uploads can be resumed with UploadResumer.ResumeUpload.
+// Implementations must be safe for concurrent use.
+type UploadStateStore interface {
+	// SaveUploadState is called when a multipart upload starts and
+	// each time a part has been uploaded. If it fails, so does the upload.
+	SaveUploadState(ctx context.Context, state UploadState) error
+
+	// DeleteUploadState is called when the upload completes or is aborted,
+	// after which it can no longer be resumed. Errors are ignored.
+	DeleteUploadState(ctx context.Context, state UploadState) error
+}
+
+// SeekDownloader is implemented by providers that can download
+// objects for random access.
+type SeekDownloader interface {
+	DownloadSeeker(data DownloadSeekerData) (io.ReadSeekCloser, error)
+}
+
+type DownloadSeekerData struct {
+	Ctx    context.Context
+	Object CloudObject
+
+	Version string // non-zero means specific version
+}
+
+// ResultDownloader is implemented by providers that can download
+// objects along with their attributes, using a single request.
+type ResultDownloader interface {
+	DownloadWithResult(data DownloadData) (*DownloadResult, error)
+}
+
+// DownloadResult is an object being downloaded, along with its attributes.
+type DownloadResult struct {
+	// Body is the contents of the object, which must be closed.
+	Body Downloader
+
+	// Attrs are the attributes of the object. Attrs.Size is the
+	// number of bytes in Body, which is less than the size of the
+	// object if a range of it is downloaded.
+	Attrs *ObjectAttrs
+}
+
+// PartCopier is implemented by providers that can create objects by
+// concatenating byte ranges of existing objects, without downloading them.
+type PartCopier interface {
+	CopyParts(data CopyPartsData) (*ObjectAttrs, error)
+}
+
+type CopyPartsData struct {
+	Ctx    context.Context
+	Object CloudObject // the object to create
+	Attrs  UploadAttrs
+
+	// Parts are concatenated in order to form the object.
+	Parts []CopyPart
+}
+
+// CopyPart is a byte range of an existing object, to be copied
+// into a new object by PartCopier.CopyParts.
+type CopyPart struct {
+	// Bucket is the bucket containing the object.
+	// If nil, it's the bucket being copied to.
+	Bucket BucketImpl
+
+	Object  CloudObject
+	Version string // non-zero to copy a specific version
+
+	// Offset and Length specify the byte range to copy.
+	// A zero Length copies the whole object, and requires a zero Offset.
+	Offset int64
+	Length int64
+
+	// ETag, if non-empty, is the ETag the object must have,
+	// so that the range is copied from the expected contents.
+	ETag string
+}
+
+// PageLister is implemented by providers that can list objects
+// a page at a time, continuing from an opaque cursor.
+type PageLister interface {
+	// ListPage returns a page of entries, and the cursor for the next
+	// page. An empty nextCursor means there are no more pages.
+	ListPage(data ListPageData) (entries []*ListEntry, nextCursor string, err error)
+}
+
+type ListPageData struct {
+	Ctx       context.Context
+	Prefix    string
+	Delimiter string
+
+	// PageSize is the maximum number of entries in the page.
+	// Zero means the provider default.
+	PageSize int
+
+	// Cursor is empty for the first page, and otherwise the
+	// nextCursor returned for the previous page.
+	Cursor string
+}
+
+// ReaderAtUploader is implemented by providers that can upload objects
+// from an io.ReaderAt, reading the parts of multipart uploads from it
+// in parallel rather than buffering them.
+type ReaderAtUploader interface {
+	UploadReaderAt(data UploadReaderAtData) (*ObjectAttrs, error)
+}
+
+type UploadReaderAtData struct {
+	UploadData
+
+	// Reader is read from concurrently, so it must be safe for concurrent use.
+	Reader io.ReaderAt
+
+	// Size is the number of bytes to upload from the start of Reader.
+	Size int64
+}
+
+// TrashPurger is implemented by providers that can soft-delete objects,
+// to permanently delete the objects in the trash.
+type TrashPurger interface {
+	// PurgeTrash deletes trashed objects and
+	// returns the number of objects deleted.
+	PurgeTrash(data PurgeTrashData) (int, error)
+}
+
+type PurgeTrashData struct {
+	Ctx context.Context
+
+	// Prefix limits the purge to objects whose names,
+	// before they were trashed, start with it.
+	Prefix string
+
+	// OlderThan is how long ago objects must have been
+	// trashed to be deleted.
+	OlderThan time.Duration
+}
+
+// Watcher is implemented by providers that can report changes
+// to the objects in a bucket.
+type Watcher interface {
+	// Watch reports changes on the returned channel,
+	// which is closed once data.Ctx is canceled.
+	Watch(data WatchData) (<-chan ObjectEvent, error)
+}
+
+type WatchData struct {
+	Ctx    context.Context
+	Prefix string
+
+	// Interval is how often providers that poll for changes do so.
+	// Zero means the provider default.
+	Interval time.Duration
+}
+
+// ObjectEventKind is the kind of change reported by Watcher.Watch.
+type ObjectEventKind int
+
+const (
+	// ObjectCreated is reported for objects that didn't exist before.
+	ObjectCreated ObjectEventKind = iota + 1
+
+	// ObjectUpdated is reported for objects whose ETag or size changed,
+	// such as when they're overwritten.
+	ObjectUpdated
+
+	// ObjectDeleted is reported for objects that no longer exist.
+	ObjectDeleted
+)
+
+func (k ObjectEventKind) String() string {
+	switch k {
+	case ObjectCreated:
+		return "created"
+	case ObjectUpdated:
+		return "updated"
+	case ObjectDeleted:
+		return "deleted"
+	default:
+		return fmt.Sprintf("ObjectEventKind(%d)", int(k))
+	}
+}
+
+// ObjectEvent is a change to an object observed by Watcher.Watch.
+type ObjectEvent struct {
+	Kind   ObjectEventKind
+	Object CloudObject
+
+	// Size and ETag are those of the object as last observed,
+	// which for deleted objects is before they were deleted.
+	Size int64
+	ETag string
+}
+
+// CapabilityProber is implemented by providers whose
+// optional features vary between services or buckets.
+type CapabilityProber interface {
+	Capabilities(ctx context.Context) (Capabilities, error)
+}
+
+// Capabilities reports which optional features are available
+// for a bucket, as probed by CapabilityProber.Capabilities.
+type Capabilities struct {
+	// Multipart reports whether multipart uploads are supported.
+	Multipart bool
+
+	// Tagging reports whether object tags are supported.
+	Tagging bool
+
+	// ObjectLock reports whether the bucket has S3 Object Lock enabled,
+	// which is required to upload objects with retention settings.
+	// Object Lock can only be enabled when a bucket is created.
+	ObjectLock bool
+
+	// Versioning reports whether bucket versioning is supported,
+	// whether or not it's enabled for the bucket.
+	Versioning bool
+
+	// SSEKMS reports whether server-side encryption with KMS keys is
+	// supported. It's always true for buckets on AWS, and otherwise
+	// only if the bucket's default encryption uses a KMS key.
+	SSEKMS bool
+}
+
+// PartUploader is implemented by providers that can split the steps
+// of multipart uploads across processes.
+type PartUploader interface {
+	// InitUpload starts a multipart upload.
+	InitUpload(data InitUploadData) (MultipartUpload, error)
+
+	// UploadPart uploads a part of a multipart upload.
+	UploadPart(data UploadPartData) (UploadedPart, error)
+
+	// CompleteUpload completes a multipart upload from its parts.
+	CompleteUpload(data CompleteUploadData) (*ObjectAttrs, error)
+
+	// AbortUpload aborts a multipart upload, discarding its parts.
+	AbortUpload(ctx context.Context, upload MultipartUpload) error
+}
+
+// MultipartUpload identifies a multipart upload started with
+// PartUploader.InitUpload. It can be serialized as JSON.
+type MultipartUpload struct {
+	Bucket   string `json:"bucket"`
+	Key      string `json:"key"`
+	UploadID string `json:"upload_id"`
+}
+
+type InitUploadData struct {
+	Ctx    context.Context
+	Object CloudObject
+	Attrs  UploadAttrs
+}
+
+type UploadPartData struct {
+	Ctx    context.Context
+	Upload MultipartUpload
+
+	// PartNumber is the position of the part in the object, from 1.
+	PartNumber int32
+	Data       []byte
+}
+
+type CompleteUploadData struct {
+	Ctx    context.Context
+	Upload MultipartUpload
+	Parts  []UploadedPart
+}
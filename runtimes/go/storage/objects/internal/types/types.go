@@ -3,19 +3,33 @@ package types
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"iter"
+	"net/http"
+	"strings"
 	"time"
 )
 
+// BucketImpl is the interface implemented by each object storage provider.
+//
+// It's the seam between the public objects.Bucket API and the providers:
+// application code only uses objects.Bucket, and the provider backing it
+// is chosen from the runtime configuration, so switching between S3, GCS
+// and local storage requires no code changes. Provider-specific clients,
+// such as the S3 API client, stay internal to each provider.
 type BucketImpl interface {
 	Upload(data UploadData) (Uploader, error)
 	Download(data DownloadData) (Downloader, error)
 	List(data ListData) iter.Seq2[*ListEntry, error]
 	Remove(data RemoveData) error
+	RemoveAll(data RemoveAllData) ([]RemoveResult, error)
 	Attrs(data AttrsData) (*ObjectAttrs, error)
-	SignedUploadURL(data UploadURLData) (string, error)
-	SignedDownloadURL(data DownloadURLData) (string, error)
+	Exists(data ExistsData) (bool, error)
+	Copy(data CopyData) (*ObjectAttrs, error)
+	SetTags(data SetTagsData) error
+	SignedUploadURL(data UploadURLData) (*SignedURL, error)
+	SignedDownloadURL(data DownloadURLData) (*SignedURL, error)
 }
 
 // CloudObject is the cloud name for an object.
@@ -30,6 +44,19 @@ type UploadData struct {
 
 	Attrs UploadAttrs
 	Pre   Preconditions
+
+	// PartSize is the size of each part for providers that upload
+	// objects in multiple parts. Zero means the provider default.
+	PartSize int64
+
+	// Progress, if non-nil, is called as data is uploaded with the
+	// number of bytes uploaded so far and the total number of bytes,
+	// or -1 if the total is not known.
+	Progress func(uploaded, total int64)
+
+	// StateStore, if non-nil, persists the state of multipart uploads
+	// for providers that can resume them; see UploadResumer.
+	StateStore UploadStateStore
 }
 
 type Preconditions struct {
@@ -37,7 +64,14 @@ type Preconditions struct {
 }
 
 type UploadAttrs struct {
-	ContentType string
+	ContentType     string
+	CacheControl    string
+	ContentEncoding string
+	Metadata        map[string]string
+
+	// Tags are key-value tags to set on the object,
+	// for providers that support object tagging.
+	Tags map[string]string
 }
 
 type Uploader interface {
@@ -52,6 +86,34 @@ type DownloadData struct {
 
 	// Non-zero to download a specific version
 	Version string
+
+	// Offset and Length specify the byte range to download.
+	// A zero Length means the rest of the object.
+	Offset int64
+	Length int64
+
+	// Conditions, if set, make the download fail with ErrNotModified
+	// if the object hasn't changed.
+	Conditions DownloadConditions
+}
+
+type DownloadConditions struct {
+	IfNoneMatch     string    // ETag the object must not match
+	IfModifiedSince time.Time // time the object must have been modified after
+}
+
+// NotModified reports whether an object with the given ETag and
+// modification time doesn't satisfy the conditions.
+// ETags are compared with any surrounding quotes removed,
+// and times with the one-second precision of HTTP dates.
+func (c DownloadConditions) NotModified(etag string, modified time.Time) bool {
+	if c.IfNoneMatch != "" {
+		return strings.Trim(c.IfNoneMatch, `"`) == strings.Trim(etag, `"`)
+	}
+	if !c.IfModifiedSince.IsZero() {
+		return !modified.Truncate(time.Second).After(c.IfModifiedSince)
+	}
+	return false
 }
 
 type Downloader interface {
@@ -65,18 +127,35 @@ type ObjectAttrs struct {
 	ContentType string
 	Size        int64
 	ETag        string
+
+	// LastModified and Metadata are set by Attrs,
+	// but other operations may leave them unset.
+	LastModified time.Time
+	Metadata     map[string]string
 }
 
 type ListData struct {
 	Ctx    context.Context
 	Prefix string
 	Limit  *int64
+
+	// Delimiter, if non-empty, groups objects whose names contain the
+	// delimiter after the prefix into a single entry with IsPrefix set.
+	Delimiter string
+
+	// PageSize is the number of entries to fetch per request.
+	// Zero means the provider default.
+	PageSize int
 }
 
 type ListEntry struct {
 	Object CloudObject
 	Size   int64
 	ETag   string
+
+	// IsPrefix is true if the entry is a common prefix
+	// rather than an object, when listing with a delimiter.
+	IsPrefix bool
 }
 
 type RemoveData struct {
@@ -86,6 +165,18 @@ type RemoveData struct {
 	Version string // non-zero means specific version
 }
 
+type RemoveAllData struct {
+	Ctx     context.Context
+	Objects []CloudObject
+}
+
+// RemoveResult is the outcome of removing a single object
+// as part of a RemoveAll operation.
+type RemoveResult struct {
+	Object CloudObject
+	Err    error // nil if the object was removed
+}
+
 type AttrsData struct {
 	Ctx    context.Context
 	Object CloudObject
@@ -93,6 +184,41 @@ type AttrsData struct {
 	Version string // non-zero means specific version
 }
 
+type ExistsData struct {
+	Ctx    context.Context
+	Object CloudObject
+
+	Version string // non-zero means specific version
+}
+
+type SetTagsData struct {
+	Ctx    context.Context
+	Object CloudObject
+
+	Version string // non-zero means specific version
+
+	// Tags replaces the object's existing tags.
+	// An empty map removes all tags.
+	Tags map[string]string
+}
+
+type CopyData struct {
+	Ctx    context.Context
+	Object CloudObject // the source object
+
+	Version string // non-zero means specific version of the source
+
+	// DstBucket is the bucket to copy to.
+	// If nil the object is copied within the same bucket.
+	// It must be provided by the same provider as the source bucket.
+	DstBucket BucketImpl
+	DstObject CloudObject
+
+	// Attrs, if non-nil, replaces the attributes of the copy.
+	// Otherwise they are copied from the source object.
+	Attrs *UploadAttrs
+}
+
 type UploadURLData struct {
 	Ctx    context.Context
 	Object CloudObject
@@ -107,6 +233,15 @@ type DownloadURLData struct {
 	TTL time.Duration
 }
 
+type SignedURL struct {
+	URL    string
+	Method string
+
+	// Headers are the headers included in the signature,
+	// which must be sent with requests using the URL.
+	Headers http.Header
+}
+
 //publicapigen:keep
 var (
 	//publicapigen:keep
@@ -114,5 +249,13 @@ var (
 	//publicapigen:keep
 	ErrPreconditionFailed = errors.New("objects: precondition failed")
 	//publicapigen:keep
+	ErrObjectExists = fmt.Errorf("%w: object already exists", ErrPreconditionFailed)
+	//publicapigen:keep
 	ErrInvalidArgument = errors.New("objects: invalid argument")
+	//publicapigen:keep
+	ErrChecksumMismatch = errors.New("objects: checksum mismatch")
+	//publicapigen:keep
+	ErrNotModified = errors.New("objects: not modified")
+	//publicapigen:keep
+	ErrUnsupportedByProvider = errors.New("objects: operation not supported by provider")
 )
@@ -0,0 +1,28 @@
+package objects
+
+import (
+	"strings"
+)
+
+// NormalizeKey removes redundant slashes from key: leading slashes,
+// which many tools strip when resolving keys, and repeated slashes
+// between path segments. A trailing slash is kept, as it's commonly
+// used to mark a "directory" object.
+//
+// NormalizeKey is not applied automatically, since "a//b" and "a/b"
+// are distinct object names.
+func NormalizeKey(key string) string {
+	key = strings.TrimLeft(key, "/")
+	if !strings.Contains(key, "//") {
+		return key
+	}
+	var b strings.Builder
+	b.Grow(len(key))
+	for i := 0; i < len(key); i++ {
+		if key[i] == '/' && i > 0 && key[i-1] == '/' {
+			continue
+		}
+		b.WriteByte(key[i])
+	}
+	return b.String()
+}
@@ -9,6 +9,7 @@ import (
 	"encore.dev/appruntime/shared/reqtrack"
 	"encore.dev/appruntime/shared/shutdown"
 	"encore.dev/appruntime/shared/testsupport"
+	"encore.dev/metrics"
 )
 
 type Manager struct {
@@ -23,7 +24,7 @@ type Manager struct {
 }
 
 func NewManager(static *config.Static, runtime *config.Runtime, rt *reqtrack.RequestTracker,
-	ts *testsupport.Manager, rootLogger zerolog.Logger) *Manager {
+	ts *testsupport.Manager, rootLogger zerolog.Logger, reg *metrics.Registry) *Manager {
 	ctx, cancel := context.WithCancel(context.Background())
 	mgr := &Manager{
 		ctx:        ctx,
@@ -36,7 +37,7 @@ func NewManager(static *config.Static, runtime *config.Runtime, rt *reqtrack.Req
 	}
 
 	for _, p := range providerRegistry {
-		mgr.providers = append(mgr.providers, p(mgr.ctx, mgr.runtime))
+		mgr.providers = append(mgr.providers, p(mgr.ctx, mgr.runtime, rt, reg))
 	}
 
 	return mgr
@@ -0,0 +1,506 @@
+package objects
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"encore.dev/storage/objects/internal/types"
+)
+
+// The operations in this file are only supported by some providers.
+// They return ErrUnsupportedByProvider for buckets whose provider doesn't.
+
+// EnsureBucket creates the bucket in the storage provider,
+// unless it already exists and is owned by the caller.
+//
+// It's intended for provisioning isolated buckets in tests and against
+// S3-compatible stores such as MinIO; buckets used by applications are
+// otherwise provisioned by Encore. It's supported by S3 buckets.
+func (b *Bucket) EnsureBucket(ctx context.Context) error {
+	p, err := optionalImpl[types.Provisioner](b)
+	if err != nil {
+		return err
+	}
+	return p.EnsureBucket(ctx)
+}
+
+// BucketExists reports whether the bucket exists in the storage provider
+// and is accessible to the caller. It's supported by S3 buckets.
+func (b *Bucket) BucketExists(ctx context.Context) (bool, error) {
+	p, err := optionalImpl[types.Provisioner](b)
+	if err != nil {
+		return false, err
+	}
+	return p.BucketExists(ctx)
+}
+
+// CleanupIncompleteUploads aborts the multipart uploads to the bucket that
+// were started more than olderThan ago and never completed, and returns
+// the number of uploads aborted. Storage providers keep the uploaded parts
+// of such uploads, and charge for storing them, until they're aborted.
+//
+// It's intended to be run periodically as a maintenance operation.
+// It's supported by S3 buckets.
+func (b *Bucket) CleanupIncompleteUploads(ctx context.Context, olderThan time.Duration) (int, error) {
+	c, err := optionalImpl[types.UploadCleaner](b)
+	if err != nil {
+		return 0, err
+	}
+	return c.CleanupIncompleteUploads(types.CleanupUploadsData{
+		Ctx:       ctx,
+		Prefix:    b.listPrefix(),
+		OlderThan: olderThan,
+	})
+}
+
+// UploadState is the state of an in-progress upload in multiple parts,
+// as persisted by an UploadStateStore. It can be serialized as JSON.
+type UploadState = types.UploadState
+
+// UploadedPart is a part of an upload that has been uploaded.
+type UploadedPart = types.UploadedPart
+
+// UploadStateStore persists the state of uploads in multiple parts;
+// see WithUploadStateStore.
+type UploadStateStore = types.UploadStateStore
+
+// ResumeUpload continues an interrupted upload from its state, as
+// persisted by the UploadStateStore passed to WithUploadStateStore.
+// The state can come from another process.
+//
+// It returns a writer along with the offset into the object's contents
+// to continue writing from. The caller must write the contents from that
+// offset onwards and close the writer to complete the upload as usual.
+// Only WithUploadStateStore is used from the options, to keep persisting
+// the state of the resumed upload. It's supported by S3 buckets.
+func (b *Bucket) ResumeUpload(ctx context.Context, state UploadState, options ...UploadOption) (w *Writer, offset int64, err error) {
+	r, err := optionalImpl[types.UploadResumer](b)
+	if err != nil {
+		return nil, 0, err
+	}
+	var opt uploadOptions
+	for _, o := range options {
+		o.applyUpload(&opt)
+	}
+
+	u, offset, err := r.ResumeUpload(types.ResumeUploadData{
+		Ctx:        ctx,
+		State:      state,
+		StateStore: opt.stateStore,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	opt.pre.NotExists = state.NotExists
+	return &Writer{
+		bkt: b,
+		ctx: ctx,
+		obj: b.fromCloudObject(types.CloudObject(state.Key)),
+		opt: opt,
+		u:   u,
+	}, offset, nil
+}
+
+// DownloadSeeker opens an object in the bucket for random access, which
+// suits file formats like zip and Parquet that are read starting from a
+// footer, without downloading the whole object. The returned reader must
+// be closed to release resources.
+//
+// Only WithVersion is used from the options. If the object is replaced
+// while it's being read, reads fail with ErrPreconditionFailed.
+// It's supported by S3 buckets.
+func (b *Bucket) DownloadSeeker(ctx context.Context, object string, options ...DownloadOption) (io.ReadSeekCloser, error) {
+	d, err := optionalImpl[types.SeekDownloader](b)
+	if err != nil {
+		return nil, err
+	}
+	var opt downloadOptions
+	for _, o := range options {
+		o.applyDownload(&opt)
+	}
+	return d.DownloadSeeker(types.DownloadSeekerData{
+		Ctx:     ctx,
+		Object:  b.toCloudObject(object),
+		Version: opt.version,
+	})
+}
+
+// DownloadWithAttrs downloads an object from the bucket along with its
+// attributes, which are read from the same response as the contents
+// rather than with a separate call to Attrs. It accepts the same options
+// as Download. If a range of the object is downloaded, the size in the
+// attributes is the size of the range. It's supported by S3 buckets.
+func (b *Bucket) DownloadWithAttrs(ctx context.Context, object string, options ...DownloadOption) (*Reader, *ObjectAttrs, error) {
+	d, err := optionalImpl[types.ResultDownloader](b)
+	if err != nil {
+		return nil, nil, err
+	}
+	var opt downloadOptions
+	for _, o := range options {
+		o.applyDownload(&opt)
+	}
+
+	res, err := d.DownloadWithResult(types.DownloadData{
+		Ctx:     ctx,
+		Object:  b.toCloudObject(object),
+		Version: opt.version,
+		Offset:  opt.offset,
+		Length:  opt.length,
+
+		Conditions: types.DownloadConditions{
+			IfNoneMatch:     opt.conditions.IfNoneMatch,
+			IfModifiedSince: opt.conditions.IfModifiedSince,
+		},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Reader{r: res.Body}, b.mapAttrs(res.Attrs), nil
+}
+
+// CopyPart is a byte range of an existing object,
+// to be copied into a new object by CopyParts.
+type CopyPart struct {
+	// Bucket is the bucket containing the object.
+	// If nil, it's the bucket being copied to.
+	Bucket *Bucket
+
+	Object  string
+	Version string // non-zero to copy a specific version
+
+	// Offset and Length specify the byte range to copy.
+	// A zero Length copies the whole object, and requires a zero Offset.
+	Offset int64
+	Length int64
+
+	// ETag, if non-empty, is the ETag the object must have,
+	// so that the range is copied from the expected contents.
+	ETag string
+}
+
+// CopyParts creates an object in the bucket by concatenating byte ranges
+// of existing objects in order. The parts are copied within the storage
+// provider, so large objects can be concatenated without downloading them.
+// The source buckets must be provided by the same provider as the bucket.
+//
+// Only WithUploadAttrs is used from the options. With S3, every part
+// except the last must be at least 5 MiB, and at most 5 GiB.
+// It's supported by S3 buckets.
+func (b *Bucket) CopyParts(ctx context.Context, object string, parts []CopyPart, options ...UploadOption) (*ObjectAttrs, error) {
+	c, err := optionalImpl[types.PartCopier](b)
+	if err != nil {
+		return nil, err
+	}
+	var opt uploadOptions
+	for _, o := range options {
+		o.applyUpload(&opt)
+	}
+
+	cloudParts := make([]types.CopyPart, len(parts))
+	for i, p := range parts {
+		src := b
+		if p.Bucket != nil {
+			src = p.Bucket
+		}
+		var srcImpl types.BucketImpl
+		if src.impl != b.impl {
+			srcImpl = src.impl
+		}
+		cloudParts[i] = types.CopyPart{
+			Bucket:  srcImpl,
+			Object:  src.toCloudObject(p.Object),
+			Version: p.Version,
+			Offset:  p.Offset,
+			Length:  p.Length,
+			ETag:    p.ETag,
+		}
+	}
+
+	attrs, err := c.CopyParts(types.CopyPartsData{
+		Ctx:    ctx,
+		Object: b.toCloudObject(object),
+		Attrs:  opt.attrs,
+		Parts:  cloudParts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b.mapAttrs(attrs), nil
+}
+
+// ListPage returns a single page of the objects in the bucket matching
+// the query, for paging through listings across requests, such as in an
+// API endpoint that returns a page of objects at a time.
+//
+// The cursor is empty for the first page, and otherwise the nextCursor
+// returned for the previous page with the same query. Cursors are opaque
+// strings that are safe to hand to clients. An empty nextCursor means
+// there are no more pages. The query's PageSize is the maximum number of
+// entries in the page, and its Limit is ignored. It's supported by S3 buckets.
+func (b *Bucket) ListPage(ctx context.Context, query *Query, cursor string) (entries []*ListEntry, nextCursor string, err error) {
+	l, err := optionalImpl[types.PageLister](b)
+	if err != nil {
+		return nil, "", err
+	}
+	page, nextCursor, err := l.ListPage(types.ListPageData{
+		Ctx:       ctx,
+		Prefix:    b.listPrefix() + query.Prefix,
+		Delimiter: query.Delimiter,
+		PageSize:  query.PageSize,
+		Cursor:    cursor,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	entries = make([]*ListEntry, len(page))
+	for i, entry := range page {
+		entries[i] = b.mapListEntry(entry)
+	}
+	return entries, nextCursor, nil
+}
+
+// UploadReaderAt uploads the first size bytes of r to an object in the
+// bucket, returning the attributes of the uploaded object. It accepts the
+// same options as Upload, except WithUploadStateStore.
+//
+// The parts of multipart uploads are read from their own ranges of r as
+// they're uploaded in parallel, rather than being copied into buffers
+// first, so r must be safe for concurrent use; *os.File is.
+// It's supported by S3 buckets.
+func (b *Bucket) UploadReaderAt(ctx context.Context, object string, r io.ReaderAt, size int64, options ...UploadOption) (*ObjectAttrs, error) {
+	u, err := optionalImpl[types.ReaderAtUploader](b)
+	if err != nil {
+		return nil, err
+	}
+	var opt uploadOptions
+	for _, o := range options {
+		o.applyUpload(&opt)
+	}
+
+	attrs, err := u.UploadReaderAt(types.UploadReaderAtData{
+		UploadData: types.UploadData{
+			Ctx:    ctx,
+			Object: b.toCloudObject(object),
+			Attrs:  opt.attrs,
+			Pre: types.Preconditions{
+				NotExists: opt.pre.NotExists,
+			},
+			PartSize: opt.partSize,
+			Progress: opt.progress,
+		},
+		Reader: r,
+		Size:   size,
+	})
+	if opt.pre.NotExists && errors.Is(err, ErrPreconditionFailed) {
+		err = ErrObjectExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b.mapAttrs(attrs), nil
+}
+
+// PurgeTrash permanently deletes the objects in the bucket that were moved
+// to the trash by soft deletes more than olderThan ago, and returns the
+// number of objects deleted. Soft deletes are enabled by the bucket's
+// infrastructure configuration; without them the trash is empty.
+//
+// It's intended to be run periodically as a maintenance operation.
+// It's supported by S3 buckets.
+func (b *Bucket) PurgeTrash(ctx context.Context, olderThan time.Duration) (int, error) {
+	p, err := optionalImpl[types.TrashPurger](b)
+	if err != nil {
+		return 0, err
+	}
+	return p.PurgeTrash(types.PurgeTrashData{
+		Ctx:       ctx,
+		Prefix:    b.listPrefix(),
+		OlderThan: olderThan,
+	})
+}
+
+// ObjectEventKind is the kind of change to an object reported by Watch.
+type ObjectEventKind = types.ObjectEventKind
+
+const (
+	ObjectCreated = types.ObjectCreated
+
+	ObjectUpdated = types.ObjectUpdated
+
+	ObjectDeleted = types.ObjectDeleted
+)
+
+// ObjectEvent is a change to an object observed by Watch.
+type ObjectEvent struct {
+	Kind ObjectEventKind
+
+	// The name of the object.
+	Name string
+
+	// Size and ETag are those of the object as last observed,
+	// which for deleted objects is before they were deleted.
+	Size int64
+	ETag string
+}
+
+// Watch reports changes to the objects in the bucket whose names start
+// with prefix. It's an alternative to event notifications from the storage
+// provider, for buckets without them set up.
+//
+// The objects are listed every interval, or every 30 seconds if it's zero,
+// and each listing is compared to the previous one. Changes are only observed
+// at that granularity: an object created and deleted between two listings
+// isn't reported. Objects that already exist when Watch is called aren't
+// reported, and errors making the first listing are returned.
+//
+// The returned channel is closed once ctx is canceled.
+// It's supported by S3 buckets.
+func (b *Bucket) Watch(ctx context.Context, prefix string, interval time.Duration) (<-chan ObjectEvent, error) {
+	w, err := optionalImpl[types.Watcher](b)
+	if err != nil {
+		return nil, err
+	}
+	cloudEvents, err := w.Watch(types.WatchData{
+		Ctx:      ctx,
+		Prefix:   b.listPrefix() + prefix,
+		Interval: interval,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan ObjectEvent)
+	go func() {
+		defer close(events)
+		for ev := range cloudEvents {
+			select {
+			case events <- ObjectEvent{
+				Kind: ev.Kind,
+				Name: b.fromCloudObject(ev.Object),
+				Size: ev.Size,
+				ETag: ev.ETag,
+			}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// Capabilities reports which optional features are available for a bucket.
+type Capabilities = types.Capabilities
+
+// Capabilities reports which optional features are available for the
+// bucket. This allows adapting to S3-compatible services like MinIO and
+// Ceph that don't implement every S3 API.
+//
+// The features are probed the first time Capabilities is called for the
+// bucket, and the result is reused after that. A feature is reported as
+// unavailable if the service responds that it isn't implemented; other
+// errors, such as from missing permissions, are returned.
+// It's supported by S3 buckets.
+func (b *Bucket) Capabilities(ctx context.Context) (Capabilities, error) {
+	p, err := optionalImpl[types.CapabilityProber](b)
+	if err != nil {
+		return Capabilities{}, err
+	}
+	return p.Capabilities(ctx)
+}
+
+// MultipartUpload identifies a multipart upload started with InitUpload.
+// It can be serialized as JSON and passed to other processes, which upload
+// parts of the object with UploadPart before one of them completes the
+// upload with CompleteUpload.
+type MultipartUpload = types.MultipartUpload
+
+// InitUpload starts a multipart upload of an object to the bucket.
+//
+// Unlike Upload, the steps of the upload can be split across processes:
+// the parts are uploaded with UploadPart, in any order and concurrently,
+// and the upload is then completed with CompleteUpload or aborted with
+// AbortUpload, using the same bucket. Uploads that are never completed
+// or aborted keep their parts, which are billed for; see
+// CleanupIncompleteUploads.
+//
+// Only WithUploadAttrs is used from the options, and the content type
+// isn't detected. It's supported by S3 buckets.
+func (b *Bucket) InitUpload(ctx context.Context, object string, options ...UploadOption) (MultipartUpload, error) {
+	p, err := optionalImpl[types.PartUploader](b)
+	if err != nil {
+		return MultipartUpload{}, err
+	}
+	var opt uploadOptions
+	for _, o := range options {
+		o.applyUpload(&opt)
+	}
+	return p.InitUpload(types.InitUploadData{
+		Ctx:    ctx,
+		Object: b.toCloudObject(object),
+		Attrs:  opt.attrs,
+	})
+}
+
+// UploadPart uploads data as the part with the given number of the upload.
+// Part numbers start at 1, and the parts make up the object in order of
+// their numbers. Uploading a part again with the same number replaces it.
+// With S3, there can be up to 10,000 parts and every part except the last
+// must be at least 5 MiB.
+//
+// The returned part must be passed to CompleteUpload.
+// It's supported by S3 buckets.
+func (b *Bucket) UploadPart(ctx context.Context, upload MultipartUpload, partNumber int32, data []byte) (UploadedPart, error) {
+	p, err := optionalImpl[types.PartUploader](b)
+	if err != nil {
+		return UploadedPart{}, err
+	}
+	return p.UploadPart(types.UploadPartData{
+		Ctx:        ctx,
+		Upload:     upload,
+		PartNumber: partNumber,
+		Data:       data,
+	})
+}
+
+// CompleteUpload completes the upload, assembling the object from the
+// given parts as returned by UploadPart. The parts may be in any order,
+// but their numbers must be unique. Parts that were uploaded but aren't
+// listed are discarded. It's supported by S3 buckets.
+func (b *Bucket) CompleteUpload(ctx context.Context, upload MultipartUpload, parts []UploadedPart) (*ObjectAttrs, error) {
+	p, err := optionalImpl[types.PartUploader](b)
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := p.CompleteUpload(types.CompleteUploadData{
+		Ctx:    ctx,
+		Upload: upload,
+		Parts:  parts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b.mapAttrs(attrs), nil
+}
+
+// AbortUpload aborts the upload, discarding the parts uploaded so far.
+// It's supported by S3 buckets.
+func (b *Bucket) AbortUpload(ctx context.Context, upload MultipartUpload) error {
+	p, err := optionalImpl[types.PartUploader](b)
+	if err != nil {
+		return err
+	}
+	return p.AbortUpload(ctx, upload)
+}
+
+// optionalImpl returns the bucket's implementation as T, an interface
+// for operations only some providers support, or ErrUnsupportedByProvider
+// if the bucket's provider doesn't implement it.
+func optionalImpl[T any](b *Bucket) (T, error) {
+	impl, ok := b.impl.(T)
+	if !ok {
+		return impl, ErrUnsupportedByProvider
+	}
+	return impl, nil
+}
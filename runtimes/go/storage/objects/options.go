@@ -37,6 +37,12 @@ func (o withVersionOption) attrsOption() {}
 //publicapigen:keep
 func (o withVersionOption) existsOption() {}
 
+//publicapigen:keep
+func (o withVersionOption) copyOption() {}
+
+//publicapigen:keep
+func (o withVersionOption) setTagsOption() {}
+
 //publicapigen:keep
 func (o withTTLOption) uploadURLOption() {}
 
@@ -47,6 +53,8 @@ func (o withVersionOption) applyDownload(opts *downloadOptions)   { opts.version
 func (o withVersionOption) applyRemove(opts *removeOptions)       { opts.version = o.version }
 func (o withVersionOption) applyAttrs(opts *attrsOptions)         { opts.version = o.version }
 func (o withVersionOption) applyExists(opts *existsOptions)       { opts.version = o.version }
+func (o withVersionOption) applyCopy(opts *copyOptions)           { opts.version = o.version }
+func (o withVersionOption) applySetTags(opts *setTagsOptions)     { opts.version = o.version }
 func (o withTTLOption) applyUploadURL(opts *uploadURLOptions)     { opts.TTL = o.TTL }
 func (o withTTLOption) applyDownloadURL(opts *downloadURLOptions) { opts.TTL = o.TTL }
 
@@ -62,9 +70,60 @@ type withTTLOption struct {
 	TTL time.Duration
 }
 
+// WithByteRange is a DownloadOption for downloading only part of an object,
+// starting at offset and spanning length bytes. A length of zero
+// downloads the rest of the object.
+func WithByteRange(offset, length int64) withByteRangeOption {
+	return withByteRangeOption{offset: offset, length: length}
+}
+
+//publicapigen:keep
+type withByteRangeOption struct {
+	offset, length int64
+}
+
+//publicapigen:keep
+func (o withByteRangeOption) downloadOption() {}
+
+func (o withByteRangeOption) applyDownload(opts *downloadOptions) {
+	opts.offset, opts.length = o.offset, o.length
+}
+
 //publicapigen:keep
 type downloadOptions struct {
-	version string
+	version        string
+	offset, length int64
+	conditions     DownloadConditions
+}
+
+// WithDownloadConditions is a DownloadOption for only downloading an object
+// if it has changed. If it hasn't, the download fails with ErrNotModified.
+func WithDownloadConditions(cond DownloadConditions) withDownloadConditionsOption {
+	return withDownloadConditionsOption{cond: cond}
+}
+
+// DownloadConditions are the available conditions for a download operation,
+// for example to avoid downloading an object that's already cached.
+// GCS doesn't support download conditions.
+type DownloadConditions struct {
+	// IfNoneMatch specifies that the object's ETag must not match the given ETag.
+	IfNoneMatch string
+
+	// IfModifiedSince specifies that the object must have been modified
+	// after the given time. It's ignored if IfNoneMatch is set.
+	IfModifiedSince time.Time
+}
+
+//publicapigen:keep
+type withDownloadConditionsOption struct {
+	cond DownloadConditions
+}
+
+//publicapigen:keep
+func (o withDownloadConditionsOption) downloadOption() {}
+
+func (o withDownloadConditionsOption) applyDownload(opts *downloadOptions) {
+	opts.conditions = o.cond
 }
 
 // UploadOption describes available options for the Upload operation.
@@ -102,6 +161,24 @@ func (o withPreconditionsOption) applyUpload(opts *uploadOptions) {
 type UploadAttrs struct {
 	// ContentType specifies the content type of the object.
 	ContentType string
+
+	// CacheControl specifies the Cache-Control header to serve the object with.
+	CacheControl string
+
+	// ContentEncoding specifies the Content-Encoding header to serve the object
+	// with, such as "gzip" for pre-compressed contents. The contents are
+	// uploaded as written; they're not compressed by the upload.
+	ContentEncoding string
+
+	// Metadata specifies custom metadata to store with the object.
+	// For S3 the keys are sent as "x-amz-meta-" headers; the prefix
+	// is added automatically.
+	Metadata map[string]string
+
+	// Tags specifies key-value tags to set on the object.
+	// S3 allows at most 10 tags per object; GCS doesn't support
+	// object tags and rejects uploads that specify them.
+	Tags map[string]string
 }
 
 // WithUploadAttrs is an UploadOption for specifying additional object attributes
@@ -120,13 +197,99 @@ func (o withUploadAttrsOption) uploadOption() {}
 
 func (o withUploadAttrsOption) applyUpload(opts *uploadOptions) {
 	opts.attrs = types.UploadAttrs{
-		ContentType: o.attrs.ContentType,
+		ContentType:     o.attrs.ContentType,
+		CacheControl:    o.attrs.CacheControl,
+		ContentEncoding: o.attrs.ContentEncoding,
+		Metadata:        o.attrs.Metadata,
+		Tags:            o.attrs.Tags,
+	}
+}
+
+//publicapigen:keep
+func (o withUploadAttrsOption) copyOption() {}
+
+func (o withUploadAttrsOption) applyCopy(opts *copyOptions) {
+	opts.attrs = &types.UploadAttrs{
+		ContentType:     o.attrs.ContentType,
+		CacheControl:    o.attrs.CacheControl,
+		ContentEncoding: o.attrs.ContentEncoding,
+		Metadata:        o.attrs.Metadata,
+		Tags:            o.attrs.Tags,
 	}
 }
 
+// WithPartSize is an UploadOption for specifying the size of each part
+// when the object is uploaded in multiple parts.
+//
+// Larger parts use more memory but require fewer requests.
+// For S3 the part size must be at least 5 MiB, and an object can consist
+// of at most 10,000 parts. If not specified, a provider default is used.
+func WithPartSize(size int64) withPartSizeOption {
+	return withPartSizeOption{size: size}
+}
+
+//publicapigen:keep
+type withPartSizeOption struct {
+	size int64
+}
+
+//publicapigen:keep
+func (o withPartSizeOption) uploadOption() {}
+
+func (o withPartSizeOption) applyUpload(opts *uploadOptions) {
+	opts.partSize = o.size
+}
+
+// WithProgress is an UploadOption for tracking the progress of an upload.
+//
+// The callback is called as data is uploaded with the number of bytes
+// uploaded so far and the total size of the object, or -1 if the total
+// size is not known. Calls are never made concurrently.
+func WithProgress(fn func(uploaded, total int64)) withProgressOption {
+	return withProgressOption{fn: fn}
+}
+
+//publicapigen:keep
+type withProgressOption struct {
+	fn func(uploaded, total int64)
+}
+
+//publicapigen:keep
+func (o withProgressOption) uploadOption() {}
+
+func (o withProgressOption) applyUpload(opts *uploadOptions) {
+	opts.progress = o.fn
+}
+
+// WithUploadStateStore is an UploadOption for persisting the state of
+// the upload to store, for providers that upload objects in multiple parts,
+// so that the upload can be continued with ResumeUpload if interrupted.
+//
+// Uploads that fail are then kept rather than aborted; use
+// CleanupIncompleteUploads to remove the ones that are never resumed.
+// It's supported by S3 buckets, and ignored by other providers.
+func WithUploadStateStore(store UploadStateStore) withUploadStateStoreOption {
+	return withUploadStateStoreOption{store: store}
+}
+
+//publicapigen:keep
+type withUploadStateStoreOption struct {
+	store UploadStateStore
+}
+
+//publicapigen:keep
+func (o withUploadStateStoreOption) uploadOption() {}
+
+func (o withUploadStateStoreOption) applyUpload(opts *uploadOptions) {
+	opts.stateStore = o.store
+}
+
 type uploadOptions struct {
-	attrs types.UploadAttrs
-	pre   Preconditions
+	attrs      types.UploadAttrs
+	pre        Preconditions
+	partSize   int64
+	progress   func(uploaded, total int64)
+	stateStore UploadStateStore
 }
 
 // ListOption describes available options for the List operation.
@@ -139,6 +302,37 @@ type ListOption interface {
 
 type listOptions struct{}
 
+// WalkOption describes available options for the Walk operation.
+type WalkOption interface {
+	//publicapigen:keep
+	walkOption()
+
+	applyWalk(*walkOptions)
+}
+
+// WithConcurrency is a WalkOption for calling the callback for up to
+// n objects in parallel, from other goroutines. The callback must then
+// be safe for concurrent use, and objects are not processed in order.
+func WithConcurrency(n int) withConcurrencyOption {
+	return withConcurrencyOption{n: n}
+}
+
+//publicapigen:keep
+type withConcurrencyOption struct {
+	n int
+}
+
+//publicapigen:keep
+func (o withConcurrencyOption) walkOption() {}
+
+func (o withConcurrencyOption) applyWalk(opts *walkOptions) {
+	opts.concurrency = o.n
+}
+
+type walkOptions struct {
+	concurrency int
+}
+
 // RemoveOption describes available options for the Remove operation.
 type RemoveOption interface {
 	//publicapigen:keep
@@ -151,6 +345,17 @@ type removeOptions struct {
 	version string
 }
 
+// RemoveAllOption describes available options for the RemoveAll operation.
+type RemoveAllOption interface {
+	//publicapigen:keep
+	removeAllOption()
+
+	applyRemoveAll(*removeAllOptions)
+}
+
+// No options yet
+type removeAllOptions struct{}
+
 // AttrsOption describes available options for the Attrs operation.
 type AttrsOption interface {
 	//publicapigen:keep
@@ -199,6 +404,50 @@ type existsOptions struct {
 	version string
 }
 
+// SetTagsOption describes available options for the SetTags operation.
+type SetTagsOption interface {
+	//publicapigen:keep
+	setTagsOption()
+
+	applySetTags(*setTagsOptions)
+}
+
+type setTagsOptions struct {
+	version string
+}
+
+// CopyOption describes available options for the Copy and Move operations.
+type CopyOption interface {
+	//publicapigen:keep
+	copyOption()
+
+	applyCopy(*copyOptions)
+}
+
+// WithDestinationBucket is a CopyOption for copying an object to another bucket.
+// Both buckets must use the same cloud provider.
+func WithDestinationBucket(dst *Bucket) withDestinationBucketOption {
+	return withDestinationBucketOption{dst: dst}
+}
+
+//publicapigen:keep
+type withDestinationBucketOption struct {
+	dst *Bucket
+}
+
+//publicapigen:keep
+func (o withDestinationBucketOption) copyOption() {}
+
+func (o withDestinationBucketOption) applyCopy(opts *copyOptions) {
+	opts.dst = o.dst
+}
+
+type copyOptions struct {
+	version string
+	dst     *Bucket
+	attrs   *types.UploadAttrs // nil means copy the source's attributes
+}
+
 // PublicURLOption describes available options for the PublicURL operation.
 type PublicURLOption interface {
 	//publicapigen:keep
@@ -6,11 +6,13 @@ import (
 	"context"
 
 	"encore.dev/appruntime/exported/config"
+	"encore.dev/appruntime/shared/reqtrack"
+	"encore.dev/metrics"
 	"encore.dev/storage/objects/internal/providers/gcs"
 )
 
 func init() {
-	registerProvider(func(ctx context.Context, runtimeCfg *config.Runtime) provider {
+	registerProvider(func(ctx context.Context, runtimeCfg *config.Runtime, rt *reqtrack.RequestTracker, reg *metrics.Registry) provider {
 		return gcs.NewManager(ctx, runtimeCfg)
 	})
 }
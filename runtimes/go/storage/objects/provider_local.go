@@ -0,0 +1,16 @@
+package objects
+
+import (
+	"context"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/appruntime/shared/reqtrack"
+	"encore.dev/metrics"
+	"encore.dev/storage/objects/internal/providers/local"
+)
+
+func init() {
+	registerProvider(func(ctx context.Context, runtimeCfg *config.Runtime, rt *reqtrack.RequestTracker, reg *metrics.Registry) provider {
+		return local.NewManager(ctx, runtimeCfg)
+	})
+}
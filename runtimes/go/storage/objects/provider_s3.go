@@ -6,11 +6,13 @@ import (
 	"context"
 
 	"encore.dev/appruntime/exported/config"
+	"encore.dev/appruntime/shared/reqtrack"
+	"encore.dev/metrics"
 	"encore.dev/storage/objects/internal/providers/s3"
 )
 
 func init() {
-	registerProvider(func(ctx context.Context, runtimeCfg *config.Runtime) provider {
-		return s3.NewManager(ctx, runtimeCfg)
+	registerProvider(func(ctx context.Context, runtimeCfg *config.Runtime, rt *reqtrack.RequestTracker, reg *metrics.Registry) provider {
+		return s3.NewManager(ctx, runtimeCfg, rt, reg)
 	})
 }
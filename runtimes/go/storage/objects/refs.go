@@ -2,8 +2,10 @@ package objects
 
 import (
 	"context"
+	"io"
 	"iter"
 	"net/url"
+	"time"
 )
 
 // BucketPerms is the type constraint for all permission-declaring
@@ -22,6 +24,9 @@ type ReadWriter interface {
 	Remover
 	Lister
 	Attrser
+	Copier
+	Mover
+	Tagger
 }
 
 // Uploader is the interface for uploading objects to a bucket.
@@ -40,6 +45,27 @@ type Uploader interface {
 	// Upload begins uploading an object to the bucket.
 	Upload(ctx context.Context, object string, options ...UploadOption) *Writer
 
+	// UploadFromFile uploads the file at path to an object in the bucket.
+	UploadFromFile(ctx context.Context, object, path string, options ...UploadOption) (*ObjectAttrs, error)
+
+	// UploadReaderAt uploads the first size bytes of r to an object in the bucket.
+	UploadReaderAt(ctx context.Context, object string, r io.ReaderAt, size int64, options ...UploadOption) (*ObjectAttrs, error)
+
+	// ResumeUpload continues an interrupted upload from its state.
+	ResumeUpload(ctx context.Context, state UploadState, options ...UploadOption) (w *Writer, offset int64, err error)
+
+	// InitUpload starts a multipart upload of an object to the bucket.
+	InitUpload(ctx context.Context, object string, options ...UploadOption) (MultipartUpload, error)
+
+	// UploadPart uploads a part of a multipart upload.
+	UploadPart(ctx context.Context, upload MultipartUpload, partNumber int32, data []byte) (UploadedPart, error)
+
+	// CompleteUpload completes a multipart upload from its parts.
+	CompleteUpload(ctx context.Context, upload MultipartUpload, parts []UploadedPart) (*ObjectAttrs, error)
+
+	// AbortUpload aborts a multipart upload.
+	AbortUpload(ctx context.Context, upload MultipartUpload) error
+
 	perms()
 }
 
@@ -79,6 +105,15 @@ type Downloader interface {
 	// Download downloads an object from the bucket.
 	Download(ctx context.Context, object string, options ...DownloadOption) *Reader
 
+	// DownloadToFile downloads an object from the bucket to the file at path.
+	DownloadToFile(ctx context.Context, object, path string, options ...DownloadOption) error
+
+	// DownloadWithAttrs downloads an object from the bucket along with its attributes.
+	DownloadWithAttrs(ctx context.Context, object string, options ...DownloadOption) (*Reader, *ObjectAttrs, error)
+
+	// DownloadSeeker opens an object in the bucket for random access.
+	DownloadSeeker(ctx context.Context, object string, options ...DownloadOption) (io.ReadSeekCloser, error)
+
 	perms()
 }
 
@@ -119,6 +154,15 @@ type Lister interface {
 	// List lists objects in the bucket.
 	List(ctx context.Context, query *Query, options ...ListOption) iter.Seq2[*ListEntry, error]
 
+	// Walk calls fn for each object in the bucket whose name starts with prefix.
+	Walk(ctx context.Context, prefix string, fn func(*ListEntry) error, options ...WalkOption) error
+
+	// ListPage returns a single page of the objects in the bucket.
+	ListPage(ctx context.Context, query *Query, cursor string) (entries []*ListEntry, nextCursor string, err error)
+
+	// Watch reports changes to the objects in the bucket.
+	Watch(ctx context.Context, prefix string, interval time.Duration) (<-chan ObjectEvent, error)
+
 	perms()
 }
 
@@ -138,6 +182,9 @@ type Remover interface {
 	// Remove removes an object from the bucket.
 	Remove(ctx context.Context, object string, options ...RemoveOption) error
 
+	// RemoveAll removes several objects from the bucket.
+	RemoveAll(ctx context.Context, objects []string, options ...RemoveAllOption) ([]RemoveResult, error)
+
 	perms()
 }
 
@@ -182,6 +229,95 @@ type PublicURLer interface {
 	perms()
 }
 
+// Copier is the interface for copying objects within a bucket,
+// or to other buckets. It can be used in conjunction with [BucketRef]
+// to declare a reference that can copy objects in the bucket.
+//
+// For example:
+//
+//	var MyBucket = objects.NewBucket(...)
+//	var ref = objects.BucketRef[objects.Copier](MyBucket)
+type Copier interface {
+	// Copy copies an object to dst.
+	Copy(ctx context.Context, src, dst string, options ...CopyOption) (*ObjectAttrs, error)
+
+	// CopyParts creates an object by concatenating byte ranges of existing objects.
+	CopyParts(ctx context.Context, object string, parts []CopyPart, options ...UploadOption) (*ObjectAttrs, error)
+
+	perms()
+}
+
+// Mover is the interface for moving objects within a bucket,
+// or to other buckets. It can be used in conjunction with [BucketRef]
+// to declare a reference that can move objects in the bucket.
+//
+// For example:
+//
+//	var MyBucket = objects.NewBucket(...)
+//	var ref = objects.BucketRef[objects.Mover](MyBucket)
+type Mover interface {
+	// Move moves an object to dst.
+	Move(ctx context.Context, src, dst string, options ...CopyOption) (*ObjectAttrs, error)
+
+	perms()
+}
+
+// Tagger is the interface for setting the tags of objects in a bucket.
+// It can be used in conjunction with [BucketRef] to declare
+// a reference that can tag objects in the bucket.
+//
+// For example:
+//
+//	var MyBucket = objects.NewBucket(...)
+//	var ref = objects.BucketRef[objects.Tagger](MyBucket)
+type Tagger interface {
+	// SetTags replaces the tags of an object.
+	SetTags(ctx context.Context, object string, tags map[string]string, options ...SetTagsOption) error
+
+	perms()
+}
+
+// Maintainer is the interface for maintenance operations on a bucket,
+// such as cleaning up incomplete uploads and purging soft-deleted objects.
+// It can be used in conjunction with [BucketRef] to declare
+// a reference that can maintain the bucket.
+//
+// For example:
+//
+//	var MyBucket = objects.NewBucket(...)
+//	var ref = objects.BucketRef[objects.Maintainer](MyBucket)
+type Maintainer interface {
+	// CleanupIncompleteUploads aborts incomplete multipart uploads.
+	CleanupIncompleteUploads(ctx context.Context, olderThan time.Duration) (int, error)
+
+	// PurgeTrash permanently deletes soft-deleted objects.
+	PurgeTrash(ctx context.Context, olderThan time.Duration) (int, error)
+
+	perms()
+}
+
+// Provisioner is the interface for checking the bucket itself,
+// and creating it in the storage provider. It can be used in
+// conjunction with [BucketRef] to declare a reference that
+// can provision the bucket.
+//
+// For example:
+//
+//	var MyBucket = objects.NewBucket(...)
+//	var ref = objects.BucketRef[objects.Provisioner](MyBucket)
+type Provisioner interface {
+	// EnsureBucket creates the bucket unless it already exists.
+	EnsureBucket(ctx context.Context) error
+
+	// BucketExists reports whether the bucket exists.
+	BucketExists(ctx context.Context) (bool, error)
+
+	// Capabilities reports which optional features are available for the bucket.
+	Capabilities(ctx context.Context) (Capabilities, error)
+
+	perms()
+}
+
 // BucketRef returns an interface reference to a bucket,
 // that can be freely passed around within a service
 // without being subject to Encore's typical static analysis
@@ -212,3 +348,10 @@ type bucketRef struct {
 }
 
 func (r bucketRef) perms() {}
+
+var (
+	_ ReadWriter  = bucketRef{}
+	_ PublicURLer = bucketRef{}
+	_ Maintainer  = bucketRef{}
+	_ Provisioner = bucketRef{}
+)
@@ -4,6 +4,8 @@ import (
 	"context"
 
 	"encore.dev/appruntime/exported/config"
+	"encore.dev/appruntime/shared/reqtrack"
+	"encore.dev/metrics"
 	"encore.dev/storage/objects/internal/types"
 )
 
@@ -13,8 +15,8 @@ type provider interface {
 	NewBucket(providerCfg *config.BucketProvider, runtimeCfg *config.Bucket) types.BucketImpl
 }
 
-var providerRegistry []func(context.Context, *config.Runtime) provider
+var providerRegistry []func(context.Context, *config.Runtime, *reqtrack.RequestTracker, *metrics.Registry) provider
 
-func registerProvider(p func(context.Context, *config.Runtime) provider) {
+func registerProvider(p func(context.Context, *config.Runtime, *reqtrack.RequestTracker, *metrics.Registry) provider) {
 	providerRegistry = append(providerRegistry, p)
 }
@@ -0,0 +1,40 @@
+package objects
+
+import (
+	"strings"
+
+	"encore.dev/storage/objects/internal/providers/memory"
+)
+
+// GetTestBucketInstance is an internal API for Encore. This function should
+// never be directly called as it is considered an unstable API and Encore
+// can change it at any time
+func GetTestBucketInstance(bkt *Bucket) any {
+	mem, ok := bkt.impl.(*memory.Bucket)
+	if !ok {
+		panic("GetTestBucketInstance not called with an in-memory bucket; enable the memory-buckets experiment")
+	}
+	return &testBucket{bkt: bkt, mem: mem}
+}
+
+// testBucket provides access to the objects of an in-memory bucket
+// stored by the current test.
+type testBucket struct {
+	bkt *Bucket
+	mem *memory.Bucket
+}
+
+func (t *testBucket) Seed(object string, data []byte) {
+	t.mem.Seed(string(t.bkt.toCloudObject(object)), data)
+}
+
+func (t *testBucket) Dump() map[string][]byte {
+	prefix := t.bkt.cloudPrefix()
+	dump := make(map[string][]byte)
+	for name, data := range t.mem.Dump() {
+		if object, ok := strings.CutPrefix(name, prefix); ok {
+			dump[object] = data
+		}
+	}
+	return dump
+}
@@ -8,6 +8,7 @@ import (
 	"encore.dev/appruntime/shared/reqtrack"
 	"encore.dev/appruntime/shared/shutdown"
 	"encore.dev/appruntime/shared/testsupport"
+	"encore.dev/metrics"
 )
 
 // Initialize the singleton instance.
@@ -20,6 +21,6 @@ var Singleton *Manager
 
 func init() {
 	Singleton = NewManager(appconf.Static, appconf.Runtime, reqtrack.Singleton,
-		testsupport.Singleton, logging.RootLogger)
+		testsupport.Singleton, logging.RootLogger, metrics.Singleton)
 	shutdown.Singleton.RegisterShutdownHandler(Singleton.Shutdown)
 }
@@ -344,7 +344,7 @@ func (b *builder) Build() *meta.Data {
 				switch u := u.(type) {
 				case *objects.MethodUsage:
 					if svc, ok := b.app.ServiceForPath(u.DeclaredIn().FSPath); ok {
-						addPerms(svc.Name, u.Perm)
+						addPerms(svc.Name, u.Perms...)
 					}
 				case *objects.RefUsage:
 					if svc, ok := b.app.ServiceForPath(u.DeclaredIn().FSPath); ok {
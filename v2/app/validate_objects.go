@@ -38,7 +38,7 @@ func (d *Desc) validateObjects(pc *parsectx.Context, result *parser.Result) {
 					}
 
 				case *objects.MethodUsage:
-					if use.Perm == objects.GetPublicURL && !res.Public {
+					if use.HasPerm(objects.GetPublicURL) && !res.Public {
 						pc.Errs.Add(objects.ErrBucketNotPublic.
 							AtGoNode(use, errors.AsError("used here")))
 					}
@@ -49,7 +49,10 @@ func GenAppConfig(p GenParams, test option.Option[testParams]) *config.Static {
 		TestAppRootPath:    rootDir,
 		BundledServices:    bundledServices(p.Desc),
 		EnabledExperiments: p.Gen.Build.Experiments.StringList(),
+		ServiceExperiments: p.Gen.Build.Experiments.ServiceOverrides(),
 		EmbeddedEnvs:       make(map[string]string),
+
+		ExperimentsFingerprint: p.Gen.Build.Experiments.Fingerprint(),
 	}
 
 	if test, ok := test.Get(); ok {
@@ -16,7 +16,11 @@ import (
 type MethodUsage struct {
 	usage.Base
 	Method string
-	Perm   Perm
+	Perms  []Perm
+}
+
+func (u *MethodUsage) HasPerm(perm Perm) bool {
+	return slices.Contains(u.Perms, perm)
 }
 
 type RefUsage struct {
@@ -72,32 +76,61 @@ func (p Perm) ToMeta() (meta.BucketUsage_Operation, bool) {
 	}
 }
 
+// readWritePerms are the permissions of the ReadWriter permission interface.
+var readWritePerms = []Perm{
+	WriteObject, ReadObjectContents, ListObjects, DeleteObject,
+	GetObjectMetadata, SignedUploadURL, SignedDownloadURL, UpdateObjectMetadata,
+}
+
 func ResolveBucketUsage(data usage.ResolveData, bkt *Bucket) usage.Usage {
 	switch expr := data.Expr.(type) {
 	case *usage.MethodCall:
-		var perm Perm
+		var perms []Perm
 		switch expr.Method {
-		case "Upload":
-			perm = WriteObject
-		case "Download":
-			perm = ReadObjectContents
-		case "List":
-			perm = ListObjects
-		case "Remove":
-			perm = DeleteObject
-		case "PublicURL":
-			perm = GetPublicURL
-			if !bkt.Public {
-
+		case "Upload", "UploadFromFile", "UploadReaderAt", "ResumeUpload",
+			"InitUpload", "UploadPart", "CompleteUpload", "AbortUpload":
+			perms = []Perm{WriteObject}
+		case "Download", "DownloadToFile", "DownloadSeeker", "DownloadWithAttrs":
+			perms = []Perm{ReadObjectContents}
+		case "List", "Walk", "ListPage", "Watch":
+			perms = []Perm{ListObjects}
+		case "Remove", "RemoveAll":
+			perms = []Perm{DeleteObject}
+		case "Attrs", "Exists":
+			perms = []Perm{GetObjectMetadata}
+		case "SetTags":
+			perms = []Perm{UpdateObjectMetadata}
+		case "Copy", "CopyParts":
+			perms = []Perm{ReadObjectContents, WriteObject}
+		case "Move":
+			perms = []Perm{ReadObjectContents, WriteObject, DeleteObject}
+		case "PurgeTrash":
+			perms = []Perm{ListObjects, DeleteObject}
+		case "CleanupIncompleteUploads":
+			perms = []Perm{ListObjects, WriteObject}
+		case "EnsureBucket", "BucketExists", "Capabilities":
+			perms = []Perm{ListObjects, GetObjectMetadata}
+		case "Sub":
+			// The returned bucket can be used for any operation,
+			// which the parser can't follow.
+			perms = slices.Clone(readWritePerms)
+			if bkt.Public {
+				perms = append(perms, GetPublicURL)
 			}
+		case "PublicURL":
+			perms = []Perm{GetPublicURL}
 		case "SignedUploadURL":
-			perm = SignedUploadURL
+			perms = []Perm{SignedUploadURL}
 		case "SignedDownloadURL":
-			perm = SignedDownloadURL
+			perms = []Perm{SignedDownloadURL}
 		default:
 			return nil
 		}
 
+		// Sort and de-dup the perms.
+		slices.Sort(perms)
+		perms = slices.Compact(perms)
+
 		return &MethodUsage{
 			Base: usage.Base{
 				File: expr.File,
@@ -105,7 +138,7 @@ func ResolveBucketUsage(data usage.ResolveData, bkt *Bucket) usage.Usage {
 				Expr: expr,
 			},
 			Method: expr.Method,
-			Perm:   perm,
+			Perms:  perms,
 		}
 
 	case *usage.FuncArg:
@@ -149,10 +182,18 @@ func parseBucketRef(errs *perr.List, expr *usage.FuncArg) usage.Usage {
 				perms = append(perms, GetObjectMetadata)
 			case isNamed(typ, "PublicURLer"):
 				perms = append(perms, GetPublicURL)
+			case isNamed(typ, "Copier"):
+				perms = append(perms, ReadObjectContents, WriteObject)
+			case isNamed(typ, "Mover"):
+				perms = append(perms, ReadObjectContents, WriteObject, DeleteObject)
+			case isNamed(typ, "Tagger"):
+				perms = append(perms, UpdateObjectMetadata)
+			case isNamed(typ, "Maintainer"):
+				perms = append(perms, ListObjects, WriteObject, DeleteObject)
+			case isNamed(typ, "Provisioner"):
+				perms = append(perms, ListObjects, GetObjectMetadata)
 			case isNamed(typ, "ReadWriter"):
-				perms = append(perms,
-					WriteObject, ReadObjectContents, ListObjects, DeleteObject,
-					GetObjectMetadata, SignedUploadURL, SignedDownloadURL, UpdateObjectMetadata)
+				perms = append(perms, readWritePerms...)
 			default:
 				return nil, false
 			}
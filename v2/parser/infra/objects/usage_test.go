@@ -26,7 +26,7 @@ var bkt = objects.NewBucket("bucket", objects.BucketConfig{})
 func Foo() { bkt.Upload(context.Background(), "key") }
 
 `,
-			Want: []usage.Usage{&objects.MethodUsage{Method: "Upload", Perm: objects.WriteObject}},
+			Want: []usage.Usage{&objects.MethodUsage{Method: "Upload", Perms: []objects.Perm{objects.WriteObject}}},
 		},
 		{
 			Name: "sign_upload_url",
@@ -36,7 +36,7 @@ var bkt = objects.NewBucket("bucket", objects.BucketConfig{})
 func Foo() { bkt.SignedUploadURL(context.Background(), "key") }
 
 `,
-			Want: []usage.Usage{&objects.MethodUsage{Method: "SignedUploadURL", Perm: objects.SignedUploadURL}},
+			Want: []usage.Usage{&objects.MethodUsage{Method: "SignedUploadURL", Perms: []objects.Perm{objects.SignedUploadURL}}},
 		},
 		{
 			Name: "sign_download_url",
@@ -46,7 +46,134 @@ var bkt = objects.NewBucket("bucket", objects.BucketConfig{})
 func Foo() { bkt.SignedDownloadURL(context.Background(), "key") }
 
 `,
-			Want: []usage.Usage{&objects.MethodUsage{Method: "SignedDownloadURL", Perm: objects.SignedDownloadURL}},
+			Want: []usage.Usage{&objects.MethodUsage{Method: "SignedDownloadURL", Perms: []objects.Perm{objects.SignedDownloadURL}}},
+		},
+		{
+			Name: "copy",
+			Code: `
+var bkt = objects.NewBucket("bucket", objects.BucketConfig{})
+
+func Foo() { bkt.Copy(context.Background(), "src", "dst") }
+
+`,
+			Want: []usage.Usage{&objects.MethodUsage{Method: "Copy", Perms: []objects.Perm{
+				objects.ReadObjectContents, objects.WriteObject,
+			}}},
+		},
+		{
+			Name: "move",
+			Code: `
+var bkt = objects.NewBucket("bucket", objects.BucketConfig{})
+
+func Foo() { bkt.Move(context.Background(), "src", "dst") }
+
+`,
+			Want: []usage.Usage{&objects.MethodUsage{Method: "Move", Perms: []objects.Perm{
+				objects.DeleteObject, objects.ReadObjectContents, objects.WriteObject,
+			}}},
+		},
+		{
+			Name: "remove_all",
+			Code: `
+var bkt = objects.NewBucket("bucket", objects.BucketConfig{})
+
+func Foo() { bkt.RemoveAll(context.Background(), []string{"a", "b"}) }
+
+`,
+			Want: []usage.Usage{&objects.MethodUsage{Method: "RemoveAll", Perms: []objects.Perm{objects.DeleteObject}}},
+		},
+		{
+			Name: "set_tags",
+			Code: `
+var bkt = objects.NewBucket("bucket", objects.BucketConfig{})
+
+func Foo() { bkt.SetTags(context.Background(), "key", nil) }
+
+`,
+			Want: []usage.Usage{&objects.MethodUsage{Method: "SetTags", Perms: []objects.Perm{objects.UpdateObjectMetadata}}},
+		},
+		{
+			Name: "files",
+			Code: `
+var bkt = objects.NewBucket("bucket", objects.BucketConfig{})
+
+func Foo() {
+	bkt.DownloadToFile(context.Background(), "key", "path")
+	bkt.UploadFromFile(context.Background(), "key", "path")
+}
+
+`,
+			Want: []usage.Usage{
+				&objects.MethodUsage{Method: "DownloadToFile", Perms: []objects.Perm{objects.ReadObjectContents}},
+				&objects.MethodUsage{Method: "UploadFromFile", Perms: []objects.Perm{objects.WriteObject}},
+			},
+		},
+		{
+			Name: "walk",
+			Code: `
+var bkt = objects.NewBucket("bucket", objects.BucketConfig{})
+
+func Foo() { bkt.Walk(context.Background(), "", nil) }
+
+`,
+			Want: []usage.Usage{&objects.MethodUsage{Method: "Walk", Perms: []objects.Perm{objects.ListObjects}}},
+		},
+		{
+			Name: "optional",
+			Code: `
+var bkt = objects.NewBucket("bucket", objects.BucketConfig{})
+
+func Foo() {
+	bkt.ListPage(context.Background(), &objects.Query{}, "")
+	bkt.PurgeTrash(context.Background(), 0)
+	bkt.CopyParts(context.Background(), "key", nil)
+	bkt.Capabilities(context.Background())
+}
+
+`,
+			Want: []usage.Usage{
+				&objects.MethodUsage{Method: "ListPage", Perms: []objects.Perm{objects.ListObjects}},
+				&objects.MethodUsage{Method: "PurgeTrash", Perms: []objects.Perm{objects.DeleteObject, objects.ListObjects}},
+				&objects.MethodUsage{Method: "CopyParts", Perms: []objects.Perm{objects.ReadObjectContents, objects.WriteObject}},
+				&objects.MethodUsage{Method: "Capabilities", Perms: []objects.Perm{objects.GetObjectMetadata, objects.ListObjects}},
+			},
+		},
+		{
+			Name: "sub",
+			Code: `
+var bkt = objects.NewBucket("bucket", objects.BucketConfig{})
+
+func Foo() { bkt.Sub("dir/").Remove(context.Background(), "key") }
+
+`,
+			Want: []usage.Usage{&objects.MethodUsage{Method: "Sub", Perms: []objects.Perm{
+				objects.DeleteObject,
+				objects.GetObjectMetadata,
+				objects.ListObjects,
+				objects.ReadObjectContents,
+				objects.SignedDownloadURL,
+				objects.SignedUploadURL,
+				objects.UpdateObjectMetadata,
+				objects.WriteObject,
+			}}},
+		},
+		{
+			Name: "ref_mover",
+			Code: `
+var bkt = objects.NewBucket("bucket", objects.BucketConfig{})
+
+type MyRef interface { objects.Mover; objects.Tagger }
+
+var ref = objects.BucketRef[MyRef](bkt)
+`,
+			Want: []usage.Usage{&objects.RefUsage{
+				Perms: []objects.Perm{
+					objects.DeleteObject,
+					objects.ReadObjectContents,
+					objects.UpdateObjectMetadata,
+					objects.WriteObject,
+				},
+			}},
 		},
 		{
 			Name: "ref",